@@ -2,17 +2,27 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
 	"strings"
 	"syscall"
 	"time"
+	// Redirect rules name IANA zones; the alpine image ships no zoneinfo.
+	_ "time/tzdata"
 
+	"click-service/internal/auth"
 	"click-service/internal/httpapi"
-	"click-service/internal/middleware"
+	"click-service/internal/ingest"
+	"click-service/internal/live"
+	"click-service/internal/qrcache"
 	"click-service/internal/qrclient"
+	"click-service/internal/ratelimit"
+	"click-service/internal/report"
+	"click-service/internal/scanhook"
 	"click-service/internal/store"
 )
 
@@ -22,6 +32,18 @@ func main() {
 	qrBaseURL := envOr("QR_SERVICE_BASE_URL", "http://localhost:8080")
 	databaseURL := strings.TrimSpace(os.Getenv("DATABASE_URL"))
 
+	region := envOr("AWS_REGION", "us-east-1")
+	userPoolID := envOr("COGNITO_USER_POOL_ID", "")
+	clientID := envOr("COGNITO_CLIENT_ID", "")
+	issuer := envOr("COGNITO_ISSUER", "")
+	if issuer == "" && userPoolID != "" {
+		issuer = fmt.Sprintf("https://cognito-idp.%s.amazonaws.com/%s", region, userPoolID)
+	}
+	jwksURL := envOr("COGNITO_JWKS_URL", "")
+	if jwksURL == "" && issuer != "" {
+		jwksURL = issuer + "/.well-known/jwks.json"
+	}
+
 	ctx := context.Background()
 
 	var st store.Store
@@ -40,8 +62,106 @@ func main() {
 		log.Printf("click-service using in-memory storage (set DATABASE_URL to persist)")
 	}
 	qr := qrclient.New(qrBaseURL)
+	qr.InternalKey = envOr("INTERNAL_API_KEY", "")
+	qr.Retry.Attempts = envInt("QR_CLIENT_ATTEMPTS", qr.Retry.Attempts)
+	qr.Breaker = qrclient.NewBreaker(envInt("QR_CLIENT_BREAKER_THRESHOLD", qr.Breaker.Threshold), envDuration("QR_CLIENT_BREAKER_COOLDOWN", qr.Breaker.Cooldown))
+	if qr.InternalKey == "" {
+		log.Fatalf("INTERNAL_API_KEY is required: qr-service only resolves codes for callers holding it")
+	}
+
+	// Scans read codes through a cache so a qr-service blip does not break
+	// printed codes; edits reach scanners within QR_CACHE_TTL.
+	cacheOpts := qrcache.DefaultOptions()
+	cacheOpts.TTL = envDuration("QR_CACHE_TTL", cacheOpts.TTL)
+	cacheOpts.StaleTTL = envDuration("QR_CACHE_STALE_TTL", cacheOpts.StaleTTL)
+	cached := qrcache.New(qr, cacheOpts)
+
+	ingestOpts := ingest.DefaultOptions()
+	ingestOpts.Workers = envInt("CLICK_INGEST_WORKERS", ingestOpts.Workers)
+	ingestOpts.QueueSize = envInt("CLICK_INGEST_QUEUE_SIZE", ingestOpts.QueueSize)
+	ingestOpts.BatchSize = envInt("CLICK_INGEST_BATCH_SIZE", ingestOpts.BatchSize)
+	ingestOpts.SpoolDir = envOr("CLICK_SPOOL_DIR", "")
+	ingestOpts.Counter = qr
+	clicks, err := ingest.New(st, ingestOpts)
+	if err != nil {
+		log.Fatalf("click ingest init failed: %v", err)
+	}
+	if ingestOpts.SpoolDir == "" {
+		log.Printf("CLICK_SPOOL_DIR not set; clicks that fail to write will be dropped")
+	}
+
+	// Raw events carry IPs, so they are only kept for a limited time.
+	retentionCtx, stopRetention := context.WithCancel(ctx)
+	defer stopRetention()
+	retentionDays := envInt("CLICK_EVENT_RETENTION_DAYS", 90)
+	if retentionDays <= 0 {
+		log.Fatalf("CLICK_EVENT_RETENTION_DAYS must be at least 1, got %d", retentionDays)
+	}
+	go store.RunEventRetention(retentionCtx, st, time.Duration(retentionDays)*24*time.Hour, time.Hour)
+
+	// Weekly reports go to owners who set a report email in qr-service.
+	reportsCtx, stopReports := context.WithCancel(ctx)
+	defer stopReports()
+	var mailer report.Mailer = report.LogMailer{}
+	if smtpAddr := envOr("SMTP_ADDR", ""); smtpAddr != "" {
+		mailer = &report.SMTPMailer{Addr: smtpAddr, From: envOr("SMTP_FROM", ""), Username: envOr("SMTP_USERNAME", ""), Password: envOr("SMTP_PASSWORD", "")}
+	} else {
+		log.Printf("SMTP_ADDR not set; weekly reports will only be logged")
+	}
+	if qr.InternalKey != "" {
+		reports := report.New(st, qr, mailer)
+		reports.Hour = envInt("REPORT_HOUR", reports.Hour)
+		go reports.Run(reportsCtx, envDuration("REPORT_INTERVAL", 15*time.Minute))
+	}
+
+	adminKey := envOr("ADMIN_API_KEY", "")
+	if adminKey == "" {
+		log.Printf("ADMIN_API_KEY not set; /api/clicks/events is disabled")
+	}
+
+	var verifier *auth.Verifier
+	if jwksURL != "" {
+		verifier = &auth.Verifier{Keys: auth.NewJWKS(jwksURL), Issuer: issuer, ClientID: clientID, Leeway: 30 * time.Second}
+		log.Printf("click-service verifying tokens against %s", jwksURL)
+	} else {
+		log.Printf("click-service auth not configured (set COGNITO_USER_POOL_ID or COGNITO_JWKS_URL); /api/clicks/stream will answer 401")
+	}
+	var apiKeys *auth.APIKeys
+	if userServiceURL := envOr("USER_SERVICE_BASE_URL", ""); userServiceURL != "" && qr.InternalKey != "" {
+		apiKeys = auth.NewAPIKeys(userServiceURL, qr.InternalKey)
+		log.Printf("click-service verifying API keys with %s", userServiceURL)
+	} else {
+		log.Printf("API keys not accepted (set USER_SERVICE_BASE_URL and INTERNAL_API_KEY)")
+	}
+	hub := live.NewHub(envInt("LIVE_MAX_STREAMS_PER_USER", live.DefaultMaxPerUser))
+
+	// qr.scanned webhooks are delivered by qr-service; scans of subscribed
+	// codes are handed over in batches.
+	var scanHooks *scanhook.Forwarder
+	if qr.InternalKey != "" {
+		scanHooks = scanhook.New(qr, scanhook.DefaultOptions())
+	}
+
+	// Scanners are metered per IP; dashboard users and API keys per caller on
+	// their plan.
+	limits, err := ratelimit.ParseLimits(envOr("RATE_LIMITS", ""), map[string]ratelimit.Limit{
+		ratelimit.AnonymousPlan: ratelimit.PerMinute(500),
+		"free":                  ratelimit.PerMinute(600),
+		"basic":                 ratelimit.PerMinute(2400),
+		"enterprise":            ratelimit.PerMinute(12000),
+		"admin":                 {},
+	})
+	if err != nil {
+		log.Fatalf("RATE_LIMITS: %v", err)
+	}
+	limitStore, err := ratelimit.Open(ctx, envOr("RATE_LIMIT_BACKEND", ""), envOr("REDIS_URL", ""), databaseURL)
+	if err != nil {
+		log.Fatalf("rate limit store init failed: %v", err)
+	}
+	defer func() { _ = limitStore.Close() }()
+	limiter := &ratelimit.Limiter{Store: limitStore, Name: "click", Limits: limits}
 
-	router := httpapi.NewRouter(httpapi.Server{Store: st, QrClient: qr})
+	router := httpapi.NewRouter(httpapi.Server{Store: st, QrClient: cached, ClickCounter: qr, Tags: qr, Clicks: clicks, AdminAPIKey: adminKey, Auth: verifier, APIKeys: apiKeys, Live: hub, ScanHooks: scanHooks, RateLimit: limiter})
 
 	// Apply middleware layers (order matters!)
 	var handler http.Handler = router
@@ -49,13 +169,10 @@ func main() {
 	// 1. CORS (outermost)
 	handler = httpapi.NewCorsMiddleware(httpapi.CorsOptions{
 		AllowedOrigins:   allowedOrigins,
+		AllowedHeaders:   []string{"Content-Type", "Authorization", "X-API-Key"},
 		AllowCredentials: true,
 	})(handler)
 
-	// 2. Rate limiting (500 requests per minute per IP for click tracking)
-	rateLimiter := middleware.NewRateLimiter(500, time.Minute)
-	handler = rateLimiter.Middleware(handler)
-
 	srv := &http.Server{
 		Addr:              ":" + port,
 		Handler:           handler,
@@ -64,6 +181,8 @@ func main() {
 		WriteTimeout:      15 * time.Second,
 		IdleTimeout:       60 * time.Second,
 	}
+	// Live streams never go idle, so end them as soon as shutdown starts.
+	srv.RegisterOnShutdown(hub.Close)
 
 	go func() {
 		log.Printf("click-service listening on http://localhost:%s", port)
@@ -75,11 +194,23 @@ func main() {
 	stop := make(chan os.Signal, 1)
 	signal.Notify(stop, syscall.SIGINT, syscall.SIGTERM)
 	<-stop
-	closeStore()
 
+	// Stop taking requests first, then drain queued clicks while the store is
+	// still open.
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 	_ = srv.Shutdown(ctx)
+	stopRetention()
+	stopReports()
+	if err := clicks.Close(ctx); err != nil {
+		log.Printf("click ingest drain incomplete: %v", err)
+	}
+	if scanHooks != nil {
+		if err := scanHooks.Close(ctx); err != nil {
+			log.Printf("scan webhook drain incomplete: %v", err)
+		}
+	}
+	closeStore()
 }
 
 func envOr(key, fallback string) string {
@@ -90,6 +221,32 @@ func envOr(key, fallback string) string {
 	return v
 }
 
+func envInt(key string, fallback int) int {
+	raw := strings.TrimSpace(os.Getenv(key))
+	if raw == "" {
+		return fallback
+	}
+	n, err := strconv.Atoi(raw)
+	if err != nil {
+		log.Printf("ignoring invalid %s=%q: %v", key, raw, err)
+		return fallback
+	}
+	return n
+}
+
+func envDuration(key string, fallback time.Duration) time.Duration {
+	raw := strings.TrimSpace(os.Getenv(key))
+	if raw == "" {
+		return fallback
+	}
+	d, err := time.ParseDuration(raw)
+	if err != nil {
+		log.Printf("ignoring invalid %s=%q: %v", key, raw, err)
+		return fallback
+	}
+	return d
+}
+
 func splitCSV(raw string) []string {
 	parts := strings.Split(raw, ",")
 	out := make([]string, 0, len(parts))
@@ -0,0 +1,44 @@
+package auth
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestAPIKeys_CacheDropsLeastRecentlyUsed(t *testing.T) {
+	k := &APIKeys{TTL: time.Minute}
+	k.store("qrk_busy", Identity{UserID: "user-1", APIKeyID: "k1"}, nil)
+	for i := 1; i < maxCachedAPIKeys; i++ {
+		k.store(fmt.Sprintf("qrk_made_up_%d", i), Identity{}, ErrInvalidAPIKey)
+	}
+	if _, ok := k.cached("qrk_busy"); !ok {
+		t.Fatalf("expected the key to be cached")
+	}
+	k.store("qrk_one_more", Identity{}, ErrInvalidAPIKey)
+
+	if c, ok := k.cached("qrk_busy"); !ok || c.id.UserID != "user-1" {
+		t.Fatalf("expected a key in use to survive a full cache")
+	}
+	if _, ok := k.cached("qrk_made_up_1"); ok {
+		t.Fatalf("expected the least recently used answer to be dropped")
+	}
+	if n := k.lru.Len(); n != maxCachedAPIKeys || len(k.cache) != n {
+		t.Fatalf("expected the cache to stay at %d, got %d and %d", maxCachedAPIKeys, n, len(k.cache))
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/qr-codes", nil)
+	req.Header.Set("X-API-Key", "qrk_busy")
+	if k.Unverified(req) {
+		t.Fatalf("expected a cached key not to need a lookup")
+	}
+	req.Header.Set("Authorization", "Bearer qrk_made_up_1")
+	if !k.Unverified(req) {
+		t.Fatalf("expected an unknown key to need a lookup")
+	}
+	if (*APIKeys)(nil).Unverified(req) {
+		t.Fatalf("expected no lookups without API key support")
+	}
+}
@@ -0,0 +1,65 @@
+package auth_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"click-service/internal/auth"
+	"click-service/internal/auth/authtest"
+)
+
+func TestAPIKeys_VerifiesAndCaches(t *testing.T) {
+	var calls, down atomic.Int32
+	stub := authtest.APIKeys(t, map[string]authtest.APIKey{"qrk_good": {UserID: "user-1", Entitlements: "basic", Scopes: []string{auth.ScopeQrRead}}})
+	upstream := stub.URL
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		if down.Load() > 0 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		http.Redirect(w, r, upstream+r.URL.Path, http.StatusTemporaryRedirect)
+	}))
+	defer srv.Close()
+	keys := auth.NewAPIKeys(srv.URL, "internal")
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		id, err := keys.Verify(ctx, "qrk_good")
+		if err != nil || id.UserID != "user-1" || id.Entitlements != "basic" || !id.Allows(auth.ScopeQrRead) || id.Allows(auth.ScopeQrWrite) {
+			t.Fatalf("unexpected identity %+v %v", id, err)
+		}
+	}
+	if _, err := keys.Verify(ctx, "qrk_bad"); !errors.Is(err, auth.ErrInvalidAPIKey) {
+		t.Fatalf("expected an invalid key, got %v", err)
+	}
+	if _, err := keys.Verify(ctx, "eyJhbGciOi.not.akey"); !errors.Is(err, auth.ErrInvalidAPIKey) {
+		t.Fatalf("expected a non-key to be refused locally, got %v", err)
+	}
+	if n := calls.Load(); n != 2 {
+		t.Fatalf("expected one lookup per key, got %d", n)
+	}
+
+	down.Store(1)
+	for i := 0; i < 2; i++ {
+		if _, err := keys.Verify(ctx, "qrk_other"); err == nil || errors.Is(err, auth.ErrInvalidAPIKey) {
+			t.Fatalf("expected an upstream error, got %v", err)
+		}
+	}
+	if n := calls.Load(); n != 4 {
+		t.Fatalf("expected upstream failures not to be cached, got %d lookups", n)
+	}
+}
+
+func TestIdentity_Allows(t *testing.T) {
+	if !(auth.Identity{UserID: "user-1"}).Allows(auth.ScopeQrWrite) {
+		t.Fatalf("expected a session to carry every scope")
+	}
+	if (auth.Identity{UserID: "user-1", APIKeyID: "k"}).Allows(auth.ScopeQrRead) {
+		t.Fatalf("expected a key without scopes to carry none")
+	}
+}
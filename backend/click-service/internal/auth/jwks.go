@@ -0,0 +1,139 @@
+package auth
+
+import (
+	"context"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+)
+
+var ErrUnknownKey = errors.New("unknown signing key")
+
+// KeySource resolves the public key a token was signed with.
+type KeySource interface {
+	Key(ctx context.Context, kid string) (*rsa.PublicKey, error)
+}
+
+// JWKS fetches and caches a JSON Web Key Set such as the one Cognito publishes at
+// https://cognito-idp.{region}.amazonaws.com/{userPoolId}/.well-known/jwks.json.
+type JWKS struct {
+	URL  string
+	HTTP *http.Client
+
+	// TTL bounds how long a fetched key set is trusted before it is refreshed.
+	TTL time.Duration
+	// MinRefreshInterval stops a stream of tokens with unknown kids from
+	// turning into a stream of JWKS requests.
+	MinRefreshInterval time.Duration
+
+	mu        sync.Mutex
+	keys      map[string]*rsa.PublicKey
+	fetchedAt time.Time
+}
+
+func NewJWKS(url string) *JWKS {
+	return &JWKS{
+		URL:                url,
+		HTTP:               &http.Client{Timeout: 5 * time.Second},
+		TTL:                time.Hour,
+		MinRefreshInterval: time.Minute,
+	}
+}
+
+func (j *JWKS) Key(ctx context.Context, kid string) (*rsa.PublicKey, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	age := time.Since(j.fetchedAt)
+	key, ok := j.keys[kid]
+	if ok && age < j.TTL {
+		return key, nil
+	}
+
+	// Refresh on expiry, or when the kid is unknown (keys rotate), but never
+	// more often than MinRefreshInterval.
+	if j.keys == nil || age >= j.MinRefreshInterval {
+		keys, err := j.fetch(ctx)
+		if err != nil {
+			if ok {
+				// Keep serving the cached key if the refresh fails.
+				return key, nil
+			}
+			return nil, err
+		}
+		j.keys = keys
+		j.fetchedAt = time.Now()
+		key, ok = keys[kid]
+	}
+
+	if !ok {
+		return nil, ErrUnknownKey
+	}
+	return key, nil
+}
+
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+func (j *JWKS) fetch(ctx context.Context) (map[string]*rsa.PublicKey, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, j.URL, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := j.HTTP.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("jwks unexpected status: %d", resp.StatusCode)
+	}
+
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string]*rsa.PublicKey, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Kty != "RSA" || k.Kid == "" || (k.Use != "" && k.Use != "sig") {
+			continue
+		}
+		pub, err := parseRSAKey(k.N, k.E)
+		if err != nil {
+			return nil, fmt.Errorf("jwks key %s: %w", k.Kid, err)
+		}
+		keys[k.Kid] = pub
+	}
+	return keys, nil
+}
+
+func parseRSAKey(n, e string) (*rsa.PublicKey, error) {
+	nb, err := base64.RawURLEncoding.DecodeString(n)
+	if err != nil {
+		return nil, err
+	}
+	eb, err := base64.RawURLEncoding.DecodeString(e)
+	if err != nil {
+		return nil, err
+	}
+	exp := new(big.Int).SetBytes(eb)
+	if !exp.IsInt64() || exp.Int64() < 3 || exp.Int64() > 1<<31-1 {
+		return nil, errors.New("invalid exponent")
+	}
+	return &rsa.PublicKey{N: new(big.Int).SetBytes(nb), E: int(exp.Int64())}, nil
+}
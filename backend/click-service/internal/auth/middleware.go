@@ -0,0 +1,80 @@
+package auth
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strings"
+)
+
+type contextKey struct{}
+
+// WithIdentity returns a copy of ctx carrying id.
+func WithIdentity(ctx context.Context, id Identity) context.Context {
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+// FromContext returns the identity attached by Middleware, if any.
+func FromContext(ctx context.Context) (Identity, bool) {
+	id, ok := ctx.Value(contextKey{}).(Identity)
+	return id, ok && id.UserID != ""
+}
+
+// Middleware verifies the caller's token and attaches the resulting Identity to
+// the request context. It never rejects a request itself: handlers that need a
+// user decide how to answer anonymous callers, and public routes keep working.
+//
+// Tokens are read from "Authorization: Bearer", then the id_token and
+// access_token cookies set by user-service. The id token is preferred because
+// only it carries the custom:entitlements attribute. Personal API keys are
+// taken from "Authorization: Bearer" or X-API-Key and checked with keys; a
+// nil v or keys ignores that kind of credential.
+func Middleware(v *Verifier, keys *APIKeys) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if v == nil && keys == nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+			for _, token := range tokensFromRequest(r) {
+				var id Identity
+				var err error
+				switch {
+				case strings.HasPrefix(token, APIKeyPrefix):
+					if keys == nil {
+						continue
+					}
+					id, err = keys.Verify(r.Context(), token)
+				default:
+					if v == nil {
+						continue
+					}
+					id, err = v.Verify(r.Context(), token)
+				}
+				if err != nil {
+					log.Printf("auth: token rejected request_id=%s err=%v", w.Header().Get("X-Request-Id"), err)
+					continue
+				}
+				r = r.WithContext(WithIdentity(r.Context(), id))
+				break
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func tokensFromRequest(r *http.Request) []string {
+	tokens := make([]string, 0, 2)
+	if h := strings.TrimSpace(r.Header.Get("Authorization")); len(h) > 7 && strings.EqualFold(h[:7], "bearer ") {
+		tokens = append(tokens, strings.TrimSpace(h[7:]))
+	}
+	if k := strings.TrimSpace(r.Header.Get("X-API-Key")); k != "" {
+		tokens = append(tokens, k)
+	}
+	for _, name := range []string{"id_token", "access_token"} {
+		if c, err := r.Cookie(name); err == nil && c.Value != "" {
+			tokens = append(tokens, c.Value)
+		}
+	}
+	return tokens
+}
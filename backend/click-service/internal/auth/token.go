@@ -0,0 +1,142 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+)
+
+var (
+	ErrMalformedToken = errors.New("malformed token")
+	ErrInvalidToken   = errors.New("invalid token")
+	ErrExpiredToken   = errors.New("token expired")
+)
+
+// Identity is the verified caller behind a request.
+type Identity struct {
+	UserID       string
+	Email        string
+	Entitlements string
+
+	// APIKeyID is set when the caller used a personal API key, which limits
+	// them to Scopes.
+	APIKeyID string
+	Scopes   []string
+}
+
+// Allows reports whether the caller may act within scope. Cognito sessions
+// may do anything their owner can; API keys only what they were issued for.
+func (id Identity) Allows(scope string) bool {
+	return id.APIKeyID == "" || slices.Contains(id.Scopes, scope)
+}
+
+// Verifier checks Cognito-issued RS256 tokens (both id and access tokens).
+type Verifier struct {
+	Keys KeySource
+	// Issuer is the user pool URL, e.g. https://cognito-idp.us-east-1.amazonaws.com/us-east-1_abc.
+	Issuer string
+	// ClientID, when set, must match the id token audience or the access token client_id.
+	ClientID string
+	// Leeway tolerates small clock skew when checking exp.
+	Leeway time.Duration
+
+	now func() time.Time
+}
+
+type tokenHeader struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+type tokenClaims struct {
+	Sub          string `json:"sub"`
+	Iss          string `json:"iss"`
+	Exp          int64  `json:"exp"`
+	TokenUse     string `json:"token_use"`
+	Aud          string `json:"aud"`
+	ClientID     string `json:"client_id"`
+	Email        string `json:"email"`
+	Entitlements string `json:"custom:entitlements"`
+	UserType     string `json:"custom:user_type"`
+}
+
+func (v *Verifier) Verify(ctx context.Context, token string) (Identity, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return Identity{}, ErrMalformedToken
+	}
+
+	var header tokenHeader
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return Identity{}, ErrMalformedToken
+	}
+	if header.Alg != "RS256" || header.Kid == "" {
+		return Identity{}, ErrInvalidToken
+	}
+
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return Identity{}, ErrMalformedToken
+	}
+	key, err := v.Keys.Key(ctx, header.Kid)
+	if err != nil {
+		return Identity{}, err
+	}
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], sig); err != nil {
+		return Identity{}, ErrInvalidToken
+	}
+
+	var claims tokenClaims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return Identity{}, ErrMalformedToken
+	}
+
+	now := time.Now
+	if v.now != nil {
+		now = v.now
+	}
+	if claims.Exp == 0 || now().After(time.Unix(claims.Exp, 0).Add(v.Leeway)) {
+		return Identity{}, ErrExpiredToken
+	}
+	if v.Issuer != "" && claims.Iss != v.Issuer {
+		return Identity{}, ErrInvalidToken
+	}
+	if claims.Sub == "" {
+		return Identity{}, ErrInvalidToken
+	}
+
+	switch claims.TokenUse {
+	case "id":
+		if v.ClientID != "" && claims.Aud != v.ClientID {
+			return Identity{}, ErrInvalidToken
+		}
+	case "access":
+		if v.ClientID != "" && claims.ClientID != v.ClientID {
+			return Identity{}, ErrInvalidToken
+		}
+	default:
+		return Identity{}, ErrInvalidToken
+	}
+
+	entitlements := claims.Entitlements
+	if entitlements == "" {
+		entitlements = claims.UserType
+	}
+	return Identity{UserID: claims.Sub, Email: claims.Email, Entitlements: entitlements}, nil
+}
+
+func decodeSegment(seg string, out any) error {
+	raw, err := base64.RawURLEncoding.DecodeString(seg)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(raw, out)
+}
@@ -0,0 +1,127 @@
+package auth_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"click-service/internal/auth"
+	"click-service/internal/auth/authtest"
+)
+
+func baseClaims() map[string]any {
+	return map[string]any{
+		"sub":       "user-1",
+		"iss":       authtest.Issuer,
+		"aud":       authtest.ClientID,
+		"token_use": "id",
+		"exp":       time.Now().Add(time.Hour).Unix(),
+	}
+}
+
+func TestVerify_IDToken(t *testing.T) {
+	ks := authtest.NewKeySet(t)
+	v := ks.Verifier()
+
+	id, err := v.Verify(context.Background(), ks.IDToken(t, "user-1", "enterprise"))
+	if err != nil {
+		t.Fatalf("verify: %v", err)
+	}
+	if id.UserID != "user-1" || id.Entitlements != "enterprise" {
+		t.Fatalf("unexpected identity %+v", id)
+	}
+}
+
+func TestVerify_AccessTokenChecksClientID(t *testing.T) {
+	ks := authtest.NewKeySet(t)
+	v := ks.Verifier()
+
+	claims := baseClaims()
+	delete(claims, "aud")
+	claims["token_use"] = "access"
+	claims["client_id"] = authtest.ClientID
+	if _, err := v.Verify(context.Background(), ks.Sign(t, claims)); err != nil {
+		t.Fatalf("verify: %v", err)
+	}
+
+	claims["client_id"] = "someone-else"
+	if _, err := v.Verify(context.Background(), ks.Sign(t, claims)); !errors.Is(err, auth.ErrInvalidToken) {
+		t.Fatalf("expected invalid token, got %v", err)
+	}
+}
+
+func TestVerify_FallsBackToUserTypeClaim(t *testing.T) {
+	ks := authtest.NewKeySet(t)
+
+	claims := baseClaims()
+	claims["custom:user_type"] = "basic"
+	id, err := ks.Verifier().Verify(context.Background(), ks.Sign(t, claims))
+	if err != nil {
+		t.Fatalf("verify: %v", err)
+	}
+	if id.Entitlements != "basic" {
+		t.Fatalf("expected basic, got %q", id.Entitlements)
+	}
+}
+
+func TestVerify_Rejects(t *testing.T) {
+	ks := authtest.NewKeySet(t)
+	other := authtest.NewKeySet(t)
+	v := ks.Verifier()
+
+	cases := map[string]struct {
+		token string
+		want  error
+	}{
+		"expired": {
+			token: ks.Sign(t, merge(baseClaims(), map[string]any{"exp": time.Now().Add(-time.Minute).Unix()})),
+			want:  auth.ErrExpiredToken,
+		},
+		"wrong issuer": {
+			token: ks.Sign(t, merge(baseClaims(), map[string]any{"iss": "https://evil.example.com"})),
+			want:  auth.ErrInvalidToken,
+		},
+		"wrong audience": {
+			token: ks.Sign(t, merge(baseClaims(), map[string]any{"aud": "other-client"})),
+			want:  auth.ErrInvalidToken,
+		},
+		"missing token_use": {
+			token: ks.Sign(t, merge(baseClaims(), map[string]any{"token_use": ""})),
+			want:  auth.ErrInvalidToken,
+		},
+		"signed by another key": {
+			token: other.IDToken(t, "user-1", "admin"),
+			want:  auth.ErrInvalidToken,
+		},
+		"garbage": {
+			token: "not-a-token",
+			want:  auth.ErrMalformedToken,
+		},
+	}
+
+	for name, tc := range cases {
+		if _, err := v.Verify(context.Background(), tc.token); !errors.Is(err, tc.want) {
+			t.Fatalf("%s: expected %v, got %v", name, tc.want, err)
+		}
+	}
+}
+
+func TestJWKS_UnknownKid(t *testing.T) {
+	ks := authtest.NewKeySet(t)
+	jwks := auth.NewJWKS(ks.Server.URL)
+
+	if _, err := jwks.Key(context.Background(), authtest.KeyID); err != nil {
+		t.Fatalf("key: %v", err)
+	}
+	if _, err := jwks.Key(context.Background(), "rotated-away"); !errors.Is(err, auth.ErrUnknownKey) {
+		t.Fatalf("expected unknown key, got %v", err)
+	}
+}
+
+func merge(base, overrides map[string]any) map[string]any {
+	for k, v := range overrides {
+		base[k] = v
+	}
+	return base
+}
@@ -0,0 +1,67 @@
+package hll
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"testing"
+)
+
+func hashOf(i int) uint64 {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("visitor-%d", i)))
+	return binary.BigEndian.Uint64(sum[:8])
+}
+
+func TestSketch_EstimatesWithinErrorBounds(t *testing.T) {
+	for _, n := range []int{1, 10, 1000, 50000} {
+		var s Sketch
+		for i := 0; i < n; i++ {
+			s.Add(hashOf(i))
+			s.Add(hashOf(i)) // repeats don't count
+		}
+		got := s.Estimate()
+		if math.Abs(float64(got-n)) > math.Max(1, 0.05*float64(n)) {
+			t.Fatalf("n=%d: estimate %d is off by more than 5%%", n, got)
+		}
+	}
+}
+
+func TestSketch_MergeEstimatesUnion(t *testing.T) {
+	var a, b Sketch
+	for i := 0; i < 6000; i++ {
+		a.Add(hashOf(i))
+	}
+	for i := 4000; i < 10000; i++ {
+		b.Add(hashOf(i))
+	}
+	a.Merge(&b)
+	if got := a.Estimate(); math.Abs(float64(got-10000)) > 500 {
+		t.Fatalf("expected about 10000 after merge, got %d", got)
+	}
+}
+
+func TestSketch_BinaryRoundTrip(t *testing.T) {
+	for _, n := range []int{0, 3, 20000} {
+		var s Sketch
+		for i := 0; i < n; i++ {
+			s.Add(hashOf(i))
+		}
+		b, _ := s.MarshalBinary()
+		if n == 3 && len(b) != 1+3*3 {
+			t.Fatalf("expected a sparse encoding for 3 items, got %d bytes", len(b))
+		}
+		var back Sketch
+		if err := back.UnmarshalBinary(b); err != nil {
+			t.Fatalf("n=%d: unmarshal: %v", n, err)
+		}
+		if back.Estimate() != s.Estimate() {
+			t.Fatalf("n=%d: estimate changed from %d to %d", n, s.Estimate(), back.Estimate())
+		}
+	}
+
+	var s Sketch
+	if err := s.UnmarshalBinary([]byte{formatSparse, 0xff, 0xff}); err != ErrInvalidSketch {
+		t.Fatalf("expected ErrInvalidSketch, got %v", err)
+	}
+}
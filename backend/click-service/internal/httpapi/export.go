@@ -0,0 +1,144 @@
+package httpapi
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"click-service/internal/store"
+	"click-service/internal/xlsx"
+)
+
+// exportRows writes one spreadsheet row at a time.
+type exportRows interface {
+	WriteRow(cells []any) error
+	Close() error
+}
+
+type csvRows struct{ w *csv.Writer }
+
+func (c csvRows) WriteRow(cells []any) error {
+	record := make([]string, len(cells))
+	for i, cell := range cells {
+		record[i] = fmt.Sprint(cell)
+	}
+	return c.w.Write(record)
+}
+
+func (c csvRows) Close() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+// serveExport answers /api/clicks/export?qrId=&from=&to=&format=csv|xlsx with
+// one row per stored day: its totals, the 24 hourly counts and one column per
+// region seen in the range. Days are the code's own calendar days (see
+// DailyClickStats.TimeZone) and days without clicks are left out.
+func serveExport(st store.Store, w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	qrID := strings.TrimSpace(q.Get("qrId"))
+	if qrID == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "qrId_required"})
+		return
+	}
+	format := strings.TrimSpace(q.Get("format"))
+	switch format {
+	case "":
+		format = "csv"
+	case "csv", "xlsx":
+	default:
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "format_invalid"})
+		return
+	}
+	fromDay, toDay, code := parseDayRange(q, time.UTC)
+	if code != "" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": code})
+		return
+	}
+
+	var days []time.Time
+	for d := fromDay; !d.After(toDay); d = d.AddDate(0, 0, 1) {
+		days = append(days, d)
+	}
+	byDay, err := st.GetDailyBatch(qrID, days)
+	if err != nil && !errors.Is(err, store.ErrNotFound) {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "export_failed"})
+		return
+	}
+	// Region columns depend on every row, so rows are loaded before the
+	// header is written; a year of one code's days is small.
+	rows := make([]store.DailyClickStats, 0, len(byDay))
+	regionSet := map[string]bool{}
+	for _, ds := range byDay {
+		rows = append(rows, ds)
+		for region := range ds.RegionCounts {
+			regionSet[region] = true
+		}
+	}
+	sort.Slice(rows, func(i, j int) bool { return rows[i].DayIso < rows[j].DayIso })
+	regions := make([]string, 0, len(regionSet))
+	for region := range regionSet {
+		regions = append(regions, region)
+	}
+	sort.Strings(regions)
+
+	safeID := strings.Map(func(r rune) rune {
+		if r == '-' || r == '_' || ('0' <= r && r <= '9') || ('a' <= r && r <= 'z') || ('A' <= r && r <= 'Z') {
+			return r
+		}
+		return -1
+	}, qrID)
+	name := fmt.Sprintf("clicks-%s-%s-%s.%s", safeID, fromDay.Format("20060102"), toDay.Format("20060102"), format)
+	contentType := "text/csv; charset=utf-8"
+	if format == "xlsx" {
+		contentType = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, name))
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+
+	// From here on errors can only be logged and the file cut short.
+	var out exportRows = csvRows{csv.NewWriter(w)}
+	if format == "xlsx" {
+		xw, err := xlsx.NewWriter(w, "Clicks")
+		if err != nil {
+			log.Printf("export: start failed request_id=%s err=%v", w.Header().Get("X-Request-Id"), err)
+			return
+		}
+		out = xw
+	}
+
+	header := []any{"qrCodeId", "day", "timeZone", "total", "uniqueVisitors"}
+	for h := 0; h < 24; h++ {
+		header = append(header, fmt.Sprintf("hour%02d", h))
+	}
+	for _, region := range regions {
+		header = append(header, "region_"+region)
+	}
+	if err := out.WriteRow(header); err != nil {
+		log.Printf("export: write failed request_id=%s err=%v", w.Header().Get("X-Request-Id"), err)
+		return
+	}
+	for _, ds := range rows {
+		row := []any{qrID, ds.DayIso, ds.TimeZone, ds.Total, ds.UniqueVisitors}
+		for _, n := range ds.Hours() {
+			row = append(row, n)
+		}
+		for _, region := range regions {
+			row = append(row, ds.RegionCounts[region])
+		}
+		if err := out.WriteRow(row); err != nil {
+			log.Printf("export: write failed request_id=%s err=%v", w.Header().Get("X-Request-Id"), err)
+			return
+		}
+	}
+	if err := out.Close(); err != nil {
+		log.Printf("export: close failed request_id=%s err=%v", w.Header().Get("X-Request-Id"), err)
+	}
+}
@@ -2,15 +2,22 @@ package httpapi
 
 import (
 	"context"
+	"crypto/subtle"
 	"encoding/json"
 	"errors"
 	"net"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
+	"click-service/internal/auth"
+	"click-service/internal/ingest"
+	"click-service/internal/live"
 	"click-service/internal/middleware"
 	"click-service/internal/qrclient"
+	"click-service/internal/ratelimit"
+	"click-service/internal/scanhook"
 	"click-service/internal/store"
 )
 
@@ -18,22 +25,123 @@ type Server struct {
 	Store    store.Store
 	QrClient interface {
 		GetQrCode(ctx context.Context, id string) (qrclient.QrCode, error)
-		GetSettings(ctx context.Context) (qrclient.Settings, error)
+		GetSettings(ctx context.Context, ownerID string) (qrclient.Settings, error)
 	}
+	// ClickCounter, when set, is told about every recorded click so qr-service
+	// can sort codes by clicks.
+	ClickCounter interface {
+		ReportClicks(ctx context.Context, id string, n int64) error
+	}
+	// Tags, when set, resolves the caller's tag to its codes for
+	// /api/clicks/tag-stats.
+	Tags interface {
+		GetTagQrCodeIDs(ctx context.Context, ownerID, tagID string) ([]string, error)
+	}
+	// AdminAPIKey guards /api/clicks/events, which exposes visitor IPs and
+	// user agents. When empty the endpoint is disabled.
+	AdminAPIKey string
+	// Clicks takes recorded clicks off the redirect path. When nil, NewRouter
+	// starts a default ingest pipeline that is never drained, which is fine
+	// for tests; servers should pass their own and Close it on shutdown.
+	Clicks *ingest.Pipeline
+	// Auth verifies dashboard users' tokens; without it, endpoints that need
+	// a user answer 401.
+	Auth *auth.Verifier
+	// APIKeys verifies personal API keys with user-service; when nil they are
+	// ignored.
+	APIKeys *auth.APIKeys
+	// RateLimit meters requests once the caller is known; NewRouter fills in
+	// its Identify when unset. When nil nothing is metered.
+	RateLimit *ratelimit.Limiter
+	// Live fans redirected clicks out to /api/clicks/stream. When nil,
+	// NewRouter starts a hub allowing live.DefaultMaxPerUser streams per user.
+	Live *live.Hub
+	// StreamHeartbeat is how often an idle stream sends a keep-alive; zero
+	// means every 15 seconds.
+	StreamHeartbeat time.Duration
+	// ScanHooks, when set, forwards scans of codes flagged ScanWebhook to
+	// qr-service for its qr.scanned webhooks.
+	ScanHooks *scanhook.Forwarder
+}
+
+// requireScope answers 403 when a caller using a personal API key lacks
+// scope. Cognito sessions and anonymous callers are left to the handler,
+// which refuses the latter.
+func requireScope(scope string, h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if id, ok := auth.FromContext(r.Context()); ok && !id.Allows(scope) {
+			writeJSON(w, http.StatusForbidden, map[string]string{"error": "insufficient_scope"})
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+// authorizeQrCode answers for qrID unless userID owns it: 404 for someone
+// else's code exactly as for a missing one, and 502 when qr-service cannot
+// tell. It reports whether the caller may go on.
+func (srv Server) authorizeQrCode(w http.ResponseWriter, r *http.Request, userID, qrID string) bool {
+	if srv.QrClient == nil {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "not_found"})
+		return false
+	}
+	qr, err := srv.QrClient.GetQrCode(r.Context(), qrID)
+	if err != nil && !errors.Is(err, qrclient.ErrNotFound) {
+		writeJSON(w, http.StatusBadGateway, map[string]string{"error": "qr_lookup_failed"})
+		return false
+	}
+	if err != nil || qr.OwnerID != userID {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "not_found"})
+		return false
+	}
+	return true
+}
+
+// rateLimitKey buckets API key callers per key and everyone else signed in
+// per user, on the plan from their entitlements. Scanners are anonymous.
+func rateLimitKey(r *http.Request) (string, string, bool) {
+	id, ok := auth.FromContext(r.Context())
+	if !ok {
+		return "", "", false
+	}
+	plan := strings.ToLower(strings.TrimSpace(id.Entitlements))
+	if id.APIKeyID != "" {
+		return "key:" + id.APIKeyID, plan, true
+	}
+	return "user:" + id.UserID, plan, true
 }
 
 func NewRouter(srv Server) http.Handler {
 	mux := http.NewServeMux()
 
+	if srv.Clicks == nil {
+		opts := ingest.DefaultOptions()
+		opts.Counter = srv.ClickCounter
+		srv.Clicks, _ = ingest.New(srv.Store, opts)
+	}
+	if srv.Live == nil {
+		srv.Live = live.NewHub(live.DefaultMaxPerUser)
+	}
+
+	authenticate := auth.Middleware(srv.Auth, srv.APIKeys)
+	if srv.RateLimit != nil && srv.RateLimit.Identify == nil {
+		limiter := *srv.RateLimit
+		limiter.Identify = rateLimitKey
+		srv.RateLimit = &limiter
+	}
+	exposed := append([]string{"X-Request-Id", "X-Next-Cursor"}, ratelimit.Headers...)
+	// API keys missing from the cache cost a call to user-service before the
+	// caller is known, so those are metered per IP first.
+	meterLookups := srv.RateLimit.Before(srv.APIKeys.Unverified)
 	wrapAPI := func(h http.Handler) http.Handler {
-		return middleware.Recoverer(middleware.RequestID(middleware.ExposeResponseHeaders(middleware.EnforceJSONHandler(h))))
+		return middleware.Recoverer(middleware.RequestID(middleware.ExposeResponseHeaders(meterLookups(authenticate(srv.RateLimit.Middleware(middleware.EnforceJSONHandler(h)))), exposed...)))
 	}
 	wrapAny := func(h http.Handler) http.Handler {
-		return middleware.Recoverer(middleware.RequestID(h))
+		return middleware.Recoverer(middleware.RequestID(srv.RateLimit.Middleware(h)))
 	}
 
 	healthHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+		writeJSON(w, http.StatusOK, map[string]any{"status": "ok", "ingest": srv.Clicks.Stats()})
 	})
 
 	redirectHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -64,29 +172,62 @@ func NewRouter(srv Server) http.Handler {
 			return
 		}
 
-		// If inactive, check for global default redirect URL
-		if !qr.Active {
-			settings, err := srv.QrClient.GetSettings(ctx)
+		// fallback serves a code that must not redirect to its target: to the
+		// owner's default redirect URL if set, without recording a click.
+		fallback := func() {
+			settings, err := srv.QrClient.GetSettings(ctx, qr.OwnerID)
 			if err == nil && strings.TrimSpace(settings.DefaultRedirectURL) != "" {
-				// Redirect to global default URL without recording click
 				w.Header().Set("Cache-Control", "no-store")
 				http.Redirect(w, r, strings.TrimSpace(settings.DefaultRedirectURL), http.StatusFound)
 				return
 			}
 			// No default URL, return 404
 			w.WriteHeader(http.StatusNotFound)
+		}
+
+		// If inactive or outside its schedule, check for the owner's default redirect URL
+		if !qr.LiveAt(time.Now()) {
+			fallback()
 			return
 		}
 
+		now := time.Now()
 		targetURL := strings.TrimSpace(qr.URL)
+		variantID := ""
+		if rule, ok := matchRule(qr.Rules, r, now); ok {
+			targetURL = strings.TrimSpace(rule.URL)
+		} else if v, ok := pickVariant(w, r, qr); ok {
+			targetURL, variantID = strings.TrimSpace(v.URL), v.ID
+		}
 		if targetURL == "" {
 			w.WriteHeader(http.StatusNotFound)
 			return
 		}
 
+		// Limited codes spend a scan on GET only, so link previews sending
+		// HEAD do not use up one-time codes. The scan is claimed once there is
+		// a target to send it to, so a 404 does not use one up.
+		if qr.MaxScans != nil && r.Method == http.MethodGet {
+			ok, err := srv.Store.ClaimScan(id, *qr.MaxScans)
+			if err != nil {
+				// Fail closed rather than let a coupon overshoot its limit.
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			if !ok {
+				if exhausted := strings.TrimSpace(qr.ExhaustedURL); exhausted != "" {
+					w.Header().Set("Cache-Control", "no-store")
+					http.Redirect(w, r, exhausted, http.StatusFound)
+					return
+				}
+				fallback()
+				return
+			}
+		}
+
 		// Build the click event now, but record it asynchronously so the redirect is as fast as possible.
 		event := store.ClickEvent{
-			At:         time.Now().UTC(),
+			At:         now.UTC(),
 			QrCodeID:   id,
 			TargetURL:  targetURL,
 			IP:         clientIP(r),
@@ -95,17 +236,35 @@ func NewRouter(srv Server) http.Handler {
 			Country:    countryFromHeaders(r),
 			RequestID:  strings.TrimSpace(w.Header().Get("X-Request-Id")),
 			AcceptLang: strings.TrimSpace(r.Header.Get("Accept-Language")),
+			Variant:    variantID,
+			TimeZone:   qr.TimeZone,
 		}
 
 		w.Header().Set("Cache-Control", "no-store")
 		http.Redirect(w, r, targetURL, http.StatusFound)
 
-		go func(ev store.ClickEvent) {
-			defer func() { _ = recover() }()
-			_ = srv.Store.RecordClick(ev)
-		}(event)
+		// A full queue drops the click (and counts it) rather than delay scans.
+		srv.Clicks.Enqueue(event)
+		srv.Live.Publish(event)
+		if qr.ScanWebhook && srv.ScanHooks != nil {
+			srv.ScanHooks.Forward(scanEventFrom(event))
+		}
 	})
 
+	serveSeries := func(w http.ResponseWriter, r *http.Request, qrID string) {
+		q, code := parseSeriesQuery(r)
+		if code != "" {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": code})
+			return
+		}
+		series, err := clickSeriesFor(srv.Store, qrID, q)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "series_failed"})
+			return
+		}
+		writeJSON(w, http.StatusOK, series)
+	}
+
 	clicksHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodGet {
 			w.WriteHeader(http.StatusMethodNotAllowed)
@@ -115,14 +274,48 @@ func NewRouter(srv Server) http.Handler {
 		rest := strings.TrimPrefix(r.URL.Path, "/api/clicks/")
 		rest = strings.Trim(rest, "/")
 
+		// Analytics belong to the code's owner. The admin event log has its
+		// own key, and the stream checks the caller itself.
+		if rest != "events" && rest != "stream" {
+			caller, ok := auth.FromContext(r.Context())
+			if !ok {
+				writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "unauthorized"})
+				return
+			}
+			qrID := strings.TrimSpace(r.URL.Query().Get("qrId"))
+			switch rest {
+			case "stats", "series", "export", "daily", "daily-batch", "tag-stats":
+			default:
+				// Legacy /api/clicks/{qrId}[/...] paths.
+				qrID, _, _ = strings.Cut(rest, "/")
+			}
+			if rest != "tag-stats" && qrID != "" && !srv.authorizeQrCode(w, r, caller.UserID, qrID) {
+				return
+			}
+		}
+
 		// Check for query-based endpoints first
 		if rest == "stats" {
-			// /api/clicks/stats?qrId=xxx
+			// /api/clicks/stats?qrId=xxx[&from=2026-01-01&to=2026-01-31]
 			qrID := strings.TrimSpace(r.URL.Query().Get("qrId"))
 			if qrID == "" {
 				writeJSON(w, http.StatusBadRequest, map[string]string{"error": "qrId_required"})
 				return
 			}
+			rawFrom := strings.TrimSpace(r.URL.Query().Get("from"))
+			rawTo := strings.TrimSpace(r.URL.Query().Get("to"))
+			var from, to time.Time
+			if rawFrom != "" || rawTo != "" {
+				var err error
+				if from, err = time.Parse("2006-01-02", rawFrom); err != nil {
+					writeJSON(w, http.StatusBadRequest, map[string]string{"error": "from_invalid"})
+					return
+				}
+				if to, err = time.Parse("2006-01-02", rawTo); err != nil || to.Before(from) {
+					writeJSON(w, http.StatusBadRequest, map[string]string{"error": "to_invalid"})
+					return
+				}
+			}
 			st, err := srv.Store.GetStats(qrID)
 			if err != nil {
 				if errors.Is(err, store.ErrNotFound) {
@@ -132,10 +325,120 @@ func NewRouter(srv Server) http.Handler {
 				writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "stats_failed"})
 				return
 			}
+			if !from.IsZero() {
+				vr, err := srv.Store.GetUniqueVisitors(qrID, from, to)
+				if err != nil {
+					writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "stats_failed"})
+					return
+				}
+				st.Range = &vr
+			}
 			writeJSON(w, http.StatusOK, st)
 			return
 		}
 
+		if rest == "tag-stats" {
+			// /api/clicks/tag-stats?tagId=xxx
+			tagID := strings.TrimSpace(r.URL.Query().Get("tagId"))
+			if tagID == "" {
+				writeJSON(w, http.StatusBadRequest, map[string]string{"error": "tagId_required"})
+				return
+			}
+			if srv.Tags == nil {
+				writeJSON(w, http.StatusNotFound, map[string]string{"error": "not_found"})
+				return
+			}
+			caller, _ := auth.FromContext(r.Context())
+			ids, err := srv.Tags.GetTagQrCodeIDs(r.Context(), caller.UserID, tagID)
+			if err != nil {
+				if errors.Is(err, qrclient.ErrNotFound) {
+					writeJSON(w, http.StatusNotFound, map[string]string{"error": "not_found"})
+					return
+				}
+				writeJSON(w, http.StatusBadGateway, map[string]string{"error": "tag_lookup_failed"})
+				return
+			}
+			byCode, err := srv.Store.GetStatsBatch(ids)
+			if err != nil {
+				writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "stats_failed"})
+				return
+			}
+			writeJSON(w, http.StatusOK, tagStats(tagID, ids, byCode))
+			return
+		}
+
+		if rest == "series" {
+			// /api/clicks/series?qrId=xxx&from=2026-01-01&to=2026-03-31&granularity=week&tz=Europe/Berlin
+			qrID := strings.TrimSpace(r.URL.Query().Get("qrId"))
+			if qrID == "" {
+				writeJSON(w, http.StatusBadRequest, map[string]string{"error": "qrId_required"})
+				return
+			}
+			serveSeries(w, r, qrID)
+			return
+		}
+
+		if rest == "stream" {
+			// /api/clicks/stream?qrId=xxx
+			serveStream(srv, w, r)
+			return
+		}
+
+		if rest == "export" {
+			// /api/clicks/export?qrId=xxx&from=2026-01-01&to=2026-03-31&format=xlsx
+			serveExport(srv.Store, w, r)
+			return
+		}
+
+		if rest == "events" {
+			// /api/clicks/events?qrId=xxx&from=2026-01-02&to=2026-01-03&limit=100&cursor=...
+			if srv.AdminAPIKey == "" || subtle.ConstantTimeCompare([]byte(r.Header.Get("X-Admin-Key")), []byte(srv.AdminAPIKey)) != 1 {
+				writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "unauthorized"})
+				return
+			}
+			q := r.URL.Query()
+			query := store.EventQuery{QrCodeID: strings.TrimSpace(q.Get("qrId")), Cursor: strings.TrimSpace(q.Get("cursor"))}
+			if query.QrCodeID == "" {
+				writeJSON(w, http.StatusBadRequest, map[string]string{"error": "qrId_required"})
+				return
+			}
+			var ok bool
+			if query.From, ok = parseEventBound(q.Get("from")); !ok {
+				writeJSON(w, http.StatusBadRequest, map[string]string{"error": "from_invalid"})
+				return
+			}
+			if query.To, ok = parseEventBound(q.Get("to")); !ok {
+				writeJSON(w, http.StatusBadRequest, map[string]string{"error": "to_invalid"})
+				return
+			}
+			if raw := strings.TrimSpace(q.Get("limit")); raw != "" {
+				n, err := strconv.Atoi(raw)
+				if err != nil || n <= 0 {
+					writeJSON(w, http.StatusBadRequest, map[string]string{"error": "limit_invalid"})
+					return
+				}
+				query.Limit = n
+			}
+
+			page, err := srv.Store.ListEvents(query)
+			if err != nil {
+				if errors.Is(err, store.ErrInvalidCursor) {
+					writeJSON(w, http.StatusBadRequest, map[string]string{"error": "cursor_invalid"})
+					return
+				}
+				writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "events_failed"})
+				return
+			}
+			if page.NextCursor != "" {
+				w.Header().Set("X-Next-Cursor", page.NextCursor)
+			}
+			if page.Items == nil {
+				page.Items = []store.ClickEvent{}
+			}
+			writeJSON(w, http.StatusOK, page.Items)
+			return
+		}
+
 		if rest == "daily" {
 			// /api/clicks/daily?qrId=xxx&day=2026-01-02
 			qrID := strings.TrimSpace(r.URL.Query().Get("qrId"))
@@ -247,7 +550,8 @@ func NewRouter(srv Server) http.Handler {
 		}
 
 		if len(parts) == 2 && parts[1] == "series" {
-			w.WriteHeader(http.StatusNotFound)
+			// /api/clicks/{qrId}/series?from=...&to=...&granularity=day&tz=UTC
+			serveSeries(w, r, parts[0])
 			return
 		}
 
@@ -333,11 +637,45 @@ func NewRouter(srv Server) http.Handler {
 
 	mux.Handle("/healthz", wrapAPI(healthHandler))
 	mux.Handle("/r/", wrapAny(redirectHandler))
-	mux.Handle("/api/clicks/", wrapAPI(clicksHandler))
+	mux.Handle("/api/clicks/", wrapAPI(requireScope(auth.ScopeClicksRead, clicksHandler)))
 
 	return mux
 }
 
+// tagStats folds per-code stats into one TagClickStats, listing codes in the
+// order qr-service returned them.
+func tagStats(tagID string, ids []string, byCode map[string]store.ClickStats) store.TagClickStats {
+	out := store.TagClickStats{TagID: tagID, QrCodeCount: len(ids), QrCodes: []store.ClickStats{}}
+	for _, id := range ids {
+		st, ok := byCode[id]
+		if !ok {
+			continue
+		}
+		out.Total += st.Total
+		out.QrCodes = append(out.QrCodes, st)
+		// RFC 3339 UTC timestamps compare correctly as strings.
+		if st.LastAtIso > out.LastAtIso {
+			out.LastAtIso = st.LastAtIso
+			out.LastCountry = st.LastCountry
+		}
+	}
+	return out
+}
+
+// parseEventBound accepts an RFC 3339 timestamp or a YYYY-MM-DD day (UTC
+// midnight). Empty means unbounded.
+func parseEventBound(raw string) (time.Time, bool) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return time.Time{}, true
+	}
+	if t, err := time.Parse(time.RFC3339, raw); err == nil {
+		return t, true
+	}
+	t, err := time.Parse("2006-01-02", raw)
+	return t, err == nil
+}
+
 func writeJSON(w http.ResponseWriter, status int, payload any) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(status)
@@ -1,13 +1,20 @@
 package httpapi
 
 import (
+	"bytes"
 	"context"
+	"encoding/csv"
+	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 	"time"
 
+	"click-service/internal/auth"
+	"click-service/internal/auth/authtest"
 	"click-service/internal/qrclient"
+	"click-service/internal/ratelimit"
+	"click-service/internal/scanhook"
 	"click-service/internal/store"
 )
 
@@ -23,10 +30,18 @@ func (s *storeSpy) RecordClick(ev store.ClickEvent) error {
 	return nil
 }
 
+func (s *storeSpy) ClaimScan(qrCodeID string, limit int64) (bool, error) {
+	return true, nil
+}
+
 func (s *storeSpy) GetStats(qrCodeID string) (store.ClickStats, error) {
 	return store.ClickStats{}, store.ErrNotFound
 }
 
+func (s *storeSpy) GetStatsBatch(qrCodeIDs []string) (map[string]store.ClickStats, error) {
+	return map[string]store.ClickStats{}, nil
+}
+
 func (s *storeSpy) GetDaily(qrCodeID string, day time.Time) (store.DailyClickStats, error) {
 	return store.DailyClickStats{}, store.ErrNotFound
 }
@@ -35,11 +50,32 @@ func (s *storeSpy) GetDailyBatch(qrCodeID string, days []time.Time) (map[string]
 	return nil, store.ErrNotFound
 }
 
+func (s *storeSpy) GetUniqueVisitors(qrCodeID string, from, to time.Time) (store.VisitorRange, error) {
+	return store.VisitorRange{}, nil
+}
+
+func (s *storeSpy) ListEvents(query store.EventQuery) (store.EventPage, error) {
+	return store.EventPage{}, nil
+}
+
+func (s *storeSpy) PurgeEventsBefore(cutoff time.Time) error {
+	return nil
+}
+
+func (s *storeSpy) ClaimReport(ownerID, week string) (bool, error) {
+	return true, nil
+}
+
+func (s *storeSpy) ReleaseReport(ownerID, week string) error {
+	return nil
+}
+
 type qrClientSpy struct {
-	called bool
-	gotID  string
-	resp   qrclient.QrCode
-	err    error
+	called   bool
+	gotID    string
+	resp     qrclient.QrCode
+	err      error
+	settings qrclient.Settings
 }
 
 func (q *qrClientSpy) GetQrCode(_ context.Context, id string) (qrclient.QrCode, error) {
@@ -48,8 +84,8 @@ func (q *qrClientSpy) GetQrCode(_ context.Context, id string) (qrclient.QrCode,
 	return q.resp, q.err
 }
 
-func (q *qrClientSpy) GetSettings(_ context.Context) (qrclient.Settings, error) {
-	return qrclient.Settings{}, nil
+func (q *qrClientSpy) GetSettings(_ context.Context, _ string) (qrclient.Settings, error) {
+	return q.settings, nil
 }
 
 func TestRedirect_UsesDbUrlAndChecksActive(t *testing.T) {
@@ -85,3 +121,547 @@ func TestRedirect_UsesDbUrlAndChecksActive(t *testing.T) {
 		// ok
 	}
 }
+
+type clickCounterSpy struct {
+	ch chan string
+}
+
+func (c *clickCounterSpy) ReportClicks(_ context.Context, id string, n int64) error {
+	if n == 1 {
+		c.ch <- id
+	}
+	return nil
+}
+
+func TestRedirect_ReportsClickToCounter(t *testing.T) {
+	spy := &storeSpy{ch: make(chan store.ClickEvent, 1)}
+	counter := &clickCounterSpy{ch: make(chan string, 1)}
+	qrSpy := &qrClientSpy{resp: qrclient.QrCode{ID: "abc123", URL: "https://example.com/db", Active: true}}
+	router := NewRouter(Server{Store: spy, QrClient: qrSpy, ClickCounter: counter})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/r/abc123", nil))
+	if w.Code != http.StatusFound {
+		t.Fatalf("expected %d, got %d", http.StatusFound, w.Code)
+	}
+
+	select {
+	case id := <-counter.ch:
+		if id != "abc123" {
+			t.Fatalf("expected click reported for %q, got %q", "abc123", id)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("expected click to be reported")
+	}
+}
+
+type scanSenderSpy struct {
+	scans []qrclient.ScanEvent
+}
+
+func (s *scanSenderSpy) ReportScans(_ context.Context, scans []qrclient.ScanEvent) error {
+	s.scans = append(s.scans, scans...)
+	return nil
+}
+
+func TestRedirect_ForwardsScansOfWebhookCodes(t *testing.T) {
+	sender := &scanSenderSpy{}
+	hooks := scanhook.New(sender, scanhook.Options{FlushInterval: time.Hour})
+	qrSpy := &qrClientSpy{resp: qrclient.QrCode{ID: "abc123", URL: "https://example.com/db", Active: true}}
+	router := NewRouter(Server{Store: store.NewMemoryStore(), QrClient: qrSpy, ScanHooks: hooks})
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/r/abc123", nil))
+	qrSpy.resp.ScanWebhook = true
+	req := httptest.NewRequest(http.MethodGet, "/r/abc123", nil)
+	req.Header.Set("CF-IPCountry", "NL")
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	if err := hooks.Close(context.Background()); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+	if len(sender.scans) != 1 || sender.scans[0].QrCodeID != "abc123" || sender.scans[0].Country != "NL" || sender.scans[0].TargetURL != "https://example.com/db" {
+		t.Fatalf("expected only the flagged scan forwarded, got %+v", sender.scans)
+	}
+}
+
+// signedInAs builds a router whose codes all belong to owner, unless srv
+// brings its own QrClient, and returns a GET helper signed in as owner.
+func signedInAs(t *testing.T, owner string, srv Server) func(path string) *httptest.ResponseRecorder {
+	t.Helper()
+	ks := authtest.NewKeySet(t)
+	srv.Auth = ks.Verifier()
+	if srv.QrClient == nil {
+		srv.QrClient = &qrClientSpy{resp: qrclient.QrCode{ID: "abc123", OwnerID: owner}}
+	}
+	router := NewRouter(srv)
+	token := ks.IDToken(t, owner, "free")
+	return func(path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+}
+
+// tagsStub holds user-1's tags.
+type tagsStub map[string][]string
+
+func (t tagsStub) GetTagQrCodeIDs(_ context.Context, ownerID, tagID string) ([]string, error) {
+	ids, ok := t[tagID]
+	if !ok || ownerID != "user-1" {
+		return nil, qrclient.ErrNotFound
+	}
+	return ids, nil
+}
+
+func TestTagStats_AggregatesMemberCodes(t *testing.T) {
+	st := store.NewMemoryStore()
+	base := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	_ = st.RecordClick(store.ClickEvent{At: base, QrCodeID: "a", Country: "US"})
+	_ = st.RecordClick(store.ClickEvent{At: base.Add(time.Hour), QrCodeID: "a", Country: "US"})
+	_ = st.RecordClick(store.ClickEvent{At: base.Add(2 * time.Hour), QrCodeID: "b", Country: "DE"})
+	_ = st.RecordClick(store.ClickEvent{At: base.Add(3 * time.Hour), QrCodeID: "untagged", Country: "FR"})
+	get := signedInAs(t, "user-1", Server{Store: st, Tags: tagsStub{"spring": {"a", "b", "quiet"}}})
+
+	w := get("/api/clicks/tag-stats?tagId=spring")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected %d, got %d", http.StatusOK, w.Code)
+	}
+	var got store.TagClickStats
+	_ = json.NewDecoder(w.Body).Decode(&got)
+	if got.QrCodeCount != 3 || got.Total != 3 || len(got.QrCodes) != 2 || got.LastCountry != "DE" {
+		t.Fatalf("unexpected tag stats %+v", got)
+	}
+
+	w = get("/api/clicks/tag-stats?tagId=missing")
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected %d, got %d", http.StatusNotFound, w.Code)
+	}
+	other := signedInAs(t, "user-2", Server{Store: st, Tags: tagsStub{"spring": {"a", "b", "quiet"}}})
+	if w := other("/api/clicks/tag-stats?tagId=spring"); w.Code != http.StatusNotFound {
+		t.Fatalf("expected someone else's tag to be hidden, got %d", w.Code)
+	}
+}
+
+func TestRedirect_OutsideScheduleFallsBackToDefault(t *testing.T) {
+	past := time.Now().Add(-time.Hour)
+	future := time.Now().Add(time.Hour)
+	cases := map[string]qrclient.QrCode{
+		"not started": {ID: "abc123", URL: "https://example.com/db", Active: true, ActiveFrom: &future},
+		"expired":     {ID: "abc123", URL: "https://example.com/db", Active: true, ActiveUntil: &past},
+	}
+	for name, qr := range cases {
+		spy := &storeSpy{ch: make(chan store.ClickEvent, 1)}
+		qrSpy := &qrClientSpy{resp: qr, settings: qrclient.Settings{DefaultRedirectURL: "https://example.com/fallback"}}
+		router := NewRouter(Server{Store: spy, QrClient: qrSpy})
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/r/abc123", nil))
+		if w.Code != http.StatusFound || w.Header().Get("Location") != "https://example.com/fallback" {
+			t.Fatalf("%s: expected fallback redirect, got %d %q", name, w.Code, w.Header().Get("Location"))
+		}
+		select {
+		case <-spy.ch:
+			t.Fatalf("%s: expected no click to be recorded", name)
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+}
+
+func TestRedirect_ScanLimitSendsLaterScansToExhaustedURL(t *testing.T) {
+	limit := int64(2)
+	qrSpy := &qrClientSpy{resp: qrclient.QrCode{ID: "abc123", URL: "https://example.com/coupon", Active: true, MaxScans: &limit, ExhaustedURL: "https://example.com/used"}}
+	router := NewRouter(Server{Store: store.NewMemoryStore(), QrClient: qrSpy})
+
+	scan := func(method string) string {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(method, "/r/abc123", nil))
+		return w.Header().Get("Location")
+	}
+	if got := scan(http.MethodHead); got != "https://example.com/coupon" {
+		t.Fatalf("expected HEAD to redirect without spending a scan, got %q", got)
+	}
+	qrSpy.resp.URL = ""
+	if got := scan(http.MethodGet); got != "" {
+		t.Fatalf("expected a code without a target to answer 404, got %q", got)
+	}
+	qrSpy.resp.URL = "https://example.com/coupon"
+	for i := 0; i < 2; i++ {
+		if got := scan(http.MethodGet); got != "https://example.com/coupon" {
+			t.Fatalf("scan %d: expected target, since neither HEAD nor a 404 spends a scan, got %q", i+1, got)
+		}
+	}
+	if got := scan(http.MethodGet); got != "https://example.com/used" {
+		t.Fatalf("expected exhausted URL after the limit, got %q", got)
+	}
+}
+
+func TestRedirect_FirstMatchingRuleWins(t *testing.T) {
+	qrSpy := &qrClientSpy{resp: qrclient.QrCode{ID: "abc123", URL: "https://example.com/default", Active: true, Rules: []qrclient.RedirectRule{
+		{Platforms: []string{"ios"}, URL: "https://apps.example.com/ios"},
+		{Countries: []string{"DE"}, Languages: []string{"de"}, URL: "https://example.com/de"},
+		{Platforms: []string{"android"}, URL: "https://apps.example.com/android"},
+	}}}
+	router := NewRouter(Server{Store: store.NewMemoryStore(), QrClient: qrSpy})
+
+	cases := []struct {
+		name    string
+		headers map[string]string
+		want    string
+	}{
+		{"ios", map[string]string{"User-Agent": "Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X)"}, "https://apps.example.com/ios"},
+		{"german android", map[string]string{"User-Agent": "Mozilla/5.0 (Linux; Android 14)", "CF-IPCountry": "de", "Accept-Language": "en;q=0.5, de-AT"}, "https://example.com/de"},
+		{"android elsewhere", map[string]string{"User-Agent": "Mozilla/5.0 (Linux; Android 14)", "CF-IPCountry": "FR", "Accept-Language": "de"}, "https://apps.example.com/android"},
+		{"desktop", map[string]string{"User-Agent": "Mozilla/5.0 (X11; Linux x86_64)"}, "https://example.com/default"},
+	}
+	for _, tc := range cases {
+		req := httptest.NewRequest(http.MethodGet, "/r/abc123", nil)
+		for k, v := range tc.headers {
+			req.Header.Set(k, v)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if got := w.Header().Get("Location"); w.Code != http.StatusFound || got != tc.want {
+			t.Fatalf("%s: expected %q, got %d %q", tc.name, tc.want, w.Code, got)
+		}
+	}
+}
+
+func TestRuleTimeMatches_WrapsPastMidnightInZone(t *testing.T) {
+	rule := qrclient.RedirectRule{Weekdays: []string{"fri", "sat"}, TimeFrom: "22:00", TimeUntil: "02:00", TimeZone: "Europe/Berlin"}
+	// 2026-01-02 is a Friday; Berlin is UTC+1 in winter.
+	cases := map[string]bool{
+		"2026-01-02T21:30:00Z": true,  // Fri 22:30 local
+		"2026-01-02T20:59:00Z": false, // Fri 21:59 local
+		"2026-01-03T00:30:00Z": true,  // Sat 01:30 local
+		"2026-01-04T00:30:00Z": false, // Sun 01:30 local
+	}
+	for raw, want := range cases {
+		at, _ := time.Parse(time.RFC3339, raw)
+		if got := ruleTimeMatches(rule, at); got != want {
+			t.Fatalf("%s: expected %v, got %v", raw, want, got)
+		}
+	}
+}
+
+func TestRedirect_StickySplitRecordsVariant(t *testing.T) {
+	spy := &storeSpy{ch: make(chan store.ClickEvent, 1)}
+	qrSpy := &qrClientSpy{resp: qrclient.QrCode{ID: "abc123", URL: "https://example.com/db", Active: true, StickyVariants: true, Variants: []qrclient.Variant{
+		{ID: "a", URL: "https://example.com/a", Weight: 1},
+		{ID: "b", URL: "https://example.com/b", Weight: 1},
+	}}}
+	router := NewRouter(Server{Store: spy, QrClient: qrSpy})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/r/abc123", nil))
+	cookies := w.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != "qrv_abc123" {
+		t.Fatalf("expected a variant cookie, got %v", cookies)
+	}
+	want := "https://example.com/" + cookies[0].Value
+	if got := w.Header().Get("Location"); got != want {
+		t.Fatalf("expected %q, got %q", want, got)
+	}
+	select {
+	case ev := <-spy.ch:
+		if ev.Variant != cookies[0].Value {
+			t.Fatalf("expected variant %q recorded, got %q", cookies[0].Value, ev.Variant)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("expected click to be recorded")
+	}
+
+	// A returning visitor keeps their variant.
+	for i := 0; i < 20; i++ {
+		req := httptest.NewRequest(http.MethodGet, "/r/abc123", nil)
+		req.AddCookie(&http.Cookie{Name: "qrv_abc123", Value: "b"})
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if got := w.Header().Get("Location"); got != "https://example.com/b" {
+			t.Fatalf("expected sticky variant b, got %q", got)
+		}
+	}
+}
+
+func TestRedirect_SplitUsesEveryWeightedVariant(t *testing.T) {
+	qrSpy := &qrClientSpy{resp: qrclient.QrCode{ID: "abc123", URL: "https://example.com/db", Active: true, Variants: []qrclient.Variant{
+		{ID: "a", URL: "https://example.com/a", Weight: 1},
+		{ID: "b", URL: "https://example.com/b", Weight: 1},
+	}}}
+	router := NewRouter(Server{Store: store.NewMemoryStore(), QrClient: qrSpy})
+
+	seen := map[string]bool{}
+	for i := 0; i < 200; i++ {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/r/abc123", nil))
+		if len(w.Result().Cookies()) != 0 {
+			t.Fatalf("expected no cookie for a non-sticky split")
+		}
+		seen[w.Header().Get("Location")] = true
+	}
+	if len(seen) != 2 || !seen["https://example.com/a"] || !seen["https://example.com/b"] {
+		t.Fatalf("expected both variants to be served, got %v", seen)
+	}
+}
+
+func TestClickEvents_RequiresAdminKeyAndPages(t *testing.T) {
+	st := store.NewMemoryStore()
+	at := time.Date(2026, 1, 2, 10, 0, 0, 0, time.UTC)
+	for i := 0; i < 3; i++ {
+		_ = st.RecordClick(store.ClickEvent{QrCodeID: "abc123", At: at.Add(time.Duration(i) * time.Second), UserAgent: "curl"})
+	}
+	router := NewRouter(Server{Store: st, AdminAPIKey: "secret"})
+
+	get := func(path, key string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if key != "" {
+			req.Header.Set("X-Admin-Key", key)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := get("/api/clicks/events?qrId=abc123", "wrong"); w.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 without the admin key, got %d", w.Code)
+	}
+	if w := get("/api/clicks/events?qrId=abc123&from=yesterday", "secret"); w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for a bad bound, got %d", w.Code)
+	}
+
+	w := get("/api/clicks/events?qrId=abc123&from=2026-01-02&to=2026-01-03&limit=2", "secret")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var first []store.ClickEvent
+	if err := json.Unmarshal(w.Body.Bytes(), &first); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	cursor := w.Header().Get("X-Next-Cursor")
+	if len(first) != 2 || cursor == "" || first[0].UserAgent != "curl" {
+		t.Fatalf("unexpected first page %+v (cursor %q)", first, cursor)
+	}
+
+	w = get("/api/clicks/events?qrId=abc123&limit=2&cursor="+cursor, "secret")
+	var second []store.ClickEvent
+	_ = json.Unmarshal(w.Body.Bytes(), &second)
+	if len(second) != 1 || w.Header().Get("X-Next-Cursor") != "" || second[0].AtIso != "2026-01-02T10:00:00Z" {
+		t.Fatalf("unexpected last page %+v", second)
+	}
+}
+
+func TestClickStats_UniqueVisitorsOverRange(t *testing.T) {
+	st := store.NewMemoryStore()
+	today := time.Now().UTC()
+	yesterday := today.AddDate(0, 0, -1)
+	for _, ev := range []store.ClickEvent{
+		{QrCodeID: "abc123", At: yesterday, IP: "198.51.100.1", UserAgent: "phone"},
+		{QrCodeID: "abc123", At: today, IP: "198.51.100.1", UserAgent: "phone"},
+		{QrCodeID: "abc123", At: today, IP: "198.51.100.1", UserAgent: "phone"},
+		{QrCodeID: "abc123", At: today, IP: "198.51.100.2", UserAgent: "phone"},
+	} {
+		_ = st.RecordClick(ev)
+	}
+	get := signedInAs(t, "user-1", Server{Store: st})
+
+	w := get("/api/clicks/stats?qrId=abc123&from=" + today.Format("2006-01-02") + "&to=" + today.Format("2006-01-02"))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var got store.ClickStats
+	if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if got.Total != 4 || got.Range == nil || got.Range.UniqueVisitors != 2 {
+		t.Fatalf("expected 4 scans and 2 visitors today, got %+v (range %+v)", got, got.Range)
+	}
+	// Salts rotate daily, so yesterday's visit by the same person counts again.
+	if got.UniqueVisitors != 3 {
+		t.Fatalf("expected 3 visitor-days overall, got %d", got.UniqueVisitors)
+	}
+
+	w = get("/api/clicks/stats?qrId=abc123&from=2026-01-02")
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for a range without an end, got %d", w.Code)
+	}
+}
+
+func getSeries(t *testing.T, get func(string) *httptest.ResponseRecorder, path string) clickSeries {
+	t.Helper()
+	w := get(path)
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET %s: expected 200, got %d: %s", path, w.Code, w.Body.String())
+	}
+	var out clickSeries
+	if err := json.Unmarshal(w.Body.Bytes(), &out); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	return out
+}
+
+func TestClickSeries_ZeroFilledBucketsInZone(t *testing.T) {
+	st := store.NewMemoryStore()
+	for _, at := range []time.Time{
+		time.Date(2026, 3, 2, 23, 30, 0, 0, time.UTC), // 00:30 on Mar 3 in Berlin
+		time.Date(2026, 3, 3, 12, 0, 0, 0, time.UTC),
+		time.Date(2026, 3, 20, 9, 0, 0, 0, time.UTC),
+	} {
+		_ = st.RecordClick(store.ClickEvent{QrCodeID: "abc123", At: at, Country: "DE"})
+	}
+	get := signedInAs(t, "user-1", Server{Store: st})
+
+	days := getSeries(t, get, "/api/clicks/abc123/series?from=2026-03-01&to=2026-03-31&tz=Europe/Berlin")
+	if len(days.Buckets) != 31 || days.Total != 3 || days.RegionCounts["DE"] != 3 {
+		t.Fatalf("unexpected daily series: %d buckets, total %d, regions %v", len(days.Buckets), days.Total, days.RegionCounts)
+	}
+	if days.Buckets[2].Start != "2026-03-03T00:00:00+01:00" || days.Buckets[2].Total != 2 || days.Buckets[1].Total != 0 {
+		t.Fatalf("expected both Mar 3 clicks in the Berlin day, got %+v %+v", days.Buckets[1], days.Buckets[2])
+	}
+	// Berlin moves to summer time on Mar 29; days stay midnight-aligned.
+	if days.Buckets[29].Start != "2026-03-30T00:00:00+02:00" {
+		t.Fatalf("unexpected bucket start after DST: %s", days.Buckets[29].Start)
+	}
+
+	weeks := getSeries(t, get, "/api/clicks/series?qrId=abc123&from=2026-03-01&to=2026-03-31&granularity=week")
+	if weeks.Buckets[0].Start != "2026-02-23T00:00:00Z" || len(weeks.Buckets) != 6 || weeks.Buckets[1].Total != 2 {
+		t.Fatalf("unexpected weekly series %+v", weeks.Buckets)
+	}
+
+	hours := getSeries(t, get, "/api/clicks/series?qrId=abc123&from=2026-03-03&to=2026-03-03&granularity=hour")
+	if len(hours.Buckets) != 24 || hours.Buckets[12].Total != 1 || hours.Total != 1 {
+		t.Fatalf("unexpected hourly series %+v", hours)
+	}
+
+	w := get("/api/clicks/series?qrId=abc123&from=2024-01-01&to=2026-01-01")
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for a two-year range, got %d", w.Code)
+	}
+}
+
+func TestClickExport_FlattensRegionsIntoColumns(t *testing.T) {
+	st := store.NewMemoryStore()
+	_ = st.RecordClick(store.ClickEvent{QrCodeID: "abc123", At: time.Date(2026, 3, 3, 9, 0, 0, 0, time.UTC), Country: "DE"})
+	_ = st.RecordClick(store.ClickEvent{QrCodeID: "abc123", At: time.Date(2026, 3, 3, 9, 30, 0, 0, time.UTC), Country: "US"})
+	_ = st.RecordClick(store.ClickEvent{QrCodeID: "abc123", At: time.Date(2026, 3, 1, 18, 0, 0, 0, time.UTC), Country: "DE"})
+	get := signedInAs(t, "user-1", Server{Store: st})
+
+	w := get("/api/clicks/export?qrId=abc123&from=2026-03-01&to=2026-03-31")
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "text/csv; charset=utf-8" {
+		t.Fatalf("expected a CSV, got %d %s", w.Code, w.Header().Get("Content-Type"))
+	}
+	records, err := csv.NewReader(w.Body).ReadAll()
+	if err != nil {
+		t.Fatalf("csv: %v", err)
+	}
+	if len(records) != 3 {
+		t.Fatalf("expected a header and 2 days, got %v", records)
+	}
+	header, mar3 := records[0], records[2]
+	if header[14] != "hour09" || header[29] != "region_DE" || header[30] != "region_US" {
+		t.Fatalf("unexpected header %v", header)
+	}
+	if mar3[1] != "2026-03-03" || mar3[3] != "2" || mar3[14] != "2" || mar3[29] != "1" || mar3[30] != "1" {
+		t.Fatalf("unexpected Mar 3 row %v", mar3)
+	}
+
+	w = get("/api/clicks/export?qrId=abc123&format=xlsx")
+	if w.Code != http.StatusOK || !bytes.HasPrefix(w.Body.Bytes(), []byte("PK")) {
+		t.Fatalf("expected an xlsx archive, got %d", w.Code)
+	}
+
+	w = get("/api/clicks/export?qrId=abc123&format=pdf")
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for an unknown format, got %d", w.Code)
+	}
+}
+
+func TestClickAnalytics_OwnersOnly(t *testing.T) {
+	st := store.NewMemoryStore()
+	_ = st.RecordClick(store.ClickEvent{QrCodeID: "abc123", At: time.Now().UTC(), IP: "198.51.100.1"})
+	qrSpy := &qrClientSpy{resp: qrclient.QrCode{ID: "abc123", OwnerID: "alice"}}
+	router := NewRouter(Server{Store: st, QrClient: qrSpy, Tags: tagsStub{"spring": {"abc123"}}})
+	mallory := signedInAs(t, "mallory", Server{Store: st, QrClient: qrSpy})
+	alice := signedInAs(t, "alice", Server{Store: st, QrClient: qrSpy})
+
+	paths := []string{
+		"/api/clicks/stats?qrId=abc123",
+		"/api/clicks/series?qrId=abc123",
+		"/api/clicks/export?qrId=abc123",
+		"/api/clicks/daily?qrId=abc123",
+		"/api/clicks/daily-batch?qrId=abc123&days=2026-01-02",
+		"/api/clicks/abc123",
+		"/api/clicks/abc123/daily",
+	}
+	for _, path := range append(paths, "/api/clicks/tag-stats?tagId=spring") {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		if w.Code != http.StatusUnauthorized {
+			t.Fatalf("anonymous %s: expected %d, got %d", path, http.StatusUnauthorized, w.Code)
+		}
+	}
+	for _, path := range paths {
+		if w := mallory(path); w.Code != http.StatusNotFound {
+			t.Fatalf("mallory %s: expected %d, got %d", path, http.StatusNotFound, w.Code)
+		}
+		if w := alice(path); w.Code != http.StatusOK {
+			t.Fatalf("alice %s: expected %d, got %d %s", path, http.StatusOK, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestClickStats_APIKeysNeedClicksRead(t *testing.T) {
+	st := store.NewMemoryStore()
+	_ = st.RecordClick(store.ClickEvent{QrCodeID: "abc123", At: time.Now().UTC(), IP: "198.51.100.1"})
+	keys := authtest.APIKeys(t, map[string]authtest.APIKey{
+		"qrk_clicks": {UserID: "user-1", Scopes: []string{auth.ScopeClicksRead}},
+		"qrk_qr":     {UserID: "user-1", Scopes: []string{auth.ScopeQrRead, auth.ScopeQrWrite}},
+	})
+	qrSpy := &qrClientSpy{resp: qrclient.QrCode{ID: "abc123", OwnerID: "user-1"}}
+	router := NewRouter(Server{Store: st, QrClient: qrSpy, APIKeys: keys})
+
+	for key, want := range map[string]int{"": http.StatusUnauthorized, "qrk_clicks": http.StatusOK, "qrk_qr": http.StatusForbidden} {
+		req := httptest.NewRequest(http.MethodGet, "/api/clicks/stats?qrId=abc123", nil)
+		if key != "" {
+			req.Header.Set("Authorization", "Bearer "+key)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != want {
+			t.Fatalf("%s: expected %d, got %d", key, want, w.Code)
+		}
+	}
+}
+
+func TestRateLimit_ScannersPerIPAndKeysOnTheirPlan(t *testing.T) {
+	st := store.NewMemoryStore()
+	_ = st.RecordClick(store.ClickEvent{QrCodeID: "abc123", At: time.Now().UTC(), IP: "198.51.100.1"})
+	keys := authtest.APIKeys(t, map[string]authtest.APIKey{"qrk_clicks": {UserID: "user-1", Entitlements: "basic", Scopes: []string{auth.ScopeClicksRead}}})
+	qrSpy := &qrClientSpy{resp: qrclient.QrCode{ID: "abc123", OwnerID: "user-1", URL: "https://example.com/db", Active: true}}
+	limiter := &ratelimit.Limiter{Store: ratelimit.NewMemoryStore(), Name: "click", Limits: map[string]ratelimit.Limit{
+		ratelimit.AnonymousPlan: ratelimit.PerMinute(1),
+		"basic":                 ratelimit.PerMinute(3),
+	}}
+	router := NewRouter(Server{Store: st, QrClient: qrSpy, APIKeys: keys, RateLimit: limiter})
+
+	for i, want := range []int{http.StatusFound, http.StatusTooManyRequests} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/r/abc123", nil))
+		if w.Code != want {
+			t.Fatalf("scan %d: expected %d, got %d", i+1, want, w.Code)
+		}
+	}
+
+	for i, want := range []int{http.StatusOK, http.StatusOK, http.StatusOK, http.StatusTooManyRequests} {
+		req := httptest.NewRequest(http.MethodGet, "/api/clicks/stats?qrId=abc123", nil)
+		req.Header.Set("X-API-Key", "qrk_clicks")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != want {
+			t.Fatalf("stats %d: expected %d, got %d", i+1, want, w.Code)
+		}
+		if w.Header().Get("X-RateLimit-Limit") != "3" {
+			t.Fatalf("expected the basic plan's limit in headers, got %v", w.Header())
+		}
+	}
+}
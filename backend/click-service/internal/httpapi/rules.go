@@ -0,0 +1,119 @@
+package httpapi
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"click-service/internal/qrclient"
+)
+
+// matchRule returns the first rule whose conditions all hold for r at now.
+func matchRule(rules []qrclient.RedirectRule, r *http.Request, now time.Time) (qrclient.RedirectRule, bool) {
+	if len(rules) == 0 {
+		return qrclient.RedirectRule{}, false
+	}
+	platform := platformFromUserAgent(r.UserAgent())
+	lang := preferredLanguage(r.Header.Get("Accept-Language"))
+	country := strings.ToUpper(countryFromHeaders(r))
+	for _, rule := range rules {
+		if len(rule.Platforms) > 0 && !slices.Contains(rule.Platforms, platform) {
+			continue
+		}
+		if len(rule.Languages) > 0 && !slices.ContainsFunc(rule.Languages, func(l string) bool { return languageMatches(l, lang) }) {
+			continue
+		}
+		if len(rule.Countries) > 0 && !slices.Contains(rule.Countries, country) {
+			continue
+		}
+		if !ruleTimeMatches(rule, now) {
+			continue
+		}
+		return rule, true
+	}
+	return qrclient.RedirectRule{}, false
+}
+
+// platformFromUserAgent buckets a user agent as "ios", "android" or
+// "desktop". Anything that is not recognisably a phone counts as desktop.
+func platformFromUserAgent(ua string) string {
+	switch {
+	case strings.Contains(ua, "iPhone"), strings.Contains(ua, "iPad"), strings.Contains(ua, "iPod"):
+		return "ios"
+	case strings.Contains(ua, "Android"):
+		return "android"
+	default:
+		return "desktop"
+	}
+}
+
+// preferredLanguage returns the lowercased Accept-Language entry with the
+// highest q value, or "" when there is none. Ties keep header order.
+func preferredLanguage(header string) string {
+	best, bestQ := "", 0.0
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || tag == "*" {
+			continue
+		}
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+		if q > bestQ {
+			best, bestQ = tag, q
+		}
+	}
+	return best
+}
+
+// languageMatches reports whether rule language want covers tag got: "pt"
+// covers pt and pt-br, "pt-br" only pt-br.
+func languageMatches(want, got string) bool {
+	return got == want || strings.HasPrefix(got, want+"-")
+}
+
+// ruleTimeMatches checks the weekday and time-of-day conditions in the
+// rule's zone. Time windows that end before they start wrap past midnight.
+func ruleTimeMatches(rule qrclient.RedirectRule, now time.Time) bool {
+	if len(rule.Weekdays) == 0 && rule.TimeFrom == "" {
+		return true
+	}
+	loc := time.UTC
+	if rule.TimeZone != "" {
+		l, err := time.LoadLocation(rule.TimeZone)
+		if err != nil {
+			return false
+		}
+		loc = l
+	}
+	local := now.In(loc)
+	if len(rule.Weekdays) > 0 {
+		day := strings.ToLower(local.Weekday().String()[:3])
+		if !slices.Contains(rule.Weekdays, day) {
+			return false
+		}
+	}
+	if rule.TimeFrom == "" {
+		return true
+	}
+	from, errFrom := time.Parse("15:04", rule.TimeFrom)
+	until, errUntil := time.Parse("15:04", rule.TimeUntil)
+	if errFrom != nil || errUntil != nil {
+		return false
+	}
+	minute := local.Hour()*60 + local.Minute()
+	start := from.Hour()*60 + from.Minute()
+	end := until.Hour()*60 + until.Minute()
+	if start < end {
+		return minute >= start && minute < end
+	}
+	return minute >= start || minute < end
+}
@@ -0,0 +1,206 @@
+package httpapi
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"click-service/internal/store"
+)
+
+// maxSeriesDays bounds one series request; a year of hourly buckets is
+// still a small response.
+const maxSeriesDays = 366
+
+type seriesBucket struct {
+	// Start is the bucket's first instant in the requested zone.
+	Start string `json:"start"`
+	Total int    `json:"total"`
+}
+
+type clickSeries struct {
+	QrCodeID    string `json:"qrCodeId"`
+	From        string `json:"from"`
+	To          string `json:"to"`
+	Granularity string `json:"granularity"`
+	TZ          string `json:"tz"`
+	Total       int    `json:"total"`
+	// RegionCounts rolls up the stored days that start inside the range.
+	RegionCounts map[string]int `json:"regionCounts"`
+	Buckets      []seriesBucket `json:"buckets"`
+}
+
+// seriesQuery is a parsed series request: [start, end) in loc.
+type seriesQuery struct {
+	start, end  time.Time
+	granularity string
+	loc         *time.Location
+}
+
+// parseSeriesQuery reads from/to (inclusive days, default the last 30 days),
+// granularity (default day) and tz (default UTC). It returns the error code
+// to report when a parameter is invalid.
+func parseSeriesQuery(r *http.Request) (seriesQuery, string) {
+	q := r.URL.Query()
+
+	tz := strings.TrimSpace(q.Get("tz"))
+	if tz == "" {
+		tz = "UTC"
+	}
+	loc, err := time.LoadLocation(tz)
+	if err != nil {
+		return seriesQuery{}, "tz_invalid"
+	}
+
+	granularity := strings.TrimSpace(q.Get("granularity"))
+	switch granularity {
+	case "":
+		granularity = "day"
+	case "hour", "day", "week", "month":
+	default:
+		return seriesQuery{}, "granularity_invalid"
+	}
+
+	fromDay, toDay, code := parseDayRange(q, loc)
+	if code != "" {
+		return seriesQuery{}, code
+	}
+	return seriesQuery{start: fromDay, end: toDay.AddDate(0, 0, 1), granularity: granularity, loc: loc}, ""
+}
+
+// parseDayRange reads the inclusive from/to days in loc, defaulting to the
+// 30 days up to today, and caps the range at maxSeriesDays.
+func parseDayRange(q url.Values, loc *time.Location) (fromDay, toDay time.Time, code string) {
+	now := time.Now().In(loc)
+	toDay = time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+	if raw := strings.TrimSpace(q.Get("to")); raw != "" {
+		d, err := time.ParseInLocation("2006-01-02", raw, loc)
+		if err != nil {
+			return time.Time{}, time.Time{}, "to_invalid"
+		}
+		toDay = d
+	}
+	fromDay = toDay.AddDate(0, 0, -29)
+	if raw := strings.TrimSpace(q.Get("from")); raw != "" {
+		d, err := time.ParseInLocation("2006-01-02", raw, loc)
+		if err != nil {
+			return time.Time{}, time.Time{}, "from_invalid"
+		}
+		fromDay = d
+	}
+	if toDay.Before(fromDay) {
+		return time.Time{}, time.Time{}, "to_invalid"
+	}
+	if fromDay.AddDate(0, 0, maxSeriesDays).Before(toDay.AddDate(0, 0, 1)) {
+		return time.Time{}, time.Time{}, "range_too_large"
+	}
+	return fromDay, toDay, ""
+}
+
+// bucketStarts lists the start of every bucket covering [start, end). The
+// first week or month bucket may begin before start. Calendar steps use
+// AddDate so days stay midnight-aligned across DST changes; hours step in
+// absolute time, so a repeated local hour gets its own bucket.
+func (q seriesQuery) bucketStarts() []time.Time {
+	first := q.start
+	switch q.granularity {
+	case "week":
+		// ISO weeks start on Monday.
+		first = first.AddDate(0, 0, -((int(first.Weekday()) + 6) % 7))
+	case "month":
+		first = time.Date(first.Year(), first.Month(), 1, 0, 0, 0, 0, q.loc)
+	}
+
+	var starts []time.Time
+	for t, i := first, 0; t.Before(q.end); i++ {
+		starts = append(starts, t)
+		switch q.granularity {
+		case "hour":
+			t = t.Add(time.Hour)
+		case "day":
+			t = first.AddDate(0, 0, i+1)
+		case "week":
+			t = first.AddDate(0, 0, 7*(i+1))
+		case "month":
+			t = first.AddDate(0, i+1, 0)
+		}
+	}
+	return starts
+}
+
+// storedDays lists the dates of stored days that can hold clicks in
+// [start, end). Stored days are dated in their own zone, never more than 14
+// hours from UTC, so a day of margin on each side covers every zone.
+func (q seriesQuery) storedDays() []time.Time {
+	s, e := q.start.UTC().AddDate(0, 0, -1), q.end.UTC().AddDate(0, 0, 1)
+	day := time.Date(s.Year(), s.Month(), s.Day(), 0, 0, 0, 0, time.UTC)
+	var days []time.Time
+	for ; day.Before(e); day = day.AddDate(0, 0, 1) {
+		days = append(days, day)
+	}
+	return days
+}
+
+// buildSeries spreads stored hours over zero-filled buckets. Stored hours
+// are clock hours in the day's own zone; each goes to the bucket holding its
+// first instant, which is exact whenever the series zone is the code's zone
+// or differs from it by whole hours.
+func buildSeries(qrID string, q seriesQuery, byDay map[string]store.DailyClickStats) clickSeries {
+	starts := q.bucketStarts()
+	out := clickSeries{
+		QrCodeID:     qrID,
+		From:         q.start.Format("2006-01-02"),
+		To:           q.end.AddDate(0, 0, -1).Format("2006-01-02"),
+		Granularity:  q.granularity,
+		TZ:           q.loc.String(),
+		RegionCounts: map[string]int{},
+		Buckets:      make([]seriesBucket, len(starts)),
+	}
+	for i, t := range starts {
+		out.Buckets[i].Start = t.Format(time.RFC3339)
+	}
+
+	zones := map[string]*time.Location{}
+	for _, ds := range byDay {
+		loc, ok := zones[ds.TimeZone]
+		if !ok {
+			var err error
+			if loc, err = time.LoadLocation(ds.TimeZone); err != nil {
+				loc = time.UTC
+			}
+			zones[ds.TimeZone] = loc
+		}
+		day, err := time.ParseInLocation("2006-01-02", ds.DayIso, loc)
+		if err != nil {
+			continue
+		}
+		if !day.Before(q.start) && day.Before(q.end) {
+			for region, n := range ds.RegionCounts {
+				out.RegionCounts[region] += n
+			}
+		}
+		for h, n := range ds.Hours() {
+			at := time.Date(day.Year(), day.Month(), day.Day(), h, 0, 0, 0, loc)
+			if n == 0 || at.Before(q.start) || !at.Before(q.end) {
+				continue
+			}
+			i := sort.Search(len(starts), func(i int) bool { return starts[i].After(at) }) - 1
+			out.Buckets[i].Total += n
+			out.Total += n
+		}
+	}
+	return out
+}
+
+// clickSeriesFor loads the days a series needs in one batch. A code without
+// clicks gets an all-zero series.
+func clickSeriesFor(st store.Store, qrID string, q seriesQuery) (clickSeries, error) {
+	byDay, err := st.GetDailyBatch(qrID, q.storedDays())
+	if err != nil && !errors.Is(err, store.ErrNotFound) {
+		return clickSeries{}, err
+	}
+	return buildSeries(qrID, q, byDay), nil
+}
@@ -0,0 +1,126 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"click-service/internal/auth"
+	"click-service/internal/live"
+	"click-service/internal/qrclient"
+	"click-service/internal/store"
+	"click-service/internal/useragent"
+)
+
+const defaultStreamHeartbeat = 15 * time.Second
+
+// liveClick is what a stream shows of a click. Visitor IPs and raw user
+// agents stay out of it, as they do everywhere outside the admin event log.
+type liveClick struct {
+	QrCodeID  string `json:"qrCodeId"`
+	AtIso     string `json:"atIso"`
+	TargetURL string `json:"targetUrl"`
+	Country   string `json:"country,omitempty"`
+	Variant   string `json:"variant,omitempty"`
+	Device    string `json:"device"`
+	OS        string `json:"os"`
+	Browser   string `json:"browser"`
+}
+
+func liveClickFrom(ev store.ClickEvent) liveClick {
+	ua := useragent.Parse(ev.UserAgent)
+	return liveClick{
+		QrCodeID:  ev.QrCodeID,
+		AtIso:     ev.At.UTC().Format(time.RFC3339),
+		TargetURL: ev.TargetURL,
+		Country:   ev.Country,
+		Variant:   ev.Variant,
+		Device:    ua.Device,
+		OS:        ua.OS,
+		Browser:   ua.Browser,
+	}
+}
+
+// scanEventFrom is the same view of a click, for qr.scanned webhooks.
+func scanEventFrom(ev store.ClickEvent) qrclient.ScanEvent {
+	c := liveClickFrom(ev)
+	return qrclient.ScanEvent{QrCodeID: c.QrCodeID, AtIso: c.AtIso, TargetURL: c.TargetURL, Country: c.Country, Variant: c.Variant, Device: c.Device, OS: c.OS, Browser: c.Browser}
+}
+
+// serveStream answers /api/clicks/stream?qrId=xxx with Server-Sent Events: a
+// "click" event for every scan of the caller's code redirected by this
+// instance, and a comment line every heartbeat so proxies keep the
+// connection open.
+func serveStream(srv Server, w http.ResponseWriter, r *http.Request) {
+	id, ok := auth.FromContext(r.Context())
+	if !ok {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "unauthorized"})
+		return
+	}
+	qrID := strings.TrimSpace(r.URL.Query().Get("qrId"))
+	if qrID == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "qrId_required"})
+		return
+	}
+	if !srv.authorizeQrCode(w, r, id.UserID, qrID) {
+		return
+	}
+
+	sub, err := srv.Live.Subscribe(id.UserID, qrID)
+	if err != nil {
+		if errors.Is(err, live.ErrTooManySubscribers) {
+			writeJSON(w, http.StatusTooManyRequests, map[string]string{"error": "too_many_streams"})
+			return
+		}
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "stream_unavailable"})
+		return
+	}
+	defer sub.Close()
+
+	rc := http.NewResponseController(w)
+	// A stream outlives the server's WriteTimeout.
+	_ = rc.SetWriteDeadline(time.Time{})
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-store")
+	// Stop nginx-style proxies from buffering the stream.
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	if _, err := io.WriteString(w, "retry: 5000\n\n"); err != nil {
+		return
+	}
+	if err := rc.Flush(); err != nil {
+		return
+	}
+
+	heartbeat := srv.StreamHeartbeat
+	if heartbeat <= 0 {
+		heartbeat = defaultStreamHeartbeat
+	}
+	ticker := time.NewTicker(heartbeat)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case ev, ok := <-sub.C():
+			if !ok {
+				return
+			}
+			data, _ := json.Marshal(liveClickFrom(ev))
+			if _, err := fmt.Fprintf(w, "event: click\ndata: %s\n\n", data); err != nil {
+				return
+			}
+		case <-ticker.C:
+			if _, err := io.WriteString(w, ": heartbeat\n\n"); err != nil {
+				return
+			}
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
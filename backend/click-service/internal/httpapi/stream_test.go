@@ -0,0 +1,110 @@
+package httpapi
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"click-service/internal/auth/authtest"
+	"click-service/internal/live"
+	"click-service/internal/qrclient"
+	"click-service/internal/store"
+)
+
+func TestClickStream_DeliversOwnScansWithHeartbeats(t *testing.T) {
+	ks := authtest.NewKeySet(t)
+	qrSpy := &qrClientSpy{resp: qrclient.QrCode{ID: "abc123", OwnerID: "alice", URL: "https://example.com/menu", Active: true}}
+	srv := httptest.NewServer(NewRouter(Server{
+		Store:           store.NewMemoryStore(),
+		QrClient:        qrSpy,
+		Auth:            ks.Verifier(),
+		Live:            live.NewHub(1),
+		StreamHeartbeat: 20 * time.Millisecond,
+	}))
+	defer srv.Close()
+
+	open := func(token string) *http.Response {
+		req, _ := http.NewRequest(http.MethodGet, srv.URL+"/api/clicks/stream?qrId=abc123", nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("stream: %v", err)
+		}
+		return resp
+	}
+
+	for token, want := range map[string]int{"": http.StatusUnauthorized, ks.IDToken(t, "mallory", "free"): http.StatusNotFound} {
+		resp := open(token)
+		resp.Body.Close()
+		if resp.StatusCode != want {
+			t.Fatalf("expected %d, got %d", want, resp.StatusCode)
+		}
+	}
+
+	alice := ks.IDToken(t, "alice", "free")
+	resp := open(alice)
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("expected an event stream, got %d %s", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+	second := open(alice)
+	second.Body.Close()
+	if second.StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("expected the per-user cap to reject a second stream, got %d", second.StatusCode)
+	}
+
+	lines := make(chan string, 64)
+	go func() {
+		sc := bufio.NewScanner(resp.Body)
+		for sc.Scan() {
+			lines <- sc.Text()
+		}
+		close(lines)
+	}()
+	waitFor := func(prefix string) string {
+		t.Helper()
+		deadline := time.After(2 * time.Second)
+		for {
+			select {
+			case line, ok := <-lines:
+				if !ok {
+					t.Fatalf("stream ended waiting for %q", prefix)
+				}
+				if strings.HasPrefix(line, prefix) {
+					return line
+				}
+			case <-deadline:
+				t.Fatalf("timed out waiting for %q", prefix)
+			}
+		}
+	}
+	waitFor(": heartbeat")
+
+	req, _ := http.NewRequest(http.MethodGet, srv.URL+"/r/abc123", nil)
+	req.Header.Set("CF-IPCountry", "DE")
+	req.Header.Set("User-Agent", "Mozilla/5.0 (iPhone; CPU iPhone OS 17_4 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.4 Mobile/15E148 Safari/604.1")
+	noRedirect := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	scan, err := noRedirect.Do(req)
+	if err != nil || scan.StatusCode != http.StatusFound {
+		t.Fatalf("redirect failed: %v", err)
+	}
+	scan.Body.Close()
+
+	waitFor("event: click")
+	var got map[string]any
+	if err := json.Unmarshal([]byte(strings.TrimPrefix(waitFor("data: "), "data: ")), &got); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if got["qrCodeId"] != "abc123" || got["country"] != "DE" || got["device"] != "mobile" {
+		t.Fatalf("unexpected click %v", got)
+	}
+	if _, leaked := got["ip"]; leaked {
+		t.Fatalf("stream must not expose visitor IPs: %v", got)
+	}
+}
@@ -0,0 +1,58 @@
+package httpapi
+
+import (
+	"math/rand/v2"
+	"net/http"
+
+	"click-service/internal/qrclient"
+)
+
+// variantCookieMaxAge is how long a sticky split remembers a visitor.
+const variantCookieMaxAge = 90 * 24 * 60 * 60
+
+// pickVariant chooses one of the code's split variants in proportion to their
+// weights. For sticky splits a visitor who already has a variant cookie keeps
+// that variant while it still exists, and new visitors are given the cookie.
+func pickVariant(w http.ResponseWriter, r *http.Request, qr qrclient.QrCode) (qrclient.Variant, bool) {
+	if len(qr.Variants) == 0 {
+		return qrclient.Variant{}, false
+	}
+	name := "qrv_" + qr.ID
+	if qr.StickyVariants {
+		if c, err := r.Cookie(name); err == nil {
+			for _, v := range qr.Variants {
+				if v.ID == c.Value {
+					return v, true
+				}
+			}
+		}
+	}
+
+	total := 0
+	for _, v := range qr.Variants {
+		total += max(v.Weight, 0)
+	}
+	chosen := qr.Variants[0]
+	if total > 0 {
+		n := rand.IntN(total)
+		for _, v := range qr.Variants {
+			if n < max(v.Weight, 0) {
+				chosen = v
+				break
+			}
+			n -= max(v.Weight, 0)
+		}
+	}
+
+	if qr.StickyVariants {
+		http.SetCookie(w, &http.Cookie{
+			Name:     name,
+			Value:    chosen.ID,
+			Path:     "/r/" + qr.ID,
+			MaxAge:   variantCookieMaxAge,
+			HttpOnly: true,
+			SameSite: http.SameSiteLaxMode,
+		})
+	}
+	return chosen, true
+}
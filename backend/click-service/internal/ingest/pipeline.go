@@ -0,0 +1,302 @@
+// Package ingest records clicks off the redirect path: a bounded queue feeds
+// a fixed pool of workers that write in batches, with an optional on-disk
+// spool for batches the store cannot take.
+package ingest
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"click-service/internal/store"
+)
+
+// ErrClosed is returned by Close when called twice.
+var ErrClosed = errors.New("ingest pipeline closed")
+
+// Counter is told how many clicks each code received per written batch.
+type Counter interface {
+	ReportClicks(ctx context.Context, id string, n int64) error
+}
+
+type Options struct {
+	// QueueSize bounds clicks waiting for a worker; when full, new clicks
+	// are dropped and counted rather than slowing redirects down.
+	QueueSize int
+	Workers   int
+	// BatchSize and FlushInterval bound how much and how long a worker
+	// buffers before writing.
+	BatchSize     int
+	FlushInterval time.Duration
+	// SpoolDir, when set, receives batches that fail to write. They are
+	// replayed every ReplayInterval until the store accepts them.
+	SpoolDir       string
+	ReplayInterval time.Duration
+	// Counter, when set, is told about every written click.
+	Counter Counter
+}
+
+func DefaultOptions() Options {
+	return Options{QueueSize: 10000, Workers: 4, BatchSize: 200, FlushInterval: 500 * time.Millisecond, ReplayInterval: 10 * time.Second}
+}
+
+// Stats are running counters for monitoring backpressure.
+type Stats struct {
+	Queued   int    `json:"queued"`
+	Capacity int    `json:"capacity"`
+	Enqueued uint64 `json:"enqueued"`
+	Dropped  uint64 `json:"dropped"`
+	Written  uint64 `json:"written"`
+	Failed   uint64 `json:"failed"`
+	Spooled  uint64 `json:"spooled"`
+	Replayed uint64 `json:"replayed"`
+	Batches  uint64 `json:"batches"`
+}
+
+type Pipeline struct {
+	store store.Store
+	opts  Options
+	spool *spool
+
+	mu     sync.RWMutex // guards closed against sends on queue
+	closed bool
+	queue  chan store.ClickEvent
+
+	workers sync.WaitGroup
+	stop    chan struct{}
+	replay  sync.WaitGroup
+
+	enqueued, dropped, written, failed, spooled, replayed, batches atomic.Uint64
+}
+
+// New starts the workers. Callers must Close the pipeline to flush it.
+func New(st store.Store, opts Options) (*Pipeline, error) {
+	def := DefaultOptions()
+	if opts.QueueSize <= 0 {
+		opts.QueueSize = def.QueueSize
+	}
+	if opts.Workers <= 0 {
+		opts.Workers = def.Workers
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = def.BatchSize
+	}
+	if opts.FlushInterval <= 0 {
+		opts.FlushInterval = def.FlushInterval
+	}
+	if opts.ReplayInterval <= 0 {
+		opts.ReplayInterval = def.ReplayInterval
+	}
+
+	p := &Pipeline{store: st, opts: opts, queue: make(chan store.ClickEvent, opts.QueueSize), stop: make(chan struct{})}
+	if opts.SpoolDir != "" {
+		sp, err := openSpool(opts.SpoolDir)
+		if err != nil {
+			return nil, err
+		}
+		p.spool = sp
+		p.replay.Add(1)
+		go p.replayLoop()
+	}
+	for i := 0; i < opts.Workers; i++ {
+		p.workers.Add(1)
+		go p.work()
+	}
+	return p, nil
+}
+
+// Enqueue hands a click to the workers without blocking. It reports false
+// when the click was dropped because the queue is full or closed.
+func (p *Pipeline) Enqueue(ev store.ClickEvent) bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.closed {
+		p.dropped.Add(1)
+		return false
+	}
+	select {
+	case p.queue <- ev:
+		p.enqueued.Add(1)
+		return true
+	default:
+		p.dropped.Add(1)
+		return false
+	}
+}
+
+func (p *Pipeline) Stats() Stats {
+	return Stats{
+		Queued:   len(p.queue),
+		Capacity: cap(p.queue),
+		Enqueued: p.enqueued.Load(),
+		Dropped:  p.dropped.Load(),
+		Written:  p.written.Load(),
+		Failed:   p.failed.Load(),
+		Spooled:  p.spooled.Load(),
+		Replayed: p.replayed.Load(),
+		Batches:  p.batches.Load(),
+	}
+}
+
+// Close stops accepting clicks and waits for queued ones to be written or
+// spooled. If ctx ends first, clicks still queued are lost.
+func (p *Pipeline) Close(ctx context.Context) error {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return ErrClosed
+	}
+	p.closed = true
+	close(p.queue)
+	p.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		p.workers.Wait()
+		close(p.stop)
+		p.replay.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (p *Pipeline) work() {
+	defer p.workers.Done()
+
+	batch := make([]store.ClickEvent, 0, p.opts.BatchSize)
+	ticker := time.NewTicker(p.opts.FlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case ev, ok := <-p.queue:
+			if !ok {
+				p.flush(batch)
+				return
+			}
+			batch = append(batch, ev)
+			if len(batch) >= p.opts.BatchSize {
+				p.flush(batch)
+				batch = batch[:0]
+			}
+		case <-ticker.C:
+			if len(batch) > 0 {
+				p.flush(batch)
+				batch = batch[:0]
+			}
+		}
+	}
+}
+
+// flush writes one batch, retrying briefly. A batch that still fails goes to
+// the spool, or is counted as failed when there is none.
+func (p *Pipeline) flush(batch []store.ClickEvent) {
+	if len(batch) == 0 {
+		return
+	}
+	p.batches.Add(1)
+
+	var err error
+	for attempt := 0; attempt < 3; attempt++ {
+		if attempt > 0 {
+			time.Sleep(time.Duration(attempt) * 100 * time.Millisecond)
+		}
+		if err = p.write(batch); err == nil {
+			p.written.Add(uint64(len(batch)))
+			p.report(batch)
+			return
+		}
+		if p.spool != nil {
+			// Don't hold the worker up; the replay loop keeps trying.
+			break
+		}
+	}
+
+	if p.spool != nil {
+		serr := p.spool.write(batch)
+		if serr == nil {
+			p.spooled.Add(uint64(len(batch)))
+			return
+		}
+		log.Printf("click spool write failed: %v", serr)
+	}
+	p.failed.Add(uint64(len(batch)))
+	log.Printf("dropped %d clicks after write failure: %v", len(batch), err)
+}
+
+// write uses the store's batch path when it has one.
+func (p *Pipeline) write(batch []store.ClickEvent) error {
+	if br, ok := p.store.(store.BatchRecorder); ok {
+		return br.RecordClicks(batch)
+	}
+	for _, ev := range batch {
+		if err := p.store.RecordClick(ev); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// report sends one counter update per code in the batch.
+func (p *Pipeline) report(batch []store.ClickEvent) {
+	if p.opts.Counter == nil {
+		return
+	}
+	counts := map[string]int64{}
+	for _, ev := range batch {
+		counts[ev.QrCodeID]++
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	for id, n := range counts {
+		_ = p.opts.Counter.ReportClicks(ctx, id, n)
+	}
+}
+
+func (p *Pipeline) replayLoop() {
+	defer p.replay.Done()
+	ticker := time.NewTicker(p.opts.ReplayInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.stop:
+			return
+		case <-ticker.C:
+			p.replaySpool()
+		}
+	}
+}
+
+// replaySpool writes spooled batches oldest first, stopping at the first
+// failure so the order of a backlog is kept.
+func (p *Pipeline) replaySpool() {
+	files, err := p.spool.list()
+	if err != nil {
+		log.Printf("click spool list failed: %v", err)
+		return
+	}
+	for _, name := range files {
+		batch, err := p.spool.read(name)
+		if err != nil {
+			log.Printf("click spool read %s failed: %v", name, err)
+			continue
+		}
+		if err := p.write(batch); err != nil {
+			return
+		}
+		if err := p.spool.remove(name); err != nil {
+			// The batch is written; leaving the file would count it twice.
+			log.Printf("click spool remove %s failed: %v", name, err)
+			return
+		}
+		p.replayed.Add(uint64(len(batch)))
+		p.report(batch)
+	}
+}
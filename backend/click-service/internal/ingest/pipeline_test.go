@@ -0,0 +1,145 @@
+package ingest
+
+import (
+	"context"
+	"errors"
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"click-service/internal/store"
+)
+
+// flakyStore fails writes while down is set and can hold writers on gate.
+type flakyStore struct {
+	*store.MemoryStore
+	down  atomic.Bool
+	gate  chan struct{}
+	calls atomic.Int64
+}
+
+func (s *flakyStore) RecordClicks(events []store.ClickEvent) error {
+	s.calls.Add(1)
+	if s.gate != nil {
+		<-s.gate
+	}
+	if s.down.Load() {
+		return errors.New("database unavailable")
+	}
+	return s.MemoryStore.RecordClicks(events)
+}
+
+type counterSpy struct {
+	mu     sync.Mutex
+	counts map[string]int64
+}
+
+func (c *counterSpy) ReportClicks(_ context.Context, id string, n int64) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.counts[id] += n
+	return nil
+}
+
+func click(id string) store.ClickEvent {
+	return store.ClickEvent{QrCodeID: id, At: time.Date(2026, 1, 2, 10, 30, 0, 0, time.UTC), Country: "US"}
+}
+
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestPipeline_BatchesAndDrainsOnClose(t *testing.T) {
+	st := &flakyStore{MemoryStore: store.NewMemoryStore()}
+	counter := &counterSpy{counts: map[string]int64{}}
+	p, err := New(st, Options{Workers: 2, BatchSize: 50, FlushInterval: time.Hour, Counter: counter})
+	if err != nil {
+		t.Fatalf("new: %v", err)
+	}
+
+	for i := 0; i < 500; i++ {
+		if !p.Enqueue(click("abc")) {
+			t.Fatalf("enqueue %d refused", i)
+		}
+	}
+	if err := p.Close(context.Background()); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+
+	stats, _ := st.GetStats("abc")
+	if stats.Total != 500 {
+		t.Fatalf("expected all 500 clicks written, got %d", stats.Total)
+	}
+	if n := st.calls.Load(); n > 12 {
+		t.Fatalf("expected batched writes, got %d calls", n)
+	}
+	if counter.counts["abc"] != 500 {
+		t.Fatalf("expected 500 clicks reported, got %d", counter.counts["abc"])
+	}
+	if p.Enqueue(click("abc")) {
+		t.Fatalf("expected enqueue after close to be refused")
+	}
+	if s := p.Stats(); s.Written != 500 || s.Dropped != 1 {
+		t.Fatalf("unexpected stats %+v", s)
+	}
+}
+
+func TestPipeline_DropsWhenQueueIsFull(t *testing.T) {
+	st := &flakyStore{MemoryStore: store.NewMemoryStore(), gate: make(chan struct{})}
+	p, _ := New(st, Options{Workers: 1, QueueSize: 2, BatchSize: 1, FlushInterval: time.Hour})
+
+	p.Enqueue(click("abc"))
+	waitFor(t, "the worker to block", func() bool { return st.calls.Load() == 1 })
+	accepted := 0
+	for i := 0; i < 5; i++ {
+		if p.Enqueue(click("abc")) {
+			accepted++
+		}
+	}
+	if accepted != 2 {
+		t.Fatalf("expected 2 clicks to fit in the queue, got %d", accepted)
+	}
+	if s := p.Stats(); s.Dropped != 3 || s.Queued != 2 || s.Capacity != 2 {
+		t.Fatalf("unexpected stats %+v", s)
+	}
+	close(st.gate)
+	_ = p.Close(context.Background())
+}
+
+func TestPipeline_SpoolsFailedBatchesAndReplaysThem(t *testing.T) {
+	dir := t.TempDir()
+	st := &flakyStore{MemoryStore: store.NewMemoryStore()}
+	st.down.Store(true)
+	p, err := New(st, Options{Workers: 1, BatchSize: 10, FlushInterval: 5 * time.Millisecond, SpoolDir: dir, ReplayInterval: 10 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("new: %v", err)
+	}
+	defer p.Close(context.Background())
+
+	for i := 0; i < 25; i++ {
+		p.Enqueue(click("abc"))
+	}
+	waitFor(t, "clicks to be spooled", func() bool { return p.Stats().Spooled == 25 })
+	if files, _ := os.ReadDir(dir); len(files) == 0 {
+		t.Fatalf("expected spool files")
+	}
+
+	st.down.Store(false)
+	waitFor(t, "the spool to be replayed", func() bool { return p.Stats().Replayed == 25 })
+	stats, _ := st.GetStats("abc")
+	if stats.Total != 25 || stats.LastAtIso != "2026-01-02T10:30:00Z" {
+		t.Fatalf("unexpected replayed stats %+v", stats)
+	}
+	if files, _ := os.ReadDir(dir); len(files) != 0 {
+		t.Fatalf("expected the spool to be empty, found %d files", len(files))
+	}
+}
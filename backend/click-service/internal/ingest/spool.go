@@ -0,0 +1,104 @@
+package ingest
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"click-service/internal/store"
+)
+
+// spool keeps failed batches as one JSON file each, named so that a
+// lexical sort is oldest first.
+type spool struct {
+	dir string
+	seq atomic.Uint64
+}
+
+// spoolEvent carries At, which ClickEvent leaves out of its JSON.
+type spoolEvent struct {
+	store.ClickEvent
+	At time.Time `json:"at"`
+}
+
+func openSpool(dir string) (*spool, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+	return &spool{dir: dir}, nil
+}
+
+// write stores a batch atomically: a crash leaves either the whole file or
+// only a temp file, which list ignores.
+func (s *spool) write(batch []store.ClickEvent) error {
+	records := make([]spoolEvent, len(batch))
+	for i, ev := range batch {
+		records[i] = spoolEvent{ClickEvent: ev, At: ev.At}
+	}
+	raw, err := json.Marshal(records)
+	if err != nil {
+		return err
+	}
+
+	name := fmt.Sprintf("clicks-%020d-%06d.json", time.Now().UnixNano(), s.seq.Add(1)%1000000)
+	tmp, err := os.CreateTemp(s.dir, ".spool-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(raw); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), filepath.Join(s.dir, name))
+}
+
+func (s *spool) list() ([]string, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, e := range entries {
+		if !e.IsDir() && strings.HasPrefix(e.Name(), "clicks-") && strings.HasSuffix(e.Name(), ".json") {
+			names = append(names, e.Name())
+		}
+	}
+	slices.Sort(names)
+	return names, nil
+}
+
+func (s *spool) read(name string) ([]store.ClickEvent, error) {
+	raw, err := os.ReadFile(filepath.Join(s.dir, name))
+	if err != nil {
+		return nil, err
+	}
+	var records []spoolEvent
+	if err := json.Unmarshal(raw, &records); err != nil {
+		return nil, err
+	}
+	batch := make([]store.ClickEvent, len(records))
+	for i, r := range records {
+		batch[i] = r.ClickEvent
+		batch[i].At = r.At
+	}
+	return batch, nil
+}
+
+func (s *spool) remove(name string) error {
+	return os.Remove(filepath.Join(s.dir, name))
+}
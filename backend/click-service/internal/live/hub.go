@@ -0,0 +1,119 @@
+// Package live fans clicks out to dashboards watching a code in real time.
+// It is in-process only: each instance serves the streams connected to it
+// with the clicks it redirected itself.
+package live
+
+import (
+	"errors"
+	"sync"
+
+	"click-service/internal/store"
+)
+
+// ErrTooManySubscribers is returned when a user already has MaxPerUser
+// streams open on this instance.
+var ErrTooManySubscribers = errors.New("too many live subscribers")
+
+const (
+	DefaultMaxPerUser = 5
+	// bufferSize is how many clicks a subscriber may fall behind before it
+	// starts missing them; publishing never waits for a slow reader.
+	bufferSize = 64
+)
+
+// Hub routes published clicks to the subscribers of their code.
+type Hub struct {
+	// MaxPerUser caps concurrent subscriptions per user; 0 means no cap.
+	MaxPerUser int
+
+	mu      sync.Mutex
+	byCode  map[string]map[*Subscription]struct{}
+	perUser map[string]int
+	closed  bool
+}
+
+func NewHub(maxPerUser int) *Hub {
+	return &Hub{MaxPerUser: maxPerUser, byCode: map[string]map[*Subscription]struct{}{}, perUser: map[string]int{}}
+}
+
+// Subscription receives the clicks of one code until it is closed.
+type Subscription struct {
+	hub      *Hub
+	userID   string
+	qrCodeID string
+	ch       chan store.ClickEvent
+	once     sync.Once
+}
+
+// C delivers clicks. It is closed when the subscription or the hub is.
+func (s *Subscription) C() <-chan store.ClickEvent { return s.ch }
+
+// Subscribe starts delivering qrCodeID's clicks to userID.
+func (h *Hub) Subscribe(userID, qrCodeID string) (*Subscription, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.closed {
+		return nil, errors.New("live hub closed")
+	}
+	if h.MaxPerUser > 0 && h.perUser[userID] >= h.MaxPerUser {
+		return nil, ErrTooManySubscribers
+	}
+	sub := &Subscription{hub: h, userID: userID, qrCodeID: qrCodeID, ch: make(chan store.ClickEvent, bufferSize)}
+	subs, ok := h.byCode[qrCodeID]
+	if !ok {
+		subs = map[*Subscription]struct{}{}
+		h.byCode[qrCodeID] = subs
+	}
+	subs[sub] = struct{}{}
+	h.perUser[userID]++
+	return sub, nil
+}
+
+// Close stops delivery and frees the user's slot. It is safe to call more
+// than once.
+func (s *Subscription) Close() {
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+	s.closeLocked()
+}
+
+func (s *Subscription) closeLocked() {
+	s.once.Do(func() {
+		h := s.hub
+		delete(h.byCode[s.qrCodeID], s)
+		if len(h.byCode[s.qrCodeID]) == 0 {
+			delete(h.byCode, s.qrCodeID)
+		}
+		if h.perUser[s.userID]--; h.perUser[s.userID] <= 0 {
+			delete(h.perUser, s.userID)
+		}
+		close(s.ch)
+	})
+}
+
+// Publish hands ev to every subscriber of its code without blocking; a
+// subscriber whose buffer is full misses it.
+func (h *Hub) Publish(ev store.ClickEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for sub := range h.byCode[ev.QrCodeID] {
+		select {
+		case sub.ch <- ev:
+		default:
+		}
+	}
+}
+
+// Close ends every subscription, so streams finish before a server shutdown
+// has to wait for them.
+func (h *Hub) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.closed = true
+	for _, subs := range h.byCode {
+		for sub := range subs {
+			sub.closeLocked()
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package live
+
+import (
+	"testing"
+
+	"click-service/internal/store"
+)
+
+func TestHub_RoutesByCodeAndCapsPerUser(t *testing.T) {
+	h := NewHub(2)
+	menu, _ := h.Subscribe("alice", "menu")
+	flyer, _ := h.Subscribe("alice", "flyer")
+	if _, err := h.Subscribe("alice", "menu"); err != ErrTooManySubscribers {
+		t.Fatalf("expected ErrTooManySubscribers, got %v", err)
+	}
+	if _, err := h.Subscribe("bob", "menu"); err != nil {
+		t.Fatalf("the cap is per user: %v", err)
+	}
+
+	h.Publish(store.ClickEvent{QrCodeID: "menu", Country: "DE"})
+	if ev := <-menu.C(); ev.Country != "DE" {
+		t.Fatalf("unexpected event %+v", ev)
+	}
+	select {
+	case ev := <-flyer.C():
+		t.Fatalf("flyer subscriber got a menu click %+v", ev)
+	default:
+	}
+
+	flyer.Close()
+	flyer.Close()
+	if _, ok := <-flyer.C(); ok {
+		t.Fatalf("expected a closed channel")
+	}
+	if _, err := h.Subscribe("alice", "flyer"); err != nil {
+		t.Fatalf("closing should free a slot: %v", err)
+	}
+}
+
+func TestHub_SlowSubscriberMissesInsteadOfBlocking(t *testing.T) {
+	h := NewHub(0)
+	sub, _ := h.Subscribe("alice", "menu")
+	for i := 0; i < bufferSize+10; i++ {
+		h.Publish(store.ClickEvent{QrCodeID: "menu"})
+	}
+	if n := len(sub.C()); n != bufferSize {
+		t.Fatalf("expected a full buffer of %d, got %d", bufferSize, n)
+	}
+
+	h.Close()
+	for range sub.C() {
+	}
+	if _, err := h.Subscribe("alice", "menu"); err == nil {
+		t.Fatalf("expected subscribing to a closed hub to fail")
+	}
+}
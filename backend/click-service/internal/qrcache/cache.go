@@ -0,0 +1,255 @@
+// Package qrcache keeps the redirect path's qr-service lookups in memory so
+// scans stay fast, and keep working, when qr-service is slow or briefly down.
+package qrcache
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"click-service/internal/qrclient"
+)
+
+// Upstream is the qr-service client the cache fronts. The Fetch methods
+// return qrclient.ErrNotModified when the given ETag is still current.
+type Upstream interface {
+	FetchQrCode(ctx context.Context, id, etag string) (qrclient.QrCode, string, error)
+	FetchSettings(ctx context.Context, ownerID, etag string) (qrclient.Settings, string, error)
+}
+
+type Options struct {
+	// MaxEntries bounds each of the code and settings caches; the least
+	// recently used entry is evicted first.
+	MaxEntries int
+	// TTL is how long an entry is served without asking qr-service. It is
+	// also how long an edit can take to reach scanners.
+	TTL time.Duration
+	// StaleTTL is how much longer an entry may be served past TTL while it
+	// is revalidated in the background. Past that, lookups wait for
+	// qr-service, and fall back to the entry only if qr-service fails.
+	StaleTTL time.Duration
+	// NegativeTTL is how long an unknown code is remembered as unknown.
+	NegativeTTL time.Duration
+	// RefreshTimeout bounds each qr-service call the cache makes: background
+	// revalidation and loads shared by concurrent misses, which outlive the
+	// request that started them.
+	RefreshTimeout time.Duration
+}
+
+// DefaultOptions suit a single click-service instance in front of qr-service.
+func DefaultOptions() Options {
+	return Options{MaxEntries: 10000, TTL: 5 * time.Second, StaleTTL: 10 * time.Minute, NegativeTTL: 5 * time.Second, RefreshTimeout: 5 * time.Second}
+}
+
+// Cache implements the redirect handler's QrClient on top of an Upstream.
+type Cache struct {
+	codes    *table[qrclient.QrCode]
+	settings *table[qrclient.Settings]
+}
+
+func New(up Upstream, opts Options) *Cache {
+	def := DefaultOptions()
+	if opts.MaxEntries <= 0 {
+		opts.MaxEntries = def.MaxEntries
+	}
+	if opts.TTL <= 0 {
+		opts.TTL = def.TTL
+	}
+	if opts.StaleTTL < 0 {
+		opts.StaleTTL = 0
+	}
+	if opts.NegativeTTL <= 0 {
+		opts.NegativeTTL = def.NegativeTTL
+	}
+	if opts.RefreshTimeout <= 0 {
+		opts.RefreshTimeout = def.RefreshTimeout
+	}
+	return &Cache{
+		codes:    newTable(opts, up.FetchQrCode),
+		settings: newTable(opts, up.FetchSettings),
+	}
+}
+
+func (c *Cache) GetQrCode(ctx context.Context, id string) (qrclient.QrCode, error) {
+	return c.codes.get(ctx, id)
+}
+
+func (c *Cache) GetSettings(ctx context.Context, ownerID string) (qrclient.Settings, error) {
+	return c.settings.get(ctx, ownerID)
+}
+
+type fetchFunc[V any] func(ctx context.Context, key, etag string) (V, string, error)
+
+type entry[V any] struct {
+	key        string
+	value      V
+	etag       string
+	notFound   bool
+	fetchedAt  time.Time
+	refreshing bool
+}
+
+// call is an in-flight synchronous fetch that concurrent misses share.
+type call[V any] struct {
+	done  chan struct{}
+	value V
+	err   error
+}
+
+// table is one LRU cache keyed by ID.
+type table[V any] struct {
+	opts  Options
+	fetch fetchFunc[V]
+	now   func() time.Time
+
+	mu       sync.Mutex
+	items    map[string]*list.Element
+	order    *list.List // front is most recently used
+	inflight map[string]*call[V]
+}
+
+func newTable[V any](opts Options, fetch fetchFunc[V]) *table[V] {
+	return &table[V]{opts: opts, fetch: fetch, now: time.Now, items: map[string]*list.Element{}, order: list.New(), inflight: map[string]*call[V]{}}
+}
+
+func (t *table[V]) get(ctx context.Context, key string) (V, error) {
+	var zero V
+	now := t.now()
+
+	t.mu.Lock()
+	if el, ok := t.items[key]; ok {
+		e := el.Value.(*entry[V])
+		t.order.MoveToFront(el)
+		age := now.Sub(e.fetchedAt)
+		if e.notFound {
+			if age < t.opts.NegativeTTL {
+				t.mu.Unlock()
+				return zero, qrclient.ErrNotFound
+			}
+		} else if age < t.opts.TTL {
+			t.mu.Unlock()
+			return e.value, nil
+		} else if age < t.opts.TTL+t.opts.StaleTTL {
+			if !e.refreshing {
+				e.refreshing = true
+				go t.refresh(key, e.etag)
+			}
+			t.mu.Unlock()
+			return e.value, nil
+		}
+	}
+	c, ok := t.inflight[key]
+	if !ok {
+		c = &call[V]{done: make(chan struct{})}
+		t.inflight[key] = c
+		go t.loadShared(key, c)
+	}
+	t.mu.Unlock()
+
+	select {
+	case <-c.done:
+		return c.value, c.err
+	case <-ctx.Done():
+		return zero, ctx.Err()
+	}
+}
+
+// loadShared runs the load that concurrent misses for key wait on. It is not
+// tied to any one caller's context, so a scanner who gives up does not fail
+// the lookup for everyone else waiting on it.
+func (t *table[V]) loadShared(key string, c *call[V]) {
+	ctx, cancel := context.WithTimeout(context.Background(), t.opts.RefreshTimeout)
+	defer cancel()
+
+	c.value, c.err = t.load(ctx, key)
+
+	t.mu.Lock()
+	delete(t.inflight, key)
+	t.mu.Unlock()
+	close(c.done)
+}
+
+// load fetches key without revalidation and stores the outcome. On an
+// upstream failure, including an open circuit breaker, it falls back to the
+// last known good record however old it is: a slightly outdated redirect
+// beats a 502 on a printed code.
+func (t *table[V]) load(ctx context.Context, key string) (V, error) {
+	var zero V
+	value, etag, err := t.fetch(ctx, key, "")
+	switch {
+	case err == nil:
+		t.store(key, value, etag, false)
+		return value, nil
+	case errors.Is(err, qrclient.ErrNotFound):
+		t.store(key, zero, "", true)
+		return zero, err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if el, ok := t.items[key]; ok {
+		e := el.Value.(*entry[V])
+		if !e.notFound {
+			return e.value, nil
+		}
+	}
+	return zero, err
+}
+
+// refresh revalidates a stale entry in the background. Failures keep the
+// stale value; the next lookup past the stale window retries synchronously.
+func (t *table[V]) refresh(key, etag string) {
+	ctx, cancel := context.WithTimeout(context.Background(), t.opts.RefreshTimeout)
+	defer cancel()
+
+	value, newTag, err := t.fetch(ctx, key, etag)
+	switch {
+	case err == nil:
+		t.store(key, value, newTag, false)
+		return
+	case errors.Is(err, qrclient.ErrNotModified):
+		t.touch(key)
+		return
+	case errors.Is(err, qrclient.ErrNotFound):
+		var zero V
+		t.store(key, zero, "", true)
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if el, ok := t.items[key]; ok {
+		el.Value.(*entry[V]).refreshing = false
+	}
+}
+
+func (t *table[V]) store(key string, value V, etag string, notFound bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	e := &entry[V]{key: key, value: value, etag: etag, notFound: notFound, fetchedAt: t.now()}
+	if el, ok := t.items[key]; ok {
+		el.Value = e
+		t.order.MoveToFront(el)
+		return
+	}
+	t.items[key] = t.order.PushFront(e)
+	for t.order.Len() > t.opts.MaxEntries {
+		oldest := t.order.Back()
+		t.order.Remove(oldest)
+		delete(t.items, oldest.Value.(*entry[V]).key)
+	}
+}
+
+// touch marks an entry fresh again after qr-service confirmed it unchanged.
+func (t *table[V]) touch(key string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if el, ok := t.items[key]; ok {
+		e := el.Value.(*entry[V])
+		e.fetchedAt = t.now()
+		e.refreshing = false
+	}
+}
@@ -0,0 +1,280 @@
+package qrcache
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"click-service/internal/qrclient"
+)
+
+type upstreamFake struct {
+	mu       sync.Mutex
+	codes    map[string]qrclient.QrCode
+	versions map[string]string
+	err      error
+	calls    int
+	gotETags []string
+	fetched  chan struct{}
+}
+
+func (u *upstreamFake) FetchQrCode(_ context.Context, id, etag string) (qrclient.QrCode, string, error) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	defer func() {
+		if u.fetched != nil {
+			u.fetched <- struct{}{}
+		}
+	}()
+	u.calls++
+	u.gotETags = append(u.gotETags, etag)
+	if u.err != nil {
+		return qrclient.QrCode{}, "", u.err
+	}
+	qr, ok := u.codes[id]
+	if !ok {
+		return qrclient.QrCode{}, "", qrclient.ErrNotFound
+	}
+	if etag != "" && etag == u.versions[id] {
+		return qrclient.QrCode{}, etag, qrclient.ErrNotModified
+	}
+	return qr, u.versions[id], nil
+}
+
+func (u *upstreamFake) FetchSettings(_ context.Context, _, _ string) (qrclient.Settings, string, error) {
+	return qrclient.Settings{}, "", nil
+}
+
+func (u *upstreamFake) set(id, url, version string) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.codes[id] = qrclient.QrCode{ID: id, URL: url, Active: true}
+	u.versions[id] = version
+}
+
+func (u *upstreamFake) callCount() int {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return u.calls
+}
+
+type fakeClock struct{ t time.Time }
+
+func (c *fakeClock) now() time.Time          { return c.t }
+func (c *fakeClock) advance(d time.Duration) { c.t = c.t.Add(d) }
+
+func newTestCache(up *upstreamFake, opts Options) (*Cache, *fakeClock) {
+	c := New(up, opts)
+	clock := &fakeClock{t: time.Date(2026, 1, 2, 10, 0, 0, 0, time.UTC)}
+	c.codes.now = clock.now
+	return c, clock
+}
+
+func TestCache_ServesFreshEntriesWithoutUpstream(t *testing.T) {
+	up := &upstreamFake{codes: map[string]qrclient.QrCode{}, versions: map[string]string{}}
+	up.set("abc", "https://example.com/a", `"v1"`)
+	c, clock := newTestCache(up, Options{TTL: 5 * time.Second, StaleTTL: time.Minute})
+
+	for i := 0; i < 3; i++ {
+		qr, err := c.GetQrCode(context.Background(), "abc")
+		if err != nil || qr.URL != "https://example.com/a" {
+			t.Fatalf("unexpected lookup %+v %v", qr, err)
+		}
+		clock.advance(time.Second)
+	}
+	if n := up.callCount(); n != 1 {
+		t.Fatalf("expected 1 upstream call, got %d", n)
+	}
+}
+
+func TestCache_StaleWhileRevalidateUsesETag(t *testing.T) {
+	up := &upstreamFake{codes: map[string]qrclient.QrCode{}, versions: map[string]string{}, fetched: make(chan struct{}, 4)}
+	up.set("abc", "https://example.com/a", `"v1"`)
+	c, clock := newTestCache(up, Options{TTL: 5 * time.Second, StaleTTL: time.Minute})
+
+	_, _ = c.GetQrCode(context.Background(), "abc")
+	<-up.fetched
+	up.set("abc", "https://example.com/b", `"v2"`)
+	clock.advance(10 * time.Second)
+
+	// The stale value is served at once while the refresh runs.
+	if qr, _ := c.GetQrCode(context.Background(), "abc"); qr.URL != "https://example.com/a" {
+		t.Fatalf("expected stale value, got %q", qr.URL)
+	}
+	select {
+	case <-up.fetched:
+	case <-time.After(time.Second):
+		t.Fatalf("expected a background refresh")
+	}
+	if up.gotETags[1] != `"v1"` {
+		t.Fatalf("expected revalidation with the cached ETag, got %q", up.gotETags[1])
+	}
+	deadline := time.Now().Add(time.Second)
+	for {
+		if qr, _ := c.GetQrCode(context.Background(), "abc"); qr.URL == "https://example.com/b" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected the refreshed value")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestCache_FallsBackToLastKnownGoodWhenUpstreamFails(t *testing.T) {
+	up := &upstreamFake{codes: map[string]qrclient.QrCode{}, versions: map[string]string{}}
+	up.set("abc", "https://example.com/a", `"v1"`)
+	c, clock := newTestCache(up, Options{TTL: 5 * time.Second, StaleTTL: time.Minute})
+
+	_, _ = c.GetQrCode(context.Background(), "abc")
+	up.mu.Lock()
+	up.err = errors.New("qr-service down")
+	up.mu.Unlock()
+
+	clock.advance(30 * time.Second)
+	if qr, err := c.GetQrCode(context.Background(), "abc"); err != nil || qr.URL != "https://example.com/a" {
+		t.Fatalf("expected stale value during outage, got %+v %v", qr, err)
+	}
+	clock.advance(time.Hour)
+	if qr, err := c.GetQrCode(context.Background(), "abc"); err != nil || qr.URL != "https://example.com/a" {
+		t.Fatalf("expected last known good record past the stale window, got %+v %v", qr, err)
+	}
+	if _, err := c.GetQrCode(context.Background(), "never-seen"); err == nil {
+		t.Fatalf("expected an error for a code with no cached record")
+	}
+}
+
+func TestCache_RemembersUnknownCodesBriefly(t *testing.T) {
+	up := &upstreamFake{codes: map[string]qrclient.QrCode{}, versions: map[string]string{}}
+	c, clock := newTestCache(up, Options{NegativeTTL: 5 * time.Second})
+
+	for i := 0; i < 3; i++ {
+		if _, err := c.GetQrCode(context.Background(), "missing"); !errors.Is(err, qrclient.ErrNotFound) {
+			t.Fatalf("expected not found, got %v", err)
+		}
+	}
+	if n := up.callCount(); n != 1 {
+		t.Fatalf("expected 1 upstream call, got %d", n)
+	}
+
+	up.set("missing", "https://example.com/new", `"v1"`)
+	clock.advance(6 * time.Second)
+	if qr, err := c.GetQrCode(context.Background(), "missing"); err != nil || qr.URL != "https://example.com/new" {
+		t.Fatalf("expected the new code after the negative TTL, got %+v %v", qr, err)
+	}
+}
+
+func TestCache_EvictsLeastRecentlyUsed(t *testing.T) {
+	up := &upstreamFake{codes: map[string]qrclient.QrCode{}, versions: map[string]string{}}
+	for _, id := range []string{"a", "b", "c"} {
+		up.set(id, "https://example.com/"+id, `"v1"`)
+	}
+	c, _ := newTestCache(up, Options{MaxEntries: 2})
+
+	_, _ = c.GetQrCode(context.Background(), "a")
+	_, _ = c.GetQrCode(context.Background(), "b")
+	_, _ = c.GetQrCode(context.Background(), "a")
+	_, _ = c.GetQrCode(context.Background(), "c") // evicts b
+	if n := c.codes.order.Len(); n != 2 {
+		t.Fatalf("expected 2 entries, got %d", n)
+	}
+	before := up.callCount()
+	_, _ = c.GetQrCode(context.Background(), "a")
+	_, _ = c.GetQrCode(context.Background(), "b")
+	if n := up.callCount() - before; n != 1 {
+		t.Fatalf("expected only the evicted code to be refetched, got %d calls", n)
+	}
+}
+
+func TestCache_ServesLastKnownGoodWhileBreakerIsOpen(t *testing.T) {
+	var down atomic.Bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if down.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"id":"abc","url":"https://example.com/a","active":true}`))
+	}))
+	defer srv.Close()
+
+	client := qrclient.New(srv.URL)
+	client.Retry = qrclient.RetryPolicy{Attempts: 2, BaseDelay: time.Millisecond}
+	client.Breaker = qrclient.NewBreaker(1, time.Minute)
+	c := New(client, Options{TTL: time.Nanosecond, StaleTTL: time.Nanosecond})
+
+	if qr, err := c.GetQrCode(context.Background(), "abc"); err != nil || qr.URL != "https://example.com/a" {
+		t.Fatalf("unexpected lookup %+v %v", qr, err)
+	}
+	down.Store(true)
+	for i := 0; i < 3; i++ {
+		qr, err := c.GetQrCode(context.Background(), "abc")
+		if err != nil || qr.URL != "https://example.com/a" {
+			t.Fatalf("lookup %d: expected last known good record, got %+v %v", i+1, qr, err)
+		}
+	}
+	if _, err := client.GetQrCode(context.Background(), "abc"); !errors.Is(err, qrclient.ErrCircuitOpen) {
+		t.Fatalf("expected the breaker to have opened, got %v", err)
+	}
+}
+
+// slowUpstream holds every code fetch until release is closed, or until the
+// fetch's context ends.
+type slowUpstream struct {
+	started chan struct{}
+	release chan struct{}
+	calls   atomic.Int32
+}
+
+func (u *slowUpstream) FetchQrCode(ctx context.Context, id, _ string) (qrclient.QrCode, string, error) {
+	u.calls.Add(1)
+	u.started <- struct{}{}
+	select {
+	case <-u.release:
+		return qrclient.QrCode{ID: id, URL: "https://example.com/a", Active: true}, `"v1"`, nil
+	case <-ctx.Done():
+		return qrclient.QrCode{}, "", ctx.Err()
+	}
+}
+
+func (u *slowUpstream) FetchSettings(_ context.Context, _, _ string) (qrclient.Settings, string, error) {
+	return qrclient.Settings{}, "", nil
+}
+
+func TestCache_SharedLoadOutlivesTheFirstCaller(t *testing.T) {
+	up := &slowUpstream{started: make(chan struct{}, 1), release: make(chan struct{})}
+	c := New(up, Options{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	first := make(chan error, 1)
+	go func() {
+		_, err := c.GetQrCode(ctx, "abc")
+		first <- err
+	}()
+	<-up.started
+	cancel()
+	if err := <-first; !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected the first caller to give up with its context, got %v", err)
+	}
+
+	second := make(chan error, 1)
+	go func() {
+		qr, err := c.GetQrCode(context.Background(), "abc")
+		if err == nil && qr.URL != "https://example.com/a" {
+			err = errors.New("unexpected code " + qr.URL)
+		}
+		second <- err
+	}()
+	close(up.release)
+	if err := <-second; err != nil {
+		t.Fatalf("expected the shared load to finish for the next caller, got %v", err)
+	}
+	if n := up.calls.Load(); n != 1 {
+		t.Fatalf("expected one upstream fetch, got %d", n)
+	}
+}
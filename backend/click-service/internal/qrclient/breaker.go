@@ -0,0 +1,74 @@
+package qrclient
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned without contacting qr-service while the breaker
+// is open.
+var ErrCircuitOpen = errors.New("qr-service circuit open")
+
+// Breaker fails calls fast after Threshold consecutive failures. Once
+// Cooldown has passed it lets a single probe through: success closes the
+// breaker, failure opens it for another Cooldown.
+type Breaker struct {
+	Threshold int
+	Cooldown  time.Duration
+
+	now func() time.Time
+
+	mu       sync.Mutex
+	failures int
+	openedAt time.Time
+	open     bool
+	probing  bool
+}
+
+func NewBreaker(threshold int, cooldown time.Duration) *Breaker {
+	return &Breaker{Threshold: threshold, Cooldown: cooldown}
+}
+
+// allow reports whether a call may go ahead. A true result must be followed
+// by exactly one record or release.
+func (b *Breaker) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !b.open {
+		return true
+	}
+	if b.probing || b.clock().Sub(b.openedAt) < b.Cooldown {
+		return false
+	}
+	b.probing = true
+	return true
+}
+
+func (b *Breaker) record(ok bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+	if ok {
+		b.failures, b.open = 0, false
+		return
+	}
+	b.failures++
+	if b.open || b.failures >= max(b.Threshold, 1) {
+		b.open, b.openedAt = true, b.clock()
+	}
+}
+
+// release ends an allowed call without counting it either way.
+func (b *Breaker) release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+}
+
+func (b *Breaker) clock() time.Time {
+	if b.now != nil {
+		return b.now()
+	}
+	return time.Now()
+}
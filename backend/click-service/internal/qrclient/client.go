@@ -1,21 +1,77 @@
 package qrclient
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
+	"net/url"
 	"strings"
 	"time"
 )
 
 var ErrNotFound = errors.New("not found")
 
+// ErrNotModified is returned by the Fetch methods when the caller's ETag is
+// still current.
+var ErrNotModified = errors.New("not modified")
+
 type QrCode struct {
+	ID      string `json:"id"`
+	OwnerID string `json:"ownerId"`
+	URL     string `json:"url"`
+	Active  bool   `json:"active"`
+	// ActiveFrom (inclusive) and ActiveUntil (exclusive) optionally limit
+	// when an active code redirects.
+	ActiveFrom  *time.Time `json:"activeFrom,omitempty"`
+	ActiveUntil *time.Time `json:"activeUntil,omitempty"`
+	// MaxScans caps redirects when set; ExhaustedURL is where later scans go.
+	MaxScans     *int64 `json:"maxScans,omitempty"`
+	ExhaustedURL string `json:"exhaustedUrl,omitempty"`
+	// Rules are tried in order; the first match overrides URL.
+	Rules []RedirectRule `json:"rules,omitempty"`
+	// Variants split scans that match no rule between weighted URLs.
+	Variants       []Variant `json:"variants,omitempty"`
+	StickyVariants bool      `json:"stickyVariants,omitempty"`
+	// TimeZone is the IANA zone the code's stats are bucketed in; empty
+	// means UTC.
+	TimeZone string `json:"timeZone,omitempty"`
+	// ScanWebhook is set when the owner has a qr.scanned webhook, so the
+	// code's scans must be forwarded with ReportScans.
+	ScanWebhook bool `json:"scanWebhook,omitempty"`
+}
+
+// Variant is one weighted target of an A/B split.
+type Variant struct {
 	ID     string `json:"id"`
 	URL    string `json:"url"`
-	Active bool   `json:"active"`
+	Weight int    `json:"weight"`
+}
+
+// RedirectRule mirrors qr-service's rule. Lists are already canonical:
+// platforms, languages and weekdays lowercase, countries uppercase.
+type RedirectRule struct {
+	Platforms []string `json:"platforms,omitempty"`
+	Languages []string `json:"languages,omitempty"`
+	Countries []string `json:"countries,omitempty"`
+	Weekdays  []string `json:"weekdays,omitempty"`
+	TimeFrom  string   `json:"timeFrom,omitempty"`
+	TimeUntil string   `json:"timeUntil,omitempty"`
+	TimeZone  string   `json:"timeZone,omitempty"`
+	URL       string   `json:"url"`
+}
+
+// LiveAt reports whether the code should redirect at t.
+func (q QrCode) LiveAt(t time.Time) bool {
+	if !q.Active {
+		return false
+	}
+	if q.ActiveFrom != nil && t.Before(*q.ActiveFrom) {
+		return false
+	}
+	return q.ActiveUntil == nil || t.Before(*q.ActiveUntil)
 }
 
 type Settings struct {
@@ -25,6 +81,11 @@ type Settings struct {
 type Client struct {
 	BaseURL string
 	HTTP    *http.Client
+	// InternalKey authenticates calls to qr-service's internal routes.
+	InternalKey string
+	// Retry applies to GETs only; Breaker, when set, guards every call.
+	Retry   RetryPolicy
+	Breaker *Breaker
 }
 
 func New(baseURL string) *Client {
@@ -34,59 +95,227 @@ func New(baseURL string) *Client {
 		HTTP: &http.Client{
 			Timeout: 5 * time.Second,
 		},
+		Retry:   DefaultRetryPolicy(),
+		Breaker: NewBreaker(5, 10*time.Second),
 	}
 }
 
 func (c *Client) GetQrCode(ctx context.Context, id string) (QrCode, error) {
+	out, _, err := c.FetchQrCode(ctx, id, "")
+	return out, err
+}
+
+// FetchQrCode is GetQrCode with HTTP revalidation: when etag is set and the
+// code is unchanged it returns ErrNotModified. The returned tag identifies
+// the version fetched.
+func (c *Client) FetchQrCode(ctx context.Context, id, etag string) (QrCode, string, error) {
 	id = strings.TrimSpace(id)
 	if id == "" {
-		return QrCode{}, ErrNotFound
+		return QrCode{}, "", ErrNotFound
+	}
+
+	var out QrCode
+	tag, err := c.fetch(ctx, fmt.Sprintf("%s/api/public/qr-codes/%s", c.BaseURL, url.PathEscape(id)), etag, &out)
+	return out, tag, err
+}
+
+// GetSettings returns the redirect settings of the user that owns a code.
+func (c *Client) GetSettings(ctx context.Context, ownerID string) (Settings, error) {
+	out, _, err := c.FetchSettings(ctx, ownerID, "")
+	return out, err
+}
+
+// FetchSettings is GetSettings with revalidation, like FetchQrCode.
+func (c *Client) FetchSettings(ctx context.Context, ownerID, etag string) (Settings, string, error) {
+	ownerID = strings.TrimSpace(ownerID)
+	if ownerID == "" {
+		return Settings{}, "", nil
+	}
+
+	var out Settings
+	tag, err := c.fetch(ctx, fmt.Sprintf("%s/api/public/settings/%s", c.BaseURL, url.PathEscape(ownerID)), etag, &out)
+	return out, tag, err
+}
+
+// fetch GETs a public lookup into out, sending etag as If-None-Match.
+func (c *Client) fetch(ctx context.Context, endpoint, etag string, out any) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return "", err
+	}
+	if etag != "" {
+		req.Header.Set("If-None-Match", etag)
+	}
+	// qr-service refuses these reads without the key; it also exempts them
+	// from the per-IP rate limit, which every scan would otherwise count against.
+	if c.InternalKey != "" {
+		req.Header.Set("X-Internal-Key", c.InternalKey)
 	}
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("%s/api/qr-codes/%s", c.BaseURL, id), nil)
+	resp, err := c.do(req)
 	if err != nil {
-		return QrCode{}, err
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotModified:
+		return etag, ErrNotModified
+	case resp.StatusCode == http.StatusNotFound:
+		return "", ErrNotFound
+	case resp.StatusCode < 200 || resp.StatusCode >= 300:
+		return "", fmt.Errorf("qr-service unexpected status: %d", resp.StatusCode)
 	}
 
-	resp, err := c.HTTP.Do(req)
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return "", err
+	}
+	return resp.Header.Get("ETag"), nil
+}
+
+// GetTagQrCodeIDs lists the codes carrying one of ownerID's tags, for per-tag
+// stats. Someone else's tag is ErrNotFound.
+func (c *Client) GetTagQrCodeIDs(ctx context.Context, ownerID, tagID string) ([]string, error) {
+	tagID = strings.TrimSpace(tagID)
+	if tagID == "" || ownerID == "" {
+		return nil, ErrNotFound
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("%s/api/internal/tags/%s/qr-codes?ownerId=%s", c.BaseURL, url.PathEscape(tagID), url.QueryEscape(ownerID)), nil)
 	if err != nil {
-		return QrCode{}, err
+		return nil, err
+	}
+	req.Header.Set("X-Internal-Key", c.InternalKey)
+
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode == http.StatusNotFound {
-		return QrCode{}, ErrNotFound
+		return nil, ErrNotFound
 	}
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return QrCode{}, fmt.Errorf("qr-service unexpected status: %d", resp.StatusCode)
+		return nil, fmt.Errorf("qr-service unexpected status: %d", resp.StatusCode)
 	}
 
-	var out QrCode
+	var out struct {
+		QrCodeIDs []string `json:"qrCodeIds"`
+	}
 	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
-		return QrCode{}, err
+		return nil, err
 	}
-	return out, nil
+	return out.QrCodeIDs, nil
+}
+
+// ReportRecipient is an owner who asked for weekly click reports.
+type ReportRecipient struct {
+	OwnerID  string       `json:"ownerId"`
+	Email    string       `json:"email"`
+	TimeZone string       `json:"timeZone,omitempty"`
+	QrCodes  []ReportCode `json:"qrCodes"`
+}
+
+type ReportCode struct {
+	ID    string `json:"id"`
+	Label string `json:"label"`
 }
 
-func (c *Client) GetSettings(ctx context.Context) (Settings, error) {
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("%s/api/settings", c.BaseURL), nil)
+// GetReportRecipients lists every owner subscribed to weekly reports.
+func (c *Client) GetReportRecipients(ctx context.Context) ([]ReportRecipient, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.BaseURL+"/api/internal/report-recipients", nil)
 	if err != nil {
-		return Settings{}, err
+		return nil, err
 	}
+	req.Header.Set("X-Internal-Key", c.InternalKey)
 
-	resp, err := c.HTTP.Do(req)
+	resp, err := c.do(req)
 	if err != nil {
-		return Settings{}, err
+		return nil, err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return Settings{}, fmt.Errorf("qr-service unexpected status: %d", resp.StatusCode)
+		return nil, fmt.Errorf("qr-service unexpected status: %d", resp.StatusCode)
 	}
 
-	var out Settings
+	var out struct {
+		Recipients []ReportRecipient `json:"recipients"`
+	}
 	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
-		return Settings{}, err
+		return nil, err
+	}
+	return out.Recipients, nil
+}
+
+// ReportClicks adds n to the code's click counter in qr-service, which uses it
+// to sort lists by popularity.
+func (c *Client) ReportClicks(ctx context.Context, id string, n int64) error {
+	if c.InternalKey == "" {
+		return nil
+	}
+	body, _ := json.Marshal(map[string]int64{"count": n})
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, fmt.Sprintf("%s/api/internal/qr-codes/%s/clicks", c.BaseURL, url.PathEscape(id)), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Internal-Key", c.InternalKey)
+
+	resp, err := c.do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return ErrNotFound
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("qr-service unexpected status: %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// ScanEvent is a scan forwarded to qr-service for its owner's qr.scanned
+// webhooks. It never carries the visitor's IP or raw user agent.
+type ScanEvent struct {
+	QrCodeID  string `json:"qrCodeId"`
+	AtIso     string `json:"atIso"`
+	TargetURL string `json:"targetUrl"`
+	Country   string `json:"country,omitempty"`
+	Variant   string `json:"variant,omitempty"`
+	Device    string `json:"device,omitempty"`
+	OS        string `json:"os,omitempty"`
+	Browser   string `json:"browser,omitempty"`
+}
+
+// ReportScans hands a batch of scans to qr-service, which queues the webhook
+// deliveries. qr-service takes a batch whole or not at all.
+func (c *Client) ReportScans(ctx context.Context, scans []ScanEvent) error {
+	if c.InternalKey == "" || len(scans) == 0 {
+		return nil
+	}
+	body, err := json.Marshal(map[string][]ScanEvent{"scans": scans})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.BaseURL+"/api/internal/webhook-events", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Internal-Key", c.InternalKey)
+
+	resp, err := c.do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("qr-service unexpected status: %d", resp.StatusCode)
 	}
-	return out, nil
+	return nil
 }
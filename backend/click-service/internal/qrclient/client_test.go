@@ -0,0 +1,132 @@
+package qrclient
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// flakyServer answers with failStatus for the first fail requests, then
+// serves a code.
+func flakyServer(t *testing.T, fail int64, failStatus int) (*httptest.Server, *atomic.Int64) {
+	t.Helper()
+	var hits atomic.Int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if hits.Add(1) <= fail {
+			w.WriteHeader(failStatus)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"id":"abc","url":"https://example.com","active":true}`))
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &hits
+}
+
+func testClient(baseURL string) *Client {
+	c := New(baseURL)
+	c.InternalKey = "secret"
+	c.Retry = RetryPolicy{Attempts: 3, BaseDelay: time.Millisecond, MaxDelay: 5 * time.Millisecond}
+	c.Breaker = NewBreaker(2, time.Minute)
+	return c
+}
+
+func TestClient_RetriesGetOnServerErrors(t *testing.T) {
+	srv, hits := flakyServer(t, 2, http.StatusServiceUnavailable)
+	c := testClient(srv.URL)
+
+	qr, err := c.GetQrCode(context.Background(), "abc")
+	if err != nil || qr.URL != "https://example.com" {
+		t.Fatalf("expected success after retries, got %+v %v", qr, err)
+	}
+	if n := hits.Load(); n != 3 {
+		t.Fatalf("expected 3 attempts, got %d", n)
+	}
+}
+
+func TestClient_DoesNotRetryNotFoundOrPosts(t *testing.T) {
+	srv, hits := flakyServer(t, 100, http.StatusNotFound)
+	c := testClient(srv.URL)
+	if _, err := c.GetQrCode(context.Background(), "abc"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected not found, got %v", err)
+	}
+	if n := hits.Load(); n != 1 {
+		t.Fatalf("expected a single attempt for 404, got %d", n)
+	}
+
+	srv, hits = flakyServer(t, 100, http.StatusInternalServerError)
+	c = testClient(srv.URL)
+	if err := c.ReportClicks(context.Background(), "abc", 1); err == nil {
+		t.Fatalf("expected an error")
+	}
+	if n := hits.Load(); n != 1 {
+		t.Fatalf("expected POST not to be retried, got %d attempts", n)
+	}
+}
+
+func TestClient_BreakerFailsFastAndProbesAfterCooldown(t *testing.T) {
+	srv, hits := flakyServer(t, 6, http.StatusBadGateway)
+	c := testClient(srv.URL)
+	now := time.Date(2026, 1, 2, 10, 0, 0, 0, time.UTC)
+	c.Breaker.now = func() time.Time { return now }
+
+	// Two failed calls of three attempts each open the breaker.
+	for i := 0; i < 2; i++ {
+		if _, err := c.GetQrCode(context.Background(), "abc"); err == nil || errors.Is(err, ErrCircuitOpen) {
+			t.Fatalf("call %d: expected an upstream error, got %v", i+1, err)
+		}
+	}
+	if _, err := c.GetQrCode(context.Background(), "abc"); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("expected the breaker to be open, got %v", err)
+	}
+	if n := hits.Load(); n != 6 {
+		t.Fatalf("expected no request while open, got %d", n)
+	}
+
+	now = now.Add(2 * time.Minute)
+	if _, err := c.GetQrCode(context.Background(), "abc"); err != nil {
+		t.Fatalf("expected the probe to succeed, got %v", err)
+	}
+	if _, err := c.GetQrCode(context.Background(), "abc"); err != nil {
+		t.Fatalf("expected the breaker to be closed, got %v", err)
+	}
+}
+
+func TestClient_RetriesAndTripsOnTransportErrors(t *testing.T) {
+	// Hang up on every request, as a qr-service that is going down would.
+	var hits atomic.Int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		conn, _, err := http.NewResponseController(w).Hijack()
+		if err != nil {
+			t.Errorf("hijack: %v", err)
+			return
+		}
+		conn.Close()
+	}))
+	t.Cleanup(srv.Close)
+	c := testClient(srv.URL)
+
+	for i := 0; i < 2; i++ {
+		if _, err := c.GetQrCode(context.Background(), "abc"); err == nil || errors.Is(err, ErrCircuitOpen) {
+			t.Fatalf("call %d: expected a transport error, got %v", i+1, err)
+		}
+	}
+	if n := hits.Load(); n != 6 {
+		t.Fatalf("expected every attempt to be retried, got %d", n)
+	}
+	if _, err := c.GetQrCode(context.Background(), "abc"); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("expected transport errors to open the breaker, got %v", err)
+	}
+
+	// Nothing listening at all.
+	srv.Close()
+	c = testClient(srv.URL)
+	if _, err := c.GetQrCode(context.Background(), "abc"); err == nil {
+		t.Fatalf("expected connection refused to surface as an error")
+	}
+}
@@ -0,0 +1,104 @@
+package qrclient
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"time"
+)
+
+// RetryPolicy retries GETs that fail with a transport error or a 5xx/429
+// status. Delays use full jitter: a random wait up to BaseDelay doubled per
+// attempt, capped at MaxDelay.
+type RetryPolicy struct {
+	// Attempts is the total number of tries; 0 or 1 disables retries.
+	Attempts  int
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+}
+
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{Attempts: 3, BaseDelay: 50 * time.Millisecond, MaxDelay: 500 * time.Millisecond}
+}
+
+func (p RetryPolicy) delay(attempt int) time.Duration {
+	d := p.BaseDelay << attempt
+	if d <= 0 || (p.MaxDelay > 0 && d > p.MaxDelay) {
+		d = p.MaxDelay
+	}
+	if d <= 0 {
+		return 0
+	}
+	return rand.N(d + 1)
+}
+
+// do sends req through the breaker, retrying idempotent GETs per c.Retry.
+// Only the overall outcome counts toward the breaker.
+func (c *Client) do(req *http.Request) (*http.Response, error) {
+	if c.Breaker != nil && !c.Breaker.allow() {
+		return nil, ErrCircuitOpen
+	}
+
+	attempts := 1
+	if req.Method == http.MethodGet && c.Retry.Attempts > 1 {
+		attempts = c.Retry.Attempts
+	}
+	var (
+		resp *http.Response
+		err  error
+	)
+	for attempt := 0; attempt < attempts; attempt++ {
+		if attempt > 0 {
+			if !sleepCtx(req.Context(), c.Retry.delay(attempt-1)) {
+				break
+			}
+		}
+		resp, err = c.HTTP.Do(req)
+		if !retryable(resp, err) || attempt == attempts-1 {
+			break
+		}
+		// A transport error leaves no response to drain.
+		if resp != nil {
+			_, _ = io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+			resp = nil
+		}
+	}
+
+	if resp == nil && err == nil {
+		err = req.Context().Err()
+	}
+	if c.Breaker != nil {
+		// A caller that gave up says nothing about qr-service's health.
+		if errors.Is(err, context.Canceled) {
+			c.Breaker.release()
+		} else {
+			c.Breaker.record(!retryable(resp, err))
+		}
+	}
+	return resp, err
+}
+
+func retryable(resp *http.Response, err error) bool {
+	if err != nil {
+		return !errors.Is(err, context.Canceled)
+	}
+	return resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests
+}
+
+// sleepCtx waits for d, returning false if ctx ends first.
+func sleepCtx(ctx context.Context, d time.Duration) bool {
+	if d <= 0 {
+		return ctx.Err() == nil
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
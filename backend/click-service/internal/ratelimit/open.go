@@ -0,0 +1,53 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+)
+
+// Open connects to the store named by backend: "redis" (redisURL),
+// "postgres" (databaseURL) or "memory". An empty backend picks Redis when
+// redisURL is set, then Postgres when databaseURL is set, then memory.
+func Open(ctx context.Context, backend, redisURL, databaseURL string) (Store, error) {
+	backend = strings.ToLower(strings.TrimSpace(backend))
+	if backend == "" {
+		switch {
+		case redisURL != "":
+			backend = "redis"
+		case databaseURL != "":
+			backend = "postgres"
+		default:
+			backend = "memory"
+		}
+	}
+
+	switch backend {
+	case "redis":
+		if redisURL == "" {
+			return nil, fmt.Errorf("ratelimit: redis backend needs REDIS_URL")
+		}
+		st, err := NewRedisStore(ctx, redisURL)
+		if err != nil {
+			return nil, err
+		}
+		log.Printf("rate limits shared through redis")
+		return st, nil
+	case "postgres":
+		if databaseURL == "" {
+			return nil, fmt.Errorf("ratelimit: postgres backend needs DATABASE_URL")
+		}
+		st, err := NewPostgresStore(ctx, databaseURL)
+		if err != nil {
+			return nil, err
+		}
+		log.Printf("rate limits shared through postgres")
+		return st, nil
+	case "memory":
+		log.Printf("rate limits kept in memory (per instance, reset on restart)")
+		return NewMemoryStore(), nil
+	default:
+		return nil, fmt.Errorf("ratelimit: unknown backend %q", backend)
+	}
+}
@@ -0,0 +1,261 @@
+// Package ratelimit meters requests with token buckets kept in a Store, so
+// that limits survive deploys and are shared by every instance using the same
+// store. Callers are identified by user or API key where possible and by
+// client IP otherwise, and each plan has its own limit.
+package ratelimit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Plans with a special meaning in a limits table.
+const (
+	// AnonymousPlan is the limit for callers Identify does not recognize,
+	// bucketed by client IP.
+	AnonymousPlan = "anonymous"
+	// DefaultPlan is the fallback for identified callers whose plan has no
+	// entry of its own.
+	DefaultPlan = "free"
+)
+
+// Headers are the response headers the middleware sets, for CORS exposure.
+var Headers = []string{"X-RateLimit-Limit", "X-RateLimit-Remaining", "X-RateLimit-Reset", "Retry-After"}
+
+// Limit is a token bucket: it holds up to Burst requests and refills at Rate
+// requests per second. A zero Limit means no limit.
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+// PerMinute allows n requests a minute, all of which may come at once.
+func PerMinute(n int) Limit {
+	return Limit{Rate: float64(n) / 60, Burst: n}
+}
+
+func (l Limit) unlimited() bool { return l.Burst <= 0 || l.Rate <= 0 }
+
+// ttl is how long until an untouched bucket is full again, after which it can
+// be forgotten.
+func (l Limit) ttl() time.Duration {
+	return time.Duration(float64(l.Burst)/l.Rate*float64(time.Second)) + time.Second
+}
+
+// Result describes a bucket after a request was metered.
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// Reset is how long until the bucket is full again.
+	Reset time.Duration
+	// RetryAfter is how long until the next request would be allowed; zero
+	// when this one was.
+	RetryAfter time.Duration
+}
+
+// Store keeps token buckets. Take refills key's bucket for the time since it
+// was last used, then removes one token if there is one. Implementations must
+// be safe for concurrent use, including from several processes.
+type Store interface {
+	Take(ctx context.Context, key string, limit Limit, now time.Time) (Result, error)
+	Close() error
+}
+
+// refill returns the tokens in a bucket that held tokens at last, as of now.
+func refill(tokens float64, last, now time.Time, limit Limit) float64 {
+	if elapsed := now.Sub(last).Seconds(); elapsed > 0 {
+		tokens += elapsed * limit.Rate
+	}
+	return math.Min(tokens, float64(limit.Burst))
+}
+
+// result turns the tokens left after a Take into a Result.
+func result(limit Limit, tokens float64, allowed bool) Result {
+	r := Result{Allowed: allowed, Limit: limit.Burst, Remaining: int(math.Max(0, math.Floor(tokens)))}
+	r.Reset = seconds((float64(limit.Burst) - tokens) / limit.Rate)
+	if !allowed {
+		r.RetryAfter = seconds((1 - tokens) / limit.Rate)
+	}
+	return r
+}
+
+func seconds(s float64) time.Duration {
+	if s <= 0 {
+		return 0
+	}
+	return time.Duration(s * float64(time.Second))
+}
+
+// ParseLimits reads per-minute limits such as "anonymous=100,free=200" on
+// top of defaults. An empty raw returns defaults. Lifting a plan's limit
+// takes the explicit "plan=unlimited"; 0 is rejected so that a typo cannot
+// turn limiting off.
+func ParseLimits(raw string, defaults map[string]Limit) (map[string]Limit, error) {
+	out := make(map[string]Limit, len(defaults))
+	for plan, l := range defaults {
+		out[plan] = l
+	}
+	for _, part := range strings.Split(raw, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		plan, n, ok := strings.Cut(part, "=")
+		plan, n = strings.ToLower(strings.TrimSpace(plan)), strings.TrimSpace(n)
+		if ok && plan != "" && strings.EqualFold(n, "unlimited") {
+			out[plan] = Limit{}
+			continue
+		}
+		perMinute, err := strconv.Atoi(n)
+		if !ok || plan == "" || err != nil || perMinute <= 0 {
+			return nil, fmt.Errorf("rate limit %q: want plan=requests_per_minute or plan=unlimited", part)
+		}
+		out[plan] = PerMinute(perMinute)
+	}
+	return out, nil
+}
+
+// Limiter is HTTP middleware enforcing Limits. It must run after whatever
+// attaches the caller's identity for Identify to read.
+type Limiter struct {
+	Store Store
+	// Name prefixes bucket keys so several services can share one store.
+	Name string
+	// Limits maps plans to limits; see AnonymousPlan and DefaultPlan.
+	Limits map[string]Limit
+	// Identify returns the caller's bucket key and plan, or ok false for
+	// anonymous callers. Nil treats every caller as anonymous.
+	Identify func(r *http.Request) (key, plan string, ok bool)
+	// Exempt, when set, lets matching requests through unmetered.
+	Exempt func(r *http.Request) bool
+	// Timeout bounds each store call. When the store fails or is too slow
+	// the request is let through: an outage of the limiter's store should
+	// not take the API down with it.
+	Timeout time.Duration
+
+	now func() time.Time
+}
+
+func (l *Limiter) limitFor(plan string) Limit {
+	if limit, ok := l.Limits[plan]; ok {
+		return limit
+	}
+	return l.Limits[DefaultPlan]
+}
+
+// Middleware meters next. A nil Limiter lets everything through.
+func (l *Limiter) Middleware(next http.Handler) http.Handler {
+	if l == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodOptions || (l.Exempt != nil && l.Exempt(r)) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		key, plan, ok := "", "", false
+		if l.Identify != nil {
+			key, plan, ok = l.Identify(r)
+		}
+		if !ok {
+			key, plan = "ip:"+ClientIP(r), AnonymousPlan
+		}
+		if l.take(w, r, key, plan) {
+			next.ServeHTTP(w, r)
+		}
+	})
+}
+
+// Before meters, per client IP and at the anonymous limit, the requests for
+// which costly reports true, ahead of whatever attaches the caller's
+// identity. It is for work an unknown caller could otherwise trigger
+// unmetered, such as checking a made-up API key with another service. These
+// requests share a bucket of their own and are metered again by Middleware.
+// A nil Limiter or costly lets everything through.
+func (l *Limiter) Before(costly func(r *http.Request) bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if l == nil || costly == nil {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodOptions || (l.Exempt != nil && l.Exempt(r)) || !costly(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if l.take(w, r, "before:ip:"+ClientIP(r), AnonymousPlan) {
+				next.ServeHTTP(w, r)
+			}
+		})
+	}
+}
+
+// take meters one request against key's bucket and reports whether it may
+// proceed. When it may not, take has already answered 429.
+func (l *Limiter) take(w http.ResponseWriter, r *http.Request, key, plan string) bool {
+	limit := l.limitFor(plan)
+	if limit.unlimited() {
+		return true
+	}
+
+	now := time.Now
+	if l.now != nil {
+		now = l.now
+	}
+	timeout := l.Timeout
+	if timeout <= 0 {
+		timeout = 250 * time.Millisecond
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), timeout)
+	res, err := l.Store.Take(ctx, l.Name+":"+key, limit, now())
+	cancel()
+	if err != nil {
+		log.Printf("ratelimit: store failed, letting request through request_id=%s err=%v", w.Header().Get("X-Request-Id"), err)
+		return true
+	}
+
+	h := w.Header()
+	h.Set("X-RateLimit-Limit", strconv.Itoa(res.Limit))
+	h.Set("X-RateLimit-Remaining", strconv.Itoa(res.Remaining))
+	h.Set("X-RateLimit-Reset", strconv.Itoa(ceilSeconds(res.Reset)))
+	if !res.Allowed {
+		h.Set("Retry-After", strconv.Itoa(max(1, ceilSeconds(res.RetryAfter))))
+		h.Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusTooManyRequests)
+		_ = json.NewEncoder(w).Encode(map[string]string{"error": "rate_limited"})
+		return false
+	}
+	return true
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
+
+// ClientIP returns the caller's address. Behind Heroku's router the last
+// X-Forwarded-For entry is the one the router added; earlier ones come from
+// the client and can be forged.
+func ClientIP(r *http.Request) string {
+	if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+		parts := strings.Split(forwarded, ",")
+		if ip := strings.TrimSpace(parts[len(parts)-1]); ip != "" {
+			return ip
+		}
+	}
+	if realIP := strings.TrimSpace(r.Header.Get("X-Real-IP")); realIP != "" {
+		return realIP
+	}
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return host
+	}
+	return r.RemoteAddr
+}
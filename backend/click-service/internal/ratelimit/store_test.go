@@ -0,0 +1,57 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+// testStores returns each store that can run without outside services; the
+// Redis one talks to an in-process stand-in.
+func testStores(t *testing.T) map[string]Store {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	rs, err := NewRedisStore(context.Background(), "redis://"+mr.Addr())
+	if err != nil {
+		t.Fatalf("redis: %v", err)
+	}
+	t.Cleanup(func() { _ = rs.Close() })
+	return map[string]Store{"memory": NewMemoryStore(), "redis": rs}
+}
+
+func TestStores_TakeRefillsAtRate(t *testing.T) {
+	for name, st := range testStores(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			limit := Limit{Rate: 2, Burst: 3}
+			now := time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)
+
+			for i, want := range []int{2, 1, 0} {
+				res, err := st.Take(ctx, "user:alice", limit, now)
+				if err != nil || !res.Allowed || res.Remaining != want || res.Limit != 3 {
+					t.Fatalf("take %d: unexpected %+v %v", i+1, res, err)
+				}
+			}
+			res, _ := st.Take(ctx, "user:alice", limit, now)
+			if res.Allowed || res.RetryAfter != 500*time.Millisecond || res.Reset != 1500*time.Millisecond {
+				t.Fatalf("expected an empty bucket to refuse, got %+v", res)
+			}
+			if res, _ := st.Take(ctx, "user:bob", limit, now); !res.Allowed || res.Remaining != 2 {
+				t.Fatalf("expected buckets to be per key, got %+v", res)
+			}
+
+			// Half a second buys one request; a clock running behind does not.
+			if res, _ := st.Take(ctx, "user:alice", limit, now.Add(500*time.Millisecond)); !res.Allowed || res.Remaining != 0 {
+				t.Fatalf("expected a refilled token, got %+v", res)
+			}
+			if res, _ := st.Take(ctx, "user:alice", limit, now); res.Allowed {
+				t.Fatalf("expected an earlier clock not to refill, got %+v", res)
+			}
+			if res, _ := st.Take(ctx, "user:alice", limit, now.Add(time.Hour)); !res.Allowed || res.Remaining != 2 {
+				t.Fatalf("expected refill to stop at the burst, got %+v", res)
+			}
+		})
+	}
+}
@@ -0,0 +1,133 @@
+package report
+
+import (
+	"bufio"
+	"context"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeSMTP accepts one plain-text SMTP session and hands back the envelope
+// and the DATA it received.
+type fakeSMTP struct {
+	addr string
+	got  chan fakeDelivery
+}
+
+type fakeDelivery struct {
+	from, to string
+	data     string
+}
+
+func startFakeSMTP(t *testing.T) *fakeSMTP {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	f := &fakeSMTP{addr: ln.Addr().String(), got: make(chan fakeDelivery, 1)}
+
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		reply := func(s string) { _, _ = conn.Write([]byte(s + "\r\n")) }
+
+		var d fakeDelivery
+		reply("220 fake ESMTP")
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			cmd := strings.TrimRight(line, "\r\n")
+			switch verb := strings.ToUpper(strings.SplitN(cmd, " ", 2)[0]); verb {
+			case "EHLO", "HELO":
+				reply("250-fake")
+				reply("250 8BITMIME")
+			case "MAIL":
+				d.from = angleAddr(cmd)
+				reply("250 ok")
+			case "RCPT":
+				d.to = angleAddr(cmd)
+				reply("250 ok")
+			case "DATA":
+				reply("354 go ahead")
+				var b strings.Builder
+				for {
+					l, err := r.ReadString('\n')
+					if err != nil {
+						return
+					}
+					if l == ".\r\n" {
+						break
+					}
+					b.WriteString(l)
+				}
+				d.data = b.String()
+				reply("250 queued")
+			case "QUIT":
+				reply("221 bye")
+				f.got <- d
+				return
+			default:
+				reply("502 unknown command")
+			}
+		}
+	}()
+	return f
+}
+
+// angleAddr picks the address out of "MAIL FROM:<a@b> BODY=8BITMIME".
+func angleAddr(cmd string) string {
+	start, end := strings.Index(cmd, "<"), strings.Index(cmd, ">")
+	if start < 0 || end < start {
+		return ""
+	}
+	return cmd[start+1 : end]
+}
+
+func TestSMTPMailer_DeliversToFakeServer(t *testing.T) {
+	srv := startFakeSMTP(t)
+	m := &SMTPMailer{Addr: srv.addr, From: "QR Reports <reports@example.com>"}
+
+	body := "Total: 12 clicks\n.leading dot and ünïcödé\n"
+	if err := m.Send(context.Background(), Message{To: "alice@example.com", Subject: "Weekly clicks", Body: body}); err != nil {
+		t.Fatalf("send: %v", err)
+	}
+
+	var d fakeDelivery
+	select {
+	case d = <-srv.got:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("fake server got nothing")
+	}
+	if d.from != "reports@example.com" || d.to != "alice@example.com" {
+		t.Fatalf("unexpected envelope %q -> %q", d.from, d.to)
+	}
+
+	// Undo dot-stuffing, then parse the message like a mail client would.
+	data := strings.ReplaceAll(d.data, "\r\n..", "\r\n.")
+	msg, err := mail.ReadMessage(strings.NewReader(data))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if msg.Header.Get("Subject") != "Weekly clicks" || msg.Header.Get("To") != "alice@example.com" {
+		t.Fatalf("unexpected headers %v", msg.Header)
+	}
+	var decoded strings.Builder
+	if _, err := bufio.NewReader(quotedprintable.NewReader(msg.Body)).WriteTo(&decoded); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if got := strings.ReplaceAll(decoded.String(), "\r\n", "\n"); got != body {
+		t.Fatalf("body changed in transit: %q", got)
+	}
+}
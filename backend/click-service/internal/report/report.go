@@ -0,0 +1,213 @@
+// Package report emails owners a weekly summary of their codes' clicks.
+package report
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"click-service/internal/qrclient"
+	"click-service/internal/store"
+)
+
+// maxReportCodes bounds the per-code table; the rest are summed up in one line.
+const maxReportCodes = 20
+
+// Recipients lists the owners subscribed to weekly reports.
+type Recipients interface {
+	GetReportRecipients(ctx context.Context) ([]qrclient.ReportRecipient, error)
+}
+
+// Scheduler sends every subscribed owner a summary of the last full Monday to
+// Sunday week in their time zone, from Monday at Hour onwards. Reports are
+// claimed in the store before sending, so any number of instances can run a
+// scheduler and a run that missed Monday still catches up later in the week.
+type Scheduler struct {
+	Store      store.Store
+	Recipients Recipients
+	Mailer     Mailer
+	// Hour is the owner's local hour on Monday from which a report is due.
+	Hour int
+	Now  func() time.Time
+}
+
+func New(st store.Store, recipients Recipients, mailer Mailer) *Scheduler {
+	return &Scheduler{Store: st, Recipients: recipients, Mailer: mailer, Hour: 8, Now: time.Now}
+}
+
+// Run sends due reports every interval until ctx is done.
+func (s *Scheduler) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := s.RunOnce(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("report: run failed: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce sends every due report that no one has claimed yet. A report that
+// fails to send is released so the next run retries it.
+func (s *Scheduler) RunOnce(ctx context.Context) error {
+	recipients, err := s.Recipients.GetReportRecipients(ctx)
+	if err != nil {
+		return err
+	}
+	now := s.Now()
+	for _, rec := range recipients {
+		if rec.Email == "" || len(rec.QrCodes) == 0 {
+			continue
+		}
+		weekStart := s.dueWeek(rec.TimeZone, now)
+		year, wk := weekStart.ISOWeek()
+		week := fmt.Sprintf("%d-W%02d", year, wk)
+		claimed, err := s.Store.ClaimReport(rec.OwnerID, week)
+		if err != nil {
+			return err
+		}
+		if !claimed {
+			continue
+		}
+		msg, err := s.build(rec, weekStart)
+		if err == nil {
+			err = s.Mailer.Send(ctx, msg)
+		}
+		if err != nil {
+			log.Printf("report: send failed owner=%s week=%s err=%v", rec.OwnerID, week, err)
+			if err := s.Store.ReleaseReport(rec.OwnerID, week); err != nil {
+				log.Printf("report: release failed owner=%s week=%s err=%v", rec.OwnerID, week, err)
+			}
+		}
+	}
+	return nil
+}
+
+// dueWeek returns the Monday starting the latest week whose report is due at
+// now in zone, as a date like the stored day column.
+func (s *Scheduler) dueWeek(zone string, now time.Time) time.Time {
+	loc, err := time.LoadLocation(zone)
+	if err != nil || zone == "" {
+		loc = time.UTC
+	}
+	// Until Monday at Hour, the week before last is still the latest due.
+	t := now.In(loc).Add(-time.Duration(s.Hour) * time.Hour)
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	monday := day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+	return monday.AddDate(0, 0, -7)
+}
+
+type codeWeek struct {
+	label            string
+	total, prevTotal int
+}
+
+// build summarizes the week starting weekStart and compares it with the week
+// before. Each code's days are its own calendar days.
+func (s *Scheduler) build(rec qrclient.ReportRecipient, weekStart time.Time) (Message, error) {
+	days := make([]time.Time, 14)
+	for i := range days {
+		days[i] = weekStart.AddDate(0, 0, i-7)
+	}
+	thisWeek := weekStart.Format("2006-01-02")
+
+	var codes []codeWeek
+	var total, prevTotal int
+	regions := map[string]int{}
+	for _, qr := range rec.QrCodes {
+		byDay, err := s.Store.GetDailyBatch(qr.ID, days)
+		if err != nil {
+			return Message{}, err
+		}
+		cw := codeWeek{label: qr.Label}
+		if cw.label == "" {
+			cw.label = qr.ID
+		}
+		for dayIso, ds := range byDay {
+			// ISO dates compare correctly as strings.
+			if dayIso < thisWeek {
+				cw.prevTotal += ds.Total
+				continue
+			}
+			cw.total += ds.Total
+			for region, n := range ds.RegionCounts {
+				regions[region] += n
+			}
+		}
+		total += cw.total
+		prevTotal += cw.prevTotal
+		codes = append(codes, cw)
+	}
+	sort.SliceStable(codes, func(i, j int) bool { return codes[i].total > codes[j].total })
+
+	weekEnd := weekStart.AddDate(0, 0, 6)
+	var b strings.Builder
+	fmt.Fprintf(&b, "Clicks on your QR codes from %s to %s", weekStart.Format("Mon 2 Jan"), weekEnd.Format("Mon 2 Jan 2006"))
+	if rec.TimeZone != "" {
+		fmt.Fprintf(&b, " (%s)", rec.TimeZone)
+	}
+	fmt.Fprintf(&b, "\n\nTotal: %d clicks (previous week: %d%s)\n\n", total, prevTotal, change(total, prevTotal))
+
+	tw := tabwriter.NewWriter(&b, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "Code\tThis week\tPrevious week\n")
+	rest, restPrev := 0, 0
+	for i, cw := range codes {
+		if i >= maxReportCodes {
+			rest += cw.total
+			restPrev += cw.prevTotal
+			continue
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%d\n", cw.label, cw.total, cw.prevTotal)
+	}
+	if n := len(codes) - maxReportCodes; n > 0 {
+		fmt.Fprintf(tw, "%d more codes\t%d\t%d\n", n, rest, restPrev)
+	}
+	_ = tw.Flush()
+
+	if top := topRegions(regions, 5); top != "" {
+		fmt.Fprintf(&b, "\nTop regions: %s\n", top)
+	}
+
+	return Message{
+		To:      rec.Email,
+		Subject: fmt.Sprintf("Your QR code clicks for the week of %s", weekStart.Format("2 Jan 2006")),
+		Body:    b.String(),
+	}, nil
+}
+
+// change formats the week-over-week change, or nothing without a baseline.
+func change(now, prev int) string {
+	if prev == 0 {
+		return ""
+	}
+	return fmt.Sprintf(", %+d%%", (now-prev)*100/prev)
+}
+
+func topRegions(regions map[string]int, n int) string {
+	keys := make([]string, 0, len(regions))
+	for k := range regions {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if regions[keys[i]] != regions[keys[j]] {
+			return regions[keys[i]] > regions[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+	if len(keys) > n {
+		keys = keys[:n]
+	}
+	parts := make([]string, len(keys))
+	for i, k := range keys {
+		parts[i] = fmt.Sprintf("%s %d", k, regions[k])
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,94 @@
+package report
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"click-service/internal/qrclient"
+	"click-service/internal/store"
+)
+
+type staticRecipients []qrclient.ReportRecipient
+
+func (r staticRecipients) GetReportRecipients(ctx context.Context) ([]qrclient.ReportRecipient, error) {
+	return r, nil
+}
+
+type mailerSpy struct {
+	sent []Message
+	err  error
+}
+
+func (m *mailerSpy) Send(ctx context.Context, msg Message) error {
+	if m.err != nil {
+		return m.err
+	}
+	m.sent = append(m.sent, msg)
+	return nil
+}
+
+func TestScheduler_SendsLastWeekOncePerOwner(t *testing.T) {
+	st := store.NewMemoryStore()
+	berlin, _ := time.LoadLocation("Europe/Berlin")
+	for _, ev := range []store.ClickEvent{
+		// Mon 5 Oct 00:30 in Berlin, still Sunday in UTC.
+		{QrCodeID: "menu", At: time.Date(2026, 10, 4, 22, 30, 0, 0, time.UTC), Country: "DE"},
+		{QrCodeID: "menu", At: time.Date(2026, 10, 7, 12, 0, 0, 0, time.UTC), Country: "DE"},
+		{QrCodeID: "flyer", At: time.Date(2026, 10, 9, 12, 0, 0, 0, time.UTC), Country: "FR"},
+		// The week before.
+		{QrCodeID: "menu", At: time.Date(2026, 9, 30, 12, 0, 0, 0, time.UTC), Country: "DE"},
+	} {
+		ev.TimeZone = "Europe/Berlin"
+		_ = st.RecordClick(ev)
+	}
+	recipients := staticRecipients{{
+		OwnerID:  "alice",
+		Email:    "alice@example.com",
+		TimeZone: "Europe/Berlin",
+		QrCodes:  []qrclient.ReportCode{{ID: "menu", Label: "Menu"}, {ID: "flyer", Label: "Flyer"}},
+	}}
+	mailer := &mailerSpy{}
+	s := New(st, recipients, mailer)
+
+	// Monday 07:30 in Berlin: the report for last week is not due yet, but
+	// the one for the week before is.
+	s.Now = func() time.Time { return time.Date(2026, 10, 12, 7, 30, 0, 0, berlin) }
+	_ = s.RunOnce(context.Background())
+	if len(mailer.sent) != 1 || !strings.Contains(mailer.sent[0].Subject, "28 Sep 2026") {
+		t.Fatalf("expected only the report for the week of 28 Sep, got %+v", mailer.sent)
+	}
+
+	s.Now = func() time.Time { return time.Date(2026, 10, 12, 8, 5, 0, 0, berlin) }
+	_ = s.RunOnce(context.Background())
+	_ = s.RunOnce(context.Background())
+	if len(mailer.sent) != 2 {
+		t.Fatalf("expected one more report, got %d", len(mailer.sent))
+	}
+	msg := mailer.sent[1]
+	if msg.To != "alice@example.com" || !strings.Contains(msg.Subject, "5 Oct 2026") {
+		t.Fatalf("unexpected message %+v", msg)
+	}
+	for _, want := range []string{"Total: 3 clicks (previous week: 1, +200%)", "Menu", "Top regions: DE 2, FR 1"} {
+		if !strings.Contains(msg.Body, want) {
+			t.Fatalf("expected %q in body:\n%s", want, msg.Body)
+		}
+	}
+}
+
+func TestScheduler_RetriesFailedSends(t *testing.T) {
+	st := store.NewMemoryStore()
+	recipients := staticRecipients{{OwnerID: "bob", Email: "bob@example.com", QrCodes: []qrclient.ReportCode{{ID: "x"}}}}
+	mailer := &mailerSpy{err: errors.New("relay down")}
+	s := New(st, recipients, mailer)
+	s.Now = func() time.Time { return time.Date(2026, 10, 13, 9, 0, 0, 0, time.UTC) }
+
+	_ = s.RunOnce(context.Background())
+	mailer.err = nil
+	_ = s.RunOnce(context.Background())
+	if len(mailer.sent) != 1 {
+		t.Fatalf("expected the failed report to be sent on the next run, got %d", len(mailer.sent))
+	}
+}
@@ -0,0 +1,173 @@
+// Package scanhook forwards scans of codes with a qr.scanned webhook to
+// qr-service, which keeps the webhook outbox and delivers them. Scans wait
+// in memory until then, so ones still queued when the process dies, or that
+// qr-service refuses through every retry, are lost.
+package scanhook
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"click-service/internal/qrclient"
+)
+
+// ErrClosed is returned by Close when called twice.
+var ErrClosed = errors.New("scan forwarder closed")
+
+// Sender hands a batch of scans to qr-service.
+type Sender interface {
+	ReportScans(ctx context.Context, scans []qrclient.ScanEvent) error
+}
+
+type Options struct {
+	// QueueSize bounds scans waiting to be sent; when full, new scans are
+	// dropped rather than slowing redirects down.
+	QueueSize int
+	// BatchSize and FlushInterval bound how much and how long scans are
+	// buffered before a send. BatchSize must stay within what qr-service
+	// takes in one request.
+	BatchSize     int
+	FlushInterval time.Duration
+	// Attempts is how many times a batch is sent before it is dropped.
+	Attempts int
+}
+
+func DefaultOptions() Options {
+	return Options{QueueSize: 10000, BatchSize: 100, FlushInterval: time.Second, Attempts: 3}
+}
+
+// Stats are running counters for monitoring.
+type Stats struct {
+	Queued    int    `json:"queued"`
+	Forwarded uint64 `json:"forwarded"`
+	Dropped   uint64 `json:"dropped"`
+	Failed    uint64 `json:"failed"`
+}
+
+type Forwarder struct {
+	sender Sender
+	opts   Options
+
+	mu     sync.RWMutex // guards closed against sends on queue
+	closed bool
+	queue  chan qrclient.ScanEvent
+	done   chan struct{}
+
+	forwarded, dropped, failed atomic.Uint64
+}
+
+// New starts the sender. Callers must Close the forwarder to flush it.
+func New(sender Sender, opts Options) *Forwarder {
+	def := DefaultOptions()
+	if opts.QueueSize <= 0 {
+		opts.QueueSize = def.QueueSize
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = def.BatchSize
+	}
+	if opts.FlushInterval <= 0 {
+		opts.FlushInterval = def.FlushInterval
+	}
+	if opts.Attempts <= 0 {
+		opts.Attempts = def.Attempts
+	}
+	f := &Forwarder{sender: sender, opts: opts, queue: make(chan qrclient.ScanEvent, opts.QueueSize), done: make(chan struct{})}
+	go f.work()
+	return f
+}
+
+// Forward queues a scan without blocking. It reports false when the scan was
+// dropped because the queue is full or closed.
+func (f *Forwarder) Forward(scan qrclient.ScanEvent) bool {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	if f.closed {
+		f.dropped.Add(1)
+		return false
+	}
+	select {
+	case f.queue <- scan:
+		return true
+	default:
+		f.dropped.Add(1)
+		return false
+	}
+}
+
+func (f *Forwarder) Stats() Stats {
+	return Stats{Queued: len(f.queue), Forwarded: f.forwarded.Load(), Dropped: f.dropped.Load(), Failed: f.failed.Load()}
+}
+
+// Close stops accepting scans and waits for queued ones to be sent. If ctx
+// ends first, scans still queued are lost.
+func (f *Forwarder) Close(ctx context.Context) error {
+	f.mu.Lock()
+	if f.closed {
+		f.mu.Unlock()
+		return ErrClosed
+	}
+	f.closed = true
+	close(f.queue)
+	f.mu.Unlock()
+
+	select {
+	case <-f.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (f *Forwarder) work() {
+	defer close(f.done)
+
+	batch := make([]qrclient.ScanEvent, 0, f.opts.BatchSize)
+	ticker := time.NewTicker(f.opts.FlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case scan, ok := <-f.queue:
+			if !ok {
+				f.send(batch)
+				return
+			}
+			batch = append(batch, scan)
+			if len(batch) >= f.opts.BatchSize {
+				f.send(batch)
+				batch = batch[:0]
+			}
+		case <-ticker.C:
+			if len(batch) > 0 {
+				f.send(batch)
+				batch = batch[:0]
+			}
+		}
+	}
+}
+
+// send retries a batch briefly; qr-service queues nothing from a batch it
+// refuses, so a retry never duplicates deliveries.
+func (f *Forwarder) send(batch []qrclient.ScanEvent) {
+	if len(batch) == 0 {
+		return
+	}
+	var err error
+	for attempt := 0; attempt < f.opts.Attempts; attempt++ {
+		if attempt > 0 {
+			time.Sleep(time.Duration(attempt) * 200 * time.Millisecond)
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		err = f.sender.ReportScans(ctx, batch)
+		cancel()
+		if err == nil {
+			f.forwarded.Add(uint64(len(batch)))
+			return
+		}
+	}
+	f.failed.Add(uint64(len(batch)))
+	log.Printf("dropped %d scans for webhooks after send failure: %v", len(batch), err)
+}
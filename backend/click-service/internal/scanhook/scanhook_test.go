@@ -0,0 +1,53 @@
+package scanhook
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"click-service/internal/qrclient"
+)
+
+type senderSpy struct {
+	mu      sync.Mutex
+	batches [][]qrclient.ScanEvent
+	fails   int
+}
+
+func (s *senderSpy) ReportScans(_ context.Context, scans []qrclient.ScanEvent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.fails > 0 {
+		s.fails--
+		return errors.New("qr-service unavailable")
+	}
+	s.batches = append(s.batches, append([]qrclient.ScanEvent(nil), scans...))
+	return nil
+}
+
+func TestForwarder_BatchesRetriesAndDrainsOnClose(t *testing.T) {
+	spy := &senderSpy{fails: 1}
+	f := New(spy, Options{BatchSize: 2, FlushInterval: time.Hour, Attempts: 2})
+	for _, id := range []string{"a", "b", "c"} {
+		if !f.Forward(qrclient.ScanEvent{QrCodeID: id}) {
+			t.Fatalf("forward %s refused", id)
+		}
+	}
+	if err := f.Close(context.Background()); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+	if f.Forward(qrclient.ScanEvent{QrCodeID: "d"}) {
+		t.Fatalf("expected a closed forwarder to refuse scans")
+	}
+
+	spy.mu.Lock()
+	defer spy.mu.Unlock()
+	if len(spy.batches) != 2 || len(spy.batches[0]) != 2 || spy.batches[1][0].QrCodeID != "c" {
+		t.Fatalf("expected a retried full batch and a drained rest, got %+v", spy.batches)
+	}
+	if st := f.Stats(); st.Forwarded != 3 || st.Dropped != 1 || st.Failed != 0 {
+		t.Fatalf("unexpected stats %+v", st)
+	}
+}
@@ -0,0 +1,105 @@
+package store
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"log"
+	"time"
+)
+
+const (
+	DefaultEventLimit = 100
+	MaxEventLimit     = 1000
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// EventQuery selects one code's raw click events, newest first. From is
+// inclusive and To exclusive; zero bounds are open.
+type EventQuery struct {
+	QrCodeID string
+	From     time.Time
+	To       time.Time
+	Limit    int
+	Cursor   string
+}
+
+type EventPage struct {
+	Items      []ClickEvent
+	NextCursor string
+}
+
+func (q EventQuery) normalize() EventQuery {
+	switch {
+	case q.Limit <= 0:
+		q.Limit = DefaultEventLimit
+	case q.Limit > MaxEventLimit:
+		q.Limit = MaxEventLimit
+	}
+	return q
+}
+
+// eventCursor is the keyset position of the last event on a page.
+type eventCursor struct {
+	At time.Time `json:"t"`
+	ID int64     `json:"i"`
+}
+
+// decodeEventCursor returns nil for the first page.
+func decodeEventCursor(raw string) (*eventCursor, error) {
+	if raw == "" {
+		return nil, nil
+	}
+	b, err := base64.RawURLEncoding.DecodeString(raw)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var c eventCursor
+	if err := json.Unmarshal(b, &c); err != nil || c.ID == 0 {
+		return nil, ErrInvalidCursor
+	}
+	return &c, nil
+}
+
+// eventPage trims a result fetched with Limit+1 rows, fills AtIso and sets
+// NextCursor when the extra row shows there is more.
+func eventPage(q EventQuery, items []ClickEvent) EventPage {
+	for i := range items {
+		items[i].AtIso = items[i].At.UTC().Format(time.RFC3339Nano)
+	}
+	if len(items) <= q.Limit {
+		return EventPage{Items: items}
+	}
+	items = items[:q.Limit]
+	last := items[len(items)-1]
+	b, _ := json.Marshal(eventCursor{At: last.At, ID: last.ID})
+	return EventPage{Items: items, NextCursor: base64.RawURLEncoding.EncodeToString(b)}
+}
+
+// RunEventRetention deletes raw events older than keep, once at start and
+// then every interval, until ctx ends. Aggregated stats are not affected. A
+// keep of zero or less would delete every event, so it purges nothing.
+func RunEventRetention(ctx context.Context, s Store, keep, interval time.Duration) {
+	if keep <= 0 {
+		log.Printf("click event retention disabled: keep must be positive, got %s", keep)
+		return
+	}
+	purge := func() {
+		if err := s.PurgeEventsBefore(time.Now().Add(-keep)); err != nil {
+			log.Printf("click event retention failed: %v", err)
+		}
+	}
+	purge()
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			purge()
+		}
+	}
+}
@@ -1,28 +1,96 @@
 package store
 
 import (
+	"maps"
+	"slices"
+	"sort"
 	"sync"
 	"time"
+
+	"click-service/internal/hll"
+	"click-service/internal/useragent"
 )
 
 type MemoryStore struct {
-	mu    sync.RWMutex
-	stats map[string]ClickStats
-	daily map[string]map[string]*DailyClickStats
+	mu     sync.RWMutex
+	stats  map[string]ClickStats
+	daily  map[string]map[string]*DailyClickStats
+	claims map[string]int64
+	events []ClickEvent
+	lastID int64
+
+	// visitors holds one unique-visitor sketch per code and day.
+	visitors map[string]map[string]*hll.Sketch
+	salts    *saltRing
+
+	// reports holds claimed weekly reports as "owner|week".
+	reports map[string]bool
 }
 
 func NewMemoryStore() *MemoryStore {
-	return &MemoryStore{stats: map[string]ClickStats{}, daily: map[string]map[string]*DailyClickStats{}}
+	return &MemoryStore{
+		stats:    map[string]ClickStats{},
+		daily:    map[string]map[string]*DailyClickStats{},
+		claims:   map[string]int64{},
+		visitors: map[string]map[string]*hll.Sketch{},
+		salts:    newSaltRing(),
+		reports:  map[string]bool{},
+	}
+}
+
+func (s *MemoryStore) ClaimScan(qrCodeID string, limit int64) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.claims[qrCodeID] >= limit {
+		return false, nil
+	}
+	s.claims[qrCodeID]++
+	return true, nil
+}
+
+func (s *MemoryStore) ClaimReport(ownerID, week string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := ownerID + "|" + week
+	if s.reports[key] {
+		return false, nil
+	}
+	s.reports[key] = true
+	return true, nil
+}
+
+func (s *MemoryStore) ReleaseReport(ownerID, week string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.reports, ownerID+"|"+week)
+	return nil
 }
 
 func (s *MemoryStore) RecordClick(event ClickEvent) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	s.recordLocked(event)
+	return nil
+}
+
+func (s *MemoryStore) RecordClicks(events []ClickEvent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, ev := range events {
+		s.recordLocked(ev)
+	}
+	return nil
+}
+
+func (s *MemoryStore) recordLocked(event ClickEvent) {
+	s.lastID++
+	event.ID = s.lastID
+	s.events = append(s.events, event)
 
-	t := event.At.UTC()
-	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	day, hour, zone := localSlot(event)
 	dayIso := day.Format("2006-01-02")
-	hour := t.Hour()
 
 	byDay, ok := s.daily[event.QrCodeID]
 	if !ok {
@@ -32,18 +100,39 @@ func (s *MemoryStore) RecordClick(event ClickEvent) error {
 
 	ds, ok := byDay[dayIso]
 	if !ok {
-		ds = &DailyClickStats{QrCodeID: event.QrCodeID, DayIso: dayIso}
+		ds = &DailyClickStats{QrCodeID: event.QrCodeID, DayIso: dayIso, TimeZone: zone}
 		byDay[dayIso] = ds
 	}
 
 	ds.Total++
 	incrementHour(ds, hour)
+	if h, ok := visitorHash(s.salts.forDay(day), event); ok {
+		sketches, ok := s.visitors[event.QrCodeID]
+		if !ok {
+			sketches = map[string]*hll.Sketch{}
+			s.visitors[event.QrCodeID] = sketches
+		}
+		if sketches[dayIso] == nil {
+			sketches[dayIso] = &hll.Sketch{}
+		}
+		sketches[dayIso].Add(h)
+	}
 	if region := event.Country; region != "" {
 		if ds.RegionCounts == nil {
 			ds.RegionCounts = map[string]int{}
 		}
 		ds.RegionCounts[region]++
 	}
+	if event.Variant != "" {
+		if ds.VariantCounts == nil {
+			ds.VariantCounts = map[string]int{}
+		}
+		ds.VariantCounts[event.Variant]++
+	}
+	ua := useragent.Parse(event.UserAgent)
+	ds.DeviceCounts = addCount(ds.DeviceCounts, ua.Device)
+	ds.OSCounts = addCount(ds.OSCounts, ua.OS)
+	ds.BrowserCounts = addCount(ds.BrowserCounts, ua.Browser)
 
 	st := s.stats[event.QrCodeID]
 	if st.QrCodeID == "" {
@@ -52,8 +141,16 @@ func (s *MemoryStore) RecordClick(event ClickEvent) error {
 	st.Total++
 	st.LastAtIso = event.At.UTC().Format(time.RFC3339)
 	st.LastCountry = event.Country
+	if event.Variant != "" {
+		// Copy so stats already handed out are not mutated.
+		counts := maps.Clone(st.VariantCounts)
+		if counts == nil {
+			counts = map[string]int{}
+		}
+		counts[event.Variant]++
+		st.VariantCounts = counts
+	}
 	s.stats[event.QrCodeID] = st
-	return nil
 }
 
 func (s *MemoryStore) GetStats(qrCodeID string) (ClickStats, error) {
@@ -64,9 +161,44 @@ func (s *MemoryStore) GetStats(qrCodeID string) (ClickStats, error) {
 	if !ok {
 		return ClickStats{}, ErrNotFound
 	}
+	st.UniqueVisitors = s.mergedVisitorsLocked(qrCodeID, func(string) bool { return true })
 	return st, nil
 }
 
+// mergedVisitorsLocked estimates a code's visitors over the days keep accepts.
+func (s *MemoryStore) mergedVisitorsLocked(qrCodeID string, keep func(dayIso string) bool) int {
+	var merged hll.Sketch
+	for dayIso, sk := range s.visitors[qrCodeID] {
+		if keep(dayIso) {
+			merged.Merge(sk)
+		}
+	}
+	return merged.Estimate()
+}
+
+func (s *MemoryStore) GetUniqueVisitors(qrCodeID string, from, to time.Time) (VisitorRange, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	fromIso, toIso := from.UTC().Format("2006-01-02"), to.UTC().Format("2006-01-02")
+	n := s.mergedVisitorsLocked(qrCodeID, func(dayIso string) bool { return dayIso >= fromIso && dayIso <= toIso })
+	return VisitorRange{FromIso: fromIso, ToIso: toIso, UniqueVisitors: n}, nil
+}
+
+func (s *MemoryStore) GetStatsBatch(qrCodeIDs []string) (map[string]ClickStats, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := make(map[string]ClickStats, len(qrCodeIDs))
+	for _, id := range qrCodeIDs {
+		if st, ok := s.stats[id]; ok {
+			st.UniqueVisitors = s.mergedVisitorsLocked(id, func(string) bool { return true })
+			result[id] = st
+		}
+	}
+	return result, nil
+}
+
 func (s *MemoryStore) GetDaily(qrCodeID string, day time.Time) (DailyClickStats, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
@@ -84,7 +216,9 @@ func (s *MemoryStore) GetDaily(qrCodeID string, day time.Time) (DailyClickStats,
 	if !ok {
 		return DailyClickStats{}, ErrNotFound
 	}
-	return *ds, nil
+	out := *ds
+	out.UniqueVisitors = s.visitors[qrCodeID][key].Estimate()
+	return out, nil
 }
 
 func (s *MemoryStore) GetDailyBatch(qrCodeID string, days []time.Time) (map[string]DailyClickStats, error) {
@@ -103,13 +237,71 @@ func (s *MemoryStore) GetDailyBatch(qrCodeID string, days []time.Time) (map[stri
 		key := day.Format("2006-01-02")
 
 		if ds, ok := byDay[key]; ok {
-			result[key] = *ds
+			out := *ds
+			out.UniqueVisitors = s.visitors[qrCodeID][key].Estimate()
+			result[key] = out
 		}
 	}
 
 	return result, nil
 }
 
+func (s *MemoryStore) ListEvents(query EventQuery) (EventPage, error) {
+	query = query.normalize()
+	cur, err := decodeEventCursor(query.Cursor)
+	if err != nil {
+		return EventPage{}, err
+	}
+
+	s.mu.RLock()
+	items := make([]ClickEvent, 0)
+	for _, ev := range s.events {
+		if ev.QrCodeID != query.QrCodeID {
+			continue
+		}
+		if !query.From.IsZero() && ev.At.Before(query.From) {
+			continue
+		}
+		if !query.To.IsZero() && !ev.At.Before(query.To) {
+			continue
+		}
+		if cur != nil && !olderThan(ev, cur.At, cur.ID) {
+			continue
+		}
+		items = append(items, ev)
+	}
+	s.mu.RUnlock()
+
+	sort.Slice(items, func(i, j int) bool { return olderThan(items[j], items[i].At, items[i].ID) })
+	if len(items) > query.Limit+1 {
+		items = items[:query.Limit+1]
+	}
+	return eventPage(query, items), nil
+}
+
+// olderThan reports whether ev comes after (at, id) in newest-first order.
+func olderThan(ev ClickEvent, at time.Time, id int64) bool {
+	if c := ev.At.Compare(at); c != 0 {
+		return c < 0
+	}
+	return ev.ID < id
+}
+
+func (s *MemoryStore) PurgeEventsBefore(cutoff time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.events = slices.DeleteFunc(s.events, func(ev ClickEvent) bool { return ev.At.Before(cutoff) })
+	return nil
+}
+
+func addCount(counts map[string]int, key string) map[string]int {
+	if counts == nil {
+		counts = map[string]int{}
+	}
+	counts[key]++
+	return counts
+}
+
 func incrementHour(ds *DailyClickStats, hour int) {
 	switch hour {
 	case 0:
@@ -1,6 +1,10 @@
 package store
 
 import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -31,3 +35,167 @@ func TestMemoryStore_RecordAndStats(t *testing.T) {
 		t.Fatalf("expected lastAtIso")
 	}
 }
+
+func TestMemoryStore_ClaimScanNeverOvershoots(t *testing.T) {
+	s := NewMemoryStore()
+
+	var granted atomic.Int64
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if ok, _ := s.ClaimScan("coupon", 3); ok {
+				granted.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+	if n := granted.Load(); n != 3 {
+		t.Fatalf("expected exactly 3 claims, got %d", n)
+	}
+}
+
+func TestMemoryStore_VariantCounts(t *testing.T) {
+	s := NewMemoryStore()
+	at := time.Date(2026, 1, 2, 10, 0, 0, 0, time.UTC)
+	for _, v := range []string{"a", "b", "a", ""} {
+		if err := s.RecordClick(ClickEvent{QrCodeID: "abc", At: at, Variant: v}); err != nil {
+			t.Fatalf("record: %v", err)
+		}
+	}
+
+	st, _ := s.GetStats("abc")
+	if st.Total != 4 || st.VariantCounts["a"] != 2 || st.VariantCounts["b"] != 1 || len(st.VariantCounts) != 2 {
+		t.Fatalf("unexpected stats %+v", st)
+	}
+	ds, _ := s.GetDaily("abc", at)
+	if ds.VariantCounts["a"] != 2 || ds.VariantCounts["b"] != 1 {
+		t.Fatalf("unexpected daily variant counts %+v", ds.VariantCounts)
+	}
+}
+
+func TestMemoryStore_ListEventsPagesNewestFirst(t *testing.T) {
+	s := NewMemoryStore()
+	base := time.Date(2026, 1, 2, 10, 0, 0, 0, time.UTC)
+	for i := 0; i < 5; i++ {
+		if err := s.RecordClick(ClickEvent{QrCodeID: "abc", At: base.Add(time.Duration(i) * time.Minute), IP: "203.0.113.7"}); err != nil {
+			t.Fatalf("record: %v", err)
+		}
+	}
+	_ = s.RecordClick(ClickEvent{QrCodeID: "other", At: base})
+
+	var got []ClickEvent
+	q := EventQuery{QrCodeID: "abc", From: base.Add(time.Minute), Limit: 2}
+	for {
+		page, err := s.ListEvents(q)
+		if err != nil {
+			t.Fatalf("list: %v", err)
+		}
+		got = append(got, page.Items...)
+		if page.NextCursor == "" {
+			break
+		}
+		q.Cursor = page.NextCursor
+	}
+	if len(got) != 4 {
+		t.Fatalf("expected 4 events from the second minute on, got %d", len(got))
+	}
+	for i, ev := range got {
+		if want := base.Add(time.Duration(4-i) * time.Minute); !ev.At.Equal(want) || ev.IP != "203.0.113.7" {
+			t.Fatalf("event %d: unexpected %+v", i, ev)
+		}
+	}
+
+	if _, err := s.ListEvents(EventQuery{QrCodeID: "abc", Cursor: "not-a-cursor"}); err != ErrInvalidCursor {
+		t.Fatalf("expected ErrInvalidCursor, got %v", err)
+	}
+}
+
+func TestMemoryStore_PurgeEventsKeepsAggregates(t *testing.T) {
+	s := NewMemoryStore()
+	old := time.Date(2026, 1, 1, 10, 0, 0, 0, time.UTC)
+	_ = s.RecordClick(ClickEvent{QrCodeID: "abc", At: old})
+	_ = s.RecordClick(ClickEvent{QrCodeID: "abc", At: old.AddDate(0, 0, 2)})
+
+	if err := s.PurgeEventsBefore(old.AddDate(0, 0, 1)); err != nil {
+		t.Fatalf("purge: %v", err)
+	}
+	page, _ := s.ListEvents(EventQuery{QrCodeID: "abc"})
+	if len(page.Items) != 1 || !page.Items[0].At.Equal(old.AddDate(0, 0, 2)) {
+		t.Fatalf("expected only the newer event, got %+v", page.Items)
+	}
+	if st, _ := s.GetStats("abc"); st.Total != 2 {
+		t.Fatalf("expected stats untouched by retention, got total=%d", st.Total)
+	}
+}
+
+func TestRunEventRetention_RefusesToKeepNothing(t *testing.T) {
+	s := NewMemoryStore()
+	_ = s.RecordClick(ClickEvent{QrCodeID: "abc", At: time.Now().Add(-time.Minute)})
+
+	// It returns at once rather than purging every hour until ctx ends.
+	RunEventRetention(context.Background(), s, 0, time.Hour)
+	if page, _ := s.ListEvents(EventQuery{QrCodeID: "abc"}); len(page.Items) != 1 {
+		t.Fatalf("expected a zero retention to purge nothing, got %+v", page.Items)
+	}
+}
+
+func TestMemoryStore_UniqueVisitorsPerDay(t *testing.T) {
+	s := NewMemoryStore()
+	day := time.Now().UTC()
+	for i := 0; i < 30; i++ {
+		ip := fmt.Sprintf("203.0.113.%d", i%10)
+		if err := s.RecordClick(ClickEvent{QrCodeID: "abc", At: day, IP: ip, UserAgent: "ua"}); err != nil {
+			t.Fatalf("record: %v", err)
+		}
+	}
+	// Same IP, different browser: a different visitor.
+	_ = s.RecordClick(ClickEvent{QrCodeID: "abc", At: day, IP: "203.0.113.0", UserAgent: "other"})
+	// No IP: a scan, but not a visitor.
+	_ = s.RecordClick(ClickEvent{QrCodeID: "abc", At: day})
+
+	ds, err := s.GetDaily("abc", day)
+	if err != nil {
+		t.Fatalf("daily: %v", err)
+	}
+	if ds.Total != 32 || ds.UniqueVisitors != 11 {
+		t.Fatalf("expected 32 scans from 11 visitors, got %d from %d", ds.Total, ds.UniqueVisitors)
+	}
+}
+
+func TestMemoryStore_UserAgentBreakdown(t *testing.T) {
+	s := NewMemoryStore()
+	at := time.Date(2026, 1, 2, 10, 0, 0, 0, time.UTC)
+	for _, ua := range []string{
+		"Mozilla/5.0 (iPhone; CPU iPhone OS 17_4 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.4 Mobile/15E148 Safari/604.1",
+		"Mozilla/5.0 (iPhone; CPU iPhone OS 17_4 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.4 Mobile/15E148 Safari/604.1",
+		"Mozilla/5.0 (Linux; Android 14; Pixel 8) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/123.0.0.0 Mobile Safari/537.36",
+	} {
+		_ = s.RecordClick(ClickEvent{QrCodeID: "abc", At: at, UserAgent: ua})
+	}
+
+	ds, _ := s.GetDaily("abc", at)
+	if ds.DeviceCounts["mobile"] != 3 || ds.OSCounts["iOS"] != 2 || ds.OSCounts["Android"] != 1 || ds.BrowserCounts["Safari"] != 2 || ds.BrowserCounts["Chrome"] != 1 {
+		t.Fatalf("unexpected breakdown %v %v %v", ds.DeviceCounts, ds.OSCounts, ds.BrowserCounts)
+	}
+}
+
+func TestMemoryStore_BucketsInCodeTimeZone(t *testing.T) {
+	s := NewMemoryStore()
+	// 22:45 UTC on Jan 1 is 04:15 on Jan 2 in Kolkata (+5:30).
+	_ = s.RecordClick(ClickEvent{QrCodeID: "abc", At: time.Date(2026, 1, 1, 22, 45, 0, 0, time.UTC), TimeZone: "Asia/Kolkata"})
+	// New York falls back on Nov 1: both 01:30s land in local hour 1.
+	for _, at := range []time.Time{time.Date(2026, 11, 1, 5, 30, 0, 0, time.UTC), time.Date(2026, 11, 1, 6, 30, 0, 0, time.UTC)} {
+		_ = s.RecordClick(ClickEvent{QrCodeID: "ny", At: at, TimeZone: "America/New_York"})
+	}
+
+	ds, err := s.GetDaily("abc", time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC))
+	if err != nil || ds.Hour04 != 1 || ds.TimeZone != "Asia/Kolkata" {
+		t.Fatalf("expected one click at 04h Kolkata time, got %+v (%v)", ds, err)
+	}
+	ny, _ := s.GetDaily("ny", time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC))
+	if ny.Total != 2 || ny.Hour01 != 2 || ny.TimeZone != "America/New_York" {
+		t.Fatalf("expected both repeated-hour clicks in hour 1, got %+v", ny)
+	}
+}
@@ -5,8 +5,13 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"strings"
+	"sync"
 	"time"
 
+	"click-service/internal/hll"
+	"click-service/internal/useragent"
+
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
@@ -14,6 +19,15 @@ import (
 
 type PostgresStore struct {
 	db *gorm.DB
+
+	// partitions caches which click_events day partitions exist.
+	partMu     sync.Mutex
+	partitions map[string]bool
+
+	// salts caches the visitor salts of recent days, which are shared with
+	// other instances through visitor_salts.
+	saltMu sync.Mutex
+	salts  map[string][]byte
 }
 
 type clickDailyStatsRow struct {
@@ -49,10 +63,48 @@ type clickDailyStatsRow struct {
 	LastCountry  string    `gorm:"not null;default:''"`
 	CreatedAt    time.Time
 	UpdatedAt    time.Time
+
+	// VariantCounts holds per-variant totals for codes with an A/B split.
+	VariantCounts []byte `gorm:"column:variant_counts;type:jsonb"`
+	// Visitors is the day's encoded HyperLogLog sketch of visitors.
+	Visitors []byte `gorm:"column:visitors;type:bytea"`
+	// Device, OS and browser family counts, like RegionCounts.
+	DeviceCounts  []byte `gorm:"column:device_counts;type:jsonb"`
+	OSCounts      []byte `gorm:"column:os_counts;type:jsonb"`
+	BrowserCounts []byte `gorm:"column:browser_counts;type:jsonb"`
+	// TimeZone is the zone day and the hour columns are in. A row keeps the
+	// zone of its first click; rows from before zones existed are UTC.
+	TimeZone string `gorm:"column:time_zone;not null;default:'UTC'"`
 }
 
 func (clickDailyStatsRow) TableName() string { return "click_daily_stats" }
 
+// visitorSaltRow is one day's visitor fingerprint salt. Rows are deleted
+// once the day is over so fingerprints cannot be recomputed.
+type visitorSaltRow struct {
+	Day  time.Time `gorm:"primaryKey;type:date;not null"`
+	Salt []byte    `gorm:"type:bytea;not null"`
+}
+
+func (visitorSaltRow) TableName() string { return "visitor_salts" }
+
+// scanClaimRow counts the scans taken from a limited code.
+type scanClaimRow struct {
+	QrCodeID string `gorm:"primaryKey;not null"`
+	Claimed  int64  `gorm:"not null;default:0"`
+}
+
+func (scanClaimRow) TableName() string { return "qr_scan_claims" }
+
+// reportClaimRow records a weekly report some instance has sent.
+type reportClaimRow struct {
+	OwnerID   string `gorm:"primaryKey;not null"`
+	Week      string `gorm:"primaryKey;not null"`
+	CreatedAt time.Time
+}
+
+func (reportClaimRow) TableName() string { return "weekly_report_claims" }
+
 func NewPostgresStore(ctx context.Context, databaseURL string) (*PostgresStore, error) {
 	db, err := gorm.Open(postgres.Open(databaseURL), &gorm.Config{
 		Logger: logger.Default.LogMode(logger.Silent),
@@ -61,7 +113,7 @@ func NewPostgresStore(ctx context.Context, databaseURL string) (*PostgresStore,
 		return nil, err
 	}
 
-	s := &PostgresStore{db: db}
+	s := &PostgresStore{db: db, partitions: map[string]bool{}, salts: map[string][]byte{}}
 	if err := s.ensureSchema(ctx); err != nil {
 		return nil, err
 	}
@@ -77,46 +129,326 @@ func (s *PostgresStore) Close() error {
 }
 
 func (s *PostgresStore) ensureSchema(ctx context.Context) error {
-	return s.db.WithContext(ctx).AutoMigrate(&clickDailyStatsRow{})
+	db := s.db.WithContext(ctx)
+	if err := db.AutoMigrate(&clickDailyStatsRow{}, &scanClaimRow{}, &visitorSaltRow{}, &reportClaimRow{}); err != nil {
+		return err
+	}
+	// AutoMigrate cannot declare partitioning, so the raw event log is
+	// created by hand: one partition per UTC day, added as clicks arrive and
+	// dropped whole by retention.
+	if err := db.Exec(
+		`CREATE TABLE IF NOT EXISTS click_events (
+		   id bigint GENERATED BY DEFAULT AS IDENTITY,
+		   at timestamptz NOT NULL,
+		   qr_code_id text NOT NULL,
+		   target_url text NOT NULL DEFAULT '',
+		   ip text NOT NULL DEFAULT '',
+		   user_agent text NOT NULL DEFAULT '',
+		   referer text NOT NULL DEFAULT '',
+		   country text NOT NULL DEFAULT '',
+		   request_id text NOT NULL DEFAULT '',
+		   accept_language text NOT NULL DEFAULT '',
+		   variant text NOT NULL DEFAULT '',
+		   PRIMARY KEY (at, id)
+		 ) PARTITION BY RANGE (at)`,
+	).Error; err != nil {
+		return err
+	}
+	return db.Exec(`CREATE INDEX IF NOT EXISTS click_events_qr_code_at_idx ON click_events (qr_code_id, at DESC, id DESC)`).Error
+}
+
+// ClaimScan relies on the upsert's row lock: the conditional update only
+// succeeds, and returns a row, while the count is below the limit.
+func (s *PostgresStore) ClaimScan(qrCodeID string, limit int64) (bool, error) {
+	if limit < 1 {
+		return false, nil
+	}
+	var claimed []int64
+	err := s.db.Raw(
+		`INSERT INTO qr_scan_claims (qr_code_id, claimed) VALUES (?, 1)
+		 ON CONFLICT (qr_code_id) DO UPDATE SET claimed = qr_scan_claims.claimed + 1
+		 WHERE qr_scan_claims.claimed < ?
+		 RETURNING claimed`,
+		qrCodeID, limit,
+	).Scan(&claimed).Error
+	if err != nil {
+		return false, err
+	}
+	return len(claimed) == 1, nil
+}
+
+func (s *PostgresStore) ClaimReport(ownerID, week string) (bool, error) {
+	res := s.db.Exec(
+		`INSERT INTO weekly_report_claims (owner_id, week, created_at) VALUES (?, ?, ?)
+		 ON CONFLICT (owner_id, week) DO NOTHING`,
+		ownerID, week, time.Now().UTC(),
+	)
+	if res.Error != nil {
+		return false, res.Error
+	}
+	return res.RowsAffected == 1, nil
+}
+
+func (s *PostgresStore) ReleaseReport(ownerID, week string) error {
+	return s.db.Where("owner_id = ? AND week = ?", ownerID, week).Delete(&reportClaimRow{}).Error
 }
 
 func (s *PostgresStore) RecordClick(event ClickEvent) error {
-	t := event.At.UTC()
-	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
-	hour := t.Hour()
-	if hour < 0 || hour > 23 {
-		return errors.New("invalid hour")
+	return s.RecordClicks([]ClickEvent{event})
+}
+
+// maxUpsertRows keeps one statement well under Postgres' 65535 bind
+// parameters (35 per row).
+const maxUpsertRows = 1000
+
+// dailyDelta is a batch's contribution to one click_daily_stats row.
+type dailyDelta struct {
+	qrCodeID    string
+	day         time.Time
+	zone        string
+	total       int
+	hours       [24]int
+	lastAt      time.Time
+	lastCountry string
+	regions     map[string]int
+	variants    map[string]int
+	visitors    *hll.Sketch
+	devices     map[string]int
+	oses        map[string]int
+	browsers    map[string]int
+}
+
+// RecordClicks folds the batch into one delta per code and day, then applies
+// them with a single multi-row upsert. Folding first matters: Postgres
+// rejects an upsert that touches the same row twice.
+func (s *PostgresStore) RecordClicks(events []ClickEvent) error {
+	if len(events) == 0 {
+		return nil
 	}
 
-	hourCol := fmt.Sprintf("hour%02d", hour)
+	byKey := map[string]*dailyDelta{}
+	deltas := make([]*dailyDelta, 0, len(events))
+	// Raw events are partitioned by UTC day, whatever the code's zone.
+	eventDays := map[time.Time]bool{}
+	for _, ev := range events {
+		t := ev.At.UTC()
+		eventDays[time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)] = true
+
+		day, hour, zone := localSlot(ev)
+		key := ev.QrCodeID + "|" + day.Format("2006-01-02")
+		d, ok := byKey[key]
+		if !ok {
+			d = &dailyDelta{qrCodeID: ev.QrCodeID, day: day, zone: zone, regions: map[string]int{}, variants: map[string]int{}, devices: map[string]int{}, oses: map[string]int{}, browsers: map[string]int{}}
+			byKey[key] = d
+			deltas = append(deltas, d)
+		}
+		d.total++
+		d.hours[hour]++
+		if !t.Before(d.lastAt) {
+			d.lastAt, d.lastCountry = t, ev.Country
+		}
+		if ev.Country != "" {
+			d.regions[ev.Country]++
+		}
+		if ev.Variant != "" {
+			d.variants[ev.Variant]++
+		}
+		ua := useragent.Parse(ev.UserAgent)
+		d.devices[ua.Device]++
+		d.oses[ua.OS]++
+		d.browsers[ua.Browser]++
+		salt, err := s.visitorSalt(day)
+		if err != nil {
+			return err
+		}
+		if h, ok := visitorHash(salt, ev); ok {
+			if d.visitors == nil {
+				d.visitors = &hll.Sketch{}
+			}
+			d.visitors.Add(h)
+		}
+	}
+
+	rows := make([]clickEventRow, len(events))
+	for i, ev := range events {
+		rows[i] = newClickEventRow(ev)
+	}
+	for day := range eventDays {
+		if err := s.ensurePartition(day); err != nil {
+			return err
+		}
+	}
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		for start := 0; start < len(deltas); start += maxUpsertRows {
+			if err := upsertDaily(tx, deltas[start:min(start+maxUpsertRows, len(deltas))]); err != nil {
+				return err
+			}
+		}
+		if err := mergeVisitors(tx, deltas); err != nil {
+			return err
+		}
+		return tx.CreateInBatches(rows, maxUpsertRows).Error
+	})
+}
+
+func upsertDaily(tx *gorm.DB, deltas []*dailyDelta) error {
+	hourCols := make([]string, 24)
+	hourSets := make([]string, 24)
+	for h := range hourCols {
+		hourCols[h] = fmt.Sprintf("hour%02d", h)
+		hourSets[h] = fmt.Sprintf("%[1]s = click_daily_stats.%[1]s + EXCLUDED.%[1]s", hourCols[h])
+	}
+	rowPlaceholder := "(?, ?, ?, " + strings.Repeat("?, ", 24) + "?, ?, ?::jsonb, ?::jsonb, ?::jsonb, ?::jsonb, ?::jsonb, ?, now(), now())"
+
+	rows := make([]string, 0, len(deltas))
+	args := make([]any, 0, len(deltas)*35)
+	for _, d := range deltas {
+		rows = append(rows, rowPlaceholder)
+		args = append(args, d.qrCodeID, d.day, d.total)
+		for _, n := range d.hours {
+			args = append(args, n)
+		}
+		args = append(args, d.lastAt, d.lastCountry)
+		for _, counts := range []map[string]int{d.regions, d.variants, d.devices, d.oses, d.browsers} {
+			raw, err := json.Marshal(counts)
+			if err != nil {
+				return err
+			}
+			args = append(args, string(raw))
+		}
+		args = append(args, d.zone)
+	}
 
-	// Atomic upsert: creates the per-day row on first click; increments the matching hour column per click.
 	sql := fmt.Sprintf(
-		`INSERT INTO click_daily_stats (qr_code_id, day, total, %s, last_at, last_country, region_counts, created_at, updated_at)
-		 VALUES (?, ?, 1, 1, ?, ?, CASE WHEN ? <> '' THEN jsonb_build_object(?, 1) ELSE '{}'::jsonb END, now(), now())
+		`INSERT INTO click_daily_stats (qr_code_id, day, total, %s, last_at, last_country, region_counts, variant_counts, device_counts, os_counts, browser_counts, time_zone, created_at, updated_at)
+		 VALUES %s
 		 ON CONFLICT (qr_code_id, day)
 		 DO UPDATE SET
-		   total = click_daily_stats.total + 1,
-		   %s = click_daily_stats.%s + 1,
+		   total = click_daily_stats.total + EXCLUDED.total,
+		   %s,
 		   last_at = GREATEST(click_daily_stats.last_at, EXCLUDED.last_at),
 		   last_country = CASE WHEN EXCLUDED.last_at >= click_daily_stats.last_at THEN EXCLUDED.last_country ELSE click_daily_stats.last_country END,
-		   region_counts = CASE
-		     WHEN EXCLUDED.last_country <> '' THEN
-		       jsonb_set(
-		         COALESCE(click_daily_stats.region_counts, '{}'::jsonb),
-		         ARRAY[EXCLUDED.last_country]::text[],
-		         to_jsonb(
-		           COALESCE((COALESCE(click_daily_stats.region_counts, '{}'::jsonb)->>EXCLUDED.last_country)::int, 0) + 1
-		         ),
-		         true
-		       )
-		     ELSE click_daily_stats.region_counts
-		   END,
+		   region_counts = %s,
+		   variant_counts = %s,
+		   device_counts = %s,
+		   os_counts = %s,
+		   browser_counts = %s,
 		   updated_at = now()`,
-		hourCol, hourCol, hourCol,
+		strings.Join(hourCols, ", "),
+		strings.Join(rows, ", "),
+		strings.Join(hourSets, ",\n\t\t   "),
+		mergeCountsSQL("region_counts"),
+		mergeCountsSQL("variant_counts"),
+		mergeCountsSQL("device_counts"),
+		mergeCountsSQL("os_counts"),
+		mergeCountsSQL("browser_counts"),
 	)
+	return tx.Exec(sql, args...).Error
+}
+
+// mergeVisitors folds each delta's visitor sketch into its row. Registers are
+// merged in Go since Postgres has no HyperLogLog type; the upsert already
+// holds the row locks, so the read-modify-write cannot race another batch.
+func mergeVisitors(tx *gorm.DB, deltas []*dailyDelta) error {
+	for _, d := range deltas {
+		if d.visitors == nil {
+			continue
+		}
+		var row clickDailyStatsRow
+		if err := tx.Select("visitors").Where("qr_code_id = ? AND day = ?", d.qrCodeID, d.day).Take(&row).Error; err != nil {
+			return err
+		}
+		merged := decodeSketch(row.Visitors)
+		merged.Merge(d.visitors)
+		raw, err := merged.MarshalBinary()
+		if err != nil {
+			return err
+		}
+		if err := tx.Model(&clickDailyStatsRow{}).Where("qr_code_id = ? AND day = ?", d.qrCodeID, d.day).Update("visitors", raw).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// visitorSalt returns day's salt, creating it if this is the first click of
+// the day on any instance. Creating one also deletes expired salts.
+func (s *PostgresStore) visitorSalt(day time.Time) ([]byte, error) {
+	key := day.Format("2006-01-02")
+
+	s.saltMu.Lock()
+	defer s.saltMu.Unlock()
+	if salt, ok := s.salts[key]; ok {
+		return salt, nil
+	}
+
+	now := time.Now()
+	for k := range s.salts {
+		if d, _ := time.Parse("2006-01-02", k); saltExpired(d, now) {
+			delete(s.salts, k)
+		}
+	}
+	if err := s.db.Where("day < ?", now.UTC().AddDate(0, 0, -2)).Delete(&visitorSaltRow{}).Error; err != nil {
+		return nil, err
+	}
+
+	err := s.db.Exec(`INSERT INTO visitor_salts (day, salt) VALUES (?, ?) ON CONFLICT (day) DO NOTHING`, day, newSalt()).Error
+	if err != nil {
+		return nil, err
+	}
+	var row visitorSaltRow
+	if err := s.db.Where("day = ?", day).Take(&row).Error; err != nil {
+		return nil, err
+	}
+	s.salts[key] = row.Salt
+	return row.Salt, nil
+}
+
+// mergedVisitors estimates visitors per code from the sketches of the rows
+// the query selects.
+func mergedVisitors(q *gorm.DB) (map[string]int, error) {
+	var rows []clickDailyStatsRow
+	if err := q.Select("qr_code_id", "visitors").Where("visitors IS NOT NULL").Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	merged := map[string]*hll.Sketch{}
+	for _, r := range rows {
+		if merged[r.QrCodeID] == nil {
+			merged[r.QrCodeID] = &hll.Sketch{}
+		}
+		merged[r.QrCodeID].Merge(decodeSketch(r.Visitors))
+	}
+	out := make(map[string]int, len(merged))
+	for id, sk := range merged {
+		out[id] = sk.Estimate()
+	}
+	return out, nil
+}
+
+func (s *PostgresStore) GetUniqueVisitors(qrCodeID string, from, to time.Time) (VisitorRange, error) {
+	from, to = from.UTC(), to.UTC()
+	from = time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, time.UTC)
+	to = time.Date(to.Year(), to.Month(), to.Day(), 0, 0, 0, 0, time.UTC)
 
-	return s.db.Exec(sql, event.QrCodeID, day, t, event.Country, event.Country, event.Country).Error
+	byCode, err := mergedVisitors(s.db.Model(&clickDailyStatsRow{}).Where("qr_code_id = ? AND day BETWEEN ? AND ?", qrCodeID, from, to))
+	if err != nil {
+		return VisitorRange{}, err
+	}
+	return VisitorRange{FromIso: from.Format("2006-01-02"), ToIso: to.Format("2006-01-02"), UniqueVisitors: byCode[qrCodeID]}, nil
+}
+
+// mergeCountsSQL adds the batch's jsonb count map for col onto the stored one.
+func mergeCountsSQL(col string) string {
+	return fmt.Sprintf(
+		`(SELECT COALESCE(jsonb_object_agg(kv.key, kv.total), '{}'::jsonb)
+		    FROM (SELECT e.key, SUM(e.value::int) AS total
+		            FROM (SELECT * FROM jsonb_each_text(COALESCE(click_daily_stats.%[1]s, '{}'::jsonb))
+		                  UNION ALL
+		                  SELECT * FROM jsonb_each_text(EXCLUDED.%[1]s)) AS e
+		           GROUP BY e.key) AS kv)`,
+		col,
+	)
 }
 
 func (s *PostgresStore) GetStats(qrCodeID string) (ClickStats, error) {
@@ -142,7 +474,98 @@ func (s *PostgresStore) GetStats(qrCodeID string) (ClickStats, error) {
 		return ClickStats{}, ErrNotFound
 	}
 
-	return ClickStats{QrCodeID: qrCodeID, Total: int(a.Total), LastAtIso: last.LastAt.UTC().Format(time.RFC3339), LastCountry: last.LastCountry}, nil
+	variants, err := s.variantTotals(qrCodeID)
+	if err != nil {
+		return ClickStats{}, err
+	}
+	visitors, err := mergedVisitors(s.db.Model(&clickDailyStatsRow{}).Where("qr_code_id = ?", qrCodeID))
+	if err != nil {
+		return ClickStats{}, err
+	}
+
+	return ClickStats{QrCodeID: qrCodeID, Total: int(a.Total), LastAtIso: last.LastAt.UTC().Format(time.RFC3339), LastCountry: last.LastCountry, VariantCounts: variants, UniqueVisitors: visitors[qrCodeID]}, nil
+}
+
+// variantTotals sums a code's per-day variant counts; nil when it has none.
+func (s *PostgresStore) variantTotals(qrCodeID string) (map[string]int, error) {
+	type agg struct {
+		Variant string
+		Total   int64
+	}
+	var rows []agg
+	err := s.db.Raw(
+		`SELECT v.key AS variant, SUM(v.value::int) AS total
+		   FROM click_daily_stats, jsonb_each_text(COALESCE(variant_counts, '{}'::jsonb)) AS v
+		  WHERE qr_code_id = ?
+		  GROUP BY v.key`,
+		qrCodeID,
+	).Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, nil
+	}
+	out := make(map[string]int, len(rows))
+	for _, r := range rows {
+		out[r.Variant] = int(r.Total)
+	}
+	return out, nil
+}
+
+// decodeCounts reads a jsonb count map column; empty maps become nil so they
+// are omitted from responses.
+func decodeCounts(raw []byte) map[string]int {
+	var counts map[string]int
+	if len(raw) > 0 {
+		_ = json.Unmarshal(raw, &counts)
+	}
+	if len(counts) == 0 {
+		return nil
+	}
+	return counts
+}
+
+func (s *PostgresStore) GetStatsBatch(qrCodeIDs []string) (map[string]ClickStats, error) {
+	result := make(map[string]ClickStats, len(qrCodeIDs))
+	if len(qrCodeIDs) == 0 {
+		return result, nil
+	}
+
+	type agg struct {
+		QrCodeID    string
+		Total       int64
+		LastAt      time.Time
+		LastCountry string
+	}
+	var rows []agg
+	// DISTINCT ON picks each code's latest day for last_at/last_country; the
+	// window sum covers all of its days.
+	err := s.db.Raw(
+		`SELECT DISTINCT ON (qr_code_id) qr_code_id,
+		        SUM(total) OVER (PARTITION BY qr_code_id) AS total,
+		        last_at, last_country
+		   FROM click_daily_stats
+		  WHERE qr_code_id IN ?
+		  ORDER BY qr_code_id, last_at DESC`,
+		qrCodeIDs,
+	).Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	visitors, err := mergedVisitors(s.db.Model(&clickDailyStatsRow{}).Where("qr_code_id IN ?", qrCodeIDs))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, r := range rows {
+		if r.Total == 0 {
+			continue
+		}
+		result[r.QrCodeID] = ClickStats{QrCodeID: r.QrCodeID, Total: int(r.Total), LastAtIso: r.LastAt.UTC().Format(time.RFC3339), LastCountry: r.LastCountry, UniqueVisitors: visitors[r.QrCodeID]}
+	}
+	return result, nil
 }
 
 func (s *PostgresStore) GetDaily(qrCodeID string, day time.Time) (DailyClickStats, error) {
@@ -195,6 +618,13 @@ func (s *PostgresStore) GetDaily(qrCodeID string, day time.Time) (DailyClickStat
 		Hour21:       row.Hour21,
 		Hour22:       row.Hour22,
 		Hour23:       row.Hour23,
+
+		VariantCounts:  decodeCounts(row.VariantCounts),
+		UniqueVisitors: decodeSketch(row.Visitors).Estimate(),
+		DeviceCounts:   decodeCounts(row.DeviceCounts),
+		OSCounts:       decodeCounts(row.OSCounts),
+		BrowserCounts:  decodeCounts(row.BrowserCounts),
+		TimeZone:       row.TimeZone,
 	}, nil
 }
 
@@ -255,8 +685,127 @@ func (s *PostgresStore) GetDailyBatch(qrCodeID string, days []time.Time) (map[st
 			Hour21:       row.Hour21,
 			Hour22:       row.Hour22,
 			Hour23:       row.Hour23,
+
+			VariantCounts:  decodeCounts(row.VariantCounts),
+			UniqueVisitors: decodeSketch(row.Visitors).Estimate(),
+			DeviceCounts:   decodeCounts(row.DeviceCounts),
+			OSCounts:       decodeCounts(row.OSCounts),
+			BrowserCounts:  decodeCounts(row.BrowserCounts),
+			TimeZone:       row.TimeZone,
 		}
 	}
 
 	return result, nil
 }
+
+// clickEventRow is one line of the raw click log.
+type clickEventRow struct {
+	ID         int64 `gorm:"primaryKey;autoIncrement"`
+	At         time.Time
+	QrCodeID   string
+	TargetURL  string
+	IP         string `gorm:"column:ip"`
+	UserAgent  string
+	Referer    string
+	Country    string
+	RequestID  string
+	AcceptLang string `gorm:"column:accept_language"`
+	Variant    string
+}
+
+func (clickEventRow) TableName() string { return "click_events" }
+
+func newClickEventRow(ev ClickEvent) clickEventRow {
+	return clickEventRow{At: ev.At.UTC(), QrCodeID: ev.QrCodeID, TargetURL: ev.TargetURL, IP: ev.IP, UserAgent: ev.UserAgent, Referer: ev.Referer, Country: ev.Country, RequestID: ev.RequestID, AcceptLang: ev.AcceptLang, Variant: ev.Variant}
+}
+
+func (r clickEventRow) toEvent() ClickEvent {
+	return ClickEvent{ID: r.ID, At: r.At.UTC(), QrCodeID: r.QrCodeID, TargetURL: r.TargetURL, IP: r.IP, UserAgent: r.UserAgent, Referer: r.Referer, Country: r.Country, RequestID: r.RequestID, AcceptLang: r.AcceptLang, Variant: r.Variant}
+}
+
+const eventPartitionPrefix = "click_events_"
+
+// ensurePartition creates the click_events partition holding day.
+func (s *PostgresStore) ensurePartition(day time.Time) error {
+	name := eventPartitionPrefix + day.Format("20060102")
+
+	s.partMu.Lock()
+	defer s.partMu.Unlock()
+	if s.partitions[name] {
+		return nil
+	}
+	err := s.db.Exec(fmt.Sprintf(
+		`CREATE TABLE IF NOT EXISTS %s PARTITION OF click_events FOR VALUES FROM ('%s') TO ('%s')`,
+		name, day.Format(time.RFC3339), day.AddDate(0, 0, 1).Format(time.RFC3339),
+	)).Error
+	if err != nil {
+		// Another instance may have created it between our check and create.
+		var exists bool
+		if s.db.Raw(`SELECT to_regclass(?) IS NOT NULL`, name).Scan(&exists).Error != nil || !exists {
+			return err
+		}
+	}
+	s.partitions[name] = true
+	return nil
+}
+
+func (s *PostgresStore) ListEvents(query EventQuery) (EventPage, error) {
+	query = query.normalize()
+	cur, err := decodeEventCursor(query.Cursor)
+	if err != nil {
+		return EventPage{}, err
+	}
+
+	q := s.db.Model(&clickEventRow{}).Where("qr_code_id = ?", query.QrCodeID)
+	if !query.From.IsZero() {
+		q = q.Where("at >= ?", query.From)
+	}
+	if !query.To.IsZero() {
+		q = q.Where("at < ?", query.To)
+	}
+	if cur != nil {
+		q = q.Where("(at, id) < (?, ?)", cur.At, cur.ID)
+	}
+	var rows []clickEventRow
+	if err := q.Order("at DESC, id DESC").Limit(query.Limit + 1).Find(&rows).Error; err != nil {
+		return EventPage{}, err
+	}
+
+	items := make([]ClickEvent, len(rows))
+	for i, r := range rows {
+		items[i] = r.toEvent()
+	}
+	return eventPage(query, items), nil
+}
+
+// PurgeEventsBefore drops every day partition that ends by cutoff, which is
+// cheap however many rows they hold, then deletes the remainder from the
+// partition cutoff falls in.
+func (s *PostgresStore) PurgeEventsBefore(cutoff time.Time) error {
+	var names []string
+	err := s.db.Raw(
+		`SELECT c.relname
+		   FROM pg_inherits i
+		   JOIN pg_class c ON c.oid = i.inhrelid
+		   JOIN pg_class p ON p.oid = i.inhparent
+		  WHERE p.relname = 'click_events'`,
+	).Scan(&names).Error
+	if err != nil {
+		return err
+	}
+
+	for _, name := range names {
+		day, err := time.Parse("20060102", strings.TrimPrefix(name, eventPartitionPrefix))
+		if err != nil || day.AddDate(0, 0, 1).After(cutoff) {
+			continue
+		}
+		s.partMu.Lock()
+		err = s.db.Exec(fmt.Sprintf(`DROP TABLE IF EXISTS %s`, name)).Error
+		delete(s.partitions, name)
+		s.partMu.Unlock()
+		if err != nil {
+			return err
+		}
+	}
+	return s.db.Where("at < ?", cutoff).Delete(&clickEventRow{}).Error
+}
@@ -8,6 +8,9 @@ import (
 var ErrNotFound = errors.New("not found")
 
 type ClickEvent struct {
+	// ID is assigned when the event is stored; it orders events recorded at
+	// the same instant.
+	ID         int64     `json:"id,omitempty"`
 	At         time.Time `json:"-"`
 	AtIso      string    `json:"atIso"`
 	IP         string    `json:"ip"`
@@ -19,6 +22,10 @@ type ClickEvent struct {
 	TargetURL  string    `json:"targetUrl"`
 	UserType   string    `json:"userType,omitempty"`
 	AcceptLang string    `json:"acceptLanguage,omitempty"`
+	// Variant is the ID of the A/B variant served, if the code has a split.
+	Variant string `json:"variant,omitempty"`
+	// TimeZone is the code's IANA zone; daily stats are bucketed in it.
+	TimeZone string `json:"timeZone,omitempty"`
 }
 
 type ClickStats struct {
@@ -26,6 +33,26 @@ type ClickStats struct {
 	Total       int    `json:"total"`
 	LastAtIso   string `json:"lastAtIso,omitempty"`
 	LastCountry string `json:"lastCountry,omitempty"`
+	// VariantCounts breaks Total down by A/B variant ID; clicks served
+	// outside a split are not included.
+	VariantCounts map[string]int `json:"variantCounts,omitempty"`
+	// UniqueVisitors estimates distinct visitors over all days. Visitors are
+	// fingerprinted with a daily salt, so one returning on another day
+	// counts again.
+	UniqueVisitors int `json:"uniqueVisitors"`
+	// Range is set when the caller asked for a date range.
+	Range *VisitorRange `json:"range,omitempty"`
+}
+
+// TagClickStats sums the stats of every code carrying a tag, so a campaign
+// can be measured as one unit. QrCodes lists only codes that have clicks.
+type TagClickStats struct {
+	TagID       string       `json:"tagId"`
+	QrCodeCount int          `json:"qrCodeCount"`
+	Total       int          `json:"total"`
+	LastAtIso   string       `json:"lastAtIso,omitempty"`
+	LastCountry string       `json:"lastCountry,omitempty"`
+	QrCodes     []ClickStats `json:"qrCodes"`
 }
 
 type DailyClickStats struct {
@@ -57,11 +84,59 @@ type DailyClickStats struct {
 	Hour21       int            `json:"hour21"`
 	Hour22       int            `json:"hour22"`
 	Hour23       int            `json:"hour23"`
+
+	// VariantCounts breaks Total down by A/B variant ID.
+	VariantCounts map[string]int `json:"variantCounts,omitempty"`
+	// UniqueVisitors is an estimate, accurate to a couple of percent.
+	UniqueVisitors int `json:"uniqueVisitors"`
+	// DeviceCounts, OSCounts and BrowserCounts break Total down by the
+	// families of the scanning user agent; see package useragent.
+	DeviceCounts  map[string]int `json:"deviceCounts,omitempty"`
+	OSCounts      map[string]int `json:"osCounts,omitempty"`
+	BrowserCounts map[string]int `json:"browserCounts,omitempty"`
+	// TimeZone is the zone DayIso and the hourN counters are in: the code's
+	// zone when its first click of the day was recorded.
+	TimeZone string `json:"timeZone"`
+}
+
+// Hours returns the hourN counters indexed by local clock hour in d.TimeZone,
+// not by UTC hour.
+func (d DailyClickStats) Hours() [24]int {
+	return [24]int{
+		d.Hour00, d.Hour01, d.Hour02, d.Hour03, d.Hour04, d.Hour05,
+		d.Hour06, d.Hour07, d.Hour08, d.Hour09, d.Hour10, d.Hour11,
+		d.Hour12, d.Hour13, d.Hour14, d.Hour15, d.Hour16, d.Hour17,
+		d.Hour18, d.Hour19, d.Hour20, d.Hour21, d.Hour22, d.Hour23,
+	}
 }
 
 type Store interface {
 	RecordClick(event ClickEvent) error
+	// ClaimScan atomically takes one of a code's limit scans. It returns false,
+	// without counting, once limit scans have been claimed.
+	ClaimScan(qrCodeID string, limit int64) (bool, error)
 	GetStats(qrCodeID string) (ClickStats, error)
+	// GetStatsBatch returns stats keyed by code ID; codes without clicks are absent.
+	GetStatsBatch(qrCodeIDs []string) (map[string]ClickStats, error)
 	GetDaily(qrCodeID string, day time.Time) (DailyClickStats, error)
 	GetDailyBatch(qrCodeID string, days []time.Time) (map[string]DailyClickStats, error)
+	// GetUniqueVisitors merges a code's daily visitor sketches from the day of
+	// from through the day of to.
+	GetUniqueVisitors(qrCodeID string, from, to time.Time) (VisitorRange, error)
+	// ListEvents pages through the raw click log; a bad cursor is ErrInvalidCursor.
+	ListEvents(query EventQuery) (EventPage, error)
+	// PurgeEventsBefore drops raw events older than cutoff.
+	PurgeEventsBefore(cutoff time.Time) error
+	// ClaimReport takes the right to send an owner's report for a week. It
+	// returns false when the report was already claimed, so instances
+	// sharing a store send each report once.
+	ClaimReport(ownerID, week string) (bool, error)
+	// ReleaseReport gives up a claim whose report could not be sent.
+	ReleaseReport(ownerID, week string) error
+}
+
+// BatchRecorder is implemented by stores that can write many clicks in one
+// round trip. The ingest pipeline uses it when available.
+type BatchRecorder interface {
+	RecordClicks(events []ClickEvent) error
 }
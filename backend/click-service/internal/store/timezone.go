@@ -0,0 +1,36 @@
+package store
+
+import (
+	"sync"
+	"time"
+)
+
+// locations caches zones by name, since LoadLocation parses tzdata each call.
+var locations sync.Map
+
+// zoneOf returns the named zone, or UTC for an empty or unknown name.
+func zoneOf(name string) *time.Location {
+	if name == "" || name == "UTC" {
+		return time.UTC
+	}
+	if loc, ok := locations.Load(name); ok {
+		return loc.(*time.Location)
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil || name == "Local" {
+		loc = time.UTC
+	}
+	locations.Store(name, loc)
+	return loc
+}
+
+// localSlot places a click in its code's zone: the local calendar date, as
+// a UTC-midnight time like the day column, and the local clock hour. Across
+// DST changes a repeated hour counts in one hourNN and a skipped one stays
+// zero; half-hour zones bucket exactly since the local time is computed
+// from the instant.
+func localSlot(ev ClickEvent) (day time.Time, hour int, zone string) {
+	loc := zoneOf(ev.TimeZone)
+	t := ev.At.In(loc)
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC), t.Hour(), loc.String()
+}
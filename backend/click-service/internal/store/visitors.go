@@ -0,0 +1,96 @@
+package store
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"sync"
+	"time"
+
+	"click-service/internal/hll"
+)
+
+// VisitorRange is the unique visitor estimate over whole UTC days, From and
+// To inclusive.
+type VisitorRange struct {
+	FromIso        string `json:"fromIso"`
+	ToIso          string `json:"toIso"`
+	UniqueVisitors int    `json:"uniqueVisitors"`
+}
+
+// Unique visitors are estimated from a fingerprint of IP and user agent,
+// hashed with a random salt that changes every UTC day. Only HyperLogLog
+// registers derived from the hash are stored, and a day's salt is thrown away
+// once late clicks for it are no longer expected, after which no one can tell
+// whether a given visitor is in a sketch. The price is that the same visitor
+// on two days looks like two visitors, so range estimates count visitor-days
+// deduplicated within each day; merging sketches still deduplicates across
+// codes, hours and repeated scans.
+
+const saltSize = 16
+
+// visitorHash fingerprints a click for the day's sketch. Clicks without an IP
+// cannot be told apart and are not counted as visitors.
+func visitorHash(salt []byte, ev ClickEvent) (uint64, bool) {
+	if ev.IP == "" {
+		return 0, false
+	}
+	h := sha256.New()
+	h.Write(salt)
+	h.Write([]byte(ev.IP))
+	h.Write([]byte{0})
+	h.Write([]byte(ev.UserAgent))
+	return binary.BigEndian.Uint64(h.Sum(nil)), true
+}
+
+func newSalt() []byte {
+	b := make([]byte, saltSize)
+	_, _ = rand.Read(b)
+	return b
+}
+
+// saltExpired reports whether day's salt is no longer needed at now: salts
+// are kept through the following day for clicks that arrive late.
+func saltExpired(day, now time.Time) bool {
+	return day.AddDate(0, 0, 2).Before(now)
+}
+
+// saltRing keeps the daily salts of a single process.
+type saltRing struct {
+	mu    sync.Mutex
+	salts map[string][]byte
+	now   func() time.Time
+}
+
+func newSaltRing() *saltRing {
+	return &saltRing{salts: map[string][]byte{}, now: time.Now}
+}
+
+func (r *saltRing) forDay(day time.Time) []byte {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := day.Format("2006-01-02")
+	if salt, ok := r.salts[key]; ok {
+		return salt
+	}
+	now := r.now()
+	for k := range r.salts {
+		if d, _ := time.Parse("2006-01-02", k); saltExpired(d, now) {
+			delete(r.salts, k)
+		}
+	}
+	salt := newSalt()
+	r.salts[key] = salt
+	return salt
+}
+
+// decodeSketch reads a stored sketch; unreadable ones count as empty rather
+// than failing the stats they are part of.
+func decodeSketch(raw []byte) *hll.Sketch {
+	s := &hll.Sketch{}
+	if err := s.UnmarshalBinary(raw); err != nil {
+		return &hll.Sketch{}
+	}
+	return s
+}
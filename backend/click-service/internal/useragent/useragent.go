@@ -0,0 +1,100 @@
+// Package useragent classifies User-Agent headers into the coarse families
+// shown in click analytics. It only looks for well-known tokens, so it
+// needs no database and unknown agents fall into "Other".
+package useragent
+
+import "strings"
+
+const (
+	DeviceMobile  = "mobile"
+	DeviceTablet  = "tablet"
+	DeviceDesktop = "desktop"
+	DeviceBot     = "bot"
+	DeviceUnknown = "unknown"
+
+	Other = "Other"
+)
+
+// Info is the device class, OS family and browser family of one agent.
+type Info struct {
+	Device  string
+	OS      string
+	Browser string
+}
+
+// botTokens mark crawlers, link unfurlers and HTTP libraries, compared in
+// lowercase.
+var botTokens = []string{"bot", "crawler", "spider", "facebookexternalhit", "curl/", "wget/", "python-requests", "go-http-client", "okhttp", "headless"}
+
+// Parse classifies ua. An empty agent has device "unknown".
+func Parse(ua string) Info {
+	ua = strings.TrimSpace(ua)
+	if ua == "" {
+		return Info{Device: DeviceUnknown, OS: Other, Browser: Other}
+	}
+	return Info{Device: device(ua), OS: osFamily(ua), Browser: browser(ua)}
+}
+
+func device(ua string) string {
+	lower := strings.ToLower(ua)
+	for _, tok := range botTokens {
+		if strings.Contains(lower, tok) {
+			return DeviceBot
+		}
+	}
+	switch {
+	case strings.Contains(ua, "iPad"), strings.Contains(ua, "Tablet"):
+		return DeviceTablet
+	case strings.Contains(ua, "Android") && !strings.Contains(ua, "Mobile"):
+		// Android tablets leave "Mobile" out of their agent.
+		return DeviceTablet
+	case strings.Contains(ua, "iPhone"), strings.Contains(ua, "iPod"), strings.Contains(ua, "Mobile"):
+		return DeviceMobile
+	default:
+		return DeviceDesktop
+	}
+}
+
+func osFamily(ua string) string {
+	switch {
+	case strings.Contains(ua, "iPhone"), strings.Contains(ua, "iPad"), strings.Contains(ua, "iPod"):
+		return "iOS"
+	case strings.Contains(ua, "Android"):
+		return "Android"
+	case strings.Contains(ua, "Windows"):
+		return "Windows"
+	case strings.Contains(ua, "CrOS"):
+		return "ChromeOS"
+	case strings.Contains(ua, "Mac OS X"), strings.Contains(ua, "Macintosh"):
+		return "macOS"
+	case strings.Contains(ua, "Linux"):
+		return "Linux"
+	default:
+		return Other
+	}
+}
+
+// browser checks the most specific tokens first: most browsers also claim to
+// be Chrome and Safari.
+func browser(ua string) string {
+	switch {
+	case strings.Contains(ua, "Instagram"):
+		return "Instagram"
+	case strings.Contains(ua, "FBAN/"), strings.Contains(ua, "FBAV/"):
+		return "Facebook"
+	case strings.Contains(ua, "Edg/"), strings.Contains(ua, "EdgA/"), strings.Contains(ua, "EdgiOS/"):
+		return "Edge"
+	case strings.Contains(ua, "OPR/"), strings.Contains(ua, "Opera"):
+		return "Opera"
+	case strings.Contains(ua, "SamsungBrowser/"):
+		return "Samsung Internet"
+	case strings.Contains(ua, "Firefox/"), strings.Contains(ua, "FxiOS/"):
+		return "Firefox"
+	case strings.Contains(ua, "Chrome/"), strings.Contains(ua, "CriOS/"):
+		return "Chrome"
+	case strings.Contains(ua, "Safari/"):
+		return "Safari"
+	default:
+		return Other
+	}
+}
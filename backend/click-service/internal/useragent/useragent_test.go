@@ -0,0 +1,38 @@
+package useragent
+
+import "testing"
+
+func TestParse(t *testing.T) {
+	cases := []struct {
+		ua   string
+		want Info
+	}{
+		{"Mozilla/5.0 (iPhone; CPU iPhone OS 17_4 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.4 Mobile/15E148 Safari/604.1",
+			Info{DeviceMobile, "iOS", "Safari"}},
+		{"Mozilla/5.0 (iPhone; CPU iPhone OS 17_4 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) CriOS/123.0.6312.52 Mobile/15E148 Safari/604.1",
+			Info{DeviceMobile, "iOS", "Chrome"}},
+		{"Mozilla/5.0 (Linux; Android 14; Pixel 8) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/123.0.0.0 Mobile Safari/537.36",
+			Info{DeviceMobile, "Android", "Chrome"}},
+		{"Mozilla/5.0 (Linux; Android 13; SAMSUNG SM-S911B) AppleWebKit/537.36 (KHTML, like Gecko) SamsungBrowser/24.0 Chrome/117.0.0.0 Mobile Safari/537.36",
+			Info{DeviceMobile, "Android", "Samsung Internet"}},
+		{"Mozilla/5.0 (Linux; Android 13; SM-X710) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/123.0.0.0 Safari/537.36",
+			Info{DeviceTablet, "Android", "Chrome"}},
+		{"Mozilla/5.0 (iPad; CPU OS 16_6 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/16.6 Mobile/15E148 Safari/604.1",
+			Info{DeviceTablet, "iOS", "Safari"}},
+		{"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/123.0.0.0 Safari/537.36 Edg/123.0.2420.65",
+			Info{DeviceDesktop, "Windows", "Edge"}},
+		{"Mozilla/5.0 (Macintosh; Intel Mac OS X 14.4; rv:124.0) Gecko/20100101 Firefox/124.0",
+			Info{DeviceDesktop, "macOS", "Firefox"}},
+		{"Mozilla/5.0 (iPhone; CPU iPhone OS 17_4 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Mobile/15E148 Instagram 323.0.0.35.65",
+			Info{DeviceMobile, "iOS", "Instagram"}},
+		{"facebookexternalhit/1.1 (+http://www.facebook.com/externalhit_uatext.php)",
+			Info{DeviceBot, Other, Other}},
+		{"curl/8.6.0", Info{DeviceBot, Other, Other}},
+		{"", Info{DeviceUnknown, Other, Other}},
+	}
+	for _, c := range cases {
+		if got := Parse(c.ua); got != c.want {
+			t.Errorf("Parse(%q) = %+v, want %+v", c.ua, got, c.want)
+		}
+	}
+}
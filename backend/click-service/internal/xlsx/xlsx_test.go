@@ -0,0 +1,75 @@
+package xlsx
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"io"
+	"testing"
+)
+
+func TestWriter_ProducesReadableSheet(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf, "Clicks")
+	if err != nil {
+		t.Fatalf("new: %v", err)
+	}
+	_ = w.WriteRow([]any{"day", "total", "label"})
+	_ = w.WriteRow([]any{"2026-01-02", 42, "Fish & <Chips>\x00"})
+	if err := w.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+	if err := w.WriteRow([]any{"late"}); err != ErrClosed {
+		t.Fatalf("expected ErrClosed, got %v", err)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("zip: %v", err)
+	}
+	var sheet []byte
+	for _, f := range zr.File {
+		rc, _ := f.Open()
+		body, _ := io.ReadAll(rc)
+		rc.Close()
+		// Every part must be well-formed XML.
+		if err := xml.Unmarshal(body, new(struct{})); err != nil {
+			t.Fatalf("%s: %v", f.Name, err)
+		}
+		if f.Name == "xl/worksheets/sheet1.xml" {
+			sheet = body
+		}
+	}
+
+	var parsed struct {
+		Rows []struct {
+			Cells []struct {
+				Ref    string `xml:"r,attr"`
+				Type   string `xml:"t,attr"`
+				Value  string `xml:"v"`
+				Inline string `xml:"is>t"`
+			} `xml:"c"`
+		} `xml:"sheetData>row"`
+	}
+	if err := xml.Unmarshal(sheet, &parsed); err != nil {
+		t.Fatalf("sheet: %v", err)
+	}
+	if len(parsed.Rows) != 2 {
+		t.Fatalf("expected 2 rows, got %d", len(parsed.Rows))
+	}
+	cells := parsed.Rows[1].Cells
+	if cells[1].Ref != "B2" || cells[1].Type != "" || cells[1].Value != "42" {
+		t.Fatalf("expected a number cell B2=42, got %+v", cells[1])
+	}
+	if cells[2].Inline != "Fish & <Chips>" {
+		t.Fatalf("expected escaped text to round-trip, got %q", cells[2].Inline)
+	}
+}
+
+func TestColumn(t *testing.T) {
+	for i, want := range map[int]string{0: "A", 25: "Z", 26: "AA", 27: "AB", 701: "ZZ", 702: "AAA"} {
+		if got := column(i); got != want {
+			t.Errorf("column(%d) = %q, want %q", i, got, want)
+		}
+	}
+}
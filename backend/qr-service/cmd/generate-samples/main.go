@@ -55,6 +55,7 @@ func main() {
 
 	for _, sample := range samples {
 		qr := model.QrCode{
+			OwnerID:   userID,
 			Label:     sample.label,
 			URL:       sample.url,
 			Active:    true,
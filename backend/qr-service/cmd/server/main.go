@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"log"
 	"net/http"
 	"os"
@@ -9,10 +10,14 @@ import (
 	"strings"
 	"syscall"
 	"time"
+	// Redirect rules name IANA zones; the alpine image ships no zoneinfo.
+	_ "time/tzdata"
 
+	"qr-service/internal/auth"
 	"qr-service/internal/httpapi"
-	"qr-service/internal/middleware"
+	"qr-service/internal/ratelimit"
 	"qr-service/internal/store"
+	"qr-service/internal/webhook"
 )
 
 func main() {
@@ -20,6 +25,20 @@ func main() {
 	allowedOrigins := splitCSV(envOr("CORS_ALLOW_ORIGINS", "http://localhost:5173"))
 	databaseURL := strings.TrimSpace(os.Getenv("DATABASE_URL"))
 	adminKey := envOr("ADMIN_API_KEY", "")
+	internalKey := envOr("INTERNAL_API_KEY", "")
+	shortLinkBaseURL := envOr("SHORT_LINK_BASE_URL", "http://localhost:8082")
+
+	region := envOr("AWS_REGION", "us-east-1")
+	userPoolID := envOr("COGNITO_USER_POOL_ID", "")
+	clientID := envOr("COGNITO_CLIENT_ID", "")
+	issuer := envOr("COGNITO_ISSUER", "")
+	if issuer == "" && userPoolID != "" {
+		issuer = fmt.Sprintf("https://cognito-idp.%s.amazonaws.com/%s", region, userPoolID)
+	}
+	jwksURL := envOr("COGNITO_JWKS_URL", "")
+	if jwksURL == "" && issuer != "" {
+		jwksURL = issuer + "/.well-known/jwks.json"
+	}
 
 	ctx := context.Background()
 
@@ -39,7 +58,48 @@ func main() {
 		log.Printf("qr-service using in-memory storage (set DATABASE_URL to persist)")
 	}
 
-	router := httpapi.NewRouter(httpapi.Server{Store: st, AdminAPIKey: adminKey})
+	var verifier *auth.Verifier
+	if jwksURL != "" {
+		verifier = &auth.Verifier{Keys: auth.NewJWKS(jwksURL), Issuer: issuer, ClientID: clientID, Leeway: 30 * time.Second}
+		log.Printf("qr-service verifying tokens against %s", jwksURL)
+	} else {
+		log.Printf("qr-service auth not configured (set COGNITO_USER_POOL_ID or COGNITO_JWKS_URL); user endpoints will answer 401")
+	}
+
+	var apiKeys *auth.APIKeys
+	if userServiceURL := envOr("USER_SERVICE_BASE_URL", ""); userServiceURL != "" && internalKey != "" {
+		apiKeys = auth.NewAPIKeys(userServiceURL, internalKey)
+		log.Printf("qr-service verifying API keys with %s", userServiceURL)
+	} else {
+		log.Printf("API keys not accepted (set USER_SERVICE_BASE_URL and INTERNAL_API_KEY)")
+	}
+
+	// Webhook deliveries wait in the store's outbox until a dispatcher sends
+	// them; every instance runs one.
+	webhooksCtx, stopWebhooks := context.WithCancel(ctx)
+	defer stopWebhooks()
+	go webhook.New(st).Run(webhooksCtx, envDuration("WEBHOOK_POLL_INTERVAL", 5*time.Second))
+
+	// Requests are metered per user or API key on their plan, and per IP
+	// for anonymous callers such as scanners resolving public codes.
+	limits, err := ratelimit.ParseLimits(envOr("RATE_LIMITS", ""), map[string]ratelimit.Limit{
+		ratelimit.AnonymousPlan: ratelimit.PerMinute(200),
+		"free":                  ratelimit.PerMinute(300),
+		"basic":                 ratelimit.PerMinute(1200),
+		"enterprise":            ratelimit.PerMinute(6000),
+		"admin":                 {},
+	})
+	if err != nil {
+		log.Fatalf("RATE_LIMITS: %v", err)
+	}
+	limitStore, err := ratelimit.Open(ctx, envOr("RATE_LIMIT_BACKEND", ""), envOr("REDIS_URL", ""), databaseURL)
+	if err != nil {
+		log.Fatalf("rate limit store init failed: %v", err)
+	}
+	defer func() { _ = limitStore.Close() }()
+	limiter := &ratelimit.Limiter{Store: limitStore, Name: "qr", Limits: limits}
+
+	router := httpapi.NewRouter(httpapi.Server{Store: st, AdminAPIKey: adminKey, InternalAPIKey: internalKey, ShortLinkBaseURL: shortLinkBaseURL, Auth: verifier, APIKeys: apiKeys, RateLimit: limiter})
 
 	// Apply middleware layers (order matters!)
 	var handler http.Handler = router
@@ -47,14 +107,10 @@ func main() {
 	// 1. CORS (outermost)
 	handler = httpapi.NewCorsMiddleware(httpapi.CorsOptions{
 		AllowedOrigins:   allowedOrigins,
-		AllowedHeaders:   []string{"Content-Type", "Authorization", "X-User-Type"},
+		AllowedHeaders:   []string{"Content-Type", "Authorization", "X-API-Key"},
 		AllowCredentials: true,
 	})(handler)
 
-	// 2. Rate limiting (200 requests per minute per IP for QR service)
-	rateLimiter := middleware.NewRateLimiter(200, time.Minute)
-	handler = rateLimiter.Middleware(handler)
-
 	srv := &http.Server{
 		Addr:              ":" + port,
 		Handler:           handler,
@@ -74,6 +130,7 @@ func main() {
 	stop := make(chan os.Signal, 1)
 	signal.Notify(stop, syscall.SIGINT, syscall.SIGTERM)
 	<-stop
+	stopWebhooks()
 	closeStore()
 
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
@@ -89,6 +146,19 @@ func envOr(key, fallback string) string {
 	return v
 }
 
+func envDuration(key string, fallback time.Duration) time.Duration {
+	raw := strings.TrimSpace(os.Getenv(key))
+	if raw == "" {
+		return fallback
+	}
+	d, err := time.ParseDuration(raw)
+	if err != nil {
+		log.Printf("ignoring invalid %s=%q: %v", key, raw, err)
+		return fallback
+	}
+	return d
+}
+
 func splitCSV(raw string) []string {
 	parts := strings.Split(raw, ",")
 	out := make([]string, 0, len(parts))
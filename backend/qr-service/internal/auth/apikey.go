@@ -0,0 +1,193 @@
+package auth
+
+import (
+	"bytes"
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// APIKeyPrefix marks personal API keys issued by user-service.
+const APIKeyPrefix = "qrk_"
+
+// Scopes a personal API key can carry.
+const (
+	ScopeQrRead     = "qr:read"
+	ScopeQrWrite    = "qr:write"
+	ScopeClicksRead = "clicks:read"
+)
+
+var ErrInvalidAPIKey = errors.New("invalid api key")
+
+// maxCachedAPIKeys bounds the cache against callers cycling through made-up
+// keys. The least recently used answer is dropped first, so keys in use stay.
+const maxCachedAPIKeys = 10000
+
+// APIKeys verifies personal API keys with user-service, which owns them.
+// Answers, including refusals, are cached for TTL, so a revoked key or a plan
+// change can take that long to apply here.
+type APIKeys struct {
+	// URL is user-service's base URL.
+	URL         string
+	InternalKey string
+	Client      *http.Client
+	TTL         time.Duration
+
+	mu    sync.Mutex
+	cache map[string]*list.Element
+	lru   list.List // of *cachedAPIKey, most recently used first
+	now   func() time.Time
+}
+
+type cachedAPIKey struct {
+	hash    string
+	id      Identity
+	err     error
+	expires time.Time
+}
+
+func NewAPIKeys(baseURL, internalKey string) *APIKeys {
+	return &APIKeys{URL: strings.TrimRight(baseURL, "/"), InternalKey: internalKey, Client: &http.Client{Timeout: 5 * time.Second}, TTL: time.Minute}
+}
+
+// Unverified reports whether r carries an API key that serving it would
+// have to check with user-service, so that such requests can be metered
+// before Middleware makes the call. A nil k reports false.
+func (k *APIKeys) Unverified(r *http.Request) bool {
+	if k == nil {
+		return false
+	}
+	for _, token := range tokensFromRequest(r) {
+		if !strings.HasPrefix(token, APIKeyPrefix) {
+			continue
+		}
+		if _, ok := k.cached(token); !ok {
+			return true
+		}
+	}
+	return false
+}
+
+func (k *APIKeys) Verify(ctx context.Context, key string) (Identity, error) {
+	if !strings.HasPrefix(key, APIKeyPrefix) {
+		return Identity{}, ErrInvalidAPIKey
+	}
+	if c, ok := k.cached(key); ok {
+		return c.id, c.err
+	}
+
+	id, err := k.fetch(ctx, key)
+	if err != nil && !errors.Is(err, ErrInvalidAPIKey) {
+		// user-service trouble is not the key's fault; ask again next time.
+		return Identity{}, err
+	}
+
+	k.store(key, id, err)
+	return id, err
+}
+
+func (k *APIKeys) clock() time.Time {
+	if k.now != nil {
+		return k.now()
+	}
+	return time.Now()
+}
+
+func cacheKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// cached returns the unexpired answer for key, marking it recently used.
+func (k *APIKeys) cached(key string) (*cachedAPIKey, bool) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	e, ok := k.cache[cacheKey(key)]
+	if !ok {
+		return nil, false
+	}
+	c := e.Value.(*cachedAPIKey)
+	if !k.clock().Before(c.expires) {
+		return nil, false
+	}
+	k.lru.MoveToFront(e)
+	return c, true
+}
+
+// store caches an answer for key, dropping the least recently used one when
+// the cache is full.
+func (k *APIKeys) store(key string, id Identity, err error) {
+	hash := cacheKey(key)
+	c := &cachedAPIKey{hash: hash, id: id, err: err, expires: k.clock().Add(k.TTL)}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if k.cache == nil {
+		k.cache = make(map[string]*list.Element)
+	}
+	if e, ok := k.cache[hash]; ok {
+		e.Value = c
+		k.lru.MoveToFront(e)
+		return
+	}
+	for k.lru.Len() >= maxCachedAPIKeys {
+		oldest := k.lru.Back()
+		k.lru.Remove(oldest)
+		delete(k.cache, oldest.Value.(*cachedAPIKey).hash)
+	}
+	k.cache[hash] = k.lru.PushFront(c)
+}
+
+func (k *APIKeys) fetch(ctx context.Context, key string) (Identity, error) {
+	body, _ := json.Marshal(map[string]string{"key": key})
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, k.URL+"/api/internal/api-keys/verify", bytes.NewReader(body))
+	if err != nil {
+		return Identity{}, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Internal-Key", k.InternalKey)
+
+	client := k.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return Identity{}, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusUnauthorized:
+		return Identity{}, ErrInvalidAPIKey
+	default:
+		return Identity{}, fmt.Errorf("api key verification: unexpected status %d", resp.StatusCode)
+	}
+
+	var out struct {
+		KeyID        string   `json:"keyId"`
+		UserID       string   `json:"userId"`
+		Email        string   `json:"email"`
+		Entitlements string   `json:"entitlements"`
+		Scopes       []string `json:"scopes"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return Identity{}, err
+	}
+	if out.UserID == "" || out.KeyID == "" {
+		return Identity{}, ErrInvalidAPIKey
+	}
+	if out.Scopes == nil {
+		out.Scopes = []string{}
+	}
+	return Identity{UserID: out.UserID, Email: out.Email, Entitlements: out.Entitlements, APIKeyID: out.KeyID, Scopes: out.Scopes}, nil
+}
@@ -0,0 +1,125 @@
+// Package authtest provides a locally generated key set and token minting for
+// tests that exercise auth.Verifier without talking to Cognito.
+package authtest
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"qr-service/internal/auth"
+)
+
+const (
+	Issuer   = "https://cognito-idp.test.local/test-pool"
+	ClientID = "test-client"
+	KeyID    = "test-key"
+)
+
+type KeySet struct {
+	Key    *rsa.PrivateKey
+	Server *httptest.Server
+}
+
+// NewKeySet generates an RSA key and serves it as a JWKS until the test ends.
+func NewKeySet(t testing.TB) *KeySet {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	ks := &KeySet{Key: key}
+	ks.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"keys": []map[string]string{{
+				"kty": "RSA",
+				"kid": KeyID,
+				"use": "sig",
+				"alg": "RS256",
+				"n":   base64.RawURLEncoding.EncodeToString(key.PublicKey.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.PublicKey.E)).Bytes()),
+			}},
+		})
+	}))
+	t.Cleanup(ks.Server.Close)
+	return ks
+}
+
+// Verifier returns a verifier that trusts this key set.
+func (ks *KeySet) Verifier() *auth.Verifier {
+	return &auth.Verifier{Keys: auth.NewJWKS(ks.Server.URL), Issuer: Issuer, ClientID: ClientID}
+}
+
+// IDToken mints a valid id token for userID with the given entitlements.
+func (ks *KeySet) IDToken(t testing.TB, userID, entitlements string) string {
+	t.Helper()
+	claims := map[string]any{
+		"sub":       userID,
+		"iss":       Issuer,
+		"aud":       ClientID,
+		"token_use": "id",
+		"exp":       time.Now().Add(time.Hour).Unix(),
+	}
+	if entitlements != "" {
+		claims["custom:entitlements"] = entitlements
+	}
+	return ks.Sign(t, claims)
+}
+
+// Sign produces an RS256 token over arbitrary claims.
+func (ks *KeySet) Sign(t testing.TB, claims map[string]any) string {
+	t.Helper()
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "kid": KeyID, "typ": "JWT"})
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		t.Fatalf("marshal claims: %v", err)
+	}
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(signingInput))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, ks.Key, crypto.SHA256, digest[:])
+	if err != nil {
+		t.Fatalf("sign: %v", err)
+	}
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+// APIKey is a personal API key accepted by the stand-in from APIKeys.
+type APIKey struct {
+	ID           string
+	UserID       string
+	Entitlements string
+	Scopes       []string
+}
+
+// APIKeys serves a stand-in for user-service's key verification until the
+// test ends and returns a client for it.
+func APIKeys(t testing.TB, keys map[string]APIKey) *auth.APIKeys {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Key string `json:"key"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		k, ok := keys[req.Key]
+		if r.Header.Get("X-Internal-Key") != "internal" || !ok {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if k.ID == "" {
+			k.ID = "key-" + k.UserID
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{"keyId": k.ID, "userId": k.UserID, "entitlements": k.Entitlements, "scopes": k.Scopes})
+	}))
+	t.Cleanup(srv.Close)
+	return auth.NewAPIKeys(srv.URL, "internal")
+}
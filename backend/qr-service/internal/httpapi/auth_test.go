@@ -0,0 +1,107 @@
+package httpapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"qr-service/internal/auth"
+	"qr-service/internal/auth/authtest"
+	"qr-service/internal/store"
+)
+
+func TestAuth_IgnoresUserTypeHeader(t *testing.T) {
+	ks := authtest.NewKeySet(t)
+	r := NewRouter(Server{Store: store.NewMemoryStore(), Auth: ks.Verifier()})
+	token := ks.IDToken(t, "user-1", "free")
+
+	// Claiming enterprise in a header must not lift the free quota of 5 active codes.
+	for i := 0; i < 6; i++ {
+		body, _ := json.Marshal(map[string]any{"label": "x", "url": "https://example.com"})
+		req := httptest.NewRequest(http.MethodPost, "/api/qr-codes", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("X-User-Type", "enterprise")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		want := http.StatusCreated
+		if i == 5 {
+			want = http.StatusForbidden
+		}
+		if w.Code != want {
+			t.Fatalf("create %d: expected %d, got %d", i, want, w.Code)
+		}
+	}
+}
+
+func TestAuth_EntitlementsFromTokenRaiseQuota(t *testing.T) {
+	ks := authtest.NewKeySet(t)
+	r := NewRouter(Server{Store: store.NewMemoryStore(), Auth: ks.Verifier()})
+	token := ks.IDToken(t, "user-1", "basic")
+
+	for i := 0; i < 6; i++ {
+		createAs(t, r, token, "x")
+	}
+}
+
+func TestAuth_RejectsTokenFromOtherKeySet(t *testing.T) {
+	trusted := authtest.NewKeySet(t)
+	forger := authtest.NewKeySet(t)
+	r := NewRouter(Server{Store: store.NewMemoryStore(), Auth: trusted.Verifier()})
+
+	req := httptest.NewRequest(http.MethodGet, "/api/qr-codes", nil)
+	req.Header.Set("Authorization", "Bearer "+forger.IDToken(t, "user-1", "admin"))
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("expected %d, got %d", http.StatusUnauthorized, w.Code)
+	}
+}
+
+func TestAuth_AcceptsIDTokenCookie(t *testing.T) {
+	ks := authtest.NewKeySet(t)
+	r := NewRouter(Server{Store: store.NewMemoryStore(), Auth: ks.Verifier()})
+
+	req := httptest.NewRequest(http.MethodGet, "/api/qr-codes", nil)
+	req.AddCookie(&http.Cookie{Name: "id_token", Value: ks.IDToken(t, "user-1", "free")})
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected %d, got %d", http.StatusOK, w.Code)
+	}
+}
+
+func TestAuth_APIKeysActAsOwnerWithinScopes(t *testing.T) {
+	ks := authtest.NewKeySet(t)
+	keys := authtest.APIKeys(t, map[string]authtest.APIKey{
+		"qrk_reader": {UserID: "user-1", Entitlements: "free", Scopes: []string{auth.ScopeQrRead}},
+		"qrk_writer": {UserID: "user-1", Entitlements: "free", Scopes: []string{auth.ScopeQrRead, auth.ScopeQrWrite}},
+	})
+	r := NewRouter(Server{Store: store.NewMemoryStore(), Auth: ks.Verifier(), APIKeys: keys, InternalAPIKey: "secret"})
+	created := createAs(t, r, ks.IDToken(t, "user-1", "free"), "menu")
+
+	w := doJSON(t, r, http.MethodGet, "/api/qr-codes/"+created.ID, "qrk_reader", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected the reader key to see its owner's code, got %d", w.Code)
+	}
+	if w := doJSON(t, r, http.MethodPatch, "/api/qr-codes/"+created.ID, "qrk_reader", map[string]any{"label": "lunch"}); w.Code != http.StatusForbidden {
+		t.Fatalf("expected a read-only key to be refused a write, got %d", w.Code)
+	}
+	if w := doJSON(t, r, http.MethodPatch, "/api/qr-codes/"+created.ID, "qrk_writer", map[string]any{"label": "lunch"}); w.Code != http.StatusOK {
+		t.Fatalf("expected the writer key to update, got %d", w.Code)
+	}
+	if w := doJSON(t, r, http.MethodGet, "/api/qr-codes", "qrk_revoked", nil); w.Code != http.StatusUnauthorized {
+		t.Fatalf("expected an unknown key to be anonymous, got %d", w.Code)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/public/qr-codes/"+created.ID, nil)
+	req.Header.Set("X-API-Key", "qrk_writer")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("expected an API key not to stand in for the internal key, got %d", w.Code)
+	}
+}
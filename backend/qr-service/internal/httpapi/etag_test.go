@@ -0,0 +1,45 @@
+package httpapi
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"qr-service/internal/auth/authtest"
+	"qr-service/internal/store"
+)
+
+func TestPublicResolve_RevalidatesWithETag(t *testing.T) {
+	ks := authtest.NewKeySet(t)
+	r := NewRouter(Server{Store: store.NewMemoryStore(), Auth: ks.Verifier(), InternalAPIKey: "secret"})
+	token := ks.IDToken(t, "user-1", "free")
+	created := createAs(t, r, token, "Menu")
+
+	resolve := func(etag string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/public/qr-codes/"+created.ID, nil)
+		req.Header.Set("X-Internal-Key", "secret")
+		if etag != "" {
+			req.Header.Set("If-None-Match", etag)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	first := resolve("")
+	etag := first.Header().Get("ETag")
+	if first.Code != http.StatusOK || etag == "" {
+		t.Fatalf("expected 200 with an ETag, got %d %q", first.Code, etag)
+	}
+	if w := resolve(etag); w.Code != http.StatusNotModified || w.Body.Len() != 0 {
+		t.Fatalf("expected 304 for an unchanged code, got %d", w.Code)
+	}
+
+	if w := doJSON(t, r, http.MethodPatch, "/api/qr-codes/"+created.ID, token, map[string]any{"url": "https://example.com/new"}); w.Code != http.StatusOK {
+		t.Fatalf("update failed: %d %s", w.Code, w.Body.String())
+	}
+	w := resolve(etag)
+	if w.Code != http.StatusOK || w.Header().Get("ETag") == etag {
+		t.Fatalf("expected a new version after an edit, got %d %q", w.Code, w.Header().Get("ETag"))
+	}
+}
@@ -0,0 +1,236 @@
+package httpapi
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"qr-service/internal/model"
+	"qr-service/internal/render"
+	"qr-service/internal/store"
+)
+
+// exportManifestEntry is one code as listed in manifest.json and manifest.csv.
+type exportManifestEntry struct {
+	ID           string `json:"id"`
+	Label        string `json:"label"`
+	URL          string `json:"url"`
+	Active       bool   `json:"active"`
+	CreatedAtIso string `json:"createdAtIso"`
+	ShortLink    string `json:"shortLink"`
+	// Image is the path of the rendered image inside the archive; empty when
+	// the code could not be rendered.
+	Image string `json:"image"`
+}
+
+// handleExport streams a ZIP of every code the caller owns: one image per code
+// under images/, plus manifest.csv and manifest.json describing them.
+//
+// Query parameters: format (png, svg or pdf) and size. Each image is otherwise
+// drawn with its code's stored style.
+func (srv *Server) handleExport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	ownerID := userIDFromRequest(r)
+	if ownerID == "" {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "unauthorized"})
+		return
+	}
+
+	q := r.URL.Query()
+	format, err := render.ParseFormat(q.Get("format"))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "format_invalid"})
+		return
+	}
+	size := render.DefaultSize
+	if raw := strings.TrimSpace(q.Get("size")); raw != "" {
+		if size, err = strconv.Atoi(raw); err != nil || size < render.MinSize || size > render.MaxSize {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "size_invalid"})
+			return
+		}
+	}
+
+	items, err := srv.listAll(ownerID)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "list_failed"})
+		return
+	}
+
+	// Rendering a large account takes longer than the server's WriteTimeout,
+	// which would cut the archive short after the 200 has gone out.
+	_ = http.NewResponseController(w).SetWriteDeadline(time.Time{})
+
+	// Headers must be final before the first byte of the archive; from here on
+	// errors can only be logged and the archive cut short.
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="qr-codes-%s.zip"`, time.Now().UTC().Format("20060102")))
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+
+	zw := zip.NewWriter(w)
+	manifest := make([]exportManifestEntry, 0, len(items))
+	used := make(map[string]bool, len(items))
+	for _, item := range items {
+		item = item.NormalizeForResponse()
+		entry := exportManifestEntry{
+			ID:           item.ID,
+			Label:        item.Label,
+			URL:          item.URL,
+			Active:       item.Active,
+			CreatedAtIso: item.CreatedAtIso,
+			ShortLink:    srv.shortLink(item.ID),
+		}
+
+		name := exportImageName(item, format, used)
+		if err := srv.writeExportImage(zw, name, item, format, size); err != nil {
+			log.Printf("export: image failed request_id=%s id=%s err=%v", w.Header().Get("X-Request-Id"), item.ID, err)
+			if isWriteError(err) {
+				return
+			}
+		} else {
+			entry.Image = name
+		}
+		manifest = append(manifest, entry)
+	}
+
+	if err := writeExportManifests(zw, manifest); err != nil {
+		log.Printf("export: manifest failed request_id=%s err=%v", w.Header().Get("X-Request-Id"), err)
+		return
+	}
+	if err := zw.Close(); err != nil {
+		log.Printf("export: close failed request_id=%s err=%v", w.Header().Get("X-Request-Id"), err)
+	}
+}
+
+// listAll walks every page of the owner's codes, newest first.
+func (srv *Server) listAll(ownerID string) ([]model.QrCode, error) {
+	var items []model.QrCode
+	query := store.ListQuery{Limit: store.MaxListLimit}
+	for {
+		page, err := srv.Store.List(ownerID, query)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, page.Items...)
+		if page.NextCursor == "" {
+			return items, nil
+		}
+		query.Cursor = page.NextCursor
+	}
+}
+
+// exportWriteError marks a failure writing to the client, after which the
+// archive cannot be continued.
+type exportWriteError struct{ err error }
+
+func (e exportWriteError) Error() string { return e.err.Error() }
+
+func isWriteError(err error) bool {
+	_, ok := err.(exportWriteError)
+	return ok
+}
+
+func (srv *Server) writeExportImage(zw *zip.Writer, name string, item model.QrCode, format render.Format, size int) error {
+	style, err := renderStyle(item.Style)
+	if err != nil {
+		return err
+	}
+	opts := render.Options{Size: size, ECC: item.Style.ErrorCorrection, Margin: item.Style.QuietZoneOrDefault(), Style: style}
+
+	// Render before opening the entry so a bad code is left out rather than
+	// written as an empty file.
+	var buf bytes.Buffer
+	if err := render.Render(&buf, srv.shortLink(item.ID), format, opts); err != nil {
+		return err
+	}
+	f, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: exportMethod(format), Modified: item.CreatedAt})
+	if err != nil {
+		return exportWriteError{err}
+	}
+	if _, err := f.Write(buf.Bytes()); err != nil {
+		return exportWriteError{err}
+	}
+	return nil
+}
+
+// exportMethod skips compression for PNG, which is already deflated.
+func exportMethod(format render.Format) uint16 {
+	if format == render.FormatPNG {
+		return zip.Store
+	}
+	return zip.Deflate
+}
+
+func writeExportManifests(zw *zip.Writer, manifest []exportManifestEntry) error {
+	jf, err := zw.Create("manifest.json")
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(jf)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(manifest); err != nil {
+		return err
+	}
+
+	cf, err := zw.Create("manifest.csv")
+	if err != nil {
+		return err
+	}
+	cw := csv.NewWriter(cf)
+	_ = cw.Write([]string{"id", "label", "url", "active", "createdAtIso", "shortLink", "image"})
+	for _, e := range manifest {
+		_ = cw.Write([]string{e.ID, e.Label, e.URL, strconv.FormatBool(e.Active), e.CreatedAtIso, e.ShortLink, e.Image})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// exportImageName builds a readable, unique file name from the label, falling
+// back to the ID when the label has nothing usable.
+func exportImageName(item model.QrCode, format render.Format, used map[string]bool) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(item.Label) {
+		switch {
+		case r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)):
+			b.WriteRune(r)
+			dash = false
+		case !dash && b.Len() > 0:
+			b.WriteByte('-')
+			dash = true
+		}
+		if b.Len() >= 40 {
+			break
+		}
+	}
+	slug := strings.Trim(b.String(), "-")
+	if slug == "" {
+		slug = "qr"
+	}
+
+	name := fmt.Sprintf("images/%s-%s.%s", slug, shortID(item.ID), format)
+	if used[name] {
+		name = fmt.Sprintf("images/%s-%s.%s", slug, item.ID, format)
+	}
+	used[name] = true
+	return name
+}
+
+func shortID(id string) string {
+	if len(id) > 8 {
+		return id[:8]
+	}
+	return id
+}
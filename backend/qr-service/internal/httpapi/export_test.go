@@ -0,0 +1,161 @@
+package httpapi
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"qr-service/internal/auth/authtest"
+	"qr-service/internal/store"
+)
+
+func TestExport_ZipWithManifestAndImages(t *testing.T) {
+	ks := authtest.NewKeySet(t)
+	r := NewRouter(Server{Store: store.NewMemoryStore(), Auth: ks.Verifier(), ShortLinkBaseURL: "https://click.example.com"})
+	token := ks.IDToken(t, "user-1", "free")
+	a := createAs(t, r, token, "Spring Flyer")
+	b := createAs(t, r, token, "Spring Flyer")
+	createAs(t, r, ks.IDToken(t, "user-2", "free"), "not-mine")
+
+	req := httptest.NewRequest(http.MethodGet, "/api/qr-codes/export?format=svg&size=128", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "application/zip" {
+		t.Fatalf("expected zip content type, got %q", ct)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(w.Body.Bytes()), int64(w.Body.Len()))
+	if err != nil {
+		t.Fatalf("open zip: %v", err)
+	}
+	files := map[string][]byte{}
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatalf("open %s: %v", f.Name, err)
+		}
+		files[f.Name], _ = io.ReadAll(rc)
+		rc.Close()
+	}
+
+	var manifest []exportManifestEntry
+	if err := json.Unmarshal(files["manifest.json"], &manifest); err != nil {
+		t.Fatalf("manifest.json: %v", err)
+	}
+	if len(manifest) != 2 {
+		t.Fatalf("expected 2 entries, got %+v", manifest)
+	}
+	seen := map[string]bool{}
+	for _, e := range manifest {
+		if e.ID != a.ID && e.ID != b.ID {
+			t.Fatalf("unexpected code in export: %+v", e)
+		}
+		if e.ShortLink != "https://click.example.com/r/"+e.ID || e.CreatedAtIso == "" {
+			t.Fatalf("unexpected manifest entry %+v", e)
+		}
+		if !strings.HasPrefix(e.Image, "images/spring-flyer-") || seen[e.Image] {
+			t.Fatalf("unexpected image name %q", e.Image)
+		}
+		seen[e.Image] = true
+		if !strings.Contains(string(files[e.Image]), "<svg") {
+			t.Fatalf("expected svg for %s", e.Image)
+		}
+	}
+
+	records, err := csv.NewReader(bytes.NewReader(files["manifest.csv"])).ReadAll()
+	if err != nil {
+		t.Fatalf("manifest.csv: %v", err)
+	}
+	if len(records) != 3 || strings.Join(records[0], ",") != "id,label,url,active,createdAtIso,shortLink,image" {
+		t.Fatalf("unexpected csv manifest %v", records)
+	}
+}
+
+func TestExport_Validation(t *testing.T) {
+	ks := authtest.NewKeySet(t)
+	r := NewRouter(Server{Store: store.NewMemoryStore(), Auth: ks.Verifier()})
+
+	req := httptest.NewRequest(http.MethodGet, "/api/qr-codes/export", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("expected %d, got %d", http.StatusUnauthorized, w.Code)
+	}
+
+	token := ks.IDToken(t, "user-1", "free")
+	for query, want := range map[string]string{"format=gif": "format_invalid", "size=1": "size_invalid"} {
+		req := httptest.NewRequest(http.MethodGet, "/api/qr-codes/export?"+query, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), want) {
+			t.Fatalf("%s: expected %s, got %d %s", query, want, w.Code, w.Body.String())
+		}
+	}
+}
+
+// slowListStore stands in for an account big enough that listing and
+// rendering it outlasts the server's WriteTimeout.
+type slowListStore struct {
+	store.Store
+	delay time.Duration
+}
+
+func (s slowListStore) List(ownerID string, query store.ListQuery) (store.ListPage, error) {
+	time.Sleep(s.delay)
+	return s.Store.List(ownerID, query)
+}
+
+func TestExport_OutlivesWriteTimeout(t *testing.T) {
+	ks := authtest.NewKeySet(t)
+	st := store.NewMemoryStore()
+	token := ks.IDToken(t, "user-1", "free")
+	setup := NewRouter(Server{Store: st, Auth: ks.Verifier()})
+	for _, label := range []string{"a", "b", "c"} {
+		createAs(t, setup, token, label)
+	}
+
+	srv := httptest.NewUnstartedServer(NewRouter(Server{Store: slowListStore{Store: st, delay: 200 * time.Millisecond}, Auth: ks.Verifier()}))
+	srv.Config.WriteTimeout = 50 * time.Millisecond
+	srv.Start()
+	t.Cleanup(srv.Close)
+
+	req, _ := http.NewRequest(http.MethodGet, srv.URL+"/api/qr-codes/export?format=svg&size=128", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := srv.Client().Do(req)
+	if err != nil {
+		t.Fatalf("export: %v", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("expected the whole archive, read failed after %d bytes: %v", len(body), err)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(body), int64(len(body)))
+	if err != nil {
+		t.Fatalf("open zip: %v", err)
+	}
+	var manifest []exportManifestEntry
+	for _, f := range zr.File {
+		if f.Name != "manifest.json" {
+			continue
+		}
+		rc, _ := f.Open()
+		_ = json.NewDecoder(rc).Decode(&manifest)
+		rc.Close()
+	}
+	if len(zr.File) != 5 || len(manifest) != 3 {
+		t.Fatalf("expected 3 images and both manifests, got %d files and %+v", len(zr.File), manifest)
+	}
+}
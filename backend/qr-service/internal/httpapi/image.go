@@ -0,0 +1,106 @@
+package httpapi
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"qr-service/internal/render"
+	"qr-service/internal/store"
+)
+
+// shortLink is the URL a printed code encodes: click-service's redirect path.
+func (srv *Server) shortLink(id string) string {
+	return strings.TrimRight(srv.ShortLinkBaseURL, "/") + "/r/" + url.PathEscape(id)
+}
+
+func (srv *Server) handleImage(w http.ResponseWriter, r *http.Request, ownerID, id string) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	q := r.URL.Query()
+	format, err := render.ParseFormat(q.Get("format"))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "format_invalid"})
+		return
+	}
+	item, err := srv.Store.Get(ownerID, id)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": "not_found"})
+			return
+		}
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "get_failed"})
+		return
+	}
+
+	// The stored style supplies the defaults; query parameters override them
+	// for one-off exports.
+	style, err := renderStyle(item.Style)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "render_failed"})
+		return
+	}
+	opts := render.Options{ECC: item.Style.ErrorCorrection, Margin: item.Style.QuietZoneOrDefault(), Style: style}
+	if raw := strings.TrimSpace(q.Get("ecc")); raw != "" {
+		opts.ECC = raw
+	}
+	if raw := strings.TrimSpace(q.Get("size")); raw != "" {
+		if opts.Size, err = strconv.Atoi(raw); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "size_invalid"})
+			return
+		}
+	}
+	if raw := strings.TrimSpace(q.Get("margin")); raw != "" {
+		if opts.Margin, err = strconv.Atoi(raw); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "margin_invalid"})
+			return
+		}
+	}
+	if opts, err = opts.Normalize(); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": renderErrorCode(err)})
+		return
+	}
+	if opts.Style.Logo != nil && (opts.ECC == "L" || opts.ECC == "M") {
+		// A logo covers modules that L and M cannot recover.
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "ecc_too_low_for_logo"})
+		return
+	}
+
+	// Render into a buffer so a failure can still be reported as JSON.
+	var buf bytes.Buffer
+	if err := render.Render(&buf, srv.shortLink(item.ID), format, opts); err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "render_failed"})
+		return
+	}
+
+	w.Header().Set("Content-Type", format.ContentType())
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`inline; filename="qr-%s.%s"`, item.ID, format))
+	w.Header().Set("Cache-Control", "private, max-age=300")
+	w.Header().Set("Content-Length", strconv.Itoa(buf.Len()))
+	w.WriteHeader(http.StatusOK)
+	if r.Method == http.MethodGet {
+		_, _ = w.Write(buf.Bytes())
+	}
+}
+
+func renderErrorCode(err error) string {
+	switch {
+	case errors.Is(err, render.ErrInvalidSize):
+		return "size_invalid"
+	case errors.Is(err, render.ErrInvalidMargin):
+		return "margin_invalid"
+	case errors.Is(err, render.ErrInvalidECC):
+		return "ecc_invalid"
+	case errors.Is(err, render.ErrInvalidFormat):
+		return "format_invalid"
+	default:
+		return "render_failed"
+	}
+}
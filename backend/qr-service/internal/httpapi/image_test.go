@@ -0,0 +1,68 @@
+package httpapi
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"qr-service/internal/auth/authtest"
+	"qr-service/internal/store"
+)
+
+func TestImage_RendersOwnCode(t *testing.T) {
+	ks := authtest.NewKeySet(t)
+	r := NewRouter(Server{Store: store.NewMemoryStore(), Auth: ks.Verifier(), ShortLinkBaseURL: "https://click.example.com"})
+	token := ks.IDToken(t, "user-1", "free")
+	created := createAs(t, r, token, "a")
+
+	for format, contentType := range map[string]string{"png": "image/png", "svg": "image/svg+xml", "pdf": "application/pdf"} {
+		req := httptest.NewRequest(http.MethodGet, "/api/qr-codes/"+created.ID+"/image?format="+format+"&size=256&ecc=H&margin=2", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: expected %d, got %d (%s)", format, http.StatusOK, w.Code, w.Body.String())
+		}
+		if ct := w.Header().Get("Content-Type"); ct != contentType {
+			t.Fatalf("%s: expected content type %q, got %q", format, contentType, ct)
+		}
+		if w.Body.Len() == 0 {
+			t.Fatalf("%s: expected a body", format)
+		}
+	}
+}
+
+func TestImage_Validation(t *testing.T) {
+	ks := authtest.NewKeySet(t)
+	r := NewRouter(Server{Store: store.NewMemoryStore(), Auth: ks.Verifier()})
+	token := ks.IDToken(t, "user-1", "free")
+	created := createAs(t, r, token, "a")
+
+	cases := map[string]string{
+		"format=gif":  "format_invalid",
+		"size=abc":    "size_invalid",
+		"size=99999":  "size_invalid",
+		"ecc=X":       "ecc_invalid",
+		"margin=-3":   "margin_invalid",
+		"margin=1000": "margin_invalid",
+	}
+	for query, want := range cases {
+		req := httptest.NewRequest(http.MethodGet, "/api/qr-codes/"+created.ID+"/image?"+query, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), want) {
+			t.Fatalf("%s: expected 400 %s, got %d %s", query, want, w.Code, w.Body.String())
+		}
+	}
+
+	// Another user's code is not found.
+	req := httptest.NewRequest(http.MethodGet, "/api/qr-codes/"+created.ID+"/image", nil)
+	req.Header.Set("Authorization", "Bearer "+ks.IDToken(t, "user-2", "free"))
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected %d, got %d", http.StatusNotFound, w.Code)
+	}
+}
@@ -0,0 +1,263 @@
+package httpapi
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"qr-service/internal/model"
+	"qr-service/internal/store"
+)
+
+const (
+	// MaxImportRows bounds a single import so one request cannot hold a
+	// transaction open indefinitely.
+	MaxImportRows  = 1000
+	maxImportBytes = 4 << 20
+)
+
+var (
+	errImportTooManyRows = errors.New("too many rows")
+	errImportHeader      = errors.New("missing url column")
+)
+
+type importRow struct {
+	Label  string `json:"label"`
+	URL    string `json:"url"`
+	Active *bool  `json:"active,omitempty"`
+
+	// activeRaw holds an unparseable CSV "active" value so it can be
+	// reported against its row instead of failing the whole file.
+	activeRaw string
+}
+
+// importRowResult is one line of the per-row report. Row is 1-based and
+// counts data rows only, so a CSV header is not row 1.
+type importRowResult struct {
+	Row    int    `json:"row"`
+	Status string `json:"status"`
+	ID     string `json:"id,omitempty"`
+	Label  string `json:"label"`
+	URL    string `json:"url"`
+	Error  string `json:"error,omitempty"`
+}
+
+type importResponse struct {
+	Created  int               `json:"created"`
+	Rejected int               `json:"rejected"`
+	Rows     []importRowResult `json:"rows"`
+}
+
+// handleImport creates many codes at once from a CSV or JSON array.
+//
+// Rows that fail validation are reported and skipped; the remaining rows are
+// checked against the caller's quota as one batch and inserted together, so
+// either all valid rows are created or none are.
+func (srv *Server) handleImport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	ownerID := userIDFromRequest(r)
+	if ownerID == "" {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "unauthorized"})
+		return
+	}
+
+	body := http.MaxBytesReader(w, r.Body, maxImportBytes)
+	var (
+		rows []importRow
+		err  error
+	)
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType == "text/csv" {
+		rows, err = parseImportCSV(body)
+	} else {
+		rows, err = parseImportJSON(body)
+	}
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		switch {
+		case errors.As(err, &tooLarge):
+			writeJSON(w, http.StatusRequestEntityTooLarge, map[string]string{"error": "import_too_large"})
+		case errors.Is(err, errImportTooManyRows):
+			writeJSON(w, http.StatusRequestEntityTooLarge, map[string]string{"error": "import_too_many_rows"})
+		case errors.Is(err, errImportHeader):
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "csv_url_column_required"})
+		case mediaType == "text/csv":
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_csv"})
+		default:
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_json"})
+		}
+		return
+	}
+	if len(rows) == 0 {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "import_empty"})
+		return
+	}
+
+	results := make([]importRowResult, len(rows))
+	inputs := make([]store.CreateInput, 0, len(rows))
+	accepted := make([]int, 0, len(rows)) // index into results for each input
+	requestedActive := 0
+	for i, row := range rows {
+		row.URL = strings.TrimSpace(row.URL)
+		row.Label = strings.TrimSpace(row.Label)
+		res := importRowResult{Row: i + 1, Label: row.Label, URL: row.URL, Status: "rejected"}
+
+		switch {
+		case row.URL == "":
+			res.Error = "url_required"
+		case !isValidHTTPURL(row.URL):
+			res.Error = "url_invalid"
+		case row.activeRaw != "":
+			res.Error = "active_invalid"
+		}
+		if res.Error != "" {
+			results[i] = res
+			continue
+		}
+
+		if row.Active == nil || *row.Active {
+			requestedActive++
+		}
+		inputs = append(inputs, store.CreateInput{OwnerID: ownerID, Label: row.Label, URL: row.URL, Active: row.Active})
+		accepted = append(accepted, i)
+		results[i] = res
+	}
+
+	if len(inputs) > 0 {
+		qt := quotaForUserType(userTypeFromRequest(r))
+		total, err := srv.Store.CountTotal(ownerID)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "quota_check_failed"})
+			return
+		}
+		if total+len(inputs) > qt.maxTotal {
+			writeJSON(w, http.StatusForbidden, map[string]any{"error": "quota_total_exceeded", "remaining": max(qt.maxTotal-total, 0), "requested": len(inputs)})
+			return
+		}
+		if requestedActive > 0 {
+			active, err := srv.Store.CountActive(ownerID)
+			if err != nil {
+				writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "quota_check_failed"})
+				return
+			}
+			if active+requestedActive > qt.maxActive {
+				writeJSON(w, http.StatusForbidden, map[string]any{"error": "quota_active_exceeded", "remaining": max(qt.maxActive-active, 0), "requested": requestedActive})
+				return
+			}
+		}
+
+		created, err := srv.Store.CreateMany(inputs)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "create_failed"})
+			return
+		}
+		events := make([]store.WebhookEvent, 0, len(created))
+		for n, item := range created {
+			res := &results[accepted[n]]
+			res.Status = "created"
+			res.ID = item.ID
+			res.Label = item.Label
+			ev, err := newWebhookEvent(ownerID, model.EventQrCreated, item.NormalizeForResponse())
+			if err != nil {
+				writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "webhook_enqueue_failed"})
+				return
+			}
+			events = append(events, ev)
+		}
+		if err := srv.emitWebhookEvents(events...); err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "webhook_enqueue_failed"})
+			return
+		}
+	}
+
+	resp := importResponse{Created: len(inputs), Rejected: len(rows) - len(inputs), Rows: results}
+	status := http.StatusOK
+	if resp.Created > 0 {
+		status = http.StatusCreated
+	}
+	writeJSON(w, status, resp)
+}
+
+func parseImportJSON(r io.Reader) ([]importRow, error) {
+	var rows []importRow
+	if err := json.NewDecoder(r).Decode(&rows); err != nil {
+		return nil, err
+	}
+	if len(rows) > MaxImportRows {
+		return nil, errImportTooManyRows
+	}
+	return rows, nil
+}
+
+// parseImportCSV reads a CSV with a header row naming the label, url and
+// active columns in any order. Only url is required; an empty active means true.
+func parseImportCSV(r io.Reader) ([]importRow, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+
+	header, err := cr.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	cols := map[string]int{}
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		cols[name] = i
+	}
+	urlCol, ok := cols["url"]
+	if !ok {
+		return nil, errImportHeader
+	}
+	field := func(rec []string, name string) string {
+		i, ok := cols[name]
+		if !ok || i >= len(rec) {
+			return ""
+		}
+		return strings.TrimSpace(rec[i])
+	}
+
+	rows := make([]importRow, 0, 64)
+	for {
+		rec, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(rec) == 1 && strings.TrimSpace(rec[0]) == "" {
+			continue
+		}
+		if len(rows) == MaxImportRows {
+			return nil, errImportTooManyRows
+		}
+
+		row := importRow{Label: field(rec, "label")}
+		if urlCol < len(rec) {
+			row.URL = rec[urlCol]
+		}
+		if raw := field(rec, "active"); raw != "" {
+			v, err := strconv.ParseBool(raw)
+			if err != nil {
+				row.activeRaw = raw
+			} else {
+				row.Active = &v
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
@@ -0,0 +1,137 @@
+package httpapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"qr-service/internal/auth/authtest"
+	"qr-service/internal/store"
+)
+
+func postImport(t *testing.T, r http.Handler, token, contentType, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, "/api/qr-codes/import", strings.NewReader(body))
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestImport_CSVReportsPerRow(t *testing.T) {
+	ks := authtest.NewKeySet(t)
+	s := store.NewMemoryStore()
+	r := NewRouter(Server{Store: s, Auth: ks.Verifier()})
+	token := ks.IDToken(t, "user-1", "basic")
+
+	csv := "URL,label,active\n" +
+		"https://example.com/a,First,true\n" +
+		"http://example.com/b,Insecure,true\n" +
+		",Missing,\n" +
+		"https://example.com/c,Paused,false\n" +
+		"https://example.com/d,Odd,maybe\n"
+	w := postImport(t, r, token, "text/csv; charset=utf-8", csv)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected %d, got %d: %s", http.StatusCreated, w.Code, w.Body.String())
+	}
+	var resp importResponse
+	_ = json.NewDecoder(w.Body).Decode(&resp)
+	if resp.Created != 2 || resp.Rejected != 3 || len(resp.Rows) != 5 {
+		t.Fatalf("unexpected report %+v", resp)
+	}
+	wantErrors := []string{"", "url_invalid", "url_required", "", "active_invalid"}
+	for i, want := range wantErrors {
+		row := resp.Rows[i]
+		if row.Row != i+1 || row.Error != want {
+			t.Fatalf("row %d: expected error %q, got %+v", i+1, want, row)
+		}
+		if want == "" && (row.Status != "created" || row.ID == "") {
+			t.Fatalf("row %d: expected created with id, got %+v", i+1, row)
+		}
+	}
+
+	page, _ := s.List("user-1", store.ListQuery{})
+	items := page.Items
+	if len(items) != 2 {
+		t.Fatalf("expected 2 stored codes, got %d", len(items))
+	}
+	paused, _ := s.Get("user-1", resp.Rows[3].ID)
+	if paused.Active {
+		t.Fatalf("expected active=false to be honoured")
+	}
+}
+
+func TestImport_JSON(t *testing.T) {
+	ks := authtest.NewKeySet(t)
+	s := store.NewMemoryStore()
+	r := NewRouter(Server{Store: s, Auth: ks.Verifier()})
+
+	body := `[{"label":"A","url":"https://example.com/a"},{"label":"B","url":"https://example.com/b","active":false}]`
+	w := postImport(t, r, ks.IDToken(t, "user-1", "free"), "application/json", body)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected %d, got %d: %s", http.StatusCreated, w.Code, w.Body.String())
+	}
+	if n, _ := s.CountTotal("user-1"); n != 2 {
+		t.Fatalf("expected 2 codes, got %d", n)
+	}
+}
+
+func TestImport_QuotaAppliesToWholeBatch(t *testing.T) {
+	ks := authtest.NewKeySet(t)
+	s := store.NewMemoryStore()
+	r := NewRouter(Server{Store: s, Auth: ks.Verifier()})
+	token := ks.IDToken(t, "user-1", "free")
+
+	// Free allows 5 active codes; six active rows must not partially land.
+	var b bytes.Buffer
+	b.WriteString("label,url\n")
+	for i := 0; i < 6; i++ {
+		fmt.Fprintf(&b, "code-%d,https://example.com/%d\n", i, i)
+	}
+	w := postImport(t, r, token, "text/csv", b.String())
+	if w.Code != http.StatusForbidden || !strings.Contains(w.Body.String(), "quota_active_exceeded") {
+		t.Fatalf("expected active quota error, got %d %s", w.Code, w.Body.String())
+	}
+	if n, _ := s.CountTotal("user-1"); n != 0 {
+		t.Fatalf("expected nothing created, got %d", n)
+	}
+
+	// Inactive rows only count against the total limit (20).
+	b.Reset()
+	b.WriteString("label,url,active\n")
+	for i := 0; i < 21; i++ {
+		fmt.Fprintf(&b, "code-%d,https://example.com/%d,false\n", i, i)
+	}
+	w = postImport(t, r, token, "text/csv", b.String())
+	if w.Code != http.StatusForbidden || !strings.Contains(w.Body.String(), "quota_total_exceeded") {
+		t.Fatalf("expected total quota error, got %d %s", w.Code, w.Body.String())
+	}
+}
+
+func TestImport_RejectsBadInput(t *testing.T) {
+	ks := authtest.NewKeySet(t)
+	r := NewRouter(Server{Store: store.NewMemoryStore(), Auth: ks.Verifier()})
+	token := ks.IDToken(t, "user-1", "free")
+
+	cases := []struct {
+		contentType, body string
+		status            int
+		code              string
+	}{
+		{"text/csv", "label,link\nA,https://example.com\n", http.StatusBadRequest, "csv_url_column_required"},
+		{"application/json", `{"label":"A"}`, http.StatusBadRequest, "invalid_json"},
+		{"application/json", `[]`, http.StatusBadRequest, "import_empty"},
+		{"text/plain", "x", http.StatusUnsupportedMediaType, "content_type_unsupported"},
+	}
+	for _, tc := range cases {
+		w := postImport(t, r, token, tc.contentType, tc.body)
+		if w.Code != tc.status || !strings.Contains(w.Body.String(), tc.code) {
+			t.Fatalf("%s %q: expected %d %s, got %d %s", tc.contentType, tc.body, tc.status, tc.code, w.Code, w.Body.String())
+		}
+	}
+}
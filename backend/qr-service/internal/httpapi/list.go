@@ -0,0 +1,87 @@
+package httpapi
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"qr-service/internal/store"
+)
+
+// listQueryFromRequest reads the collection's query parameters:
+//
+//	limit, cursor, active=true|false, q (label/URL substring),
+//	createdFrom, createdTo (RFC 3339 or YYYY-MM-DD), tag (repeatable or
+//	comma-separated; codes must carry every tag), sort=created|label|clicks,
+//	order=asc|desc.
+//
+// It returns an error code for the first invalid parameter.
+func listQueryFromRequest(r *http.Request) (store.ListQuery, string) {
+	v := r.URL.Query()
+	var query store.ListQuery
+
+	if raw := strings.TrimSpace(v.Get("limit")); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 || n > store.MaxListLimit {
+			return query, "limit_invalid"
+		}
+		query.Limit = n
+	}
+	query.Cursor = strings.TrimSpace(v.Get("cursor"))
+
+	if raw := strings.TrimSpace(v.Get("active")); raw != "" {
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return query, "active_invalid"
+		}
+		query.Active = &b
+	}
+	query.Search = strings.TrimSpace(v.Get("q"))
+	for _, raw := range v["tag"] {
+		query.TagIDs = append(query.TagIDs, strings.Split(raw, ",")...)
+	}
+
+	var ok bool
+	if query.CreatedFrom, ok = parseListTime(v.Get("createdFrom")); !ok {
+		return query, "created_from_invalid"
+	}
+	if query.CreatedTo, ok = parseListTime(v.Get("createdTo")); !ok {
+		return query, "created_to_invalid"
+	}
+
+	switch store.ListSort(strings.ToLower(strings.TrimSpace(v.Get("sort")))) {
+	case "", store.SortCreated:
+		query.Sort = store.SortCreated
+	case store.SortLabel:
+		query.Sort = store.SortLabel
+	case store.SortClicks:
+		query.Sort = store.SortClicks
+	default:
+		return query, "sort_invalid"
+	}
+
+	switch strings.ToLower(strings.TrimSpace(v.Get("order"))) {
+	case "", "desc":
+	case "asc":
+		query.Asc = true
+	default:
+		return query, "order_invalid"
+	}
+	return query, ""
+}
+
+// parseListTime accepts RFC 3339 timestamps or plain UTC dates; empty is the zero time.
+func parseListTime(raw string) (time.Time, bool) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return time.Time{}, true
+	}
+	if t, err := time.Parse(time.RFC3339, raw); err == nil {
+		return t.UTC(), true
+	}
+	if t, err := time.Parse("2006-01-02", raw); err == nil {
+		return t, true
+	}
+	return time.Time{}, false
+}
@@ -0,0 +1,124 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"qr-service/internal/auth/authtest"
+	"qr-service/internal/store"
+)
+
+func TestList_CursorPagination(t *testing.T) {
+	ks := authtest.NewKeySet(t)
+	r := NewRouter(Server{Store: store.NewMemoryStore(), Auth: ks.Verifier()})
+	token := ks.IDToken(t, "user-1", "basic")
+	for _, label := range []string{"a", "b", "c", "d", "e"} {
+		createAs(t, r, token, label)
+	}
+
+	seen := map[string]bool{}
+	cursor := ""
+	for pages := 0; ; pages++ {
+		if pages > 5 {
+			t.Fatalf("pagination did not terminate")
+		}
+		q := url.Values{"limit": {"2"}, "sort": {"label"}, "order": {"asc"}}
+		if cursor != "" {
+			q.Set("cursor", cursor)
+		}
+		req := httptest.NewRequest(http.MethodGet, "/api/qr-codes?"+q.Encode(), nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+		}
+		var items []qrResp
+		_ = json.NewDecoder(w.Body).Decode(&items)
+		for _, it := range items {
+			if seen[it.ID] {
+				t.Fatalf("duplicate item across pages")
+			}
+			seen[it.ID] = true
+		}
+		cursor = w.Header().Get("X-Next-Cursor")
+		if cursor == "" {
+			break
+		}
+		if !strings.Contains(w.Header().Get("Access-Control-Expose-Headers"), "X-Next-Cursor") {
+			t.Fatalf("expected X-Next-Cursor to be exposed to browsers")
+		}
+	}
+	if len(seen) != 5 {
+		t.Fatalf("expected 5 items across pages, got %d", len(seen))
+	}
+}
+
+func TestList_InvalidParameters(t *testing.T) {
+	ks := authtest.NewKeySet(t)
+	r := NewRouter(Server{Store: store.NewMemoryStore(), Auth: ks.Verifier()})
+	token := ks.IDToken(t, "user-1", "free")
+
+	cases := map[string]string{
+		"limit=0":               "limit_invalid",
+		"limit=1000":            "limit_invalid",
+		"active=sometimes":      "active_invalid",
+		"createdFrom=yesterday": "created_from_invalid",
+		"createdTo=2026-13-01":  "created_to_invalid",
+		"sort=color":            "sort_invalid",
+		"order=up":              "order_invalid",
+		"cursor=not-a-cursor":   "cursor_invalid",
+	}
+	for query, want := range cases {
+		req := httptest.NewRequest(http.MethodGet, "/api/qr-codes?"+query, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), want) {
+			t.Fatalf("%s: expected %s, got %d %s", query, want, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestInternalClicks_FeedsClickSort(t *testing.T) {
+	ks := authtest.NewKeySet(t)
+	r := NewRouter(Server{Store: store.NewMemoryStore(), Auth: ks.Verifier(), InternalAPIKey: "secret"})
+	token := ks.IDToken(t, "user-1", "free")
+	quiet := createAs(t, r, token, "quiet")
+	busy := createAs(t, r, token, "busy")
+
+	report := func(id, key string) int {
+		req := httptest.NewRequest(http.MethodPost, "/api/internal/qr-codes/"+id+"/clicks", strings.NewReader(`{"count":3}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Internal-Key", key)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+	if code := report(busy.ID, "wrong"); code != http.StatusUnauthorized {
+		t.Fatalf("expected %d, got %d", http.StatusUnauthorized, code)
+	}
+	if code := report(busy.ID, "secret"); code != http.StatusNoContent {
+		t.Fatalf("expected %d, got %d", http.StatusNoContent, code)
+	}
+	if code := report("missing", "secret"); code != http.StatusNotFound {
+		t.Fatalf("expected %d, got %d", http.StatusNotFound, code)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/qr-codes?sort=clicks", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	var items []struct {
+		ID         string `json:"id"`
+		ClickCount int64  `json:"clickCount"`
+	}
+	_ = json.NewDecoder(w.Body).Decode(&items)
+	if len(items) != 2 || items[0].ID != busy.ID || items[0].ClickCount != 3 || items[1].ID != quiet.ID {
+		t.Fatalf("unexpected order %+v", items)
+	}
+}
@@ -0,0 +1,27 @@
+package httpapi
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// optional is a PATCH field that distinguishes "absent" from "null": Set is
+// true whenever the key was sent, and Value is nil when it was null.
+type optional[T any] struct {
+	Set   bool
+	Value *T
+}
+
+func (o *optional[T]) UnmarshalJSON(b []byte) error {
+	o.Set = true
+	if bytes.Equal(b, []byte("null")) {
+		o.Value = nil
+		return nil
+	}
+	var v T
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	o.Value = &v
+	return nil
+}
@@ -0,0 +1,100 @@
+package httpapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"qr-service/internal/auth/authtest"
+	"qr-service/internal/store"
+)
+
+func createAs(t *testing.T, r http.Handler, token, label string) qrResp {
+	t.Helper()
+	body, _ := json.Marshal(map[string]any{"label": label, "url": "https://example.com/" + label})
+	req := httptest.NewRequest(http.MethodPost, "/api/qr-codes", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected %d, got %d", http.StatusCreated, w.Code)
+	}
+	var created qrResp
+	_ = json.NewDecoder(w.Body).Decode(&created)
+	return created
+}
+
+func TestOwnership_RequiresUser(t *testing.T) {
+	ks := authtest.NewKeySet(t)
+	r := NewRouter(Server{Store: store.NewMemoryStore(), Auth: ks.Verifier()})
+
+	req := httptest.NewRequest(http.MethodGet, "/api/qr-codes", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("expected %d, got %d", http.StatusUnauthorized, w.Code)
+	}
+}
+
+func TestOwnership_UsersOnlySeeTheirOwnCodes(t *testing.T) {
+	ks := authtest.NewKeySet(t)
+	r := NewRouter(Server{Store: store.NewMemoryStore(), Auth: ks.Verifier(), InternalAPIKey: "secret"})
+	aliceToken := ks.IDToken(t, "alice", "free")
+	bobToken := ks.IDToken(t, "bob", "free")
+
+	alice := createAs(t, r, aliceToken, "a")
+	createAs(t, r, bobToken, "b")
+
+	listReq := httptest.NewRequest(http.MethodGet, "/api/qr-codes", nil)
+	listReq.Header.Set("Authorization", "Bearer "+aliceToken)
+	listW := httptest.NewRecorder()
+	r.ServeHTTP(listW, listReq)
+	var items []qrResp
+	_ = json.NewDecoder(listW.Body).Decode(&items)
+	if len(items) != 1 || items[0].ID != alice.ID {
+		t.Fatalf("expected only alice's code, got %+v", items)
+	}
+
+	for _, method := range []string{http.MethodGet, http.MethodDelete} {
+		req := httptest.NewRequest(method, "/api/qr-codes/"+alice.ID, nil)
+		req.Header.Set("Authorization", "Bearer "+bobToken)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != http.StatusNotFound {
+			t.Fatalf("%s by other user: expected %d, got %d", method, http.StatusNotFound, w.Code)
+		}
+	}
+
+	// The lookup used by click-service still resolves the code, but only for
+	// click-service.
+	for _, path := range []string{"/api/public/qr-codes/" + alice.ID, "/api/public/settings/alice"} {
+		w := doJSON(t, r, http.MethodGet, path, bobToken, nil)
+		if w.Code != http.StatusUnauthorized {
+			t.Fatalf("GET %s without the internal key: expected %d, got %d", path, http.StatusUnauthorized, w.Code)
+		}
+	}
+	pubW := internalGet(r, "/api/public/qr-codes/"+alice.ID)
+	if pubW.Code != http.StatusOK {
+		t.Fatalf("expected %d, got %d", http.StatusOK, pubW.Code)
+	}
+	var resolved resolvedQrCode
+	_ = json.NewDecoder(pubW.Body).Decode(&resolved)
+	if resolved.OwnerID != "alice" || resolved.URL != "https://example.com/a" {
+		t.Fatalf("unexpected resolved code %+v", resolved)
+	}
+}
+
+func TestOwnership_QuotaCountsOnlyOwnCodes(t *testing.T) {
+	ks := authtest.NewKeySet(t)
+	r := NewRouter(Server{Store: store.NewMemoryStore(), Auth: ks.Verifier()})
+
+	// Free max active = 5; another user's codes must not count against bob.
+	aliceToken := ks.IDToken(t, "alice", "free")
+	for i := 0; i < 5; i++ {
+		createAs(t, r, aliceToken, "a")
+	}
+	createAs(t, r, ks.IDToken(t, "bob", "free"), "b")
+}
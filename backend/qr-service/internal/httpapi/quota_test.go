@@ -7,6 +7,7 @@ import (
 	"net/http/httptest"
 	"testing"
 
+	"qr-service/internal/auth/authtest"
 	"qr-service/internal/store"
 )
 
@@ -16,14 +17,16 @@ type errResp struct {
 
 func TestQuota_Free_TotalExceeded(t *testing.T) {
 	s := store.NewMemoryStore()
-	r := NewRouter(Server{Store: s})
+	ks := authtest.NewKeySet(t)
+	r := NewRouter(Server{Store: s, Auth: ks.Verifier()})
+	token := ks.IDToken(t, "user-1", "free")
 
 	// Free max total = 20
 	for i := 0; i < 20; i++ {
 		body, _ := json.Marshal(map[string]any{"label": "x", "url": "https://example.com", "active": false})
 		req := httptest.NewRequest(http.MethodPost, "/api/qr-codes", bytes.NewReader(body))
 		req.Header.Set("Content-Type", "application/json")
-		req.Header.Set("X-User-Type", "free")
+		req.Header.Set("Authorization", "Bearer "+token)
 		w := httptest.NewRecorder()
 		r.ServeHTTP(w, req)
 		if w.Code != http.StatusCreated {
@@ -35,7 +38,7 @@ func TestQuota_Free_TotalExceeded(t *testing.T) {
 	body, _ := json.Marshal(map[string]any{"label": "x", "url": "https://example.com", "active": false})
 	req := httptest.NewRequest(http.MethodPost, "/api/qr-codes", bytes.NewReader(body))
 	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("X-User-Type", "free")
+	req.Header.Set("Authorization", "Bearer "+token)
 	w := httptest.NewRecorder()
 	r.ServeHTTP(w, req)
 	if w.Code != http.StatusForbidden {
@@ -50,14 +53,16 @@ func TestQuota_Free_TotalExceeded(t *testing.T) {
 
 func TestQuota_Free_ActiveExceededOnActivate(t *testing.T) {
 	s := store.NewMemoryStore()
-	r := NewRouter(Server{Store: s})
+	ks := authtest.NewKeySet(t)
+	r := NewRouter(Server{Store: s, Auth: ks.Verifier()})
+	token := ks.IDToken(t, "user-1", "free")
 
 	// Create 5 active (max active for free)
 	for i := 0; i < 5; i++ {
 		body, _ := json.Marshal(map[string]any{"label": "x", "url": "https://example.com"})
 		req := httptest.NewRequest(http.MethodPost, "/api/qr-codes", bytes.NewReader(body))
 		req.Header.Set("Content-Type", "application/json")
-		req.Header.Set("X-User-Type", "free")
+		req.Header.Set("Authorization", "Bearer "+token)
 		w := httptest.NewRecorder()
 		r.ServeHTTP(w, req)
 		if w.Code != http.StatusCreated {
@@ -69,7 +74,7 @@ func TestQuota_Free_ActiveExceededOnActivate(t *testing.T) {
 	body, _ := json.Marshal(map[string]any{"label": "inactive", "url": "https://example.com", "active": false})
 	createReq := httptest.NewRequest(http.MethodPost, "/api/qr-codes", bytes.NewReader(body))
 	createReq.Header.Set("Content-Type", "application/json")
-	createReq.Header.Set("X-User-Type", "free")
+	createReq.Header.Set("Authorization", "Bearer "+token)
 	createW := httptest.NewRecorder()
 	r.ServeHTTP(createW, createReq)
 	if createW.Code != http.StatusCreated {
@@ -78,6 +83,7 @@ func TestQuota_Free_ActiveExceededOnActivate(t *testing.T) {
 
 	// Find its ID
 	listReq := httptest.NewRequest(http.MethodGet, "/api/qr-codes", nil)
+	listReq.Header.Set("Authorization", "Bearer "+token)
 	listW := httptest.NewRecorder()
 	r.ServeHTTP(listW, listReq)
 	if listW.Code != http.StatusOK {
@@ -101,7 +107,7 @@ func TestQuota_Free_ActiveExceededOnActivate(t *testing.T) {
 	patchBody, _ := json.Marshal(map[string]any{"active": true})
 	patchReq := httptest.NewRequest(http.MethodPatch, "/api/qr-codes/"+inactiveID, bytes.NewReader(patchBody))
 	patchReq.Header.Set("Content-Type", "application/json")
-	patchReq.Header.Set("X-User-Type", "free")
+	patchReq.Header.Set("Authorization", "Bearer "+token)
 	patchW := httptest.NewRecorder()
 	r.ServeHTTP(patchW, patchReq)
 	if patchW.Code != http.StatusForbidden {
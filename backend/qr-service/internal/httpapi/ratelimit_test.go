@@ -0,0 +1,87 @@
+package httpapi
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"qr-service/internal/auth"
+	"qr-service/internal/auth/authtest"
+	"qr-service/internal/ratelimit"
+	"qr-service/internal/store"
+)
+
+func TestRateLimit_PerUserOnTheirPlan(t *testing.T) {
+	ks := authtest.NewKeySet(t)
+	limiter := &ratelimit.Limiter{Store: ratelimit.NewMemoryStore(), Name: "qr", Limits: map[string]ratelimit.Limit{
+		ratelimit.AnonymousPlan: ratelimit.PerMinute(1),
+		"free":                  ratelimit.PerMinute(2),
+		"basic":                 ratelimit.PerMinute(4),
+	}}
+	r := NewRouter(Server{Store: store.NewMemoryStore(), Auth: ks.Verifier(), InternalAPIKey: "secret", RateLimit: limiter})
+
+	for token, want := range map[string]int{ks.IDToken(t, "alice", "free"): 2, ks.IDToken(t, "bob", "basic"): 4} {
+		got := 0
+		for i := 0; i < 6; i++ {
+			w := doJSON(t, r, http.MethodGet, "/api/qr-codes", token, nil)
+			if w.Code == http.StatusOK {
+				got++
+				continue
+			}
+			if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") == "" {
+				t.Fatalf("expected 429 with Retry-After, got %d %v", w.Code, w.Header())
+			}
+		}
+		if got != want {
+			t.Fatalf("expected %d requests, got %d", want, got)
+		}
+	}
+
+	// Anonymous callers share a bucket per IP; click-service is exempt.
+	for i, want := range []int{http.StatusUnauthorized, http.StatusTooManyRequests} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/public/qr-codes/missing", nil))
+		if w.Code != want {
+			t.Fatalf("anonymous request %d: expected %d, got %d", i+1, want, w.Code)
+		}
+	}
+	req := httptest.NewRequest(http.MethodGet, "/api/public/qr-codes/missing", nil)
+	req.Header.Set("X-Internal-Key", "secret")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected internal calls to skip the limit, got %d", w.Code)
+	}
+}
+
+func TestRateLimit_UnknownAPIKeysAreMeteredBeforeTheLookup(t *testing.T) {
+	keys := authtest.APIKeys(t, map[string]authtest.APIKey{
+		"qrk_good": {UserID: "alice", Entitlements: "free", Scopes: []string{auth.ScopeQrRead}},
+	})
+	limiter := &ratelimit.Limiter{Store: ratelimit.NewMemoryStore(), Name: "qr", Limits: map[string]ratelimit.Limit{
+		ratelimit.AnonymousPlan: ratelimit.PerMinute(2),
+		"free":                  ratelimit.PerMinute(10),
+	}}
+	r := NewRouter(Server{Store: store.NewMemoryStore(), APIKeys: keys, RateLimit: limiter})
+
+	for i, want := range []int{http.StatusUnauthorized, http.StatusUnauthorized, http.StatusTooManyRequests} {
+		w := doJSON(t, r, http.MethodGet, "/api/qr-codes", fmt.Sprintf("qrk_made_up_%d", i), nil)
+		if w.Code != want {
+			t.Fatalf("made-up key %d: expected %d, got %d", i+1, want, w.Code)
+		}
+	}
+
+	// A known key is looked up once and then served from the cache, metered
+	// on its owner's plan only.
+	for i := 0; i < 4; i++ {
+		req := httptest.NewRequest(http.MethodGet, "/api/qr-codes", nil)
+		req.RemoteAddr = "198.51.100.1:4711"
+		req.Header.Set("X-API-Key", "qrk_good")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("request %d with a valid key: expected %d, got %d", i+1, http.StatusOK, w.Code)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package httpapi
+
+import (
+	"net/http"
+	"sort"
+)
+
+// reportRecipient is one owner who asked for weekly click reports, with the
+// codes the report should cover.
+type reportRecipient struct {
+	OwnerID  string       `json:"ownerId"`
+	Email    string       `json:"email"`
+	TimeZone string       `json:"timeZone,omitempty"`
+	QrCodes  []reportCode `json:"qrCodes"`
+}
+
+type reportCode struct {
+	ID    string `json:"id"`
+	Label string `json:"label"`
+}
+
+// handleReportRecipients serves /api/internal/report-recipients so
+// click-service can send weekly reports. Owners are listed by ID so the order
+// is stable between runs.
+func (srv *Server) handleReportRecipients(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if !srv.internalKeyOK(r) {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "unauthorized"})
+		return
+	}
+
+	subscribers, err := srv.Store.ReportSubscribers()
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "list_failed"})
+		return
+	}
+	owners := make([]string, 0, len(subscribers))
+	for ownerID := range subscribers {
+		owners = append(owners, ownerID)
+	}
+	sort.Strings(owners)
+
+	out := make([]reportRecipient, 0, len(owners))
+	for _, ownerID := range owners {
+		items, err := srv.listAll(ownerID)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "list_failed"})
+			return
+		}
+		settings := subscribers[ownerID]
+		rec := reportRecipient{OwnerID: ownerID, Email: settings.ReportEmail, TimeZone: settings.TimeZone, QrCodes: make([]reportCode, 0, len(items))}
+		for _, item := range items {
+			rec.QrCodes = append(rec.QrCodes, reportCode{ID: item.ID, Label: item.Label})
+		}
+		out = append(out, rec)
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"recipients": out})
+}
@@ -0,0 +1,67 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"qr-service/internal/auth/authtest"
+	"qr-service/internal/store"
+)
+
+func TestReportRecipients_ListsSubscribedOwnersOnly(t *testing.T) {
+	ks := authtest.NewKeySet(t)
+	r := NewRouter(Server{Store: store.NewMemoryStore(), Auth: ks.Verifier(), InternalAPIKey: "secret"})
+	alice := ks.IDToken(t, "alice", "free")
+	bob := ks.IDToken(t, "bob", "free")
+
+	if w := doJSON(t, r, http.MethodPut, "/api/settings", alice, map[string]any{"reportEmail": "not an address"}); w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "report_email_invalid") {
+		t.Fatalf("expected report_email_invalid, got %d %s", w.Code, w.Body.String())
+	}
+	if w := doJSON(t, r, http.MethodPut, "/api/settings", alice, map[string]any{"reportEmail": "Alice <alice@example.com>", "timeZone": "Europe/Berlin"}); w.Code != http.StatusOK {
+		t.Fatalf("expected settings saved, got %d", w.Code)
+	}
+	// The default redirect form sends only its own field.
+	if w := doJSON(t, r, http.MethodPut, "/api/settings", alice, map[string]any{"defaultRedirectUrl": "https://example.com/lost"}); w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "alice@example.com") {
+		t.Fatalf("expected the subscription kept when reportEmail is omitted, got %d %s", w.Code, w.Body.String())
+	}
+	if w := doJSON(t, r, http.MethodPut, "/api/settings", alice, map[string]any{"reportEmail": "alice@example.com"}); w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "https://example.com/lost") {
+		t.Fatalf("expected the default redirect kept when defaultRedirectUrl is omitted, got %d %s", w.Code, w.Body.String())
+	}
+	menu := createAs(t, r, alice, "menu")
+	createAs(t, r, bob, "flyer")
+
+	w := internalGet(r, "/api/public/settings/alice")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected the settings lookup to succeed, got %d", w.Code)
+	}
+	if strings.Contains(w.Body.String(), "alice@example.com") {
+		t.Fatalf("public settings leaked the report address: %s", w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/internal/report-recipients", nil))
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("expected %d without the internal key, got %d", http.StatusUnauthorized, w.Code)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/internal/report-recipients", nil)
+	req.Header.Set("X-Internal-Key", "secret")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	var out struct {
+		Recipients []reportRecipient `json:"recipients"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&out); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(out.Recipients) != 1 {
+		t.Fatalf("expected only alice, got %+v", out.Recipients)
+	}
+	got := out.Recipients[0]
+	if got.OwnerID != "alice" || got.Email != "alice@example.com" || got.TimeZone != "Europe/Berlin" || len(got.QrCodes) != 1 || got.QrCodes[0].ID != menu.ID {
+		t.Fatalf("unexpected recipient %+v", got)
+	}
+}
@@ -1,20 +1,87 @@
 package httpapi
 
 import (
+	"crypto/subtle"
 	"encoding/json"
 	"errors"
 	"net/http"
+	"net/mail"
 	"net/url"
 	"strings"
+	"time"
 
+	"qr-service/internal/auth"
 	"qr-service/internal/middleware"
 	"qr-service/internal/model"
+	"qr-service/internal/ratelimit"
 	"qr-service/internal/store"
 )
 
 type Server struct {
 	Store       store.Store
 	AdminAPIKey string
+
+	// ShortLinkBaseURL is the click-service origin that serves /r/{id}; it is
+	// what rendered QR images encode.
+	ShortLinkBaseURL string
+
+	// InternalAPIKey authenticates service-to-service calls (X-Internal-Key)
+	// from click-service: click reports, tag lookups and forwarded scans.
+	// Empty disables those routes.
+	InternalAPIKey string
+
+	// Auth verifies the caller's Cognito token. When nil, every request is
+	// treated as anonymous and user-scoped endpoints answer 401.
+	Auth *auth.Verifier
+	// APIKeys verifies personal API keys with user-service. When nil, API
+	// keys are ignored and such callers are anonymous.
+	APIKeys *auth.APIKeys
+
+	// RateLimit meters requests once the caller is known. NewRouter fills
+	// in its Identify and Exempt when unset. When nil nothing is metered.
+	RateLimit *ratelimit.Limiter
+}
+
+// internalKeyOK reports whether r carries click-service's X-Internal-Key. It
+// is always false when no key is configured.
+func (srv *Server) internalKeyOK(r *http.Request) bool {
+	return keyMatches(r.Header.Get("X-Internal-Key"), srv.InternalAPIKey)
+}
+
+// keyMatches compares a presented shared key with the configured one in
+// constant time. An empty configured key matches nothing.
+func keyMatches(got, want string) bool {
+	return want != "" && subtle.ConstantTimeCompare([]byte(got), []byte(want)) == 1
+}
+
+// requireScope answers 403 when a caller using a personal API key lacks the
+// scope for the request: read for GET and HEAD, write for anything else.
+// Cognito sessions and anonymous callers are left to the handler.
+func requireScope(read, write string, h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		scope := write
+		if r.Method == http.MethodGet || r.Method == http.MethodHead {
+			scope = read
+		}
+		if id, ok := auth.FromContext(r.Context()); ok && !id.Allows(scope) {
+			writeJSON(w, http.StatusForbidden, map[string]string{"error": "insufficient_scope"})
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+// rateLimitKey buckets API key callers per key and everyone else signed in
+// per user, on the plan from their entitlements.
+func rateLimitKey(r *http.Request) (string, string, bool) {
+	id, ok := auth.FromContext(r.Context())
+	if !ok {
+		return "", "", false
+	}
+	if id.APIKeyID != "" {
+		return "key:" + id.APIKeyID, userTypeFromRequest(r), true
+	}
+	return "user:" + id.UserID, userTypeFromRequest(r), true
 }
 
 type quota struct {
@@ -22,8 +89,18 @@ type quota struct {
 	maxTotal  int
 }
 
+// userIDFromRequest returns the verified caller's user ID, or "" when the
+// request is anonymous. Every QR code and settings record is owned by exactly one user.
+func userIDFromRequest(r *http.Request) string {
+	id, _ := auth.FromContext(r.Context())
+	return id.UserID
+}
+
+// userTypeFromRequest returns the caller's tier from their verified token.
+// Client-supplied headers such as X-User-Type are deliberately ignored.
 func userTypeFromRequest(r *http.Request) string {
-	v := strings.TrimSpace(strings.ToLower(r.Header.Get("X-User-Type")))
+	id, _ := auth.FromContext(r.Context())
+	v := strings.TrimSpace(strings.ToLower(id.Entitlements))
 	if v == "" {
 		return "free"
 	}
@@ -52,15 +129,82 @@ func quotaForUserType(userType string) quota {
 }
 
 type createQrCodeRequest struct {
-	Label  string `json:"label"`
-	URL    string `json:"url"`
-	Active *bool  `json:"active,omitempty"`
+	Label  string         `json:"label"`
+	URL    string         `json:"url"`
+	Active *bool          `json:"active,omitempty"`
+	Style  *model.QrStyle `json:"style,omitempty"`
+	TagIDs []string       `json:"tagIds,omitempty"`
+	// ActiveFrom and ActiveUntil are RFC 3339 timestamps bounding when the
+	// code redirects.
+	ActiveFrom  *time.Time `json:"activeFrom,omitempty"`
+	ActiveUntil *time.Time `json:"activeUntil,omitempty"`
+	// MaxScans caps redirects; ExhaustedURL is where scans go afterwards.
+	MaxScans     *int64               `json:"maxScans,omitempty"`
+	ExhaustedURL string               `json:"exhaustedUrl,omitempty"`
+	Rules        []model.RedirectRule `json:"rules,omitempty"`
+	// Variants split scans between weighted URLs; StickyVariants pins each
+	// visitor to one of them with a cookie.
+	Variants       []model.Variant `json:"variants,omitempty"`
+	StickyVariants bool            `json:"stickyVariants,omitempty"`
+	// TimeZone is an IANA zone for the code's analytics.
+	TimeZone string `json:"timeZone,omitempty"`
 }
 
 type updateQrCodeRequest struct {
-	Label  *string `json:"label"`
-	URL    *string `json:"url"`
-	Active *bool   `json:"active,omitempty"`
+	Label  *string        `json:"label"`
+	URL    *string        `json:"url"`
+	Active *bool          `json:"active,omitempty"`
+	Style  *model.QrStyle `json:"style,omitempty"`
+	// TagIDs replaces the code's tags when present; [] clears them.
+	TagIDs *[]string `json:"tagIds,omitempty"`
+	// ActiveFrom and ActiveUntil change one schedule bound each; null clears it.
+	ActiveFrom  optional[time.Time] `json:"activeFrom"`
+	ActiveUntil optional[time.Time] `json:"activeUntil"`
+	// MaxScans null removes the limit; ExhaustedURL "" falls back to the
+	// default redirect.
+	MaxScans     optional[int64] `json:"maxScans"`
+	ExhaustedURL *string         `json:"exhaustedUrl"`
+	// Rules replaces the whole rule list when present; [] clears it.
+	Rules *[]model.RedirectRule `json:"rules,omitempty"`
+	// Variants replaces the whole split when present; [] turns it off.
+	Variants       *[]model.Variant `json:"variants,omitempty"`
+	StickyVariants *bool            `json:"stickyVariants,omitempty"`
+	// TimeZone "" makes the code inherit the owner's zone again.
+	TimeZone *string `json:"timeZone,omitempty"`
+}
+
+// updateSettingsRequest is a PUT /api/settings body. Every setting is
+// optional and only those present change, so a client updating one setting
+// leaves the others alone.
+type updateSettingsRequest struct {
+	// DefaultRedirectURL "" clears the default redirect.
+	DefaultRedirectURL *string `json:"defaultRedirectUrl,omitempty"`
+	// TimeZone "" puts the owner's analytics back on UTC.
+	TimeZone *string `json:"timeZone,omitempty"`
+	// ReportEmail "" unsubscribes from the weekly report.
+	ReportEmail *string `json:"reportEmail,omitempty"`
+}
+
+// resolvedQrCode is the owner-agnostic view click-service needs to serve /r/{id}.
+// Click-service applies the schedule itself, so Active is the manual switch.
+type resolvedQrCode struct {
+	ID           string               `json:"id"`
+	OwnerID      string               `json:"ownerId"`
+	URL          string               `json:"url"`
+	Active       bool                 `json:"active"`
+	ActiveFrom   *time.Time           `json:"activeFrom,omitempty"`
+	ActiveUntil  *time.Time           `json:"activeUntil,omitempty"`
+	MaxScans     *int64               `json:"maxScans,omitempty"`
+	ExhaustedURL string               `json:"exhaustedUrl,omitempty"`
+	Rules        []model.RedirectRule `json:"rules,omitempty"`
+	// StickyVariants is only meaningful when Variants is set.
+	Variants       []model.Variant `json:"variants,omitempty"`
+	StickyVariants bool            `json:"stickyVariants,omitempty"`
+	// TimeZone is the code's zone, or else its owner's; empty means UTC.
+	TimeZone string `json:"timeZone,omitempty"`
+	// ScanWebhook asks click-service to forward the code's scans, because its
+	// owner has an active qr.scanned webhook.
+	ScanWebhook bool `json:"scanWebhook,omitempty"`
 }
 
 func NewRouter(srv Server) http.Handler {
@@ -71,13 +215,36 @@ func NewRouter(srv Server) http.Handler {
 	})
 
 	collectionHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ownerID := userIDFromRequest(r)
+		if ownerID == "" {
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "unauthorized"})
+			return
+		}
+
 		switch r.Method {
 		case http.MethodGet:
-			items := srv.Store.List()
-			for i := range items {
-				items[i] = items[i].NormalizeForResponse()
+			query, code := listQueryFromRequest(r)
+			if code != "" {
+				writeJSON(w, http.StatusBadRequest, map[string]string{"error": code})
+				return
 			}
-			writeJSON(w, http.StatusOK, items)
+			page, err := srv.Store.List(ownerID, query)
+			if err != nil {
+				if errors.Is(err, store.ErrInvalidCursor) {
+					writeJSON(w, http.StatusBadRequest, map[string]string{"error": "cursor_invalid"})
+					return
+				}
+				writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "list_failed"})
+				return
+			}
+			for i := range page.Items {
+				page.Items[i] = page.Items[i].NormalizeForResponse()
+			}
+			// The body stays a plain array; the next page is signalled out of band.
+			if page.NextCursor != "" {
+				w.Header().Set("X-Next-Cursor", page.NextCursor)
+			}
+			writeJSON(w, http.StatusOK, page.Items)
 			return
 
 		case http.MethodPost:
@@ -97,13 +264,49 @@ func NewRouter(srv Server) http.Handler {
 				writeJSON(w, http.StatusBadRequest, map[string]string{"error": "url_invalid"})
 				return
 			}
+			if req.Style != nil {
+				st, code := normalizeStyle(*req.Style)
+				if code != "" {
+					writeJSON(w, http.StatusBadRequest, map[string]string{"error": code})
+					return
+				}
+				req.Style = &st
+			}
+
+			window := store.ActiveWindow{From: utcTime(req.ActiveFrom), Until: utcTime(req.ActiveUntil)}
+			if !validWindow(window) {
+				writeJSON(w, http.StatusBadRequest, map[string]string{"error": "active_window_invalid"})
+				return
+			}
+			limit := store.ScanLimit{MaxScans: req.MaxScans, ExhaustedURL: req.ExhaustedURL}
+			if code := checkScanLimit(&limit); code != "" {
+				writeJSON(w, http.StatusBadRequest, map[string]string{"error": code})
+				return
+			}
+			rules, code := normalizeRules(req.Rules)
+			if code != "" {
+				writeJSON(w, http.StatusBadRequest, map[string]string{"error": code})
+				return
+			}
+			variants, code := normalizeVariants(req.Variants)
+			if code != "" {
+				writeJSON(w, http.StatusBadRequest, map[string]string{"error": code})
+				return
+			}
+			tz, ok := normalizeTimeZone(req.TimeZone)
+			if !ok {
+				writeJSON(w, http.StatusBadRequest, map[string]string{"error": "time_zone_invalid"})
+				return
+			}
 
 			requestedActive := true
 			if req.Active != nil {
 				requestedActive = *req.Active
 			}
+			// An already-expired code never holds an active slot.
+			requestedActive = requestedActive && (window.Until == nil || window.Until.After(time.Now()))
 
-			total, err := srv.Store.CountTotal()
+			total, err := srv.Store.CountTotal(ownerID)
 			if err != nil {
 				writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "quota_check_failed"})
 				return
@@ -113,7 +316,7 @@ func NewRouter(srv Server) http.Handler {
 				return
 			}
 			if requestedActive {
-				active, err := srv.Store.CountActive()
+				active, err := srv.Store.CountActive(ownerID)
 				if err != nil {
 					writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "quota_check_failed"})
 					return
@@ -123,12 +326,21 @@ func NewRouter(srv Server) http.Handler {
 					return
 				}
 			}
-			created, err := srv.Store.Create(store.CreateInput{Label: req.Label, URL: req.URL, Active: req.Active})
+			created, err := srv.Store.Create(store.CreateInput{OwnerID: ownerID, Label: req.Label, URL: req.URL, Active: req.Active, Style: req.Style, TagIDs: req.TagIDs, Window: window, ScanLimit: limit, Rules: rules, Variants: variants, StickyVariants: req.StickyVariants, TimeZone: tz})
 			if err != nil {
+				if errors.Is(err, store.ErrUnknownTag) {
+					writeJSON(w, http.StatusBadRequest, map[string]string{"error": "tag_not_found"})
+					return
+				}
 				writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "create_failed"})
 				return
 			}
-			writeJSON(w, http.StatusCreated, created.NormalizeForResponse())
+			out := created.NormalizeForResponse()
+			if err := srv.emitWebhookEvent(ownerID, model.EventQrCreated, out); err != nil {
+				writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "webhook_enqueue_failed"})
+				return
+			}
+			writeJSON(w, http.StatusCreated, out)
 			return
 		default:
 			w.WriteHeader(http.StatusMethodNotAllowed)
@@ -137,16 +349,34 @@ func NewRouter(srv Server) http.Handler {
 	})
 
 	itemHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		id := strings.TrimPrefix(r.URL.Path, "/api/qr-codes/")
-		id = strings.Trim(id, "/")
-		if id == "" {
+		rest := strings.TrimPrefix(r.URL.Path, "/api/qr-codes/")
+		rest = strings.Trim(rest, "/")
+		if rest == "" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		ownerID := userIDFromRequest(r)
+		if ownerID == "" {
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "unauthorized"})
+			return
+		}
+
+		parts := strings.Split(rest, "/")
+		id := parts[0]
+		if len(parts) == 2 && parts[1] == "image" {
+			// /api/qr-codes/{id}/image?format=png|svg|pdf&size=&ecc=&margin=
+			srv.handleImage(w, r, ownerID, id)
+			return
+		}
+		if len(parts) > 1 {
 			w.WriteHeader(http.StatusNotFound)
 			return
 		}
 
 		switch r.Method {
 		case http.MethodGet:
-			item, err := srv.Store.Get(id)
+			item, err := srv.Store.Get(ownerID, id)
 			if err != nil {
 				if errors.Is(err, store.ErrNotFound) {
 					writeJSON(w, http.StatusNotFound, map[string]string{"error": "not_found"})
@@ -180,9 +410,44 @@ func NewRouter(srv Server) http.Handler {
 				v := strings.TrimSpace(*req.Label)
 				req.Label = &v
 			}
+			if req.Style != nil {
+				// The style is replaced as a whole; omitted fields fall back to defaults.
+				st, code := normalizeStyle(*req.Style)
+				if code != "" {
+					writeJSON(w, http.StatusBadRequest, map[string]string{"error": code})
+					return
+				}
+				req.Style = &st
+			}
+			if req.Rules != nil {
+				rules, code := normalizeRules(*req.Rules)
+				if code != "" {
+					writeJSON(w, http.StatusBadRequest, map[string]string{"error": code})
+					return
+				}
+				req.Rules = &rules
+			}
+			if req.Variants != nil {
+				variants, code := normalizeVariants(*req.Variants)
+				if code != "" {
+					writeJSON(w, http.StatusBadRequest, map[string]string{"error": code})
+					return
+				}
+				req.Variants = &variants
+			}
+			if req.TimeZone != nil {
+				tz, ok := normalizeTimeZone(*req.TimeZone)
+				if !ok {
+					writeJSON(w, http.StatusBadRequest, map[string]string{"error": "time_zone_invalid"})
+					return
+				}
+				req.TimeZone = &tz
+			}
 
-			if req.Active != nil && *req.Active {
-				current, err := srv.Store.Get(id)
+			var window *store.ActiveWindow
+			var limit *store.ScanLimit
+			if (req.Active != nil && *req.Active) || req.ActiveFrom.Set || req.ActiveUntil.Set || req.MaxScans.Set || req.ExhaustedURL != nil {
+				current, err := srv.Store.Get(ownerID, id)
 				if err != nil {
 					if errors.Is(err, store.ErrNotFound) {
 						writeJSON(w, http.StatusNotFound, map[string]string{"error": "not_found"})
@@ -192,9 +457,43 @@ func NewRouter(srv Server) http.Handler {
 					return
 				}
 
-				// Only enforce if we're transitioning false -> true.
-				if !current.Active {
-					active, err := srv.Store.CountActive()
+				next := current
+				if req.Active != nil {
+					next.Active = *req.Active
+				}
+				if req.ActiveFrom.Set || req.ActiveUntil.Set {
+					window = &store.ActiveWindow{From: current.ActiveFrom, Until: current.ActiveUntil}
+					if req.ActiveFrom.Set {
+						window.From = utcTime(req.ActiveFrom.Value)
+					}
+					if req.ActiveUntil.Set {
+						window.Until = utcTime(req.ActiveUntil.Value)
+					}
+					if !validWindow(*window) {
+						writeJSON(w, http.StatusBadRequest, map[string]string{"error": "active_window_invalid"})
+						return
+					}
+					next.ActiveFrom, next.ActiveUntil = window.From, window.Until
+				}
+				if req.MaxScans.Set || req.ExhaustedURL != nil {
+					limit = &store.ScanLimit{MaxScans: current.MaxScans, ExhaustedURL: current.ExhaustedURL}
+					if req.MaxScans.Set {
+						limit.MaxScans = req.MaxScans.Value
+					}
+					if req.ExhaustedURL != nil {
+						limit.ExhaustedURL = *req.ExhaustedURL
+					}
+					if code := checkScanLimit(limit); code != "" {
+						writeJSON(w, http.StatusBadRequest, map[string]string{"error": code})
+						return
+					}
+				}
+
+				// Only enforce when the code starts holding an active slot, e.g.
+				// switching on or extending an expired schedule.
+				now := time.Now()
+				if next.HoldsActiveSlot(now) && !current.HoldsActiveSlot(now) {
+					active, err := srv.Store.CountActive(ownerID)
 					if err != nil {
 						writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "quota_check_failed"})
 						return
@@ -205,19 +504,28 @@ func NewRouter(srv Server) http.Handler {
 					}
 				}
 			}
-			updated, err := srv.Store.Update(id, store.UpdateInput{Label: req.Label, URL: req.URL, Active: req.Active})
+			updated, err := srv.Store.Update(ownerID, id, store.UpdateInput{Label: req.Label, URL: req.URL, Active: req.Active, Style: req.Style, TagIDs: req.TagIDs, Window: window, ScanLimit: limit, Rules: req.Rules, Variants: req.Variants, StickyVariants: req.StickyVariants, TimeZone: req.TimeZone})
 			if err != nil {
 				if errors.Is(err, store.ErrNotFound) {
 					writeJSON(w, http.StatusNotFound, map[string]string{"error": "not_found"})
 					return
 				}
+				if errors.Is(err, store.ErrUnknownTag) {
+					writeJSON(w, http.StatusBadRequest, map[string]string{"error": "tag_not_found"})
+					return
+				}
 				writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "update_failed"})
 				return
 			}
-			writeJSON(w, http.StatusOK, updated.NormalizeForResponse())
+			out := updated.NormalizeForResponse()
+			if err := srv.emitWebhookEvent(ownerID, model.EventQrUpdated, out); err != nil {
+				writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "webhook_enqueue_failed"})
+				return
+			}
+			writeJSON(w, http.StatusOK, out)
 			return
 		case http.MethodDelete:
-			err := srv.Store.Delete(id)
+			err := srv.Store.Delete(ownerID, id)
 			if err != nil {
 				if errors.Is(err, store.ErrNotFound) {
 					writeJSON(w, http.StatusNotFound, map[string]string{"error": "not_found"})
@@ -226,6 +534,10 @@ func NewRouter(srv Server) http.Handler {
 				writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "delete_failed"})
 				return
 			}
+			if err := srv.emitWebhookEvent(ownerID, model.EventQrDeleted, map[string]string{"id": id}); err != nil {
+				writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "webhook_enqueue_failed"})
+				return
+			}
 			w.WriteHeader(http.StatusNoContent)
 			return
 		default:
@@ -235,9 +547,15 @@ func NewRouter(srv Server) http.Handler {
 	})
 
 	settingsHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ownerID := userIDFromRequest(r)
+		if ownerID == "" {
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "unauthorized"})
+			return
+		}
+
 		switch r.Method {
 		case http.MethodGet:
-			settings, err := srv.Store.GetSettings()
+			settings, err := srv.Store.GetSettings(ownerID)
 			if err != nil {
 				writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed_to_get_settings"})
 				return
@@ -245,16 +563,44 @@ func NewRouter(srv Server) http.Handler {
 			writeJSON(w, http.StatusOK, settings)
 			return
 		case http.MethodPut:
-			var req model.UserSettings
+			var req updateSettingsRequest
 			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 				writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_json"})
 				return
 			}
-			if err := srv.Store.UpdateSettings(req); err != nil {
+			settings, err := srv.Store.GetSettings(ownerID)
+			if err != nil {
+				writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed_to_update_settings"})
+				return
+			}
+			if req.DefaultRedirectURL != nil {
+				settings.DefaultRedirectURL = *req.DefaultRedirectURL
+			}
+			if req.TimeZone != nil {
+				tz, ok := normalizeTimeZone(*req.TimeZone)
+				if !ok {
+					writeJSON(w, http.StatusBadRequest, map[string]string{"error": "time_zone_invalid"})
+					return
+				}
+				settings.TimeZone = tz
+			}
+			if req.ReportEmail != nil {
+				email := strings.TrimSpace(*req.ReportEmail)
+				if email != "" {
+					addr, err := mail.ParseAddress(email)
+					if err != nil {
+						writeJSON(w, http.StatusBadRequest, map[string]string{"error": "report_email_invalid"})
+						return
+					}
+					email = addr.Address
+				}
+				settings.ReportEmail = email
+			}
+			if err := srv.Store.UpdateSettings(ownerID, settings); err != nil {
 				writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed_to_update_settings"})
 				return
 			}
-			writeJSON(w, http.StatusOK, req)
+			writeJSON(w, http.StatusOK, settings)
 			return
 		default:
 			w.WriteHeader(http.StatusMethodNotAllowed)
@@ -262,8 +608,142 @@ func NewRouter(srv Server) http.Handler {
 		}
 	})
 
+	// Read-only lookups for click-service's redirect path. They are not for
+	// browsers: the internal key is required, and they still expose only what
+	// the redirect needs, not labels or other owner data.
+	publicQrCodeHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		if !srv.internalKeyOK(r) {
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "unauthorized"})
+			return
+		}
+		id := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/public/qr-codes/"), "/")
+		if id == "" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		item, err := srv.Store.Resolve(id)
+		if err != nil {
+			if errors.Is(err, store.ErrNotFound) {
+				writeJSON(w, http.StatusNotFound, map[string]string{"error": "not_found"})
+				return
+			}
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "get_failed"})
+			return
+		}
+		tz := item.TimeZone
+		if tz == "" {
+			settings, err := srv.Store.GetSettings(item.OwnerID)
+			if err != nil {
+				writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "get_failed"})
+				return
+			}
+			tz = settings.TimeZone
+		}
+		scanWebhook, err := srv.Store.HasWebhook(item.OwnerID, model.EventQrScanned)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "get_failed"})
+			return
+		}
+		writeJSONWithETag(w, r, resolvedQrCode{ID: item.ID, OwnerID: item.OwnerID, URL: item.URL, Active: item.Active, ActiveFrom: item.ActiveFrom, ActiveUntil: item.ActiveUntil, MaxScans: item.MaxScans, ExhaustedURL: item.ExhaustedURL, Rules: item.Rules, Variants: item.Variants, StickyVariants: item.StickyVariants, TimeZone: tz, ScanWebhook: scanWebhook})
+	})
+
+	publicSettingsHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		if !srv.internalKeyOK(r) {
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "unauthorized"})
+			return
+		}
+		ownerID := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/public/settings/"), "/")
+		if ownerID == "" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		settings, err := srv.Store.GetSettings(ownerID)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed_to_get_settings"})
+			return
+		}
+		// The report address is private to the owner.
+		settings.ReportEmail = ""
+		writeJSONWithETag(w, r, settings)
+	})
+
+	authenticate := auth.Middleware(srv.Auth, srv.APIKeys)
+	if srv.RateLimit != nil {
+		limiter := *srv.RateLimit
+		if limiter.Identify == nil {
+			limiter.Identify = rateLimitKey
+		}
+		if limiter.Exempt == nil {
+			// click-service resolves codes for every scan; its calls are
+			// bounded by the scans it is itself limiting.
+			limiter.Exempt = func(r *http.Request) bool {
+				return srv.internalKeyOK(r)
+			}
+		}
+		srv.RateLimit = &limiter
+	}
+	exposed := append([]string{"X-Request-Id", "X-Next-Cursor"}, ratelimit.Headers...)
+	// API keys missing from the cache cost a call to user-service before the
+	// caller is known, so those are metered per IP first.
+	meterLookups := srv.RateLimit.Before(srv.APIKeys.Unverified)
 	wrap := func(h http.Handler) http.Handler {
-		return middleware.Recoverer(middleware.RequestID(middleware.ExposeResponseHeaders(middleware.EnforceJSONHandler(h))))
+		return middleware.Recoverer(middleware.RequestID(middleware.ExposeResponseHeaders(meterLookups(authenticate(srv.RateLimit.Middleware(middleware.EnforceJSONHandler(h)))), exposed...)))
+	}
+	// wrapUser is for routes acting on the caller's own data, which API keys
+	// reach with qr:read and qr:write.
+	wrapUser := func(h http.Handler) http.Handler {
+		return wrap(requireScope(auth.ScopeQrRead, auth.ScopeQrWrite, h))
+	}
+
+	// Internal endpoint for click-service to feed click counters used for sorting.
+	internalClicksHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		if !srv.internalKeyOK(r) {
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "unauthorized"})
+			return
+		}
+		rest := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/internal/qr-codes/"), "/")
+		id, ok := strings.CutSuffix(rest, "/clicks")
+		if !ok || id == "" || strings.Contains(id, "/") {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		var req struct {
+			Count int64 `json:"count"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_json"})
+			return
+		}
+		if req.Count <= 0 {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "count_invalid"})
+			return
+		}
+		if err := srv.Store.IncrementClicks(id, req.Count); err != nil {
+			if errors.Is(err, store.ErrNotFound) {
+				writeJSON(w, http.StatusNotFound, map[string]string{"error": "not_found"})
+				return
+			}
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "update_failed"})
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	// The import endpoint also takes text/csv, so it gets its own content-type check.
+	wrapImport := func(h http.Handler) http.Handler {
+		return middleware.Recoverer(middleware.RequestID(authenticate(middleware.ExposeResponseHeaders(srv.RateLimit.Middleware(middleware.EnforceContentTypes(requireScope(auth.ScopeQrRead, auth.ScopeQrWrite, h), "application/json", "text/csv")), exposed...))))
 	}
 
 	adminSampleDataHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -273,11 +753,17 @@ func NewRouter(srv Server) http.Handler {
 		}
 
 		// Check admin key
-		if srv.AdminAPIKey == "" || r.Header.Get("X-Admin-Key") != srv.AdminAPIKey {
+		if !keyMatches(r.Header.Get("X-Admin-Key"), srv.AdminAPIKey) {
 			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "unauthorized"})
 			return
 		}
 
+		ownerID := strings.TrimSpace(r.URL.Query().Get("ownerId"))
+		if ownerID == "" {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "owner_id_required"})
+			return
+		}
+
 		// Generate sample QR codes
 		sampleData := []struct {
 			label  string
@@ -299,9 +785,10 @@ func NewRouter(srv Server) http.Handler {
 		created := 0
 		for _, data := range sampleData {
 			_, err := srv.Store.Create(store.CreateInput{
-				Label:  data.label,
-				URL:    data.url,
-				Active: &data.active,
+				OwnerID: ownerID,
+				Label:   data.label,
+				URL:     data.url,
+				Active:  &data.active,
 			})
 			if err == nil {
 				created++
@@ -315,11 +802,23 @@ func NewRouter(srv Server) http.Handler {
 	})
 
 	mux.Handle("/healthz", wrap(healthHandler))
-	mux.Handle("/api/qr-codes", wrap(collectionHandler))
-	mux.Handle("/api/qr-codes/", wrap(itemHandler))
-	mux.Handle("/api/settings", wrap(settingsHandler))
+	mux.Handle("/api/qr-codes", wrapUser(collectionHandler))
+	mux.Handle("/api/qr-codes/", wrapUser(itemHandler))
+	mux.Handle("/api/qr-codes/import", wrapImport(http.HandlerFunc(srv.handleImport)))
+	mux.Handle("/api/qr-codes/export", wrapUser(http.HandlerFunc(srv.handleExport)))
+	mux.Handle("/api/tags", wrapUser(http.HandlerFunc(srv.handleTags)))
+	mux.Handle("/api/tags/", wrapUser(http.HandlerFunc(srv.handleTag)))
+	mux.Handle("/api/settings", wrapUser(settingsHandler))
+	mux.Handle("/api/webhooks", wrapUser(http.HandlerFunc(srv.handleWebhooks)))
+	mux.Handle("/api/webhooks/", wrapUser(http.HandlerFunc(srv.handleWebhook)))
+	mux.Handle("/api/public/qr-codes/", wrap(publicQrCodeHandler))
+	mux.Handle("/api/public/settings/", wrap(publicSettingsHandler))
+	mux.Handle("/api/internal/qr-codes/", wrap(internalClicksHandler))
+	mux.Handle("/api/internal/tags/", wrap(http.HandlerFunc(srv.handleInternalTag)))
+	mux.Handle("/api/internal/report-recipients", wrap(http.HandlerFunc(srv.handleReportRecipients)))
+	mux.Handle("/api/internal/webhook-events", wrap(http.HandlerFunc(srv.handleInternalWebhookEvents)))
 	mux.Handle("/api/admin/generate-sample-data", wrap(adminSampleDataHandler))
-	mux.Handle("/api/dev/generate-sample-data", wrap(http.HandlerFunc(srv.devSampleDataHandler)))
+	mux.Handle("/api/dev/generate-sample-data", wrapUser(http.HandlerFunc(srv.devSampleDataHandler)))
 
 	return mux
 }
@@ -330,9 +829,8 @@ func (srv *Server) devSampleDataHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	// Get user ID from context (set by auth middleware)
-	userID, ok := r.Context().Value("user_id").(string)
-	if !ok || userID == "" {
+	userID := userIDFromRequest(r)
+	if userID == "" {
 		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "unauthorized"})
 		return
 	}
@@ -358,9 +856,10 @@ func (srv *Server) devSampleDataHandler(w http.ResponseWriter, r *http.Request)
 	created := 0
 	for _, data := range sampleData {
 		_, err := srv.Store.Create(store.CreateInput{
-			Label:  data.label,
-			URL:    data.url,
-			Active: &data.active,
+			OwnerID: userID,
+			Label:   data.label,
+			URL:     data.url,
+			Active:  &data.active,
 		})
 		if err == nil {
 			created++
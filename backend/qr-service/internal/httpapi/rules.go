@@ -0,0 +1,84 @@
+package httpapi
+
+import (
+	"slices"
+	"strings"
+	"time"
+
+	"qr-service/internal/model"
+)
+
+var ruleWeekdays = []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}
+
+// normalizeRules validates client-supplied redirect rules and canonicalizes
+// their casing. It returns the error code to send back, or "" when usable.
+func normalizeRules(in []model.RedirectRule) ([]model.RedirectRule, string) {
+	if len(in) > model.MaxRedirectRules {
+		return nil, "rules_too_many"
+	}
+	out := make([]model.RedirectRule, 0, len(in))
+	for _, rule := range in {
+		rule.URL = strings.TrimSpace(rule.URL)
+		if !isValidHTTPURL(rule.URL) {
+			return nil, "rule_url_invalid"
+		}
+
+		rule.Platforms = normalizeRuleList(rule.Platforms, strings.ToLower)
+		for _, p := range rule.Platforms {
+			if p != model.PlatformIOS && p != model.PlatformAndroid && p != model.PlatformDesktop {
+				return nil, "rule_platform_invalid"
+			}
+		}
+		rule.Languages = normalizeRuleList(rule.Languages, strings.ToLower)
+		for _, l := range rule.Languages {
+			if len(l) < 2 || len(l) > 35 || strings.ContainsFunc(l, func(r rune) bool { return !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '-') }) {
+				return nil, "rule_language_invalid"
+			}
+		}
+		rule.Countries = normalizeRuleList(rule.Countries, strings.ToUpper)
+		for _, c := range rule.Countries {
+			if len(c) != 2 || c[0] < 'A' || c[0] > 'Z' || c[1] < 'A' || c[1] > 'Z' {
+				return nil, "rule_country_invalid"
+			}
+		}
+		rule.Weekdays = normalizeRuleList(rule.Weekdays, strings.ToLower)
+		for _, d := range rule.Weekdays {
+			if !slices.Contains(ruleWeekdays, d) {
+				return nil, "rule_weekday_invalid"
+			}
+		}
+
+		rule.TimeFrom = strings.TrimSpace(rule.TimeFrom)
+		rule.TimeUntil = strings.TrimSpace(rule.TimeUntil)
+		if rule.TimeFrom != "" || rule.TimeUntil != "" {
+			from, errFrom := time.Parse("15:04", rule.TimeFrom)
+			until, errUntil := time.Parse("15:04", rule.TimeUntil)
+			if errFrom != nil || errUntil != nil || from.Equal(until) {
+				return nil, "rule_time_invalid"
+			}
+		}
+		rule.TimeZone = strings.TrimSpace(rule.TimeZone)
+		if rule.TimeZone != "" {
+			if _, err := time.LoadLocation(rule.TimeZone); err != nil {
+				return nil, "rule_time_zone_invalid"
+			}
+		}
+
+		if len(rule.Platforms) == 0 && len(rule.Languages) == 0 && len(rule.Countries) == 0 && len(rule.Weekdays) == 0 && rule.TimeFrom == "" {
+			// A rule matching everything would silently replace the code's URL.
+			return nil, "rule_conditions_required"
+		}
+		out = append(out, rule)
+	}
+	return out, ""
+}
+
+func normalizeRuleList(in []string, canon func(string) string) []string {
+	var out []string
+	for _, v := range in {
+		if v = canon(strings.TrimSpace(v)); v != "" && !slices.Contains(out, v) {
+			out = append(out, v)
+		}
+	}
+	return out
+}
@@ -0,0 +1,65 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"qr-service/internal/auth/authtest"
+	"qr-service/internal/store"
+)
+
+func TestRules_ValidationAndNormalization(t *testing.T) {
+	ks := authtest.NewKeySet(t)
+	r := NewRouter(Server{Store: store.NewMemoryStore(), Auth: ks.Verifier()})
+	token := ks.IDToken(t, "user-1", "free")
+
+	invalid := map[string]map[string]any{
+		"rule_url_invalid":         {"platforms": []string{"ios"}, "url": "http://apps.example.com"},
+		"rule_platform_invalid":    {"platforms": []string{"windows-phone"}, "url": "https://example.com/x"},
+		"rule_country_invalid":     {"countries": []string{"USA"}, "url": "https://example.com/x"},
+		"rule_weekday_invalid":     {"weekdays": []string{"funday"}, "url": "https://example.com/x"},
+		"rule_time_invalid":        {"timeFrom": "09:00", "url": "https://example.com/x"},
+		"rule_time_zone_invalid":   {"timeFrom": "09:00", "timeUntil": "17:00", "timeZone": "Mars/Olympus", "url": "https://example.com/x"},
+		"rule_conditions_required": {"url": "https://example.com/x"},
+	}
+	for want, rule := range invalid {
+		w := doJSON(t, r, http.MethodPost, "/api/qr-codes", token, map[string]any{"url": "https://example.com", "rules": []any{rule}})
+		if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), want) {
+			t.Fatalf("expected %s, got %d %s", want, w.Code, w.Body.String())
+		}
+	}
+
+	w := doJSON(t, r, http.MethodPost, "/api/qr-codes", token, map[string]any{
+		"url": "https://example.com",
+		"rules": []any{
+			map[string]any{"platforms": []string{" iOS "}, "url": "https://apps.apple.com/app/x"},
+			map[string]any{"countries": []string{"de", "DE"}, "languages": []string{"DE"}, "url": "https://example.com/de"},
+		},
+	})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected %d, got %d: %s", http.StatusCreated, w.Code, w.Body.String())
+	}
+	var created struct {
+		ID    string `json:"id"`
+		Rules []struct {
+			Platforms []string `json:"platforms"`
+			Countries []string `json:"countries"`
+			Languages []string `json:"languages"`
+		} `json:"rules"`
+	}
+	_ = json.NewDecoder(w.Body).Decode(&created)
+	if len(created.Rules) != 2 || created.Rules[0].Platforms[0] != "ios" || len(created.Rules[1].Countries) != 1 || created.Rules[1].Languages[0] != "de" {
+		t.Fatalf("unexpected rules %+v", created.Rules)
+	}
+
+	w = doJSON(t, r, http.MethodPatch, "/api/qr-codes/"+created.ID, token, map[string]any{"rules": []any{}})
+	var cleared struct {
+		Rules []any `json:"rules"`
+	}
+	_ = json.NewDecoder(w.Body).Decode(&cleared)
+	if w.Code != http.StatusOK || cleared.Rules == nil || len(cleared.Rules) != 0 {
+		t.Fatalf("expected rules cleared, got %d %s", w.Code, w.Body.String())
+	}
+}
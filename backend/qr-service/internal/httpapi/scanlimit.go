@@ -0,0 +1,20 @@
+package httpapi
+
+import (
+	"strings"
+
+	"qr-service/internal/store"
+)
+
+// checkScanLimit trims the exhausted URL and returns the error code to send
+// back, or "" when the limit is usable.
+func checkScanLimit(l *store.ScanLimit) string {
+	if l.MaxScans != nil && *l.MaxScans < 1 {
+		return "max_scans_invalid"
+	}
+	l.ExhaustedURL = strings.TrimSpace(l.ExhaustedURL)
+	if l.ExhaustedURL != "" && !isValidHTTPURL(l.ExhaustedURL) {
+		return "exhausted_url_invalid"
+	}
+	return ""
+}
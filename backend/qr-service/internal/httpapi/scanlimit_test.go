@@ -0,0 +1,47 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"qr-service/internal/auth/authtest"
+	"qr-service/internal/store"
+)
+
+func TestScanLimit_ValidatesAndReachesResolve(t *testing.T) {
+	ks := authtest.NewKeySet(t)
+	r := NewRouter(Server{Store: store.NewMemoryStore(), Auth: ks.Verifier(), InternalAPIKey: "secret"})
+	token := ks.IDToken(t, "user-1", "free")
+
+	for body, want := range map[string]map[string]any{
+		"max_scans_invalid":     {"url": "https://example.com", "maxScans": 0},
+		"exhausted_url_invalid": {"url": "https://example.com", "maxScans": 1, "exhaustedUrl": "http://example.com/sorry"},
+	} {
+		if w := doJSON(t, r, http.MethodPost, "/api/qr-codes", token, want); w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), body) {
+			t.Fatalf("expected %s, got %d %s", body, w.Code, w.Body.String())
+		}
+	}
+
+	w := doJSON(t, r, http.MethodPost, "/api/qr-codes", token, map[string]any{"url": "https://example.com", "maxScans": 1, "exhaustedUrl": "https://example.com/used"})
+	var created qrResp
+	_ = json.NewDecoder(w.Body).Decode(&created)
+
+	resolve := func() map[string]any {
+		w := internalGet(r, "/api/public/qr-codes/"+created.ID)
+		var out map[string]any
+		_ = json.NewDecoder(w.Body).Decode(&out)
+		return out
+	}
+	if got := resolve(); got["maxScans"] != float64(1) || got["exhaustedUrl"] != "https://example.com/used" {
+		t.Fatalf("expected the limit in the public lookup, got %v", got)
+	}
+
+	if w := doJSON(t, r, http.MethodPatch, "/api/qr-codes/"+created.ID, token, map[string]any{"maxScans": nil}); w.Code != http.StatusOK {
+		t.Fatalf("expected %d, got %d", http.StatusOK, w.Code)
+	}
+	if got := resolve(); got["maxScans"] != nil || got["exhaustedUrl"] != "https://example.com/used" {
+		t.Fatalf("expected only the limit to be cleared, got %v", got)
+	}
+}
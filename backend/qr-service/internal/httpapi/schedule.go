@@ -0,0 +1,20 @@
+package httpapi
+
+import (
+	"time"
+
+	"qr-service/internal/store"
+)
+
+// validWindow rejects schedules that can never be live.
+func validWindow(w store.ActiveWindow) bool {
+	return w.From == nil || w.Until == nil || w.Until.After(*w.From)
+}
+
+func utcTime(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	u := t.UTC()
+	return &u
+}
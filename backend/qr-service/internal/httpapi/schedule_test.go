@@ -0,0 +1,73 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"qr-service/internal/auth/authtest"
+	"qr-service/internal/store"
+)
+
+func TestSchedule_ValidationAndActiveNow(t *testing.T) {
+	ks := authtest.NewKeySet(t)
+	r := NewRouter(Server{Store: store.NewMemoryStore(), Auth: ks.Verifier()})
+	token := ks.IDToken(t, "user-1", "free")
+	now := time.Now().UTC()
+
+	w := doJSON(t, r, http.MethodPost, "/api/qr-codes", token, map[string]any{
+		"url": "https://example.com", "activeFrom": now.Add(time.Hour), "activeUntil": now,
+	})
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected %d for an inverted window, got %d", http.StatusBadRequest, w.Code)
+	}
+
+	w = doJSON(t, r, http.MethodPost, "/api/qr-codes", token, map[string]any{
+		"url": "https://example.com", "activeFrom": now.Add(time.Hour),
+	})
+	var created struct {
+		ID         string     `json:"id"`
+		ActiveNow  bool       `json:"activeNow"`
+		ActiveFrom *time.Time `json:"activeFrom"`
+	}
+	_ = json.NewDecoder(w.Body).Decode(&created)
+	if w.Code != http.StatusCreated || created.ActiveNow || created.ActiveFrom == nil {
+		t.Fatalf("expected a scheduled, not-yet-live code, got %d %+v", w.Code, created)
+	}
+
+	w = doJSON(t, r, http.MethodPatch, "/api/qr-codes/"+created.ID, token, map[string]any{"activeFrom": nil})
+	var updated struct {
+		ActiveNow  bool       `json:"activeNow"`
+		ActiveFrom *time.Time `json:"activeFrom"`
+	}
+	_ = json.NewDecoder(w.Body).Decode(&updated)
+	if w.Code != http.StatusOK || !updated.ActiveNow || updated.ActiveFrom != nil {
+		t.Fatalf("expected clearing activeFrom to make the code live, got %d %+v", w.Code, updated)
+	}
+}
+
+func TestSchedule_ExpiredCodesFreeActiveSlots(t *testing.T) {
+	ks := authtest.NewKeySet(t)
+	r := NewRouter(Server{Store: store.NewMemoryStore(), Auth: ks.Verifier()})
+	token := ks.IDToken(t, "user-1", "free")
+	past := time.Now().Add(-time.Hour)
+
+	// Free max active = 5; expired codes do not count.
+	var expired qrResp
+	for i := 0; i < 3; i++ {
+		w := doJSON(t, r, http.MethodPost, "/api/qr-codes", token, map[string]any{"url": "https://example.com", "activeUntil": past})
+		_ = json.NewDecoder(w.Body).Decode(&expired)
+	}
+	for i := 0; i < 5; i++ {
+		if w := doJSON(t, r, http.MethodPost, "/api/qr-codes", token, map[string]any{"url": "https://example.com"}); w.Code != http.StatusCreated {
+			t.Fatalf("expected %d, got %d", http.StatusCreated, w.Code)
+		}
+	}
+
+	// Extending an expired code would take a slot.
+	w := doJSON(t, r, http.MethodPatch, "/api/qr-codes/"+expired.ID, token, map[string]any{"activeUntil": nil})
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("expected %d, got %d", http.StatusForbidden, w.Code)
+	}
+}
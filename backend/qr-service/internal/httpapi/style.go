@@ -0,0 +1,52 @@
+package httpapi
+
+import (
+	"errors"
+	"image/color"
+
+	"qr-service/internal/model"
+	"qr-service/internal/render"
+)
+
+// normalizeStyle validates a client-supplied style. It returns the error code
+// to send back, or "" when the style is usable.
+func normalizeStyle(in model.QrStyle) (model.QrStyle, string) {
+	st, err := in.Normalize()
+	if err != nil {
+		var se *model.StyleError
+		if errors.As(err, &se) {
+			return st, se.Code
+		}
+		return st, "style_invalid"
+	}
+	if st.Logo != "" {
+		// Normalize checks the data URI; make sure the bytes are really an image
+		// we can draw before storing them.
+		_, raw, _ := model.ParseLogo(st.Logo)
+		if _, err := render.DecodeLogo(raw); err != nil {
+			return st, "style_logo_invalid"
+		}
+	}
+	return st, ""
+}
+
+// renderStyle converts a stored style into render options.
+func renderStyle(st model.QrStyle) (render.Style, error) {
+	out := render.Style{ModuleShape: st.ModuleShape, FinderStyle: st.FinderStyle}
+	if r, g, b, ok := model.ParseHexColor(st.Foreground); ok {
+		out.Foreground = color.RGBA{R: r, G: g, B: b, A: 0xff}
+	}
+	if r, g, b, ok := model.ParseHexColor(st.Background); ok {
+		out.Background = color.RGBA{R: r, G: g, B: b, A: 0xff}
+	}
+	if st.Logo != "" {
+		_, raw, err := model.ParseLogo(st.Logo)
+		if err != nil {
+			return out, err
+		}
+		if out.Logo, err = render.DecodeLogo(raw); err != nil {
+			return out, err
+		}
+	}
+	return out, nil
+}
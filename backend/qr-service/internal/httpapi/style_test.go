@@ -0,0 +1,129 @@
+package httpapi
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"image"
+	"image/color"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"qr-service/internal/auth/authtest"
+	"qr-service/internal/store"
+)
+
+func doJSON(t *testing.T, r http.Handler, method, path, token string, body any) *httptest.ResponseRecorder {
+	t.Helper()
+	raw, _ := json.Marshal(body)
+	req := httptest.NewRequest(method, path, bytes.NewReader(raw))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+// internalGet calls r the way click-service does, with the "secret" internal
+// key the test servers are given.
+func internalGet(r http.Handler, path string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	req.Header.Set("X-Internal-Key", "secret")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestStyle_DefaultsAndValidation(t *testing.T) {
+	ks := authtest.NewKeySet(t)
+	r := NewRouter(Server{Store: store.NewMemoryStore(), Auth: ks.Verifier()})
+	token := ks.IDToken(t, "user-1", "free")
+
+	w := doJSON(t, r, http.MethodPost, "/api/qr-codes", token, map[string]any{"label": "a", "url": "https://example.com"})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected %d, got %d", http.StatusCreated, w.Code)
+	}
+	var created struct {
+		ID    string         `json:"id"`
+		Style map[string]any `json:"style"`
+	}
+	_ = json.NewDecoder(w.Body).Decode(&created)
+	if created.Style["foreground"] != "#000000" || created.Style["moduleShape"] != "square" {
+		t.Fatalf("expected default style, got %v", created.Style)
+	}
+
+	w = doJSON(t, r, http.MethodPost, "/api/qr-codes", token, map[string]any{
+		"label": "b", "url": "https://example.com", "style": map[string]any{"foreground": "#f0f0f0"},
+	})
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "style_contrast_too_low") {
+		t.Fatalf("expected contrast error, got %d %s", w.Code, w.Body.String())
+	}
+
+	w = doJSON(t, r, http.MethodPatch, "/api/qr-codes/"+created.ID, token, map[string]any{
+		"style": map[string]any{"logo": "data:image/png;base64,bm90IGFuIGltYWdl", "errorCorrection": "H"},
+	})
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "style_logo_invalid") {
+		t.Fatalf("expected logo error, got %d %s", w.Code, w.Body.String())
+	}
+}
+
+func TestStyle_UsedForImage(t *testing.T) {
+	ks := authtest.NewKeySet(t)
+	r := NewRouter(Server{Store: store.NewMemoryStore(), Auth: ks.Verifier(), ShortLinkBaseURL: "https://click.example.com"})
+	token := ks.IDToken(t, "user-1", "free")
+
+	var logo bytes.Buffer
+	img := image.NewRGBA(image.Rect(0, 0, 8, 8))
+	for i := range img.Pix {
+		img.Pix[i] = 0xff
+	}
+	_ = png.Encode(&logo, img)
+
+	w := doJSON(t, r, http.MethodPost, "/api/qr-codes", token, map[string]any{
+		"label": "styled",
+		"url":   "https://example.com",
+		"style": map[string]any{
+			"foreground":      "#1a237e",
+			"background":      "#fff8e7",
+			"moduleShape":     "dot",
+			"finderStyle":     "rounded",
+			"quietZone":       2,
+			"errorCorrection": "H",
+			"logo":            "data:image/png;base64," + base64.StdEncoding.EncodeToString(logo.Bytes()),
+		},
+	})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected %d, got %d: %s", http.StatusCreated, w.Code, w.Body.String())
+	}
+	var created qrResp
+	_ = json.NewDecoder(w.Body).Decode(&created)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/qr-codes/"+created.ID+"/image?size=256", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	imgW := httptest.NewRecorder()
+	r.ServeHTTP(imgW, req)
+	if imgW.Code != http.StatusOK {
+		t.Fatalf("expected %d, got %d: %s", http.StatusOK, imgW.Code, imgW.Body.String())
+	}
+	decoded, err := png.Decode(imgW.Body)
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	cr, cg, cb, _ := decoded.At(0, 0).RGBA()
+	want := color.RGBA{R: 0xff, G: 0xf8, B: 0xe7, A: 0xff}
+	if uint8(cr>>8) != want.R || uint8(cg>>8) != want.G || uint8(cb>>8) != want.B {
+		t.Fatalf("expected styled background, got %v", decoded.At(0, 0))
+	}
+
+	// Overriding ECC below what the logo needs is refused.
+	req = httptest.NewRequest(http.MethodGet, "/api/qr-codes/"+created.ID+"/image?ecc=L", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	lowW := httptest.NewRecorder()
+	r.ServeHTTP(lowW, req)
+	if lowW.Code != http.StatusBadRequest || !strings.Contains(lowW.Body.String(), "ecc_too_low_for_logo") {
+		t.Fatalf("expected ecc error, got %d %s", lowW.Code, lowW.Body.String())
+	}
+}
@@ -0,0 +1,177 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"qr-service/internal/store"
+)
+
+const maxTagNameLength = 64
+
+type tagRequest struct {
+	Name string `json:"name"`
+}
+
+// tagNameFromRequest decodes and validates a tag body, returning an error code
+// for the client when it is unusable.
+func tagNameFromRequest(r *http.Request) (string, string) {
+	var req tagRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return "", "invalid_json"
+	}
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return "", "name_required"
+	}
+	if utf8.RuneCountInString(name) > maxTagNameLength {
+		return "", "name_too_long"
+	}
+	return name, ""
+}
+
+// handleTags serves /api/tags: the caller's tags, and creating new ones.
+func (srv *Server) handleTags(w http.ResponseWriter, r *http.Request) {
+	ownerID := userIDFromRequest(r)
+	if ownerID == "" {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "unauthorized"})
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		items, err := srv.Store.ListTags(ownerID)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "list_failed"})
+			return
+		}
+		for i := range items {
+			items[i] = items[i].NormalizeForResponse()
+		}
+		writeJSON(w, http.StatusOK, items)
+	case http.MethodPost:
+		name, code := tagNameFromRequest(r)
+		if code != "" {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": code})
+			return
+		}
+		created, err := srv.Store.CreateTag(ownerID, name)
+		if err != nil {
+			if errors.Is(err, store.ErrTagExists) {
+				writeJSON(w, http.StatusConflict, map[string]string{"error": "tag_exists"})
+				return
+			}
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "create_failed"})
+			return
+		}
+		writeJSON(w, http.StatusCreated, created.NormalizeForResponse())
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// handleTag serves /api/tags/{id}. Deleting a tag leaves its codes in place.
+func (srv *Server) handleTag(w http.ResponseWriter, r *http.Request) {
+	id := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/tags/"), "/")
+	if id == "" || strings.Contains(id, "/") {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	ownerID := userIDFromRequest(r)
+	if ownerID == "" {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "unauthorized"})
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		tag, err := srv.Store.GetTag(ownerID, id)
+		if err != nil {
+			if errors.Is(err, store.ErrNotFound) {
+				writeJSON(w, http.StatusNotFound, map[string]string{"error": "not_found"})
+				return
+			}
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "get_failed"})
+			return
+		}
+		writeJSON(w, http.StatusOK, tag.NormalizeForResponse())
+	case http.MethodPatch:
+		name, code := tagNameFromRequest(r)
+		if code != "" {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": code})
+			return
+		}
+		updated, err := srv.Store.RenameTag(ownerID, id, name)
+		if err != nil {
+			switch {
+			case errors.Is(err, store.ErrNotFound):
+				writeJSON(w, http.StatusNotFound, map[string]string{"error": "not_found"})
+			case errors.Is(err, store.ErrTagExists):
+				writeJSON(w, http.StatusConflict, map[string]string{"error": "tag_exists"})
+			default:
+				writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "update_failed"})
+			}
+			return
+		}
+		writeJSON(w, http.StatusOK, updated.NormalizeForResponse())
+	case http.MethodDelete:
+		if err := srv.Store.DeleteTag(ownerID, id); err != nil {
+			if errors.Is(err, store.ErrNotFound) {
+				writeJSON(w, http.StatusNotFound, map[string]string{"error": "not_found"})
+				return
+			}
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "delete_failed"})
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// handleInternalTag serves /api/internal/tags/{id}/qr-codes?ownerId=... so
+// click-service can aggregate a tag's stats for the signed-in caller. A tag
+// belonging to anyone but ownerId answers 404.
+func (srv *Server) handleInternalTag(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if !srv.internalKeyOK(r) {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "unauthorized"})
+		return
+	}
+	rest := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/internal/tags/"), "/")
+	id, ok := strings.CutSuffix(rest, "/qr-codes")
+	if !ok || id == "" || strings.Contains(id, "/") {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	ownerID := strings.TrimSpace(r.URL.Query().Get("ownerId"))
+	if ownerID == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "ownerId_required"})
+		return
+	}
+	if _, err := srv.Store.GetTag(ownerID, id); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": "not_found"})
+			return
+		}
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "get_failed"})
+		return
+	}
+	ids, err := srv.Store.TagQrCodeIDs(id)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": "not_found"})
+			return
+		}
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "get_failed"})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"tagId": id, "qrCodeIds": ids})
+}
@@ -0,0 +1,108 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"qr-service/internal/auth/authtest"
+	"qr-service/internal/store"
+)
+
+func TestTags_CRUDAndFiltering(t *testing.T) {
+	ks := authtest.NewKeySet(t)
+	r := NewRouter(Server{Store: store.NewMemoryStore(), Auth: ks.Verifier()})
+	token := ks.IDToken(t, "user-1", "basic")
+
+	w := doJSON(t, r, http.MethodPost, "/api/tags", token, map[string]string{"name": " Spring sale "})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected %d, got %d: %s", http.StatusCreated, w.Code, w.Body.String())
+	}
+	var tag struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	}
+	_ = json.NewDecoder(w.Body).Decode(&tag)
+	if tag.Name != "Spring sale" {
+		t.Fatalf("expected trimmed name, got %q", tag.Name)
+	}
+	if w := doJSON(t, r, http.MethodPost, "/api/tags", token, map[string]string{"name": "spring SALE"}); w.Code != http.StatusConflict {
+		t.Fatalf("expected %d, got %d", http.StatusConflict, w.Code)
+	}
+
+	w = doJSON(t, r, http.MethodPost, "/api/qr-codes", token, map[string]any{"label": "a", "url": "https://example.com/a", "tagIds": []string{tag.ID}})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected %d, got %d: %s", http.StatusCreated, w.Code, w.Body.String())
+	}
+	plain := createAs(t, r, token, "b")
+	if w := doJSON(t, r, http.MethodPatch, "/api/qr-codes/"+plain.ID, token, map[string]any{"tagIds": []string{"nope"}}); w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "tag_not_found") {
+		t.Fatalf("expected tag_not_found, got %d %s", w.Code, w.Body.String())
+	}
+
+	w = doJSON(t, r, http.MethodGet, "/api/qr-codes?tag="+tag.ID, token, nil)
+	var items []struct {
+		Label  string   `json:"label"`
+		TagIDs []string `json:"tagIds"`
+	}
+	_ = json.NewDecoder(w.Body).Decode(&items)
+	if len(items) != 1 || items[0].Label != "a" || len(items[0].TagIDs) != 1 {
+		t.Fatalf("expected only the tagged code, got %+v", items)
+	}
+
+	if w := doJSON(t, r, http.MethodPatch, "/api/tags/"+tag.ID, token, map[string]string{"name": "Summer sale"}); w.Code != http.StatusOK {
+		t.Fatalf("expected %d, got %d", http.StatusOK, w.Code)
+	}
+	other := ks.IDToken(t, "user-2", "free")
+	if w := doJSON(t, r, http.MethodGet, "/api/tags/"+tag.ID, other, nil); w.Code != http.StatusNotFound {
+		t.Fatalf("expected another user's tag to be hidden, got %d", w.Code)
+	}
+	if w := doJSON(t, r, http.MethodDelete, "/api/tags/"+tag.ID, token, nil); w.Code != http.StatusNoContent {
+		t.Fatalf("expected %d, got %d", http.StatusNoContent, w.Code)
+	}
+}
+
+func TestInternalTag_ListsMemberCodes(t *testing.T) {
+	ks := authtest.NewKeySet(t)
+	r := NewRouter(Server{Store: store.NewMemoryStore(), Auth: ks.Verifier(), InternalAPIKey: "secret"})
+	token := ks.IDToken(t, "user-1", "free")
+
+	w := doJSON(t, r, http.MethodPost, "/api/tags", token, map[string]string{"name": "campaign"})
+	var tag struct {
+		ID string `json:"id"`
+	}
+	_ = json.NewDecoder(w.Body).Decode(&tag)
+	w = doJSON(t, r, http.MethodPost, "/api/qr-codes", token, map[string]any{"label": "a", "url": "https://example.com/a", "tagIds": []string{tag.ID}})
+	var code qrResp
+	_ = json.NewDecoder(w.Body).Decode(&code)
+
+	get := func(key string, owner ...string) *httptest.ResponseRecorder {
+		path := "/api/internal/tags/" + tag.ID + "/qr-codes"
+		if len(owner) > 0 {
+			path += "?ownerId=" + owner[0]
+		}
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("X-Internal-Key", key)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+	if w := get("wrong"); w.Code != http.StatusUnauthorized {
+		t.Fatalf("expected %d, got %d", http.StatusUnauthorized, w.Code)
+	}
+	if w := get("secret"); w.Code != http.StatusBadRequest {
+		t.Fatalf("expected the owner to be required, got %d", w.Code)
+	}
+	if w := get("secret", "user-2"); w.Code != http.StatusNotFound {
+		t.Fatalf("expected someone else's tag to be hidden, got %d", w.Code)
+	}
+	w = get("secret", "user-1")
+	var resp struct {
+		QrCodeIDs []string `json:"qrCodeIds"`
+	}
+	_ = json.NewDecoder(w.Body).Decode(&resp)
+	if w.Code != http.StatusOK || len(resp.QrCodeIDs) != 1 || resp.QrCodeIDs[0] != code.ID {
+		t.Fatalf("unexpected response %d %s", w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,22 @@
+package httpapi
+
+import (
+	"strings"
+	"time"
+)
+
+// normalizeTimeZone checks an IANA zone name for analytics. Empty is allowed
+// and means "inherit"; "Local" is rejected since it depends on the server.
+func normalizeTimeZone(tz string) (string, bool) {
+	tz = strings.TrimSpace(tz)
+	if tz == "" {
+		return "", true
+	}
+	if tz == "Local" {
+		return "", false
+	}
+	if _, err := time.LoadLocation(tz); err != nil {
+		return "", false
+	}
+	return tz, true
+}
@@ -0,0 +1,58 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"qr-service/internal/auth/authtest"
+	"qr-service/internal/store"
+)
+
+func TestTimeZone_CodeOverridesOwnerInPublicLookup(t *testing.T) {
+	ks := authtest.NewKeySet(t)
+	r := NewRouter(Server{Store: store.NewMemoryStore(), Auth: ks.Verifier(), InternalAPIKey: "secret"})
+	token := ks.IDToken(t, "user-1", "free")
+
+	for _, path := range []string{"/api/qr-codes", "/api/settings"} {
+		method := http.MethodPost
+		if path == "/api/settings" {
+			method = http.MethodPut
+		}
+		w := doJSON(t, r, method, path, token, map[string]any{"url": "https://example.com", "timeZone": "Mars/Olympus"})
+		if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "time_zone_invalid") {
+			t.Fatalf("%s: expected time_zone_invalid, got %d %s", path, w.Code, w.Body.String())
+		}
+	}
+
+	if w := doJSON(t, r, http.MethodPut, "/api/settings", token, map[string]any{"timeZone": "Australia/Sydney"}); w.Code != http.StatusOK {
+		t.Fatalf("expected settings saved, got %d", w.Code)
+	}
+	// The default redirect form sends only its own field.
+	if w := doJSON(t, r, http.MethodPut, "/api/settings", token, map[string]any{"defaultRedirectUrl": "https://example.com/lost"}); w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "Australia/Sydney") {
+		t.Fatalf("expected the zone kept when omitted, got %d %s", w.Code, w.Body.String())
+	}
+	// The time zone form sends only its own field too.
+	if w := doJSON(t, r, http.MethodPut, "/api/settings", token, map[string]any{"timeZone": "Australia/Sydney"}); w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "https://example.com/lost") {
+		t.Fatalf("expected the default redirect kept when omitted, got %d %s", w.Code, w.Body.String())
+	}
+	created := createAs(t, r, token, "menu")
+
+	resolveTZ := func() any {
+		w := internalGet(r, "/api/public/qr-codes/"+created.ID)
+		var out map[string]any
+		_ = json.NewDecoder(w.Body).Decode(&out)
+		return out["timeZone"]
+	}
+	if got := resolveTZ(); got != "Australia/Sydney" {
+		t.Fatalf("expected the owner's zone, got %v", got)
+	}
+
+	if w := doJSON(t, r, http.MethodPatch, "/api/qr-codes/"+created.ID, token, map[string]any{"timeZone": "Asia/Kolkata"}); w.Code != http.StatusOK {
+		t.Fatalf("expected %d, got %d", http.StatusOK, w.Code)
+	}
+	if got := resolveTZ(); got != "Asia/Kolkata" {
+		t.Fatalf("expected the code's own zone, got %v", got)
+	}
+}
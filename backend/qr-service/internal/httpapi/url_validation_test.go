@@ -7,6 +7,7 @@ import (
 	"net/http/httptest"
 	"testing"
 
+	"qr-service/internal/auth/authtest"
 	"qr-service/internal/store"
 )
 
@@ -18,11 +19,14 @@ type qrResp struct {
 
 func TestURLValidation_Create_RequiresHTTPS(t *testing.T) {
 	s := store.NewMemoryStore()
-	r := NewRouter(Server{Store: s})
+	ks := authtest.NewKeySet(t)
+	r := NewRouter(Server{Store: s, Auth: ks.Verifier()})
+	token := ks.IDToken(t, "user-1", "free")
 
 	body, _ := json.Marshal(map[string]any{"label": "x", "url": "http://example.com"})
 	req := httptest.NewRequest(http.MethodPost, "/api/qr-codes", bytes.NewReader(body))
 	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
 	w := httptest.NewRecorder()
 	r.ServeHTTP(w, req)
 
@@ -38,11 +42,14 @@ func TestURLValidation_Create_RequiresHTTPS(t *testing.T) {
 
 func TestURLValidation_Update_RequiresHTTPS(t *testing.T) {
 	s := store.NewMemoryStore()
-	r := NewRouter(Server{Store: s})
+	ks := authtest.NewKeySet(t)
+	r := NewRouter(Server{Store: s, Auth: ks.Verifier()})
+	token := ks.IDToken(t, "user-1", "free")
 
 	createBody, _ := json.Marshal(map[string]any{"label": "x", "url": "https://example.com"})
 	createReq := httptest.NewRequest(http.MethodPost, "/api/qr-codes", bytes.NewReader(createBody))
 	createReq.Header.Set("Content-Type", "application/json")
+	createReq.Header.Set("Authorization", "Bearer "+token)
 	createW := httptest.NewRecorder()
 	r.ServeHTTP(createW, createReq)
 	if createW.Code != http.StatusCreated {
@@ -57,6 +64,7 @@ func TestURLValidation_Update_RequiresHTTPS(t *testing.T) {
 	patchBody, _ := json.Marshal(map[string]any{"url": "http://example.com"})
 	patchReq := httptest.NewRequest(http.MethodPatch, "/api/qr-codes/"+created.ID, bytes.NewReader(patchBody))
 	patchReq.Header.Set("Content-Type", "application/json")
+	patchReq.Header.Set("Authorization", "Bearer "+token)
 	patchW := httptest.NewRecorder()
 	r.ServeHTTP(patchW, patchReq)
 	if patchW.Code != http.StatusBadRequest {
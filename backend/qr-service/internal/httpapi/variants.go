@@ -0,0 +1,49 @@
+package httpapi
+
+import (
+	"strings"
+
+	"qr-service/internal/model"
+)
+
+// normalizeVariants validates client-supplied split variants. It returns the
+// error code to send back, or "" when usable. An empty list turns the split off.
+func normalizeVariants(in []model.Variant) ([]model.Variant, string) {
+	if len(in) > model.MaxVariants {
+		return nil, "variants_too_many"
+	}
+	if len(in) == 1 {
+		return nil, "variants_too_few"
+	}
+	out := make([]model.Variant, 0, len(in))
+	seen := make(map[string]bool, len(in))
+	for _, v := range in {
+		v.ID = strings.TrimSpace(v.ID)
+		if !validVariantID(v.ID) {
+			return nil, "variant_id_invalid"
+		}
+		if seen[v.ID] {
+			return nil, "variant_id_duplicate"
+		}
+		seen[v.ID] = true
+		v.URL = strings.TrimSpace(v.URL)
+		if !isValidHTTPURL(v.URL) {
+			return nil, "variant_url_invalid"
+		}
+		if v.Weight < 1 || v.Weight > model.MaxVariantWeight {
+			return nil, "variant_weight_invalid"
+		}
+		out = append(out, v)
+	}
+	return out, ""
+}
+
+// validVariantID allows short IDs that are safe in cookies and CSV headers.
+func validVariantID(id string) bool {
+	if id == "" || len(id) > 32 {
+		return false
+	}
+	return !strings.ContainsFunc(id, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_')
+	})
+}
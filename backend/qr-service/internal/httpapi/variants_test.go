@@ -0,0 +1,69 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"qr-service/internal/auth/authtest"
+	"qr-service/internal/store"
+)
+
+func TestVariants_ValidationAndResolve(t *testing.T) {
+	ks := authtest.NewKeySet(t)
+	r := NewRouter(Server{Store: store.NewMemoryStore(), Auth: ks.Verifier(), InternalAPIKey: "secret"})
+	token := ks.IDToken(t, "user-1", "free")
+
+	invalid := map[string][]map[string]any{
+		"variants_too_few":       {{"id": "a", "url": "https://example.com/a", "weight": 1}},
+		"variant_id_invalid":     {{"id": "a b", "url": "https://example.com/a", "weight": 1}, {"id": "b", "url": "https://example.com/b", "weight": 1}},
+		"variant_id_duplicate":   {{"id": "a", "url": "https://example.com/a", "weight": 1}, {"id": "a", "url": "https://example.com/b", "weight": 1}},
+		"variant_url_invalid":    {{"id": "a", "url": "ftp://example.com/a", "weight": 1}, {"id": "b", "url": "https://example.com/b", "weight": 1}},
+		"variant_weight_invalid": {{"id": "a", "url": "https://example.com/a", "weight": 0}, {"id": "b", "url": "https://example.com/b", "weight": 1}},
+	}
+	for want, variants := range invalid {
+		w := doJSON(t, r, http.MethodPost, "/api/qr-codes", token, map[string]any{"url": "https://example.com", "variants": variants})
+		if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), want) {
+			t.Fatalf("expected %s, got %d %s", want, w.Code, w.Body.String())
+		}
+	}
+
+	w := doJSON(t, r, http.MethodPost, "/api/qr-codes", token, map[string]any{
+		"url":            "https://example.com",
+		"stickyVariants": true,
+		"variants": []map[string]any{
+			{"id": "a", "url": "https://example.com/a", "weight": 3},
+			{"id": "b", "url": " https://example.com/b ", "weight": 1},
+		},
+	})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected %d, got %d: %s", http.StatusCreated, w.Code, w.Body.String())
+	}
+	var created qrResp
+	_ = json.NewDecoder(w.Body).Decode(&created)
+
+	w = internalGet(r, "/api/public/qr-codes/"+created.ID)
+	var resolved struct {
+		Variants []struct {
+			ID     string `json:"id"`
+			URL    string `json:"url"`
+			Weight int    `json:"weight"`
+		} `json:"variants"`
+		StickyVariants bool `json:"stickyVariants"`
+	}
+	_ = json.NewDecoder(w.Body).Decode(&resolved)
+	if len(resolved.Variants) != 2 || resolved.Variants[1].URL != "https://example.com/b" || resolved.Variants[0].Weight != 3 || !resolved.StickyVariants {
+		t.Fatalf("unexpected resolve %d %+v", w.Code, resolved)
+	}
+
+	w = doJSON(t, r, http.MethodPatch, "/api/qr-codes/"+created.ID, token, map[string]any{"variants": []any{}})
+	var cleared struct {
+		Variants       []any `json:"variants"`
+		StickyVariants bool  `json:"stickyVariants"`
+	}
+	_ = json.NewDecoder(w.Body).Decode(&cleared)
+	if w.Code != http.StatusOK || cleared.Variants == nil || len(cleared.Variants) != 0 || !cleared.StickyVariants {
+		t.Fatalf("expected split cleared, got %d %s", w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,408 @@
+package httpapi
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"qr-service/internal/model"
+	"qr-service/internal/store"
+)
+
+const (
+	defaultDeliveryLimit = 50
+	maxDeliveryLimit     = 200
+	// maxScanEvents bounds one batch of forwarded scans.
+	maxScanEvents = 500
+)
+
+type createWebhookRequest struct {
+	URL    string   `json:"url"`
+	Events []string `json:"events"`
+	Active *bool    `json:"active,omitempty"`
+}
+
+type updateWebhookRequest struct {
+	URL    *string   `json:"url"`
+	Events *[]string `json:"events"`
+	Active *bool     `json:"active"`
+}
+
+// webhookEnvelope is the body of every delivery.
+type webhookEnvelope struct {
+	ID           string `json:"id"`
+	Type         string `json:"type"`
+	CreatedAtIso string `json:"createdAtIso"`
+	Data         any    `json:"data"`
+}
+
+// scanEvent is a scan forwarded by click-service for a qr.scanned webhook.
+// Like everything outside click-service's admin event log, it carries no
+// visitor IP or raw user agent.
+type scanEvent struct {
+	QrCodeID  string `json:"qrCodeId"`
+	Label     string `json:"label,omitempty"`
+	AtIso     string `json:"atIso"`
+	TargetURL string `json:"targetUrl"`
+	Country   string `json:"country,omitempty"`
+	Variant   string `json:"variant,omitempty"`
+	Device    string `json:"device,omitempty"`
+	OS        string `json:"os,omitempty"`
+	Browser   string `json:"browser,omitempty"`
+}
+
+// normalizeWebhookEvents de-duplicates and sorts a subscription, returning an
+// error code when it is empty or names an unknown event.
+func normalizeWebhookEvents(events []string) ([]string, string) {
+	out := make([]string, 0, len(events))
+	for _, e := range events {
+		e = strings.TrimSpace(e)
+		if !slices.Contains(model.WebhookEvents, e) {
+			return nil, "event_invalid"
+		}
+		out = append(out, e)
+	}
+	if len(out) == 0 {
+		return nil, "events_required"
+	}
+	slices.Sort(out)
+	return slices.Compact(out), ""
+}
+
+// isValidWebhookURL accepts https URLs on a named host. IP literals and
+// localhost are refused up front; the dispatcher also refuses to connect to
+// any non-public address a name resolves to.
+func isValidWebhookURL(raw string) bool {
+	if !isValidHTTPURL(raw) {
+		return false
+	}
+	u, _ := url.Parse(raw)
+	host := strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+	if host == "" || host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return false
+	}
+	if _, err := netip.ParseAddr(host); err == nil {
+		return false
+	}
+	return true
+}
+
+func newWebhookSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(b), nil
+}
+
+// newWebhookEvent wraps data in the delivery envelope for event.
+func newWebhookEvent(ownerID, event string, data any) (store.WebhookEvent, error) {
+	now := time.Now().UTC()
+	id := uuid.NewString()
+	body, err := json.Marshal(webhookEnvelope{ID: id, Type: event, CreatedAtIso: now.Format(time.RFC3339), Data: data})
+	if err != nil {
+		return store.WebhookEvent{}, err
+	}
+	return store.WebhookEvent{OwnerID: ownerID, ID: id, Event: event, Payload: body, At: now}, nil
+}
+
+// emitWebhookEvents queues events for their owners' subscribed webhooks, all
+// of them or none. Callers answer webhook_enqueue_failed when it fails, so
+// that a lost event is at least seen by the client that caused it.
+func (srv *Server) emitWebhookEvents(events ...store.WebhookEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+	if _, err := srv.Store.EnqueueWebhookEvents(events...); err != nil {
+		log.Printf("webhook: enqueue failed owner=%s event=%s count=%d err=%v", events[0].OwnerID, events[0].Event, len(events), err)
+		return err
+	}
+	return nil
+}
+
+// emitWebhookEvent queues a single event; see emitWebhookEvents.
+func (srv *Server) emitWebhookEvent(ownerID, event string, data any) error {
+	ev, err := newWebhookEvent(ownerID, event, data)
+	if err != nil {
+		return err
+	}
+	return srv.emitWebhookEvents(ev)
+}
+
+// handleWebhooks serves /api/webhooks: the caller's webhooks, and registering
+// new ones. The signing secret is only returned by the create.
+func (srv *Server) handleWebhooks(w http.ResponseWriter, r *http.Request) {
+	ownerID := userIDFromRequest(r)
+	if ownerID == "" {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "unauthorized"})
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		items, err := srv.Store.ListWebhooks(ownerID)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "list_failed"})
+			return
+		}
+		for i := range items {
+			items[i] = items[i].NormalizeForResponse()
+		}
+		writeJSON(w, http.StatusOK, items)
+	case http.MethodPost:
+		var req createWebhookRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_json"})
+			return
+		}
+		req.URL = strings.TrimSpace(req.URL)
+		if req.URL == "" {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "url_required"})
+			return
+		}
+		if !isValidWebhookURL(req.URL) {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "url_invalid"})
+			return
+		}
+		events, code := normalizeWebhookEvents(req.Events)
+		if code != "" {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": code})
+			return
+		}
+		active := true
+		if req.Active != nil {
+			active = *req.Active
+		}
+		secret, err := newWebhookSecret()
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "create_failed"})
+			return
+		}
+		created, err := srv.Store.CreateWebhook(store.WebhookInput{OwnerID: ownerID, URL: req.URL, Events: events, Active: active, Secret: secret})
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "create_failed"})
+			return
+		}
+		out := created.NormalizeForResponse()
+		out.Secret = created.Secret
+		writeJSON(w, http.StatusCreated, out)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// handleWebhook serves /api/webhooks/{id}, its delivery log at
+// /api/webhooks/{id}/deliveries and manual redelivery at
+// /api/webhooks/{id}/deliveries/{deliveryId}/redeliver.
+func (srv *Server) handleWebhook(w http.ResponseWriter, r *http.Request) {
+	rest := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/webhooks/"), "/")
+	parts := strings.Split(rest, "/")
+	if parts[0] == "" {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	ownerID := userIDFromRequest(r)
+	if ownerID == "" {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "unauthorized"})
+		return
+	}
+
+	id := parts[0]
+	switch {
+	case len(parts) == 1:
+	case len(parts) == 2 && parts[1] == "deliveries":
+		srv.handleWebhookDeliveries(w, r, ownerID, id)
+		return
+	case len(parts) == 4 && parts[1] == "deliveries" && parts[3] == "redeliver":
+		srv.handleRedeliver(w, r, ownerID, id, parts[2])
+		return
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		hook, err := srv.Store.GetWebhook(ownerID, id)
+		if err != nil {
+			if errors.Is(err, store.ErrNotFound) {
+				writeJSON(w, http.StatusNotFound, map[string]string{"error": "not_found"})
+				return
+			}
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "get_failed"})
+			return
+		}
+		writeJSON(w, http.StatusOK, hook.NormalizeForResponse())
+	case http.MethodPatch:
+		var req updateWebhookRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_json"})
+			return
+		}
+		if req.URL != nil {
+			v := strings.TrimSpace(*req.URL)
+			req.URL = &v
+			if v == "" {
+				writeJSON(w, http.StatusBadRequest, map[string]string{"error": "url_required"})
+				return
+			}
+			if !isValidWebhookURL(v) {
+				writeJSON(w, http.StatusBadRequest, map[string]string{"error": "url_invalid"})
+				return
+			}
+		}
+		if req.Events != nil {
+			events, code := normalizeWebhookEvents(*req.Events)
+			if code != "" {
+				writeJSON(w, http.StatusBadRequest, map[string]string{"error": code})
+				return
+			}
+			req.Events = &events
+		}
+		updated, err := srv.Store.UpdateWebhook(ownerID, id, store.WebhookUpdate{URL: req.URL, Events: req.Events, Active: req.Active})
+		if err != nil {
+			if errors.Is(err, store.ErrNotFound) {
+				writeJSON(w, http.StatusNotFound, map[string]string{"error": "not_found"})
+				return
+			}
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "update_failed"})
+			return
+		}
+		writeJSON(w, http.StatusOK, updated.NormalizeForResponse())
+	case http.MethodDelete:
+		if err := srv.Store.DeleteWebhook(ownerID, id); err != nil {
+			if errors.Is(err, store.ErrNotFound) {
+				writeJSON(w, http.StatusNotFound, map[string]string{"error": "not_found"})
+				return
+			}
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "delete_failed"})
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// handleWebhookDeliveries lists a webhook's deliveries newest first, with the
+// outcome of the latest attempt at each.
+func (srv *Server) handleWebhookDeliveries(w http.ResponseWriter, r *http.Request, ownerID, id string) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	limit := defaultDeliveryLimit
+	if raw := strings.TrimSpace(r.URL.Query().Get("limit")); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n <= 0 {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "limit_invalid"})
+			return
+		}
+		limit = min(n, maxDeliveryLimit)
+	}
+	items, err := srv.Store.ListWebhookDeliveries(ownerID, id, limit)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": "not_found"})
+			return
+		}
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "list_failed"})
+		return
+	}
+	for i := range items {
+		items[i] = items[i].NormalizeForResponse()
+	}
+	writeJSON(w, http.StatusOK, items)
+}
+
+// handleRedeliver queues the same payload again as a new delivery, whatever
+// became of the original.
+func (srv *Server) handleRedeliver(w http.ResponseWriter, r *http.Request, ownerID, id, deliveryID string) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	d, err := srv.Store.RedeliverWebhook(ownerID, id, deliveryID)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": "not_found"})
+			return
+		}
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "redeliver_failed"})
+		return
+	}
+	writeJSON(w, http.StatusAccepted, d.NormalizeForResponse())
+}
+
+// handleInternalWebhookEvents serves /api/internal/webhook-events, where
+// click-service forwards scans of codes whose owners have a qr.scanned
+// webhook. Scans of unknown codes are skipped.
+func (srv *Server) handleInternalWebhookEvents(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if !srv.internalKeyOK(r) {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "unauthorized"})
+		return
+	}
+	var req struct {
+		Scans []scanEvent `json:"scans"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_json"})
+		return
+	}
+	if len(req.Scans) > maxScanEvents {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "too_many_events"})
+		return
+	}
+
+	// Resolve every code before queueing anything, so a failed batch can be
+	// retried without duplicating deliveries.
+	codes := map[string]model.QrCode{}
+	for _, scan := range req.Scans {
+		if _, ok := codes[scan.QrCodeID]; ok {
+			continue
+		}
+		item, err := srv.Store.Resolve(scan.QrCodeID)
+		if err != nil && !errors.Is(err, store.ErrNotFound) {
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "get_failed"})
+			return
+		}
+		codes[scan.QrCodeID] = item
+	}
+
+	events := make([]store.WebhookEvent, 0, len(req.Scans))
+	for _, scan := range req.Scans {
+		item := codes[scan.QrCodeID]
+		if item.ID == "" {
+			continue
+		}
+		scan.Label = item.Label
+		ev, err := newWebhookEvent(item.OwnerID, model.EventQrScanned, scan)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "webhook_enqueue_failed"})
+			return
+		}
+		events = append(events, ev)
+	}
+	// The batch is queued whole, so click-service can retry it on failure.
+	if err := srv.emitWebhookEvents(events...); err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "webhook_enqueue_failed"})
+		return
+	}
+	writeJSON(w, http.StatusAccepted, map[string]int{"accepted": len(events)})
+}
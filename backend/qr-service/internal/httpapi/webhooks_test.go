@@ -0,0 +1,163 @@
+package httpapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"qr-service/internal/auth/authtest"
+	"qr-service/internal/model"
+	"qr-service/internal/store"
+)
+
+func TestWebhooks_QueueLifecycleAndScanEvents(t *testing.T) {
+	ks := authtest.NewKeySet(t)
+	r := NewRouter(Server{Store: store.NewMemoryStore(), Auth: ks.Verifier(), InternalAPIKey: "secret"})
+	alice := ks.IDToken(t, "alice", "free")
+	bob := ks.IDToken(t, "bob", "free")
+
+	for body, want := range map[string]map[string]any{
+		"url_invalid":     {"url": "http://crm.example.com/hook", "events": []string{"qr.created"}},
+		"events_required": {"url": "https://crm.example.com/hook"},
+		"event_invalid":   {"url": "https://crm.example.com/hook", "events": []string{"qr.exploded"}},
+	} {
+		if w := doJSON(t, r, http.MethodPost, "/api/webhooks", alice, want); w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), body) {
+			t.Fatalf("expected %s, got %d %s", body, w.Code, w.Body.String())
+		}
+	}
+
+	for _, u := range []string{"https://127.0.0.1/hook", "https://[::1]/hook", "https://169.254.169.254/latest/meta-data", "https://localhost:8443/hook", "https://api.localhost./hook"} {
+		if w := doJSON(t, r, http.MethodPost, "/api/webhooks", alice, map[string]any{"url": u, "events": []string{"qr.created"}}); w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "url_invalid") {
+			t.Fatalf("%s: expected url_invalid, got %d %s", u, w.Code, w.Body.String())
+		}
+	}
+
+	w := doJSON(t, r, http.MethodPost, "/api/webhooks", alice, map[string]any{"url": "https://crm.example.com/hook", "events": []string{"qr.scanned", "qr.created", "qr.deleted", "qr.created"}})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected %d, got %d %s", http.StatusCreated, w.Code, w.Body.String())
+	}
+	var hook model.Webhook
+	_ = json.NewDecoder(w.Body).Decode(&hook)
+	if !strings.HasPrefix(hook.Secret, "whsec_") || strings.Join(hook.Events, ",") != "qr.created,qr.deleted,qr.scanned" || !hook.Active {
+		t.Fatalf("unexpected webhook %+v", hook)
+	}
+	if w := doJSON(t, r, http.MethodGet, "/api/webhooks/"+hook.ID, alice, nil); strings.Contains(w.Body.String(), hook.Secret) {
+		t.Fatalf("the secret must only be shown on create: %s", w.Body.String())
+	}
+	if w := doJSON(t, r, http.MethodGet, "/api/webhooks/"+hook.ID+"/deliveries", bob, nil); w.Code != http.StatusNotFound {
+		t.Fatalf("expected someone else's webhook to be hidden, got %d", w.Code)
+	}
+
+	menu := createAs(t, r, alice, "menu")
+	createAs(t, r, bob, "flyer")
+	if w := doJSON(t, r, http.MethodPatch, "/api/qr-codes/"+menu.ID, alice, map[string]any{"label": "lunch"}); w.Code != http.StatusOK {
+		t.Fatalf("expected update, got %d", w.Code)
+	}
+
+	w = internalGet(r, "/api/public/qr-codes/"+menu.ID)
+	if !strings.Contains(w.Body.String(), `"scanWebhook":true`) {
+		t.Fatalf("expected the resolved code to ask for scans, got %s", w.Body.String())
+	}
+
+	scans, _ := json.Marshal(map[string]any{"scans": []map[string]any{
+		{"qrCodeId": menu.ID, "atIso": "2026-10-16T09:00:00Z", "targetUrl": "https://example.com/menu", "country": "DE", "device": "mobile"},
+		{"qrCodeId": "00000000-0000-0000-0000-000000000000", "atIso": "2026-10-16T09:00:00Z"},
+	}})
+	req := httptest.NewRequest(http.MethodPost, "/api/internal/webhook-events", bytes.NewReader(scans))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Internal-Key", "secret")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusAccepted || !strings.Contains(w.Body.String(), `"accepted":1`) {
+		t.Fatalf("expected one accepted scan, got %d %s", w.Code, w.Body.String())
+	}
+
+	if w := doJSON(t, r, http.MethodDelete, "/api/qr-codes/"+menu.ID, alice, nil); w.Code != http.StatusNoContent {
+		t.Fatalf("expected delete, got %d", w.Code)
+	}
+
+	w = doJSON(t, r, http.MethodGet, "/api/webhooks/"+hook.ID+"/deliveries", alice, nil)
+	var deliveries []model.WebhookDelivery
+	_ = json.NewDecoder(w.Body).Decode(&deliveries)
+	var events []string
+	for _, d := range deliveries {
+		events = append(events, d.Event)
+		if d.Status != model.DeliveryPending || d.NextAttemptAtIso == "" {
+			t.Fatalf("expected a pending delivery, got %+v", d)
+		}
+	}
+	// Newest first; bob's code and the update are not subscribed.
+	if got := strings.Join(events, ","); got != "qr.deleted,qr.scanned,qr.created" {
+		t.Fatalf("unexpected deliveries %s", got)
+	}
+	var scanned struct {
+		Type string `json:"type"`
+		Data struct {
+			QrCodeID string `json:"qrCodeId"`
+			Label    string `json:"label"`
+			Country  string `json:"country"`
+		} `json:"data"`
+	}
+	_ = json.Unmarshal(deliveries[1].Payload, &scanned)
+	if scanned.Type != model.EventQrScanned || scanned.Data.QrCodeID != menu.ID || scanned.Data.Label != "lunch" || scanned.Data.Country != "DE" {
+		t.Fatalf("unexpected scan payload %s", deliveries[1].Payload)
+	}
+
+	w = doJSON(t, r, http.MethodPost, "/api/webhooks/"+hook.ID+"/deliveries/"+deliveries[2].ID+"/redeliver", alice, nil)
+	var again model.WebhookDelivery
+	_ = json.NewDecoder(w.Body).Decode(&again)
+	if w.Code != http.StatusAccepted || again.ID == deliveries[2].ID || again.EventID != deliveries[2].EventID || !bytes.Equal(again.Payload, deliveries[2].Payload) {
+		t.Fatalf("expected a new delivery of the same event, got %d %+v", w.Code, again)
+	}
+	if w := doJSON(t, r, http.MethodPost, "/api/webhooks/"+hook.ID+"/deliveries/"+deliveries[2].ID+"/redeliver", bob, nil); w.Code != http.StatusNotFound {
+		t.Fatalf("expected bob's redelivery to be refused, got %d", w.Code)
+	}
+
+	if w := doJSON(t, r, http.MethodPatch, "/api/webhooks/"+hook.ID, alice, map[string]any{"active": false}); w.Code != http.StatusOK {
+		t.Fatalf("expected webhook update, got %d", w.Code)
+	}
+	w = internalGet(r, "/api/public/qr-codes/"+createAs(t, r, alice, "flyer").ID)
+	if strings.Contains(w.Body.String(), "scanWebhook") {
+		t.Fatalf("an inactive webhook should not ask for scans: %s", w.Body.String())
+	}
+}
+
+// failingOutboxStore loses every webhook delivery it is asked to queue.
+type failingOutboxStore struct{ store.Store }
+
+func (failingOutboxStore) EnqueueWebhookEvents(...store.WebhookEvent) (int, error) {
+	return 0, errors.New("outbox unavailable")
+}
+
+func TestWebhooks_EnqueueFailuresAreNotSwallowed(t *testing.T) {
+	ks := authtest.NewKeySet(t)
+	st := store.NewMemoryStore()
+	alice := ks.IDToken(t, "alice", "free")
+	menu := createAs(t, NewRouter(Server{Store: st, Auth: ks.Verifier()}), alice, "menu")
+	r := NewRouter(Server{Store: failingOutboxStore{st}, Auth: ks.Verifier(), InternalAPIKey: "secret"})
+
+	scans, _ := json.Marshal(map[string]any{"scans": []map[string]any{{"qrCodeId": menu.ID, "atIso": "2026-10-16T09:00:00Z"}}})
+	req := httptest.NewRequest(http.MethodPost, "/api/internal/webhook-events", bytes.NewReader(scans))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Internal-Key", "secret")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusInternalServerError || !strings.Contains(w.Body.String(), "webhook_enqueue_failed") {
+		t.Fatalf("expected the batch to be refused for click-service to retry, got %d %s", w.Code, w.Body.String())
+	}
+
+	for _, c := range []struct{ method, path string }{
+		{http.MethodPost, "/api/qr-codes"},
+		{http.MethodPatch, "/api/qr-codes/" + menu.ID},
+		{http.MethodDelete, "/api/qr-codes/" + menu.ID},
+	} {
+		w := doJSON(t, r, c.method, c.path, alice, map[string]any{"label": "flyer", "url": "https://example.com"})
+		if w.Code != http.StatusInternalServerError || !strings.Contains(w.Body.String(), "webhook_enqueue_failed") {
+			t.Fatalf("%s %s: expected webhook_enqueue_failed, got %d %s", c.method, c.path, w.Code, w.Body.String())
+		}
+	}
+}
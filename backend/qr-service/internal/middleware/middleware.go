@@ -28,6 +28,32 @@ func EnforceJSONHandler(next http.Handler) http.Handler {
 	})
 }
 
+// EnforceContentTypes is EnforceJSONHandler for endpoints that also accept
+// non-JSON bodies, such as CSV uploads. Responses are still JSON.
+func EnforceContentTypes(next http.Handler, types ...string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		switch r.Method {
+		case http.MethodPost, http.MethodPut, http.MethodPatch:
+			ct := strings.ToLower(r.Header.Get("Content-Type"))
+			allowed := false
+			for _, t := range types {
+				if ct != "" && strings.Contains(ct, t) {
+					allowed = true
+					break
+				}
+			}
+			if !allowed {
+				http.Error(w, `{"error":"content_type_unsupported"}`, http.StatusUnsupportedMediaType)
+				return
+			}
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
 // ExposeResponseHeaders configures CORS Access-Control-Expose-Headers for clients.
 func ExposeResponseHeaders(next http.Handler, headers ...string) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -1,102 +0,0 @@
-package middleware
-
-import (
-	"net/http"
-	"sync"
-	"time"
-)
-
-type RateLimiter struct {
-	visitors map[string]*visitor
-	mu       sync.RWMutex
-	rate     int
-	window   time.Duration
-}
-
-type visitor struct {
-	count     int
-	lastReset time.Time
-	mu        sync.Mutex
-}
-
-func NewRateLimiter(rate int, window time.Duration) *RateLimiter {
-	rl := &RateLimiter{
-		visitors: make(map[string]*visitor),
-		rate:     rate,
-		window:   window,
-	}
-
-	go rl.cleanup()
-
-	return rl
-}
-
-func (rl *RateLimiter) Middleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		ip := getIP(r)
-
-		if !rl.allow(ip) {
-			http.Error(w, "Rate limit exceeded", http.StatusTooManyRequests)
-			return
-		}
-
-		next.ServeHTTP(w, r)
-	})
-}
-
-func (rl *RateLimiter) allow(ip string) bool {
-	rl.mu.Lock()
-	v, exists := rl.visitors[ip]
-	if !exists {
-		v = &visitor{lastReset: time.Now()}
-		rl.visitors[ip] = v
-	}
-	rl.mu.Unlock()
-
-	v.mu.Lock()
-	defer v.mu.Unlock()
-
-	now := time.Now()
-	if now.Sub(v.lastReset) > rl.window {
-		v.count = 0
-		v.lastReset = now
-	}
-
-	if v.count >= rl.rate {
-		return false
-	}
-
-	v.count++
-	return true
-}
-
-func (rl *RateLimiter) cleanup() {
-	ticker := time.NewTicker(5 * time.Minute)
-	defer ticker.Stop()
-
-	for range ticker.C {
-		rl.mu.Lock()
-		for ip, v := range rl.visitors {
-			v.mu.Lock()
-			if time.Since(v.lastReset) > rl.window*2 {
-				delete(rl.visitors, ip)
-			}
-			v.mu.Unlock()
-		}
-		rl.mu.Unlock()
-	}
-}
-
-func getIP(r *http.Request) string {
-	forwarded := r.Header.Get("X-Forwarded-For")
-	if forwarded != "" {
-		return forwarded
-	}
-
-	realIP := r.Header.Get("X-Real-IP")
-	if realIP != "" {
-		return realIP
-	}
-
-	return r.RemoteAddr
-}
@@ -3,15 +3,72 @@ package model
 import "time"
 
 type QrCode struct {
-	ID           string    `json:"id"`
-	Label        string    `json:"label"`
-	URL          string    `json:"url"`
-	Active       bool      `json:"active"`
+	ID      string  `json:"id"`
+	OwnerID string  `json:"ownerId"`
+	Label   string  `json:"label"`
+	URL     string  `json:"url"`
+	Active  bool    `json:"active"`
+	Style   QrStyle `json:"style"`
+	// ClickCount is a running total reported by click-service; it can lag
+	// behind the click analytics by a few seconds.
+	ClickCount int64 `json:"clickCount"`
+	// TagIDs are the IDs of the owner's tags on this code, sorted.
+	TagIDs []string `json:"tagIds"`
+	// ActiveFrom and ActiveUntil optionally bound when an active code
+	// redirects; From is inclusive and Until exclusive.
+	ActiveFrom  *time.Time `json:"activeFrom,omitempty"`
+	ActiveUntil *time.Time `json:"activeUntil,omitempty"`
+	// MaxScans, when set, caps how many times the code redirects (1 makes it
+	// one-time use); click-service keeps the count. After that it goes to
+	// ExhaustedURL, or to the owner's default redirect when that is empty.
+	MaxScans     *int64 `json:"maxScans,omitempty"`
+	ExhaustedURL string `json:"exhaustedUrl,omitempty"`
+	// Rules send matching scans elsewhere; URL is the fallback when none match.
+	Rules []RedirectRule `json:"rules"`
+	// Variants split scans that match no rule between several URLs; URL is
+	// unused while a split is set. StickyVariants keeps a returning visitor
+	// on the variant they saw first.
+	Variants       []Variant `json:"variants"`
+	StickyVariants bool      `json:"stickyVariants"`
+	// TimeZone is the IANA zone click analytics are bucketed in; empty
+	// falls back to the owner's settings, then UTC.
+	TimeZone string `json:"timeZone"`
+	// ActiveNow is Active with the schedule applied, as of the response.
+	ActiveNow    bool      `json:"activeNow"`
 	CreatedAt    time.Time `json:"-"`
 	CreatedAtIso string    `json:"createdAtIso"`
 }
 
+// LiveAt reports whether the code redirects at t: it is switched on and t
+// falls inside its schedule.
+func (q QrCode) LiveAt(t time.Time) bool {
+	if !q.Active {
+		return false
+	}
+	if q.ActiveFrom != nil && t.Before(*q.ActiveFrom) {
+		return false
+	}
+	return q.ActiveUntil == nil || t.Before(*q.ActiveUntil)
+}
+
+// HoldsActiveSlot reports whether the code counts toward the active quota at
+// t. Codes scheduled to start later already hold their slot so a burst of
+// scheduled codes cannot go live over quota; expired codes give it back.
+func (q QrCode) HoldsActiveSlot(t time.Time) bool {
+	return q.Active && (q.ActiveUntil == nil || t.Before(*q.ActiveUntil))
+}
+
 func (q QrCode) NormalizeForResponse() QrCode {
 	q.CreatedAtIso = q.CreatedAt.UTC().Format(time.RFC3339)
+	q.ActiveNow = q.LiveAt(time.Now())
+	if q.TagIDs == nil {
+		q.TagIDs = []string{}
+	}
+	if q.Rules == nil {
+		q.Rules = []RedirectRule{}
+	}
+	if q.Variants == nil {
+		q.Variants = []Variant{}
+	}
 	return q
 }
@@ -0,0 +1,34 @@
+package model
+
+// Platforms a redirect rule can match, derived from the scanner's user agent.
+const (
+	PlatformIOS     = "ios"
+	PlatformAndroid = "android"
+	PlatformDesktop = "desktop"
+)
+
+// MaxRedirectRules bounds the rules on one code; they are evaluated on every scan.
+const MaxRedirectRules = 20
+
+// RedirectRule sends matching scans to URL instead of the code's own URL.
+// Rules are tried in order and the first match wins. Every condition that is
+// set must match; an empty list matches anything.
+type RedirectRule struct {
+	// Platforms are "ios", "android" or "desktop".
+	Platforms []string `json:"platforms,omitempty"`
+	// Languages match the scanner's preferred Accept-Language entry: "pt"
+	// matches pt and pt-BR, "pt-br" only pt-BR.
+	Languages []string `json:"languages,omitempty"`
+	// Countries are ISO 3166-1 alpha-2 codes, as reported by the edge proxy.
+	Countries []string `json:"countries,omitempty"`
+	// Weekdays are "mon" through "sun" in TimeZone.
+	Weekdays []string `json:"weekdays,omitempty"`
+	// TimeFrom (inclusive) and TimeUntil (exclusive) are "HH:MM" in TimeZone.
+	// A window whose end is before its start wraps past midnight.
+	TimeFrom  string `json:"timeFrom,omitempty"`
+	TimeUntil string `json:"timeUntil,omitempty"`
+	// TimeZone is an IANA zone name; empty means UTC.
+	TimeZone string `json:"timeZone,omitempty"`
+
+	URL string `json:"url"`
+}
@@ -2,4 +2,9 @@ package model
 
 type UserSettings struct {
 	DefaultRedirectURL string `json:"defaultRedirectUrl"`
+	// TimeZone is the IANA zone for analytics of codes without their own.
+	TimeZone string `json:"timeZone,omitempty"`
+	// ReportEmail receives a weekly click summary; empty turns it off. It is
+	// never served by the public settings lookup.
+	ReportEmail string `json:"reportEmail,omitempty"`
 }
@@ -0,0 +1,202 @@
+package model
+
+import (
+	"encoding/base64"
+	"math"
+	"strconv"
+	"strings"
+)
+
+const (
+	ModuleShapeSquare  = "square"
+	ModuleShapeDot     = "dot"
+	ModuleShapeRounded = "rounded"
+
+	FinderStyleSquare  = "square"
+	FinderStyleRounded = "rounded"
+	FinderStyleDot     = "dot"
+
+	DefaultQuietZone = 4
+	MaxQuietZone     = 16
+
+	// MaxLogoBytes bounds the decoded size of an embedded centre logo.
+	MaxLogoBytes = 128 * 1024
+)
+
+// QrStyle is how a code is drawn. It is stored with the code so every export
+// looks the same regardless of which client renders it.
+type QrStyle struct {
+	Foreground      string `json:"foreground"`
+	Background      string `json:"background"`
+	ModuleShape     string `json:"moduleShape"`
+	FinderStyle     string `json:"finderStyle"`
+	QuietZone       *int   `json:"quietZone,omitempty"`
+	ErrorCorrection string `json:"errorCorrection"`
+	// Logo is an optional data URI (data:image/png;base64,... or image/jpeg)
+	// drawn in the centre of the code.
+	Logo string `json:"logo,omitempty"`
+}
+
+// StyleError reports an invalid style; Code is the client-facing error string.
+type StyleError struct {
+	Code string
+}
+
+func (e *StyleError) Error() string { return e.Code }
+
+func DefaultQrStyle() QrStyle {
+	qz := DefaultQuietZone
+	return QrStyle{
+		Foreground:      "#000000",
+		Background:      "#ffffff",
+		ModuleShape:     ModuleShapeSquare,
+		FinderStyle:     FinderStyleSquare,
+		QuietZone:       &qz,
+		ErrorCorrection: "M",
+	}
+}
+
+// Normalize fills unset fields with defaults and validates the result.
+func (s QrStyle) Normalize() (QrStyle, error) {
+	def := DefaultQrStyle()
+
+	s.Foreground = strings.ToLower(strings.TrimSpace(s.Foreground))
+	if s.Foreground == "" {
+		s.Foreground = def.Foreground
+	}
+	s.Background = strings.ToLower(strings.TrimSpace(s.Background))
+	if s.Background == "" {
+		s.Background = def.Background
+	}
+	fg, ok := parseHexColor(s.Foreground)
+	if !ok {
+		return s, &StyleError{Code: "style_foreground_invalid"}
+	}
+	bg, ok := parseHexColor(s.Background)
+	if !ok {
+		return s, &StyleError{Code: "style_background_invalid"}
+	}
+	// Scanners need a clearly darker foreground; inverted or washed-out codes
+	// fail on many phones.
+	if luminance(fg) >= luminance(bg) || contrastRatio(fg, bg) < 3 {
+		return s, &StyleError{Code: "style_contrast_too_low"}
+	}
+
+	s.ModuleShape = strings.ToLower(strings.TrimSpace(s.ModuleShape))
+	switch s.ModuleShape {
+	case "":
+		s.ModuleShape = def.ModuleShape
+	case ModuleShapeSquare, ModuleShapeDot, ModuleShapeRounded:
+	default:
+		return s, &StyleError{Code: "style_module_shape_invalid"}
+	}
+
+	s.FinderStyle = strings.ToLower(strings.TrimSpace(s.FinderStyle))
+	switch s.FinderStyle {
+	case "":
+		s.FinderStyle = def.FinderStyle
+	case FinderStyleSquare, FinderStyleRounded, FinderStyleDot:
+	default:
+		return s, &StyleError{Code: "style_finder_style_invalid"}
+	}
+
+	if s.QuietZone == nil {
+		s.QuietZone = def.QuietZone
+	} else if *s.QuietZone < 0 || *s.QuietZone > MaxQuietZone {
+		return s, &StyleError{Code: "style_quiet_zone_invalid"}
+	}
+
+	s.ErrorCorrection = strings.ToUpper(strings.TrimSpace(s.ErrorCorrection))
+	switch s.ErrorCorrection {
+	case "":
+		s.ErrorCorrection = def.ErrorCorrection
+	case "L", "M", "Q", "H":
+	default:
+		return s, &StyleError{Code: "style_error_correction_invalid"}
+	}
+
+	s.Logo = strings.TrimSpace(s.Logo)
+	if s.Logo != "" {
+		if _, _, err := ParseLogo(s.Logo); err != nil {
+			return s, err
+		}
+		// A logo hides modules; only Q and H recover enough of them.
+		if s.ErrorCorrection != "Q" && s.ErrorCorrection != "H" {
+			return s, &StyleError{Code: "style_logo_requires_high_error_correction"}
+		}
+	}
+
+	return s, nil
+}
+
+// QuietZoneOrDefault returns the quiet zone in modules.
+func (s QrStyle) QuietZoneOrDefault() int {
+	if s.QuietZone == nil {
+		return DefaultQuietZone
+	}
+	return *s.QuietZone
+}
+
+// ParseLogo splits a logo data URI into its MIME type and decoded bytes.
+func ParseLogo(dataURI string) (string, []byte, error) {
+	rest, ok := strings.CutPrefix(dataURI, "data:")
+	if !ok {
+		return "", nil, &StyleError{Code: "style_logo_invalid"}
+	}
+	mime, payload, ok := strings.Cut(rest, ";base64,")
+	if !ok || (mime != "image/png" && mime != "image/jpeg") {
+		return "", nil, &StyleError{Code: "style_logo_invalid"}
+	}
+	if base64.StdEncoding.DecodedLen(len(payload)) > MaxLogoBytes+3 {
+		return "", nil, &StyleError{Code: "style_logo_too_large"}
+	}
+	raw, err := base64.StdEncoding.DecodeString(payload)
+	if err != nil {
+		return "", nil, &StyleError{Code: "style_logo_invalid"}
+	}
+	if len(raw) > MaxLogoBytes {
+		return "", nil, &StyleError{Code: "style_logo_too_large"}
+	}
+	return mime, raw, nil
+}
+
+// ParseHexColor parses #rrggbb into its components.
+func ParseHexColor(s string) (r, g, b uint8, ok bool) {
+	rgb, ok := parseHexColor(strings.ToLower(strings.TrimSpace(s)))
+	return rgb[0], rgb[1], rgb[2], ok
+}
+
+func parseHexColor(s string) ([3]uint8, bool) {
+	var rgb [3]uint8
+	if len(s) != 7 || s[0] != '#' {
+		return rgb, false
+	}
+	for i := 0; i < 3; i++ {
+		v, err := strconv.ParseUint(s[1+2*i:3+2*i], 16, 8)
+		if err != nil {
+			return rgb, false
+		}
+		rgb[i] = uint8(v)
+	}
+	return rgb, true
+}
+
+// luminance is the WCAG relative luminance of an sRGB colour.
+func luminance(rgb [3]uint8) float64 {
+	lin := func(c uint8) float64 {
+		v := float64(c) / 255
+		if v <= 0.03928 {
+			return v / 12.92
+		}
+		return math.Pow((v+0.055)/1.055, 2.4)
+	}
+	return 0.2126*lin(rgb[0]) + 0.7152*lin(rgb[1]) + 0.0722*lin(rgb[2])
+}
+
+func contrastRatio(a, b [3]uint8) float64 {
+	la, lb := luminance(a), luminance(b)
+	if la < lb {
+		la, lb = lb, la
+	}
+	return (la + 0.05) / (lb + 0.05)
+}
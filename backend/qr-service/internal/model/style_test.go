@@ -0,0 +1,41 @@
+package model
+
+import "testing"
+
+func TestQrStyle_NormalizeDefaults(t *testing.T) {
+	st, err := QrStyle{}.Normalize()
+	if err != nil {
+		t.Fatalf("normalize: %v", err)
+	}
+	def := DefaultQrStyle()
+	if st.Foreground != def.Foreground || st.ModuleShape != def.ModuleShape || st.QuietZoneOrDefault() != DefaultQuietZone || st.ErrorCorrection != "M" {
+		t.Fatalf("unexpected defaults %+v", st)
+	}
+}
+
+func TestQrStyle_NormalizeRejects(t *testing.T) {
+	tooWide := MaxQuietZone + 1
+	cases := map[string]QrStyle{
+		"style_foreground_invalid":                  {Foreground: "black"},
+		"style_contrast_too_low":                    {Foreground: "#eeeeee", Background: "#ffffff"},
+		"style_module_shape_invalid":                {ModuleShape: "star"},
+		"style_finder_style_invalid":                {FinderStyle: "star"},
+		"style_quiet_zone_invalid":                  {QuietZone: &tooWide},
+		"style_error_correction_invalid":            {ErrorCorrection: "X"},
+		"style_logo_invalid":                        {Logo: "https://example.com/logo.png", ErrorCorrection: "H"},
+		"style_logo_requires_high_error_correction": {Logo: "data:image/png;base64,iVBORw0KGgo=", ErrorCorrection: "M"},
+	}
+	for want, in := range cases {
+		_, err := in.Normalize()
+		se, ok := err.(*StyleError)
+		if !ok || se.Code != want {
+			t.Fatalf("expected %s, got %v", want, err)
+		}
+	}
+}
+
+func TestQrStyle_InvertedColoursRejected(t *testing.T) {
+	if _, err := (QrStyle{Foreground: "#ffffff", Background: "#000000"}).Normalize(); err == nil {
+		t.Fatalf("expected light-on-dark to be rejected")
+	}
+}
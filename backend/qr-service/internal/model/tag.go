@@ -0,0 +1,20 @@
+package model
+
+import "time"
+
+// Tag groups an owner's codes, e.g. one per campaign. A code can carry any
+// number of tags.
+type Tag struct {
+	ID      string `json:"id"`
+	OwnerID string `json:"ownerId"`
+	Name    string `json:"name"`
+	// QrCodeCount is how many of the owner's codes carry the tag.
+	QrCodeCount  int       `json:"qrCodeCount"`
+	CreatedAt    time.Time `json:"-"`
+	CreatedAtIso string    `json:"createdAtIso"`
+}
+
+func (t Tag) NormalizeForResponse() Tag {
+	t.CreatedAtIso = t.CreatedAt.UTC().Format(time.RFC3339)
+	return t
+}
@@ -0,0 +1,18 @@
+package model
+
+// MaxVariants bounds the split targets on one code.
+const MaxVariants = 10
+
+// MaxVariantWeight keeps weights in a range where their sum cannot overflow.
+const MaxVariantWeight = 1000
+
+// Variant is one destination of an A/B split. Scans that match no redirect
+// rule go to a variant picked at random in proportion to Weight, and
+// click-service records its ID so variants can be compared.
+type Variant struct {
+	// ID is the owner's short name for the variant, e.g. "a" or "spring-sale".
+	// It keys the analytics, so renaming it starts a fresh series.
+	ID     string `json:"id"`
+	URL    string `json:"url"`
+	Weight int    `json:"weight"`
+}
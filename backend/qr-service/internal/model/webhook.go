@@ -0,0 +1,85 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Webhook events. Lifecycle events carry the code as the API serves it;
+// qr.scanned carries the scan without the visitor's IP or user agent.
+const (
+	EventQrCreated = "qr.created"
+	EventQrUpdated = "qr.updated"
+	EventQrDeleted = "qr.deleted"
+	EventQrScanned = "qr.scanned"
+)
+
+// WebhookEvents lists every event a webhook can subscribe to.
+var WebhookEvents = []string{EventQrCreated, EventQrUpdated, EventQrDeleted, EventQrScanned}
+
+// Webhook is an owner's endpoint for event deliveries. Secret signs every
+// delivery; the API only shows it when the webhook is created.
+type Webhook struct {
+	ID      string   `json:"id"`
+	OwnerID string   `json:"ownerId"`
+	URL     string   `json:"url"`
+	Events  []string `json:"events"`
+	Active  bool     `json:"active"`
+	Secret  string   `json:"secret,omitempty"`
+
+	CreatedAt    time.Time `json:"-"`
+	CreatedAtIso string    `json:"createdAtIso"`
+}
+
+func (h Webhook) NormalizeForResponse() Webhook {
+	h.Secret = ""
+	if h.Events == nil {
+		h.Events = []string{}
+	}
+	h.CreatedAtIso = h.CreatedAt.UTC().Format(time.RFC3339)
+	return h
+}
+
+// Delivery statuses. A pending delivery is retried with backoff until it
+// succeeds or runs out of attempts and fails.
+const (
+	DeliveryPending   = "pending"
+	DeliverySucceeded = "succeeded"
+	DeliveryFailed    = "failed"
+)
+
+// WebhookDelivery is one event queued for one webhook, and the log of its
+// attempts. Payload is the exact body sent.
+type WebhookDelivery struct {
+	ID        string          `json:"id"`
+	WebhookID string          `json:"webhookId"`
+	OwnerID   string          `json:"-"`
+	EventID   string          `json:"eventId"`
+	Event     string          `json:"event"`
+	Payload   json.RawMessage `json:"payload"`
+	Status    string          `json:"status"`
+	Attempts  int             `json:"attempts"`
+	// ResponseStatus and LastError describe the latest attempt; the status is
+	// 0 when no response came back.
+	ResponseStatus int    `json:"responseStatus,omitempty"`
+	LastError      string `json:"lastError,omitempty"`
+
+	CreatedAt        time.Time  `json:"-"`
+	CreatedAtIso     string     `json:"createdAtIso"`
+	LastAttemptAt    *time.Time `json:"-"`
+	LastAttemptAtIso string     `json:"lastAttemptAtIso,omitempty"`
+	NextAttemptAt    time.Time  `json:"-"`
+	// NextAttemptAtIso is only set while the delivery is pending.
+	NextAttemptAtIso string `json:"nextAttemptAtIso,omitempty"`
+}
+
+func (d WebhookDelivery) NormalizeForResponse() WebhookDelivery {
+	d.CreatedAtIso = d.CreatedAt.UTC().Format(time.RFC3339)
+	if d.LastAttemptAt != nil {
+		d.LastAttemptAtIso = d.LastAttemptAt.UTC().Format(time.RFC3339)
+	}
+	if d.Status == DeliveryPending {
+		d.NextAttemptAtIso = d.NextAttemptAt.UTC().Format(time.RFC3339)
+	}
+	return d
+}
@@ -0,0 +1,112 @@
+package ratelimit
+
+import (
+	"context"
+	"log"
+	"sync/atomic"
+	"time"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+// PostgresStore keeps buckets in an unlogged table: a crash may reset limits,
+// which is cheaper than writing every request to the WAL.
+type PostgresStore struct {
+	db        *gorm.DB
+	lastSweep atomic.Int64
+}
+
+// takeSQL refills and takes from a bucket in one statement; a row lock
+// serializes concurrent requests for the same key. Every SET expression sees
+// the row as it was, so refill is spelled out each time it is needed.
+const takeSQL = `
+INSERT INTO rate_limit_buckets AS b (key, tokens, updated_at, expires_at, allowed)
+VALUES (@key, @burst - 1, @now, @expires, true)
+ON CONFLICT (key) DO UPDATE SET
+	allowed = ` + refillSQL + ` >= 1,
+	tokens = ` + refillSQL + ` - CASE WHEN ` + refillSQL + ` >= 1 THEN 1 ELSE 0 END,
+	updated_at = GREATEST(b.updated_at, @now),
+	expires_at = @expires
+RETURNING tokens, allowed`
+
+const refillSQL = `LEAST(@burst::float8, b.tokens + GREATEST(0, EXTRACT(EPOCH FROM (@now::timestamptz - b.updated_at))::float8) * @rate::float8)`
+
+func NewPostgresStore(ctx context.Context, databaseURL string) (*PostgresStore, error) {
+	gdb, err := gorm.Open(postgres.Open(databaseURL), &gorm.Config{})
+	if err != nil {
+		return nil, err
+	}
+	sqlDB, err := gdb.DB()
+	if err != nil {
+		return nil, err
+	}
+	// A small pool of its own, so metering never waits on the app's queries.
+	sqlDB.SetMaxOpenConns(4)
+	sqlDB.SetMaxIdleConns(4)
+	sqlDB.SetConnMaxLifetime(30 * time.Minute)
+	if err := sqlDB.PingContext(ctx); err != nil {
+		_ = sqlDB.Close()
+		return nil, err
+	}
+
+	err = gdb.WithContext(ctx).Exec(`CREATE UNLOGGED TABLE IF NOT EXISTS rate_limit_buckets (
+	key text PRIMARY KEY,
+	tokens double precision NOT NULL,
+	updated_at timestamptz NOT NULL,
+	expires_at timestamptz NOT NULL,
+	allowed boolean NOT NULL
+)`).Error
+	if err == nil {
+		err = gdb.WithContext(ctx).Exec(`CREATE INDEX IF NOT EXISTS rate_limit_buckets_expires_at_idx ON rate_limit_buckets (expires_at)`).Error
+	}
+	if err != nil {
+		_ = sqlDB.Close()
+		return nil, err
+	}
+	return &PostgresStore{db: gdb}, nil
+}
+
+func (s *PostgresStore) Take(ctx context.Context, key string, limit Limit, now time.Time) (Result, error) {
+	s.maybeSweep(now)
+
+	var row struct {
+		Tokens  float64
+		Allowed bool
+	}
+	err := s.db.WithContext(ctx).Raw(takeSQL, map[string]any{
+		"key":     key,
+		"burst":   limit.Burst,
+		"rate":    limit.Rate,
+		"now":     now.UTC(),
+		"expires": now.Add(limit.ttl()).UTC(),
+	}).Scan(&row).Error
+	if err != nil {
+		return Result{}, err
+	}
+	return result(limit, row.Tokens, row.Allowed), nil
+}
+
+// maybeSweep deletes buckets that have refilled completely, at most every
+// five minutes per instance and off the request path.
+func (s *PostgresStore) maybeSweep(now time.Time) {
+	last := s.lastSweep.Load()
+	if now.Unix()-last < 300 || !s.lastSweep.CompareAndSwap(last, now.Unix()) {
+		return
+	}
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		if err := s.db.WithContext(ctx).Exec(`DELETE FROM rate_limit_buckets WHERE expires_at < ?`, now.UTC()).Error; err != nil {
+			log.Printf("ratelimit: sweeping buckets failed: %v", err)
+		}
+	}()
+}
+
+func (s *PostgresStore) Close() error {
+	sqlDB, err := s.db.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.Close()
+}
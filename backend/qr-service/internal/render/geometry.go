@@ -0,0 +1,134 @@
+package render
+
+import "math"
+
+type shapeKind int
+
+const (
+	shapeRect shapeKind = iota
+	shapeCircle
+)
+
+// shape is one filled primitive in module coordinates, quiet zone included.
+// Shapes are painted in order, so a light shape can cut into a dark one.
+type shape struct {
+	kind       shapeKind
+	x, y, w, h float64
+	radius     float64 // corner radius for rects; w/2 for circles
+	light      bool    // filled with the background colour
+}
+
+func (s shape) contains(px, py float64) bool {
+	switch s.kind {
+	case shapeCircle:
+		cx, cy := s.x+s.w/2, s.y+s.h/2
+		return (px-cx)*(px-cx)+(py-cy)*(py-cy) <= s.radius*s.radius
+	default:
+		if px < s.x || px > s.x+s.w || py < s.y || py > s.y+s.h {
+			return false
+		}
+		r := s.radius
+		if r <= 0 {
+			return true
+		}
+		// Inside the rect; only the four corner squares need a distance check.
+		cx := math.Max(s.x+r, math.Min(px, s.x+s.w-r))
+		cy := math.Max(s.y+r, math.Min(py, s.y+s.h-r))
+		return (px-cx)*(px-cx)+(py-cy)*(py-cy) <= r*r
+	}
+}
+
+// logoBox is the square, in module coordinates, reserved for the logo.
+type logoBox struct {
+	x, y, size float64
+}
+
+// buildShapes turns the module matrix into primitives according to the style.
+func buildShapes(modules [][]bool, opts Options) []shape {
+	n := len(modules)
+	m := float64(opts.Margin)
+	style := opts.Style
+
+	reserved := func(x, y int) bool {
+		inFinder := func(fx, fy int) bool { return x >= fx && x < fx+7 && y >= fy && y < fy+7 }
+		return inFinder(0, 0) || inFinder(n-7, 0) || inFinder(0, n-7)
+	}
+
+	var box *logoBox
+	if style.Logo != nil {
+		b := centreLogoBox(n)
+		box = &b
+	}
+	hidden := func(x, y int) bool {
+		if box == nil {
+			return false
+		}
+		lx, ly := box.x, box.y
+		fx, fy := float64(x), float64(y)
+		return fx+1 > lx && fx < lx+box.size && fy+1 > ly && fy < ly+box.size
+	}
+
+	shapes := make([]shape, 0, n*n/2)
+	for _, c := range [][2]int{{0, 0}, {n - 7, 0}, {0, n - 7}} {
+		shapes = append(shapes, finderShapes(float64(c[0])+m, float64(c[1])+m, style.FinderStyle)...)
+	}
+
+	for y, row := range modules {
+		for x := 0; x < len(row); x++ {
+			if !row[x] || reserved(x, y) || hidden(x, y) {
+				continue
+			}
+			fx, fy := float64(x)+m, float64(y)+m
+			switch style.ModuleShape {
+			case ShapeDot:
+				shapes = append(shapes, shape{kind: shapeCircle, x: fx + 0.05, y: fy + 0.05, w: 0.9, h: 0.9, radius: 0.45})
+			case ShapeRounded:
+				shapes = append(shapes, shape{kind: shapeRect, x: fx, y: fy, w: 1, h: 1, radius: 0.3})
+			default:
+				// Merge horizontal runs of square modules to keep vector output small.
+				run := 1
+				for x+run < len(row) && row[x+run] && !reserved(x+run, y) && !hidden(x+run, y) {
+					run++
+				}
+				shapes = append(shapes, shape{kind: shapeRect, x: fx, y: fy, w: float64(run), h: 1})
+				x += run - 1
+			}
+		}
+	}
+	return shapes
+}
+
+// finderShapes draws one 7x7 position marker with its top-left corner at (x, y).
+func finderShapes(x, y float64, style string) []shape {
+	switch style {
+	case ShapeDot:
+		return []shape{
+			{kind: shapeCircle, x: x, y: y, w: 7, h: 7, radius: 3.5},
+			{kind: shapeCircle, x: x + 1, y: y + 1, w: 5, h: 5, radius: 2.5, light: true},
+			{kind: shapeCircle, x: x + 2, y: y + 2, w: 3, h: 3, radius: 1.5},
+		}
+	case ShapeRounded:
+		return []shape{
+			{kind: shapeRect, x: x, y: y, w: 7, h: 7, radius: 2},
+			{kind: shapeRect, x: x + 1, y: y + 1, w: 5, h: 5, radius: 1.4, light: true},
+			{kind: shapeRect, x: x + 2, y: y + 2, w: 3, h: 3, radius: 0.9},
+		}
+	default:
+		return []shape{
+			{kind: shapeRect, x: x, y: y, w: 7, h: 7},
+			{kind: shapeRect, x: x + 1, y: y + 1, w: 5, h: 5, light: true},
+			{kind: shapeRect, x: x + 2, y: y + 2, w: 3, h: 3},
+		}
+	}
+}
+
+// centreLogoBox sizes the logo to roughly a fifth of the symbol, which H-level
+// error correction recovers comfortably. Coordinates exclude the quiet zone.
+func centreLogoBox(n int) logoBox {
+	size := math.Round(float64(n) * 0.22)
+	if int(size)%2 != n%2 {
+		size++
+	}
+	start := (float64(n) - size) / 2
+	return logoBox{x: start, y: start, size: size}
+}
@@ -0,0 +1,31 @@
+package render
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	_ "image/jpeg"
+	_ "image/png"
+)
+
+// MaxLogoDimension bounds the decoded logo in pixels; larger images are
+// rejected rather than resampled on every render.
+const MaxLogoDimension = 1024
+
+var ErrInvalidLogo = errors.New("invalid logo image")
+
+// DecodeLogo decodes a PNG or JPEG logo.
+func DecodeLogo(raw []byte) (image.Image, error) {
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(raw))
+	if err != nil {
+		return nil, ErrInvalidLogo
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 || cfg.Width > MaxLogoDimension || cfg.Height > MaxLogoDimension {
+		return nil, ErrInvalidLogo
+	}
+	img, _, err := image.Decode(bytes.NewReader(raw))
+	if err != nil {
+		return nil, ErrInvalidLogo
+	}
+	return img, nil
+}
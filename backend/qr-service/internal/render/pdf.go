@@ -0,0 +1,149 @@
+package render
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+)
+
+// kappa places cubic Bezier control points so four curves approximate a circle.
+const kappa = 0.5523
+
+// writePDF emits a minimal single-page PDF with the code drawn as vector
+// shapes, so print pipelines can scale it without loss.
+func writePDF(w io.Writer, shapes []shape, l layout, style Style) error {
+	size := float64(l.size)
+	unit := float64(l.size) / float64(l.count+2*l.margin)
+	// PDF's origin is bottom-left; module coordinates grow downwards.
+	px := func(v float64) float64 { return v * unit }
+	py := func(v float64) float64 { return size - v*unit }
+
+	var content bytes.Buffer
+	fmt.Fprintf(&content, "%s 0 0 %d %d re f\n", pdfColor(style.Background), l.size, l.size)
+	current := color.RGBA{}
+	for _, s := range shapes {
+		c := style.Foreground
+		if s.light {
+			c = style.Background
+		}
+		if c != current {
+			fmt.Fprintf(&content, "%s\n", pdfColor(c))
+			current = c
+		}
+		x0, y0 := px(s.x), py(s.y+s.h)
+		w, h := s.w*unit, s.h*unit
+		if s.kind == shapeRect && s.radius == 0 {
+			fmt.Fprintf(&content, "%.3f %.3f %.3f %.3f re f\n", x0, y0, w, h)
+			continue
+		}
+		pdfRoundedRect(&content, x0, y0, w, h, s.radius*unit)
+	}
+
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		"", // page, filled in below once resources are known
+		"", // content stream
+	}
+	resources := "<< >>"
+	if style.Logo != nil {
+		img, mask, err := pdfImage(style.Logo)
+		if err != nil {
+			return err
+		}
+		objects = append(objects, img, mask)
+		b := style.Logo.Bounds()
+		box := centreLogoBox(l.count)
+		m := float64(l.margin)
+		inner := (box.size - 1) * unit
+		// Fit the logo inside the box, preserving its aspect ratio.
+		ratio := min(inner/float64(b.Dx()), inner/float64(b.Dy()))
+		lw, lh := float64(b.Dx())*ratio, float64(b.Dy())*ratio
+		lx := px(box.x+m+0.5) + (inner-lw)/2
+		ly := py(box.y+m+box.size-0.5) + (inner-lh)/2
+		fmt.Fprintf(&content, "q %.3f 0 0 %.3f %.3f %.3f cm /Im1 Do Q\n", lw, lh, lx, ly)
+		resources = "<< /XObject << /Im1 5 0 R >> >>"
+	}
+	objects[2] = fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %d %d] /Contents 4 0 R /Resources %s >>", l.size, l.size, resources)
+	objects[3] = fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", content.Len(), content.String())
+
+	var out bytes.Buffer
+	out.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, obj := range objects {
+		offsets[i] = out.Len()
+		fmt.Fprintf(&out, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+	xref := out.Len()
+	fmt.Fprintf(&out, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&out, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&out, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+
+	_, err := w.Write(out.Bytes())
+	return err
+}
+
+func pdfColor(c color.RGBA) string {
+	return fmt.Sprintf("%.3f %.3f %.3f rg", float64(c.R)/255, float64(c.G)/255, float64(c.B)/255)
+}
+
+// pdfRoundedRect fills a rect with corner radius r; a square with r = w/2 is a circle.
+func pdfRoundedRect(b *bytes.Buffer, x, y, w, h, r float64) {
+	r = min(r, w/2, h/2)
+	k := r * kappa
+	fmt.Fprintf(b, "%.3f %.3f m\n", x+r, y)
+	fmt.Fprintf(b, "%.3f %.3f l\n", x+w-r, y)
+	fmt.Fprintf(b, "%.3f %.3f %.3f %.3f %.3f %.3f c\n", x+w-r+k, y, x+w, y+r-k, x+w, y+r)
+	fmt.Fprintf(b, "%.3f %.3f l\n", x+w, y+h-r)
+	fmt.Fprintf(b, "%.3f %.3f %.3f %.3f %.3f %.3f c\n", x+w, y+h-r+k, x+w-r+k, y+h, x+w-r, y+h)
+	fmt.Fprintf(b, "%.3f %.3f l\n", x+r, y+h)
+	fmt.Fprintf(b, "%.3f %.3f %.3f %.3f %.3f %.3f c\n", x+r-k, y+h, x, y+h-r+k, x, y+h-r)
+	fmt.Fprintf(b, "%.3f %.3f l\n", x, y+r)
+	fmt.Fprintf(b, "%.3f %.3f %.3f %.3f %.3f %.3f c\n", x, y+r-k, x+r-k, y, x+r, y)
+	b.WriteString("f\n")
+}
+
+// pdfImage encodes img as an RGB image XObject (object 5) with its alpha
+// channel as a soft mask (object 6).
+func pdfImage(img image.Image) (string, string, error) {
+	b := img.Bounds()
+	rgb := make([]byte, 0, b.Dx()*b.Dy()*3)
+	alpha := make([]byte, 0, b.Dx()*b.Dy())
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			c := color.NRGBAModel.Convert(img.At(x, y)).(color.NRGBA)
+			rgb = append(rgb, c.R, c.G, c.B)
+			alpha = append(alpha, c.A)
+		}
+	}
+	rgbZ, err := deflate(rgb)
+	if err != nil {
+		return "", "", err
+	}
+	alphaZ, err := deflate(alpha)
+	if err != nil {
+		return "", "", err
+	}
+	obj := fmt.Sprintf("<< /Type /XObject /Subtype /Image /Width %d /Height %d /ColorSpace /DeviceRGB /BitsPerComponent 8 /Filter /FlateDecode /SMask 6 0 R /Length %d >>\nstream\n%s\nendstream",
+		b.Dx(), b.Dy(), len(rgbZ), rgbZ)
+	mask := fmt.Sprintf("<< /Type /XObject /Subtype /Image /Width %d /Height %d /ColorSpace /DeviceGray /BitsPerComponent 8 /Filter /FlateDecode /Length %d >>\nstream\n%s\nendstream",
+		b.Dx(), b.Dy(), len(alphaZ), alphaZ)
+	return obj, mask, nil
+}
+
+func deflate(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	zw := zlib.NewWriter(&buf)
+	if _, err := zw.Write(data); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,72 @@
+package render
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"io"
+	"math"
+)
+
+func writePNG(w io.Writer, shapes []shape, l layout, style Style) error {
+	img := image.NewRGBA(image.Rect(0, 0, l.size, l.size))
+	draw.Draw(img, img.Bounds(), &image.Uniform{C: style.Background}, image.Point{}, draw.Src)
+
+	// Integer module sizes keep edges crisp; any leftover pixels become extra
+	// quiet zone split evenly on both sides.
+	total := l.count + 2*l.margin
+	px := l.size / total
+	if px < 1 {
+		px = 1
+	}
+	pad := (l.size - px*total) / 2
+	toPixel := func(v float64) int { return pad + int(math.Round(v*float64(px))) }
+	toModule := func(p int) float64 { return (float64(p-pad) + 0.5) / float64(px) }
+
+	for _, s := range shapes {
+		c := style.Foreground
+		if s.light {
+			c = style.Background
+		}
+		x0, y0 := max(toPixel(s.x), 0), max(toPixel(s.y), 0)
+		x1, y1 := min(toPixel(s.x+s.w), l.size), min(toPixel(s.y+s.h), l.size)
+		for yy := y0; yy < y1; yy++ {
+			for xx := x0; xx < x1; xx++ {
+				if s.kind == shapeRect && s.radius == 0 || s.contains(toModule(xx), toModule(yy)) {
+					img.SetRGBA(xx, yy, c)
+				}
+			}
+		}
+	}
+
+	if style.Logo != nil {
+		b := centreLogoBox(l.count)
+		m := float64(l.margin)
+		dst := image.Rect(toPixel(b.x+m+0.5), toPixel(b.y+m+0.5), toPixel(b.x+m+b.size-0.5), toPixel(b.y+m+b.size-0.5))
+		draw.Draw(img, dst, scaleNearest(style.Logo, dst.Dx(), dst.Dy()), image.Point{}, draw.Over)
+	}
+
+	return png.Encode(w, img)
+}
+
+// scaleNearest fits src into a w x h box, preserving its aspect ratio.
+func scaleNearest(src image.Image, w, h int) image.Image {
+	sb := src.Bounds()
+	if sb.Dx() == 0 || sb.Dy() == 0 || w <= 0 || h <= 0 {
+		return image.NewRGBA(image.Rect(0, 0, w, h))
+	}
+	ratio := math.Min(float64(w)/float64(sb.Dx()), float64(h)/float64(sb.Dy()))
+	dw, dh := int(float64(sb.Dx())*ratio), int(float64(sb.Dy())*ratio)
+	ox, oy := (w-dw)/2, (h-dh)/2
+
+	out := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < dh; y++ {
+		sy := sb.Min.Y + int(float64(y)/ratio)
+		for x := 0; x < dw; x++ {
+			sx := sb.Min.X + int(float64(x)/ratio)
+			out.Set(ox+x, oy+y, color.RGBAModel.Convert(src.At(sx, sy)))
+		}
+	}
+	return out
+}
@@ -0,0 +1,206 @@
+// Package render turns a short link into a QR code image (PNG, SVG or PDF).
+package render
+
+import (
+	"errors"
+	"image"
+	"image/color"
+	"io"
+	"strings"
+
+	qrcode "github.com/skip2/go-qrcode"
+)
+
+type Format string
+
+const (
+	FormatPNG Format = "png"
+	FormatSVG Format = "svg"
+	FormatPDF Format = "pdf"
+)
+
+const (
+	DefaultSize   = 512
+	MinSize       = 64
+	MaxSize       = 4096
+	DefaultMargin = 4
+	MaxMargin     = 16
+	DefaultECC    = "M"
+)
+
+var (
+	ErrInvalidFormat = errors.New("invalid format")
+	ErrInvalidSize   = errors.New("invalid size")
+	ErrInvalidMargin = errors.New("invalid margin")
+	ErrInvalidECC    = errors.New("invalid error correction level")
+)
+
+// Options controls how a code is drawn. Zero values fall back to the defaults above.
+type Options struct {
+	// Size is the output width and height: pixels for PNG, user units for SVG,
+	// points for PDF.
+	Size int
+	// Margin is the quiet zone around the symbol, in modules.
+	Margin int
+	// ECC is the error correction level: L, M, Q or H.
+	ECC string
+	// Style controls colours and shapes; the zero value draws a plain
+	// black-on-white code.
+	Style Style
+}
+
+const (
+	ShapeSquare  = "square"
+	ShapeDot     = "dot"
+	ShapeRounded = "rounded"
+)
+
+type Style struct {
+	Foreground color.RGBA
+	Background color.RGBA
+	// ModuleShape is how data modules are drawn: square, dot or rounded.
+	ModuleShape string
+	// FinderStyle is how the three position markers are drawn: square, dot or rounded.
+	FinderStyle string
+	// Logo, when set, is drawn over a cleared area in the centre of the code.
+	Logo image.Image
+}
+
+func (s Style) normalize() Style {
+	if s.Foreground.A == 0 {
+		s.Foreground = color.RGBA{A: 0xff}
+	}
+	if s.Background.A == 0 {
+		s.Background = color.RGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff}
+	}
+	if s.ModuleShape == "" {
+		s.ModuleShape = ShapeSquare
+	}
+	if s.FinderStyle == "" {
+		s.FinderStyle = ShapeSquare
+	}
+	return s
+}
+
+func ParseFormat(raw string) (Format, error) {
+	switch Format(strings.ToLower(strings.TrimSpace(raw))) {
+	case "", FormatPNG:
+		return FormatPNG, nil
+	case FormatSVG:
+		return FormatSVG, nil
+	case FormatPDF:
+		return FormatPDF, nil
+	default:
+		return "", ErrInvalidFormat
+	}
+}
+
+// ContentType returns the MIME type for f.
+func (f Format) ContentType() string {
+	switch f {
+	case FormatSVG:
+		return "image/svg+xml"
+	case FormatPDF:
+		return "application/pdf"
+	default:
+		return "image/png"
+	}
+}
+
+// Normalize fills in defaults and validates o.
+func (o Options) Normalize() (Options, error) {
+	if o.Size == 0 {
+		o.Size = DefaultSize
+	}
+	if o.Size < MinSize || o.Size > MaxSize {
+		return o, ErrInvalidSize
+	}
+	if o.Margin < 0 || o.Margin > MaxMargin {
+		return o, ErrInvalidMargin
+	}
+	o.ECC = strings.ToUpper(strings.TrimSpace(o.ECC))
+	if o.ECC == "" {
+		o.ECC = DefaultECC
+	}
+	if _, err := recoveryLevel(o.ECC); err != nil {
+		return o, err
+	}
+	o.Style = o.Style.normalize()
+	return o, nil
+}
+
+func recoveryLevel(ecc string) (qrcode.RecoveryLevel, error) {
+	switch ecc {
+	case "L":
+		return qrcode.Low, nil
+	case "M":
+		return qrcode.Medium, nil
+	case "Q":
+		return qrcode.High, nil
+	case "H":
+		return qrcode.Highest, nil
+	default:
+		return 0, ErrInvalidECC
+	}
+}
+
+// Matrix encodes content and returns its modules without a quiet zone;
+// matrix[y][x] is true for a dark module.
+func Matrix(content, ecc string) ([][]bool, error) {
+	level, err := recoveryLevel(ecc)
+	if err != nil {
+		return nil, err
+	}
+	q, err := qrcode.New(content, level)
+	if err != nil {
+		return nil, err
+	}
+	q.DisableBorder = true
+	return q.Bitmap(), nil
+}
+
+// Render encodes content and writes it to w in the given format.
+func Render(w io.Writer, content string, format Format, opts Options) error {
+	opts, err := opts.Normalize()
+	if err != nil {
+		return err
+	}
+	modules, err := Matrix(content, opts.ECC)
+	if err != nil {
+		return err
+	}
+	l := newLayout(len(modules), opts)
+	shapes := buildShapes(modules, opts)
+
+	switch format {
+	case FormatPNG:
+		return writePNG(w, shapes, l, opts.Style)
+	case FormatSVG:
+		return writeSVG(w, shapes, l, opts.Style)
+	case FormatPDF:
+		return writePDF(w, shapes, l, opts.Style)
+	default:
+		return ErrInvalidFormat
+	}
+}
+
+// layout maps module coordinates onto the output canvas.
+type layout struct {
+	size   int // canvas width/height
+	margin int // quiet zone in modules
+	count  int // modules per side, excluding the quiet zone
+	scale  float64
+	offset float64 // canvas offset of module (0, 0)
+}
+
+func newLayout(count int, opts Options) layout {
+	total := count + 2*opts.Margin
+	scale := float64(opts.Size) / float64(total)
+	return layout{
+		size:   opts.Size,
+		margin: opts.Margin,
+		count:  count,
+		scale:  scale,
+		offset: float64(opts.Margin) * scale,
+	}
+}
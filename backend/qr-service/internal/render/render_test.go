@@ -0,0 +1,190 @@
+package render
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"strings"
+	"testing"
+)
+
+const testLink = "https://click.example.com/r/0b6f3f9e-8a4c-4a55-9d4e-4f1b3c2a1d00"
+
+func TestMatrix_HasFinderPatterns(t *testing.T) {
+	m, err := Matrix(testLink, "M")
+	if err != nil {
+		t.Fatalf("matrix: %v", err)
+	}
+	n := len(m)
+	if n < 21 || (n-17)%4 != 0 {
+		t.Fatalf("unexpected symbol size %d", n)
+	}
+	// Each finder pattern has a dark outer ring and a light separator ring.
+	for _, c := range [][2]int{{0, 0}, {n - 7, 0}, {0, n - 7}} {
+		x, y := c[0], c[1]
+		if !m[y][x] || !m[y+6][x+6] || m[y+1][x+1] || !m[y+3][x+3] {
+			t.Fatalf("finder pattern missing at %v", c)
+		}
+	}
+}
+
+func TestRender_PNG(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Render(&buf, testLink, FormatPNG, Options{Size: 300, Margin: 4}); err != nil {
+		t.Fatalf("render: %v", err)
+	}
+	img, err := png.Decode(&buf)
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if b := img.Bounds(); b.Dx() != 300 || b.Dy() != 300 {
+		t.Fatalf("expected 300x300, got %v", b)
+	}
+	if !sameColor(img.At(0, 0), color.White) {
+		t.Fatalf("expected light quiet zone")
+	}
+	if !sameColor(img.At(150, 150), color.White) && !sameColor(img.At(150, 150), color.Black) {
+		t.Fatalf("expected black or white pixels only")
+	}
+}
+
+func TestRender_SVGAndPDF(t *testing.T) {
+	var svg bytes.Buffer
+	if err := Render(&svg, testLink, FormatSVG, Options{}); err != nil {
+		t.Fatalf("svg: %v", err)
+	}
+	if !strings.Contains(svg.String(), `<svg xmlns="http://www.w3.org/2000/svg" width="512"`) {
+		t.Fatalf("unexpected svg header: %.120s", svg.String())
+	}
+
+	var pdf bytes.Buffer
+	if err := Render(&pdf, testLink, FormatPDF, Options{Size: 200}); err != nil {
+		t.Fatalf("pdf: %v", err)
+	}
+	out := pdf.String()
+	if !strings.HasPrefix(out, "%PDF-1.4") || !strings.HasSuffix(out, "%%EOF\n") {
+		t.Fatalf("not a pdf")
+	}
+	if !strings.Contains(out, "/MediaBox [0 0 200 200]") {
+		t.Fatalf("expected 200pt page")
+	}
+}
+
+func TestOptions_Normalize(t *testing.T) {
+	if _, err := (Options{Size: 10}).Normalize(); err != ErrInvalidSize {
+		t.Fatalf("expected invalid size, got %v", err)
+	}
+	if _, err := (Options{Margin: -1}).Normalize(); err != ErrInvalidMargin {
+		t.Fatalf("expected invalid margin, got %v", err)
+	}
+	if _, err := (Options{ECC: "Z"}).Normalize(); err != ErrInvalidECC {
+		t.Fatalf("expected invalid ecc, got %v", err)
+	}
+	o, err := (Options{ECC: "h"}).Normalize()
+	if err != nil || o.ECC != "H" || o.Size != DefaultSize {
+		t.Fatalf("unexpected normalized options %+v (%v)", o, err)
+	}
+}
+
+func sameColor(a, b color.Color) bool {
+	ar, ag, ab, aa := a.RGBA()
+	br, bg, bb, ba := b.RGBA()
+	return ar == br && ag == bg && ab == bb && aa == ba
+}
+
+func TestRender_StyledPNG(t *testing.T) {
+	navy := color.RGBA{R: 0x1a, G: 0x23, B: 0x7e, A: 0xff}
+	cream := color.RGBA{R: 0xff, G: 0xf8, B: 0xe7, A: 0xff}
+	opts := Options{Size: 400, Margin: 2, Style: Style{Foreground: navy, Background: cream, ModuleShape: ShapeDot, FinderStyle: ShapeRounded}}
+
+	var buf bytes.Buffer
+	if err := Render(&buf, testLink, FormatPNG, opts); err != nil {
+		t.Fatalf("render: %v", err)
+	}
+	img, err := png.Decode(&buf)
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if !sameColor(img.At(0, 0), cream) {
+		t.Fatalf("expected background colour in the quiet zone, got %v", img.At(0, 0))
+	}
+	foundNavy := false
+	b := img.Bounds()
+	for y := b.Min.Y; y < b.Max.Y && !foundNavy; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			if sameColor(img.At(x, y), navy) {
+				foundNavy = true
+				break
+			}
+		}
+	}
+	if !foundNavy {
+		t.Fatalf("expected foreground colour in the symbol")
+	}
+}
+
+func TestRender_SVGShapes(t *testing.T) {
+	var buf bytes.Buffer
+	style := Style{ModuleShape: ShapeDot, FinderStyle: ShapeDot}
+	if err := Render(&buf, testLink, FormatSVG, Options{Style: style}); err != nil {
+		t.Fatalf("render: %v", err)
+	}
+	out := buf.String()
+	if !strings.Contains(out, "<circle") {
+		t.Fatalf("expected circles for dot modules")
+	}
+	if strings.Contains(out, "<image") {
+		t.Fatalf("unexpected logo without one configured")
+	}
+}
+
+func TestRender_Logo(t *testing.T) {
+	logo := image.NewRGBA(image.Rect(0, 0, 16, 16))
+	red := color.RGBA{R: 0xff, A: 0xff}
+	for y := 0; y < 16; y++ {
+		for x := 0; x < 16; x++ {
+			logo.SetRGBA(x, y, red)
+		}
+	}
+	opts := Options{Size: 400, ECC: "H", Style: Style{Logo: logo}}
+
+	var pngBuf bytes.Buffer
+	if err := Render(&pngBuf, testLink, FormatPNG, opts); err != nil {
+		t.Fatalf("png: %v", err)
+	}
+	img, err := png.Decode(&pngBuf)
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if !sameColor(img.At(200, 200), red) {
+		t.Fatalf("expected logo in the centre, got %v", img.At(200, 200))
+	}
+
+	var svg bytes.Buffer
+	if err := Render(&svg, testLink, FormatSVG, opts); err != nil {
+		t.Fatalf("svg: %v", err)
+	}
+	if !strings.Contains(svg.String(), `href="data:image/png;base64,`) {
+		t.Fatalf("expected embedded logo in svg")
+	}
+
+	var pdf bytes.Buffer
+	if err := Render(&pdf, testLink, FormatPDF, opts); err != nil {
+		t.Fatalf("pdf: %v", err)
+	}
+	if !strings.Contains(pdf.String(), "/Im1 Do") || !strings.Contains(pdf.String(), "/SMask 6 0 R") {
+		t.Fatalf("expected logo image in pdf")
+	}
+}
+
+func TestDecodeLogo(t *testing.T) {
+	var buf bytes.Buffer
+	_ = png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 8, 8)))
+	if _, err := DecodeLogo(buf.Bytes()); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if _, err := DecodeLogo([]byte("not an image")); err != ErrInvalidLogo {
+		t.Fatalf("expected invalid logo, got %v", err)
+	}
+}
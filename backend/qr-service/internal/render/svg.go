@@ -0,0 +1,62 @@
+package render
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"image/color"
+	"image/png"
+	"io"
+	"strings"
+)
+
+func writeSVG(w io.Writer, shapes []shape, l layout, style Style) error {
+	total := l.count + 2*l.margin
+	fg, bg := hexColor(style.Foreground), hexColor(style.Background)
+
+	var b strings.Builder
+	// The viewBox is in modules so the drawing scales cleanly to any size.
+	fmt.Fprintf(&b, `<?xml version="1.0" encoding="UTF-8"?>`+"\n")
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" shape-rendering="crispEdges">`, l.size, l.size, total, total)
+	fmt.Fprintf(&b, `<rect width="%d" height="%d" fill="%s"/>`, total, total, bg)
+
+	for _, s := range shapes {
+		fill := fg
+		if s.light {
+			fill = bg
+		}
+		switch {
+		case s.kind == shapeCircle:
+			fmt.Fprintf(&b, `<circle cx="%s" cy="%s" r="%s" fill="%s"/>`, num(s.x+s.w/2), num(s.y+s.h/2), num(s.radius), fill)
+		case s.radius > 0:
+			fmt.Fprintf(&b, `<rect x="%s" y="%s" width="%s" height="%s" rx="%s" fill="%s"/>`, num(s.x), num(s.y), num(s.w), num(s.h), num(s.radius), fill)
+		default:
+			fmt.Fprintf(&b, `<rect x="%s" y="%s" width="%s" height="%s" fill="%s"/>`, num(s.x), num(s.y), num(s.w), num(s.h), fill)
+		}
+	}
+
+	if style.Logo != nil {
+		var logo bytes.Buffer
+		if err := png.Encode(&logo, style.Logo); err != nil {
+			return err
+		}
+		box := centreLogoBox(l.count)
+		m := float64(l.margin)
+		fmt.Fprintf(&b, `<image x="%s" y="%s" width="%s" height="%s" preserveAspectRatio="xMidYMid meet" href="data:image/png;base64,%s"/>`,
+			num(box.x+m+0.5), num(box.y+m+0.5), num(box.size-1), num(box.size-1), base64.StdEncoding.EncodeToString(logo.Bytes()))
+	}
+	b.WriteString(`</svg>`)
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func hexColor(c color.RGBA) string {
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}
+
+// num formats a coordinate without trailing zeros.
+func num(v float64) string {
+	s := strings.TrimRight(fmt.Sprintf("%.3f", v), "0")
+	return strings.TrimSuffix(s, ".")
+}
@@ -0,0 +1,83 @@
+package store
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"qr-service/internal/model"
+)
+
+func (q ListQuery) normalize() ListQuery {
+	switch {
+	case q.Limit <= 0:
+		q.Limit = DefaultListLimit
+	case q.Limit > MaxListLimit:
+		q.Limit = MaxListLimit
+	}
+	if q.Sort == "" {
+		q.Sort = SortCreated
+	}
+	q.Search = strings.TrimSpace(q.Search)
+	q.TagIDs = normalizeTagIDs(q.TagIDs)
+	return q
+}
+
+// listCursor is the keyset position of the last item on a page: its sort key
+// plus its ID as a tie-breaker. Sort and Asc are carried along so a cursor
+// cannot silently be replayed against a different ordering.
+type listCursor struct {
+	Sort    ListSort  `json:"s"`
+	Asc     bool      `json:"a,omitempty"`
+	Created time.Time `json:"c,omitempty"`
+	Label   string    `json:"l,omitempty"`
+	Clicks  int64     `json:"k,omitempty"`
+	ID      string    `json:"i"`
+}
+
+func encodeCursor(q ListQuery, last model.QrCode) string {
+	c := listCursor{Sort: q.Sort, Asc: q.Asc, ID: last.ID}
+	switch q.Sort {
+	case SortLabel:
+		c.Label = sortLabel(last.Label)
+	case SortClicks:
+		c.Clicks = last.ClickCount
+	default:
+		c.Created = last.CreatedAt
+	}
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// decodeCursor returns nil for the first page.
+func decodeCursor(q ListQuery) (*listCursor, error) {
+	if q.Cursor == "" {
+		return nil, nil
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(q.Cursor)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var c listCursor
+	if err := json.Unmarshal(raw, &c); err != nil || c.ID == "" || c.Sort != q.Sort || c.Asc != q.Asc {
+		return nil, ErrInvalidCursor
+	}
+	return &c, nil
+}
+
+// page trims a result fetched with Limit+1 rows and sets NextCursor when the
+// extra row shows there is more.
+func page(q ListQuery, items []model.QrCode) ListPage {
+	if len(items) <= q.Limit {
+		return ListPage{Items: items}
+	}
+	items = items[:q.Limit]
+	return ListPage{Items: items, NextCursor: encodeCursor(q, items[len(items)-1])}
+}
+
+// sortLabel is the key labels are ordered by, so "apple" and "Banana" sort
+// the way people expect.
+func sortLabel(label string) string {
+	return strings.ToLower(label)
+}
@@ -1,7 +1,10 @@
 package store
 
 import (
+	"cmp"
+	"slices"
 	"sort"
+	"strings"
 	"sync"
 	"time"
 
@@ -13,31 +16,106 @@ import (
 type MemoryStore struct {
 	mu       sync.RWMutex
 	byID     map[string]model.QrCode
-	settings model.UserSettings
+	settings map[string]model.UserSettings
+	tags     map[string]model.Tag
+
+	webhooks   map[string]model.Webhook
+	deliveries map[string]model.WebhookDelivery
 }
 
 func NewMemoryStore() *MemoryStore {
-	return &MemoryStore{byID: make(map[string]model.QrCode)}
+	return &MemoryStore{byID: make(map[string]model.QrCode), settings: make(map[string]model.UserSettings), tags: make(map[string]model.Tag), webhooks: make(map[string]model.Webhook), deliveries: make(map[string]model.WebhookDelivery)}
 }
 
-func (s *MemoryStore) List() []model.QrCode {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
+func (s *MemoryStore) List(ownerID string, query ListQuery) (ListPage, error) {
+	query = query.normalize()
+	cur, err := decodeCursor(query)
+	if err != nil {
+		return ListPage{}, err
+	}
 
+	s.mu.RLock()
 	items := make([]model.QrCode, 0, len(s.byID))
 	for _, v := range s.byID {
-		items = append(items, v)
+		if v.OwnerID == ownerID && matchesQuery(v, query) {
+			items = append(items, v)
+		}
 	}
+	s.mu.RUnlock()
 
-	// newest first
-	sort.Slice(items, func(i, j int) bool {
-		return items[i].CreatedAt.After(items[j].CreatedAt)
-	})
+	less := func(a, b model.QrCode) bool {
+		c := compareForSort(a, b, query.Sort)
+		if query.Asc {
+			return c < 0
+		}
+		return c > 0
+	}
+	sort.Slice(items, func(i, j int) bool { return less(items[i], items[j]) })
+
+	if cur != nil {
+		after := model.QrCode{ID: cur.ID, Label: cur.Label, ClickCount: cur.Clicks, CreatedAt: cur.Created}
+		start := sort.Search(len(items), func(i int) bool { return less(after, items[i]) })
+		items = items[start:]
+	}
+	if len(items) > query.Limit+1 {
+		items = items[:query.Limit+1]
+	}
+	return page(query, items), nil
+}
+
+func matchesQuery(q model.QrCode, query ListQuery) bool {
+	if query.Active != nil && q.Active != *query.Active {
+		return false
+	}
+	if !query.CreatedFrom.IsZero() && q.CreatedAt.Before(query.CreatedFrom) {
+		return false
+	}
+	if !query.CreatedTo.IsZero() && !q.CreatedAt.Before(query.CreatedTo) {
+		return false
+	}
+	for _, tagID := range query.TagIDs {
+		if !slices.Contains(q.TagIDs, tagID) {
+			return false
+		}
+	}
+	if query.Search != "" {
+		needle := strings.ToLower(query.Search)
+		if !strings.Contains(strings.ToLower(q.Label), needle) && !strings.Contains(strings.ToLower(q.URL), needle) {
+			return false
+		}
+	}
+	return true
+}
+
+// compareForSort orders by the sort key, then by ID so pages never overlap.
+func compareForSort(a, b model.QrCode, by ListSort) int {
+	var c int
+	switch by {
+	case SortLabel:
+		c = strings.Compare(sortLabel(a.Label), sortLabel(b.Label))
+	case SortClicks:
+		c = cmp.Compare(a.ClickCount, b.ClickCount)
+	default:
+		c = a.CreatedAt.Compare(b.CreatedAt)
+	}
+	if c != 0 {
+		return c
+	}
+	return strings.Compare(a.ID, b.ID)
+}
+
+func (s *MemoryStore) Get(ownerID, id string) (model.QrCode, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
 
-	return items
+	v, ok := s.byID[id]
+	if !ok || v.OwnerID != ownerID {
+		return model.QrCode{}, ErrNotFound
+	}
+	return v, nil
 }
 
-func (s *MemoryStore) Get(id string) (model.QrCode, error) {
+func (s *MemoryStore) Resolve(id string) (model.QrCode, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
@@ -48,35 +126,97 @@ func (s *MemoryStore) Get(id string) (model.QrCode, error) {
 	return v, nil
 }
 
+func (s *MemoryStore) IncrementClicks(id string, n int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	q, ok := s.byID[id]
+	if !ok {
+		return ErrNotFound
+	}
+	q.ClickCount += n
+	s.byID[id] = q
+	return nil
+}
+
 func (s *MemoryStore) Create(input CreateInput) (model.QrCode, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	if err := s.checkTagsLocked(input.OwnerID, input.TagIDs); err != nil {
+		return model.QrCode{}, err
+	}
+	q := newMemoryQrCode(input)
+	s.byID[q.ID] = q
+	return q, nil
+}
+
+func (s *MemoryStore) CreateMany(inputs []CreateInput) ([]model.QrCode, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, input := range inputs {
+		if err := s.checkTagsLocked(input.OwnerID, input.TagIDs); err != nil {
+			return nil, err
+		}
+	}
+	items := make([]model.QrCode, 0, len(inputs))
+	for _, input := range inputs {
+		q := newMemoryQrCode(input)
+		s.byID[q.ID] = q
+		items = append(items, q)
+	}
+	return items, nil
+}
+
+func newMemoryQrCode(input CreateInput) model.QrCode {
 	id := uuid.NewString()
 	q := model.QrCode{
 		ID:        id,
+		OwnerID:   input.OwnerID,
 		Label:     input.Label,
 		URL:       input.URL,
 		Active:    true,
+		Style:     model.DefaultQrStyle(),
 		CreatedAt: time.Now().UTC(),
 	}
 	if input.Active != nil {
 		q.Active = *input.Active
 	}
+	if input.Style != nil {
+		q.Style = *input.Style
+	}
 	if q.Label == "" {
 		q.Label = "Untitled"
 	}
+	if len(input.TagIDs) > 0 {
+		q.TagIDs = normalizeTagIDs(input.TagIDs)
+	}
+	q.ActiveFrom, q.ActiveUntil = input.Window.From, input.Window.Until
+	q.MaxScans, q.ExhaustedURL = input.ScanLimit.MaxScans, input.ScanLimit.ExhaustedURL
+	q.Rules = input.Rules
+	q.Variants, q.StickyVariants = input.Variants, input.StickyVariants
+	q.TimeZone = input.TimeZone
+	return q
+}
 
-	s.byID[id] = q
-	return q, nil
+// checkTagsLocked reports ErrUnknownTag unless every ID is one of the owner's
+// tags. Callers hold s.mu.
+func (s *MemoryStore) checkTagsLocked(ownerID string, tagIDs []string) error {
+	for _, id := range normalizeTagIDs(tagIDs) {
+		if t, ok := s.tags[id]; !ok || t.OwnerID != ownerID {
+			return ErrUnknownTag
+		}
+	}
+	return nil
 }
 
-func (s *MemoryStore) Update(id string, input UpdateInput) (model.QrCode, error) {
+func (s *MemoryStore) Update(ownerID, id string, input UpdateInput) (model.QrCode, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
 	q, ok := s.byID[id]
-	if !ok {
+	if !ok || q.OwnerID != ownerID {
 		return model.QrCode{}, ErrNotFound
 	}
 
@@ -89,6 +229,33 @@ func (s *MemoryStore) Update(id string, input UpdateInput) (model.QrCode, error)
 	if input.Active != nil {
 		q.Active = *input.Active
 	}
+	if input.Style != nil {
+		q.Style = *input.Style
+	}
+	if input.TagIDs != nil {
+		if err := s.checkTagsLocked(ownerID, *input.TagIDs); err != nil {
+			return model.QrCode{}, err
+		}
+		q.TagIDs = normalizeTagIDs(*input.TagIDs)
+	}
+	if input.Window != nil {
+		q.ActiveFrom, q.ActiveUntil = input.Window.From, input.Window.Until
+	}
+	if input.ScanLimit != nil {
+		q.MaxScans, q.ExhaustedURL = input.ScanLimit.MaxScans, input.ScanLimit.ExhaustedURL
+	}
+	if input.Rules != nil {
+		q.Rules = *input.Rules
+	}
+	if input.Variants != nil {
+		q.Variants = *input.Variants
+	}
+	if input.StickyVariants != nil {
+		q.StickyVariants = *input.StickyVariants
+	}
+	if input.TimeZone != nil {
+		q.TimeZone = *input.TimeZone
+	}
 	if q.Label == "" {
 		q.Label = "Untitled"
 	}
@@ -97,44 +264,365 @@ func (s *MemoryStore) Update(id string, input UpdateInput) (model.QrCode, error)
 	return q, nil
 }
 
-func (s *MemoryStore) Delete(id string) error {
+func (s *MemoryStore) Delete(ownerID, id string) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	if _, ok := s.byID[id]; !ok {
+	if q, ok := s.byID[id]; !ok || q.OwnerID != ownerID {
 		return ErrNotFound
 	}
 	delete(s.byID, id)
 	return nil
 }
 
-func (s *MemoryStore) CountTotal() (int, error) {
+func (s *MemoryStore) CountTotal(ownerID string) (int, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
-	return len(s.byID), nil
+	total := 0
+	for _, v := range s.byID {
+		if v.OwnerID == ownerID {
+			total++
+		}
+	}
+	return total, nil
 }
 
-func (s *MemoryStore) CountActive() (int, error) {
+func (s *MemoryStore) CountActive(ownerID string) (int, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
+	now := time.Now()
 	active := 0
 	for _, v := range s.byID {
-		if v.Active {
+		if v.OwnerID == ownerID && v.HoldsActiveSlot(now) {
 			active++
 		}
 	}
 	return active, nil
 }
 
-func (s *MemoryStore) GetSettings() (model.UserSettings, error) {
+func (s *MemoryStore) GetSettings(ownerID string) (model.UserSettings, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.settings[ownerID], nil
+}
+
+func (s *MemoryStore) UpdateSettings(ownerID string, settings model.UserSettings) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.settings[ownerID] = settings
+	return nil
+}
+
+func (s *MemoryStore) ReportSubscribers() (map[string]model.UserSettings, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
-	return s.settings, nil
+	out := make(map[string]model.UserSettings)
+	for ownerID, settings := range s.settings {
+		if settings.ReportEmail != "" {
+			out[ownerID] = settings
+		}
+	}
+	return out, nil
+}
+
+func (s *MemoryStore) ListTags(ownerID string) ([]model.Tag, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	items := make([]model.Tag, 0)
+	for _, t := range s.tags {
+		if t.OwnerID == ownerID {
+			t.QrCodeCount = s.countTaggedLocked(t.ID)
+			items = append(items, t)
+		}
+	}
+	sort.Slice(items, func(i, j int) bool { return sortLabel(items[i].Name) < sortLabel(items[j].Name) })
+	return items, nil
+}
+
+func (s *MemoryStore) GetTag(ownerID, id string) (model.Tag, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	t, ok := s.tags[id]
+	if !ok || t.OwnerID != ownerID {
+		return model.Tag{}, ErrNotFound
+	}
+	t.QrCodeCount = s.countTaggedLocked(id)
+	return t, nil
+}
+
+func (s *MemoryStore) CreateTag(ownerID, name string) (model.Tag, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.tagNameTakenLocked(ownerID, "", name) {
+		return model.Tag{}, ErrTagExists
+	}
+	t := model.Tag{ID: uuid.NewString(), OwnerID: ownerID, Name: name, CreatedAt: time.Now().UTC()}
+	s.tags[t.ID] = t
+	return t, nil
+}
+
+func (s *MemoryStore) RenameTag(ownerID, id, name string) (model.Tag, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t, ok := s.tags[id]
+	if !ok || t.OwnerID != ownerID {
+		return model.Tag{}, ErrNotFound
+	}
+	if s.tagNameTakenLocked(ownerID, id, name) {
+		return model.Tag{}, ErrTagExists
+	}
+	t.Name = name
+	s.tags[id] = t
+	t.QrCodeCount = s.countTaggedLocked(id)
+	return t, nil
 }
 
-func (s *MemoryStore) UpdateSettings(settings model.UserSettings) error {
+func (s *MemoryStore) DeleteTag(ownerID, id string) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	s.settings = settings
+
+	if t, ok := s.tags[id]; !ok || t.OwnerID != ownerID {
+		return ErrNotFound
+	}
+	delete(s.tags, id)
+	for codeID, q := range s.byID {
+		if slices.Contains(q.TagIDs, id) {
+			// Build a new slice; earlier reads may still hold the old one.
+			q.TagIDs = slices.DeleteFunc(slices.Clone(q.TagIDs), func(v string) bool { return v == id })
+			s.byID[codeID] = q
+		}
+	}
+	return nil
+}
+
+func (s *MemoryStore) TagQrCodeIDs(tagID string) ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if _, ok := s.tags[tagID]; !ok {
+		return nil, ErrNotFound
+	}
+	ids := make([]string, 0)
+	for _, q := range s.byID {
+		if slices.Contains(q.TagIDs, tagID) {
+			ids = append(ids, q.ID)
+		}
+	}
+	slices.Sort(ids)
+	return ids, nil
+}
+
+func (s *MemoryStore) countTaggedLocked(tagID string) int {
+	n := 0
+	for _, q := range s.byID {
+		if slices.Contains(q.TagIDs, tagID) {
+			n++
+		}
+	}
+	return n
+}
+
+// tagNameTakenLocked compares names case-insensitively, ignoring exceptID.
+func (s *MemoryStore) tagNameTakenLocked(ownerID, exceptID, name string) bool {
+	for _, t := range s.tags {
+		if t.OwnerID == ownerID && t.ID != exceptID && strings.EqualFold(t.Name, name) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *MemoryStore) ListWebhooks(ownerID string) ([]model.Webhook, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	items := make([]model.Webhook, 0)
+	for _, h := range s.webhooks {
+		if h.OwnerID == ownerID {
+			items = append(items, h)
+		}
+	}
+	sort.Slice(items, func(i, j int) bool {
+		if !items[i].CreatedAt.Equal(items[j].CreatedAt) {
+			return items[i].CreatedAt.Before(items[j].CreatedAt)
+		}
+		return items[i].ID < items[j].ID
+	})
+	return items, nil
+}
+
+func (s *MemoryStore) GetWebhook(ownerID, id string) (model.Webhook, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	h, ok := s.webhooks[id]
+	if !ok || h.OwnerID != ownerID {
+		return model.Webhook{}, ErrNotFound
+	}
+	return h, nil
+}
+
+func (s *MemoryStore) CreateWebhook(input WebhookInput) (model.Webhook, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	h := model.Webhook{ID: uuid.NewString(), OwnerID: input.OwnerID, URL: input.URL, Events: slices.Clone(input.Events), Active: input.Active, Secret: input.Secret, CreatedAt: time.Now().UTC()}
+	s.webhooks[h.ID] = h
+	return h, nil
+}
+
+func (s *MemoryStore) UpdateWebhook(ownerID, id string, input WebhookUpdate) (model.Webhook, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	h, ok := s.webhooks[id]
+	if !ok || h.OwnerID != ownerID {
+		return model.Webhook{}, ErrNotFound
+	}
+	if input.URL != nil {
+		h.URL = *input.URL
+	}
+	if input.Events != nil {
+		h.Events = slices.Clone(*input.Events)
+	}
+	if input.Active != nil {
+		h.Active = *input.Active
+	}
+	s.webhooks[id] = h
+	return h, nil
+}
+
+func (s *MemoryStore) DeleteWebhook(ownerID, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if h, ok := s.webhooks[id]; !ok || h.OwnerID != ownerID {
+		return ErrNotFound
+	}
+	delete(s.webhooks, id)
+	for did, d := range s.deliveries {
+		if d.WebhookID == id {
+			delete(s.deliveries, did)
+		}
+	}
+	return nil
+}
+
+func (s *MemoryStore) HasWebhook(ownerID, event string) (bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, h := range s.webhooks {
+		if h.OwnerID == ownerID && h.Active && slices.Contains(h.Events, event) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (s *MemoryStore) EnqueueWebhookEvents(evs ...WebhookEvent) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n := 0
+	for _, ev := range evs {
+		for _, h := range s.webhooks {
+			if h.OwnerID != ev.OwnerID || !h.Active || !slices.Contains(h.Events, ev.Event) {
+				continue
+			}
+			d := model.WebhookDelivery{ID: uuid.NewString(), WebhookID: h.ID, OwnerID: h.OwnerID, EventID: ev.ID, Event: ev.Event, Payload: slices.Clone(ev.Payload), Status: model.DeliveryPending, CreatedAt: ev.At.UTC(), NextAttemptAt: ev.At.UTC()}
+			s.deliveries[d.ID] = d
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (s *MemoryStore) ListWebhookDeliveries(ownerID, webhookID string, limit int) ([]model.WebhookDelivery, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if h, ok := s.webhooks[webhookID]; !ok || h.OwnerID != ownerID {
+		return nil, ErrNotFound
+	}
+	items := make([]model.WebhookDelivery, 0)
+	for _, d := range s.deliveries {
+		if d.WebhookID == webhookID {
+			items = append(items, d)
+		}
+	}
+	sort.Slice(items, func(i, j int) bool {
+		if !items[i].CreatedAt.Equal(items[j].CreatedAt) {
+			return items[i].CreatedAt.After(items[j].CreatedAt)
+		}
+		return items[i].ID > items[j].ID
+	})
+	if limit > 0 && len(items) > limit {
+		items = items[:limit]
+	}
+	return items, nil
+}
+
+func (s *MemoryStore) RedeliverWebhook(ownerID, webhookID, deliveryID string) (model.WebhookDelivery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if h, ok := s.webhooks[webhookID]; !ok || h.OwnerID != ownerID {
+		return model.WebhookDelivery{}, ErrNotFound
+	}
+	orig, ok := s.deliveries[deliveryID]
+	if !ok || orig.WebhookID != webhookID {
+		return model.WebhookDelivery{}, ErrNotFound
+	}
+	now := time.Now().UTC()
+	d := model.WebhookDelivery{ID: uuid.NewString(), WebhookID: webhookID, OwnerID: ownerID, EventID: orig.EventID, Event: orig.Event, Payload: orig.Payload, Status: model.DeliveryPending, CreatedAt: now, NextAttemptAt: now}
+	s.deliveries[d.ID] = d
+	return d, nil
+}
+
+func (s *MemoryStore) ClaimWebhookDeliveries(now time.Time, lease time.Duration, limit int) ([]PendingDelivery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	due := make([]model.WebhookDelivery, 0)
+	for _, d := range s.deliveries {
+		if d.Status == model.DeliveryPending && !d.NextAttemptAt.After(now) {
+			due = append(due, d)
+		}
+	}
+	sort.Slice(due, func(i, j int) bool { return due[i].NextAttemptAt.Before(due[j].NextAttemptAt) })
+	if len(due) > limit {
+		due = due[:limit]
+	}
+	out := make([]PendingDelivery, 0, len(due))
+	for _, d := range due {
+		d.NextAttemptAt = now.Add(lease).UTC()
+		s.deliveries[d.ID] = d
+		h := s.webhooks[d.WebhookID]
+		out = append(out, PendingDelivery{WebhookDelivery: d, URL: h.URL, Secret: h.Secret, Active: h.Active})
+	}
+	return out, nil
+}
+
+func (s *MemoryStore) RecordWebhookAttempt(deliveryID string, attempt WebhookAttempt) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	d, ok := s.deliveries[deliveryID]
+	if !ok {
+		return ErrNotFound
+	}
+	at := attempt.At.UTC()
+	d.Attempts++
+	d.Status = attempt.Status
+	d.ResponseStatus = attempt.ResponseStatus
+	d.LastError = attempt.Error
+	d.LastAttemptAt = &at
+	d.NextAttemptAt = attempt.NextAttemptAt.UTC()
+	s.deliveries[deliveryID] = d
 	return nil
 }
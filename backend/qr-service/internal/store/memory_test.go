@@ -1,12 +1,16 @@
 package store
 
-import "testing"
+import (
+	"strings"
+	"testing"
+	"time"
+)
 
 func TestMemoryStore_CRUD(t *testing.T) {
 
 	s := NewMemoryStore()
 
-	created, err := s.Create(CreateInput{Label: "A", URL: "https://example.com"})
+	created, err := s.Create(CreateInput{OwnerID: "user-1", Label: "A", URL: "https://example.com"})
 	if err != nil {
 		t.Fatalf("create: %v", err)
 	}
@@ -20,7 +24,7 @@ func TestMemoryStore_CRUD(t *testing.T) {
 		t.Fatalf("expected active=true by default")
 	}
 
-	got, err := s.Get(created.ID)
+	got, err := s.Get("user-1", created.ID)
 	if err != nil {
 		t.Fatalf("get: %v", err)
 	}
@@ -29,7 +33,7 @@ func TestMemoryStore_CRUD(t *testing.T) {
 	}
 
 	newLabel := "B"
-	updated, err := s.Update(created.ID, UpdateInput{Label: &newLabel})
+	updated, err := s.Update("user-1", created.ID, UpdateInput{Label: &newLabel})
 	if err != nil {
 		t.Fatalf("update: %v", err)
 	}
@@ -41,7 +45,7 @@ func TestMemoryStore_CRUD(t *testing.T) {
 	}
 
 	deactivate := false
-	updated2, err := s.Update(created.ID, UpdateInput{Active: &deactivate})
+	updated2, err := s.Update("user-1", created.ID, UpdateInput{Active: &deactivate})
 	if err != nil {
 		t.Fatalf("update active: %v", err)
 	}
@@ -49,15 +53,185 @@ func TestMemoryStore_CRUD(t *testing.T) {
 		t.Fatalf("expected active=false after update")
 	}
 
-	list := s.List()
+	page, _ := s.List("user-1", ListQuery{})
+	list := page.Items
 	if len(list) != 1 {
 		t.Fatalf("expected list size 1")
 	}
 
-	if err := s.Delete(created.ID); err != nil {
+	if err := s.Delete("user-1", created.ID); err != nil {
 		t.Fatalf("delete: %v", err)
 	}
-	if _, err := s.Get(created.ID); err == nil {
+	if _, err := s.Get("user-1", created.ID); err == nil {
 		t.Fatalf("expected not found")
 	}
 }
+
+func TestMemoryStore_ScopedByOwner(t *testing.T) {
+	s := NewMemoryStore()
+
+	mine, err := s.Create(CreateInput{OwnerID: "alice", Label: "A", URL: "https://example.com/a"})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if _, err := s.Create(CreateInput{OwnerID: "bob", Label: "B", URL: "https://example.com/b"}); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+
+	if got, _ := s.List("alice", ListQuery{}); len(got.Items) != 1 || got.Items[0].ID != mine.ID {
+		t.Fatalf("expected only alice's code, got %+v", got)
+	}
+	if n, _ := s.CountTotal("bob"); n != 1 {
+		t.Fatalf("expected bob total=1, got %d", n)
+	}
+	if n, _ := s.CountActive("carol"); n != 0 {
+		t.Fatalf("expected carol active=0, got %d", n)
+	}
+
+	if _, err := s.Get("bob", mine.ID); err != ErrNotFound {
+		t.Fatalf("expected not found for other owner, got %v", err)
+	}
+	label := "stolen"
+	if _, err := s.Update("bob", mine.ID, UpdateInput{Label: &label}); err != ErrNotFound {
+		t.Fatalf("expected not found on update by other owner, got %v", err)
+	}
+	if err := s.Delete("bob", mine.ID); err != ErrNotFound {
+		t.Fatalf("expected not found on delete by other owner, got %v", err)
+	}
+
+	resolved, err := s.Resolve(mine.ID)
+	if err != nil {
+		t.Fatalf("resolve: %v", err)
+	}
+	if resolved.OwnerID != "alice" {
+		t.Fatalf("expected owner alice, got %q", resolved.OwnerID)
+	}
+}
+
+func TestMemoryStore_CreateMany(t *testing.T) {
+	s := NewMemoryStore()
+	inactive := false
+	items, err := s.CreateMany([]CreateInput{
+		{OwnerID: "user-1", Label: "A", URL: "https://example.com/a"},
+		{OwnerID: "user-1", URL: "https://example.com/b", Active: &inactive},
+	})
+	if err != nil {
+		t.Fatalf("create many: %v", err)
+	}
+	if len(items) != 2 || items[1].Label != "Untitled" || items[1].Active {
+		t.Fatalf("unexpected items %+v", items)
+	}
+	if n, _ := s.CountActive("user-1"); n != 1 {
+		t.Fatalf("expected 1 active, got %d", n)
+	}
+}
+
+func TestMemoryStore_ListPaginatesAndFilters(t *testing.T) {
+	s := NewMemoryStore()
+	base := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	labels := []string{"delta", "Alpha", "charlie", "bravo", "echo"}
+	for i, label := range labels {
+		q, _ := s.Create(CreateInput{OwnerID: "user-1", Label: label, URL: "https://example.com/" + label})
+		// Spread creation times so ordering is deterministic.
+		s.mu.Lock()
+		q.CreatedAt = base.Add(time.Duration(i) * time.Hour)
+		q.Active = i%2 == 0
+		s.byID[q.ID] = q
+		s.mu.Unlock()
+		_ = s.IncrementClicks(q.ID, int64(len(label)*10+i))
+	}
+	s.Create(CreateInput{OwnerID: "user-2", Label: "other", URL: "https://example.com/other"})
+
+	collect := func(query ListQuery) []string {
+		t.Helper()
+		var out []string
+		for {
+			page, err := s.List("user-1", query)
+			if err != nil {
+				t.Fatalf("list: %v", err)
+			}
+			if len(page.Items) > query.Limit {
+				t.Fatalf("page larger than limit: %d", len(page.Items))
+			}
+			for _, q := range page.Items {
+				out = append(out, q.Label)
+			}
+			if page.NextCursor == "" {
+				return out
+			}
+			query.Cursor = page.NextCursor
+		}
+	}
+
+	if got := strings.Join(collect(ListQuery{Limit: 2}), ","); got != "echo,bravo,charlie,Alpha,delta" {
+		t.Fatalf("newest first: got %s", got)
+	}
+	if got := strings.Join(collect(ListQuery{Limit: 2, Sort: SortLabel, Asc: true}), ","); got != "Alpha,bravo,charlie,delta,echo" {
+		t.Fatalf("label asc: got %s", got)
+	}
+	if got := strings.Join(collect(ListQuery{Limit: 3, Sort: SortClicks}), ","); got != "charlie,bravo,Alpha,delta,echo" {
+		t.Fatalf("clicks desc: got %s", got)
+	}
+
+	active := true
+	if got := strings.Join(collect(ListQuery{Limit: 10, Active: &active}), ","); got != "echo,charlie,delta" {
+		t.Fatalf("active filter: got %s", got)
+	}
+	if got := strings.Join(collect(ListQuery{Limit: 10, Search: "EXAMPLE.com/b"}), ","); got != "bravo" {
+		t.Fatalf("search: got %s", got)
+	}
+	window := ListQuery{Limit: 10, CreatedFrom: base.Add(time.Hour), CreatedTo: base.Add(3 * time.Hour)}
+	if got := strings.Join(collect(window), ","); got != "charlie,Alpha" {
+		t.Fatalf("created range: got %s", got)
+	}
+
+	first, _ := s.List("user-1", ListQuery{Limit: 2})
+	if _, err := s.List("user-1", ListQuery{Limit: 2, Sort: SortLabel, Cursor: first.NextCursor}); err != ErrInvalidCursor {
+		t.Fatalf("expected cursor from another sort to be rejected, got %v", err)
+	}
+}
+
+func TestMemoryStore_Tags(t *testing.T) {
+	s := NewMemoryStore()
+	spring, _ := s.CreateTag("user-1", "Spring")
+	if _, err := s.CreateTag("user-1", "spring"); err != ErrTagExists {
+		t.Fatalf("expected case-insensitive duplicate to be rejected, got %v", err)
+	}
+	other, _ := s.CreateTag("user-2", "Spring")
+
+	a, err := s.Create(CreateInput{OwnerID: "user-1", Label: "a", URL: "https://example.com/a", TagIDs: []string{spring.ID, spring.ID}})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if len(a.TagIDs) != 1 || a.TagIDs[0] != spring.ID {
+		t.Fatalf("expected de-duplicated tags, got %v", a.TagIDs)
+	}
+	b, _ := s.Create(CreateInput{OwnerID: "user-1", Label: "b", URL: "https://example.com/b"})
+	if _, err := s.Create(CreateInput{OwnerID: "user-1", Label: "c", URL: "https://example.com/c", TagIDs: []string{other.ID}}); err != ErrUnknownTag {
+		t.Fatalf("expected another owner's tag to be rejected, got %v", err)
+	}
+
+	page, _ := s.List("user-1", ListQuery{TagIDs: []string{spring.ID}})
+	if len(page.Items) != 1 || page.Items[0].ID != a.ID {
+		t.Fatalf("expected only the tagged code, got %+v", page.Items)
+	}
+	if ids, _ := s.TagQrCodeIDs(spring.ID); len(ids) != 1 || ids[0] != a.ID {
+		t.Fatalf("unexpected tag members %v", ids)
+	}
+	if tags, _ := s.ListTags("user-1"); len(tags) != 1 || tags[0].QrCodeCount != 1 {
+		t.Fatalf("unexpected tags %+v", tags)
+	}
+
+	if err := s.DeleteTag("user-2", spring.ID); err != ErrNotFound {
+		t.Fatalf("expected another owner's delete to miss, got %v", err)
+	}
+	if err := s.DeleteTag("user-1", spring.ID); err != nil {
+		t.Fatalf("delete tag: %v", err)
+	}
+	if got, _ := s.Get("user-1", a.ID); len(got.TagIDs) != 0 {
+		t.Fatalf("expected tag removed from code, got %v", got.TagIDs)
+	}
+	if got, _ := s.Get("user-1", b.ID); got.ID != b.ID {
+		t.Fatalf("expected untagged code to survive")
+	}
+}
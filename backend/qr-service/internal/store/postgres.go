@@ -2,12 +2,16 @@ package store
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 
 	"qr-service/internal/model"
 )
@@ -17,22 +21,153 @@ type PostgresStore struct {
 }
 
 type qrCodeRow struct {
-	ID        uuid.UUID `gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
-	Label     string    `gorm:"not null"`
-	URL       string    `gorm:"not null"`
-	Active    bool      `gorm:"not null;default:true;index:qr_codes_active_idx"`
-	CreatedAt time.Time `gorm:"not null;index:qr_codes_created_at_idx,sort:desc"`
+	ID      uuid.UUID `gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
+	OwnerID string    `gorm:"not null;default:'';index:qr_codes_owner_id_idx"`
+	Label   string    `gorm:"not null"`
+	URL     string    `gorm:"not null"`
+	Active  bool      `gorm:"not null;default:true;index:qr_codes_active_idx"`
+	Style   string    `gorm:"type:jsonb;not null;default:'{}'"`
+	Rules   string    `gorm:"type:jsonb;not null;default:'[]'"`
+	// Variants and StickyVariants describe the code's A/B split, if any.
+	Variants       string `gorm:"type:jsonb;not null;default:'[]'"`
+	StickyVariants bool   `gorm:"not null;default:false"`
+	// ClickCount mirrors click-service totals so lists can sort by it.
+	ClickCount  int64 `gorm:"not null;default:0"`
+	ActiveFrom  *time.Time
+	ActiveUntil *time.Time
+	// MaxScans is enforced by click-service; NULL means unlimited.
+	MaxScans     *int64
+	ExhaustedURL string    `gorm:"not null;default:''"`
+	CreatedAt    time.Time `gorm:"not null;index:qr_codes_created_at_idx,sort:desc"`
+	// TimeZone is the code's analytics zone; '' inherits the owner's.
+	TimeZone string `gorm:"not null;default:''"`
 }
 
 func (qrCodeRow) TableName() string { return "qr_codes" }
 
+func (r qrCodeRow) toModel() model.QrCode {
+	return model.QrCode{ID: r.ID.String(), OwnerID: r.OwnerID, Label: r.Label, URL: r.URL, Active: r.Active, Style: decodeStyle(r.Style), Rules: decodeList[model.RedirectRule](r.Rules), Variants: decodeList[model.Variant](r.Variants), StickyVariants: r.StickyVariants, ClickCount: r.ClickCount, ActiveFrom: utcPtr(r.ActiveFrom), ActiveUntil: utcPtr(r.ActiveUntil), MaxScans: r.MaxScans, ExhaustedURL: r.ExhaustedURL, CreatedAt: r.CreatedAt, TimeZone: r.TimeZone}
+}
+
+func utcPtr(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	u := t.UTC()
+	return &u
+}
+
+// decodeStyle reads the jsonb style column. Rows written before styling
+// existed hold '{}', which normalizes to the default style.
+func decodeStyle(raw string) model.QrStyle {
+	var st model.QrStyle
+	if raw != "" {
+		_ = json.Unmarshal([]byte(raw), &st)
+	}
+	st, err := st.Normalize()
+	if err != nil {
+		return model.DefaultQrStyle()
+	}
+	return st
+}
+
+// decodeList reads a jsonb list column such as rules or variants; rows from
+// before the column existed hold '[]'.
+func decodeList[T any](raw string) []T {
+	var items []T
+	if raw != "" {
+		_ = json.Unmarshal([]byte(raw), &items)
+	}
+	return items
+}
+
+func encodeList[T any](items []T) (string, error) {
+	if items == nil {
+		items = []T{}
+	}
+	b, err := json.Marshal(items)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+func encodeStyle(st model.QrStyle) (string, error) {
+	b, err := json.Marshal(st)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
 type settingsRow struct {
 	ID                 int    `gorm:"primaryKey;autoIncrement"`
+	OwnerID            string `gorm:"not null;default:'';uniqueIndex:user_settings_owner_id_idx"`
 	DefaultRedirectURL string `gorm:"default:''"`
+	TimeZone           string `gorm:"not null;default:''"`
+	ReportEmail        string `gorm:"not null;default:''"`
 }
 
 func (settingsRow) TableName() string { return "user_settings" }
 
+type tagRow struct {
+	ID        uuid.UUID `gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
+	OwnerID   string    `gorm:"not null;index:tags_owner_id_idx"`
+	Name      string    `gorm:"not null"`
+	CreatedAt time.Time `gorm:"not null"`
+}
+
+func (tagRow) TableName() string { return "tags" }
+
+// qrCodeTagRow links a code to one of its tags.
+type qrCodeTagRow struct {
+	QrCodeID uuid.UUID `gorm:"primaryKey;type:uuid"`
+	TagID    uuid.UUID `gorm:"primaryKey;type:uuid;index:qr_code_tags_tag_id_idx"`
+}
+
+func (qrCodeTagRow) TableName() string { return "qr_code_tags" }
+
+type webhookRow struct {
+	ID        uuid.UUID `gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
+	OwnerID   string    `gorm:"not null;index:webhooks_owner_id_idx"`
+	URL       string    `gorm:"not null"`
+	Events    string    `gorm:"type:jsonb;not null;default:'[]'"`
+	Active    bool      `gorm:"not null;default:true"`
+	Secret    string    `gorm:"not null"`
+	CreatedAt time.Time `gorm:"not null"`
+}
+
+func (webhookRow) TableName() string { return "webhooks" }
+
+func (r webhookRow) toModel() model.Webhook {
+	return model.Webhook{ID: r.ID.String(), OwnerID: r.OwnerID, URL: r.URL, Events: decodeList[string](r.Events), Active: r.Active, Secret: r.Secret, CreatedAt: r.CreatedAt}
+}
+
+// webhookDeliveryRow is the webhook outbox: pending rows are sent by the
+// dispatcher, and every row stays behind as the delivery log. Payload is
+// text rather than jsonb so the signed bytes are kept exactly.
+type webhookDeliveryRow struct {
+	ID             uuid.UUID `gorm:"primaryKey;type:uuid"`
+	WebhookID      uuid.UUID `gorm:"type:uuid;not null;index:webhook_deliveries_webhook_id_idx,priority:1"`
+	OwnerID        string    `gorm:"not null"`
+	EventID        string    `gorm:"not null"`
+	Event          string    `gorm:"not null"`
+	Payload        string    `gorm:"type:text;not null"`
+	Status         string    `gorm:"not null;index:webhook_deliveries_due_idx,priority:1"`
+	Attempts       int       `gorm:"not null;default:0"`
+	ResponseStatus int       `gorm:"not null;default:0"`
+	LastError      string    `gorm:"not null;default:''"`
+	LastAttemptAt  *time.Time
+	NextAttemptAt  time.Time `gorm:"not null;index:webhook_deliveries_due_idx,priority:2"`
+	CreatedAt      time.Time `gorm:"not null;index:webhook_deliveries_webhook_id_idx,priority:2,sort:desc"`
+}
+
+func (webhookDeliveryRow) TableName() string { return "webhook_deliveries" }
+
+func (r webhookDeliveryRow) toModel() model.WebhookDelivery {
+	return model.WebhookDelivery{ID: r.ID.String(), WebhookID: r.WebhookID.String(), OwnerID: r.OwnerID, EventID: r.EventID, Event: r.Event, Payload: []byte(r.Payload), Status: r.Status, Attempts: r.Attempts, ResponseStatus: r.ResponseStatus, LastError: r.LastError, CreatedAt: r.CreatedAt, LastAttemptAt: utcPtr(r.LastAttemptAt), NextAttemptAt: r.NextAttemptAt}
+}
+
 func NewPostgresStore(ctx context.Context, databaseURL string) (*PostgresStore, error) {
 	gdb, err := gorm.Open(postgres.Open(databaseURL), &gorm.Config{})
 	if err != nil {
@@ -92,28 +227,191 @@ func (s *PostgresStore) ensureSchema(ctx context.Context) error {
 		if err := db.Exec(`ALTER TABLE qr_codes ADD COLUMN IF NOT EXISTS active boolean NOT NULL DEFAULT true;`).Error; err != nil {
 			return err
 		}
+
+		// Codes created before ownership existed get an empty owner and are
+		// no longer visible to anyone until they are reassigned.
+		if err := db.Exec(`ALTER TABLE qr_codes ADD COLUMN IF NOT EXISTS owner_id text NOT NULL DEFAULT '';`).Error; err != nil {
+			return err
+		}
 	}
 
 	if err := db.AutoMigrate(&qrCodeRow{}); err != nil {
 		return err
 	}
-	return db.AutoMigrate(&settingsRow{})
+	if err := db.AutoMigrate(&settingsRow{}); err != nil {
+		return err
+	}
+
+	if err := db.AutoMigrate(&tagRow{}, &qrCodeTagRow{}); err != nil {
+		return err
+	}
+	if err := db.AutoMigrate(&webhookRow{}, &webhookDeliveryRow{}); err != nil {
+		return err
+	}
+	// Tag names are unique per owner regardless of case.
+	return db.Exec(`CREATE UNIQUE INDEX IF NOT EXISTS tags_owner_name_idx ON tags (owner_id, lower(name));`).Error
 }
 
-func (s *PostgresStore) List() []model.QrCode {
-	rows := make([]qrCodeRow, 0, 32)
-	if err := s.db.Order("created_at desc").Find(&rows).Error; err != nil {
-		return []model.QrCode{}
+// List pages through an owner's codes with keyset pagination on (sort key, id).
+// The default newest-first order walks qr_codes_created_at_idx.
+func (s *PostgresStore) List(ownerID string, query ListQuery) (ListPage, error) {
+	query = query.normalize()
+	cur, err := decodeCursor(query)
+	if err != nil {
+		return ListPage{}, err
+	}
+
+	tx := s.db.Model(&qrCodeRow{}).Where("owner_id = ?", ownerID)
+	if query.Active != nil {
+		tx = tx.Where("active = ?", *query.Active)
+	}
+	if !query.CreatedFrom.IsZero() {
+		tx = tx.Where("created_at >= ?", query.CreatedFrom)
+	}
+	if !query.CreatedTo.IsZero() {
+		tx = tx.Where("created_at < ?", query.CreatedTo)
+	}
+	if query.Search != "" {
+		pattern := "%" + escapeLike(query.Search) + "%"
+		tx = tx.Where("(label ILIKE ? OR url ILIKE ?)", pattern, pattern)
+	}
+	for _, raw := range query.TagIDs {
+		tagID, err := uuid.Parse(raw)
+		if err != nil {
+			// No code can carry a tag that cannot exist.
+			return ListPage{Items: []model.QrCode{}}, nil
+		}
+		tx = tx.Where("id IN (SELECT qr_code_id FROM qr_code_tags WHERE tag_id = ?)", tagID)
+	}
+
+	var col string
+	var after any
+	switch query.Sort {
+	case SortLabel:
+		col = "lower(label)"
+		if cur != nil {
+			after = cur.Label
+		}
+	case SortClicks:
+		col = "click_count"
+		if cur != nil {
+			after = cur.Clicks
+		}
+	default:
+		col = "created_at"
+		if cur != nil {
+			after = cur.Created
+		}
+	}
+	dir, op := "DESC", "<"
+	if query.Asc {
+		dir, op = "ASC", ">"
+	}
+	if cur != nil {
+		afterID, err := uuid.Parse(cur.ID)
+		if err != nil {
+			return ListPage{}, ErrInvalidCursor
+		}
+		tx = tx.Where(fmt.Sprintf("(%s, id) %s (?, ?)", col, op), after, afterID)
+	}
+
+	rows := make([]qrCodeRow, 0, query.Limit+1)
+	if err := tx.Order(col + " " + dir).Order("id " + dir).Limit(query.Limit + 1).Find(&rows).Error; err != nil {
+		return ListPage{}, err
 	}
 
 	items := make([]model.QrCode, 0, len(rows))
 	for _, r := range rows {
-		items = append(items, model.QrCode{ID: r.ID.String(), Label: r.Label, URL: r.URL, Active: r.Active, CreatedAt: r.CreatedAt})
+		items = append(items, r.toModel())
 	}
-	return items
+	if err := s.attachTags(items); err != nil {
+		return ListPage{}, err
+	}
+	return page(query, items), nil
+}
+
+// attachTags fills in TagIDs for the given codes with a single query.
+func (s *PostgresStore) attachTags(items []model.QrCode) error {
+	if len(items) == 0 {
+		return nil
+	}
+	ids := make([]uuid.UUID, 0, len(items))
+	for _, q := range items {
+		ids = append(ids, uuid.MustParse(q.ID))
+	}
+	var links []qrCodeTagRow
+	if err := s.db.Where("qr_code_id IN ?", ids).Order("tag_id").Find(&links).Error; err != nil {
+		return err
+	}
+	byCode := make(map[string][]string, len(items))
+	for _, l := range links {
+		byCode[l.QrCodeID.String()] = append(byCode[l.QrCodeID.String()], l.TagID.String())
+	}
+	for i := range items {
+		items[i].TagIDs = byCode[items[i].ID]
+	}
+	return nil
 }
 
-func (s *PostgresStore) Get(id string) (model.QrCode, error) {
+// checkTags resolves tag IDs to UUIDs, failing with ErrUnknownTag unless every
+// one of them is the owner's.
+func checkTags(tx *gorm.DB, ownerID string, tagIDs []string) ([]uuid.UUID, error) {
+	tagIDs = normalizeTagIDs(tagIDs)
+	if len(tagIDs) == 0 {
+		return nil, nil
+	}
+	ids := make([]uuid.UUID, 0, len(tagIDs))
+	for _, raw := range tagIDs {
+		id, err := uuid.Parse(raw)
+		if err != nil {
+			return nil, ErrUnknownTag
+		}
+		ids = append(ids, id)
+	}
+	var n int64
+	if err := tx.Model(&tagRow{}).Where("id IN ? AND owner_id = ?", ids, ownerID).Count(&n).Error; err != nil {
+		return nil, err
+	}
+	if int(n) != len(ids) {
+		return nil, ErrUnknownTag
+	}
+	return ids, nil
+}
+
+func linkTags(codeID uuid.UUID, tagIDs []uuid.UUID) []qrCodeTagRow {
+	links := make([]qrCodeTagRow, 0, len(tagIDs))
+	for _, tagID := range tagIDs {
+		links = append(links, qrCodeTagRow{QrCodeID: codeID, TagID: tagID})
+	}
+	return links
+}
+
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+func (s *PostgresStore) Get(ownerID, id string) (model.QrCode, error) {
+	uid, err := uuid.Parse(id)
+	if err != nil {
+		return model.QrCode{}, ErrNotFound
+	}
+
+	var r qrCodeRow
+	err = s.db.First(&r, "id = ? AND owner_id = ?", uid, ownerID).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return model.QrCode{}, ErrNotFound
+		}
+		return model.QrCode{}, err
+	}
+	items := []model.QrCode{r.toModel()}
+	if err := s.attachTags(items); err != nil {
+		return model.QrCode{}, err
+	}
+	return items[0], nil
+}
+
+func (s *PostgresStore) Resolve(id string) (model.QrCode, error) {
 	uid, err := uuid.Parse(id)
 	if err != nil {
 		return model.QrCode{}, ErrNotFound
@@ -127,10 +425,88 @@ func (s *PostgresStore) Get(id string) (model.QrCode, error) {
 		}
 		return model.QrCode{}, err
 	}
-	return model.QrCode{ID: r.ID.String(), Label: r.Label, URL: r.URL, Active: r.Active, CreatedAt: r.CreatedAt}, nil
+	return r.toModel(), nil
+}
+
+func (s *PostgresStore) IncrementClicks(id string, n int64) error {
+	uid, err := uuid.Parse(id)
+	if err != nil {
+		return ErrNotFound
+	}
+	res := s.db.Model(&qrCodeRow{}).Where("id = ?", uid).UpdateColumn("click_count", gorm.Expr("click_count + ?", n))
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
 }
 
 func (s *PostgresStore) Create(input CreateInput) (model.QrCode, error) {
+	r, q, err := newQrCodeRow(input)
+	if err != nil {
+		return model.QrCode{}, err
+	}
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		tagIDs, err := checkTags(tx, input.OwnerID, input.TagIDs)
+		if err != nil {
+			return err
+		}
+		if err := tx.Create(&r).Error; err != nil {
+			return err
+		}
+		if len(tagIDs) == 0 {
+			return nil
+		}
+		return tx.Create(linkTags(r.ID, tagIDs)).Error
+	})
+	if err != nil {
+		return model.QrCode{}, err
+	}
+	return q, nil
+}
+
+// CreateMany inserts every input in a single transaction so an import either
+// lands completely or not at all.
+func (s *PostgresStore) CreateMany(inputs []CreateInput) ([]model.QrCode, error) {
+	if len(inputs) == 0 {
+		return []model.QrCode{}, nil
+	}
+	rows := make([]qrCodeRow, 0, len(inputs))
+	items := make([]model.QrCode, 0, len(inputs))
+	for _, input := range inputs {
+		r, q, err := newQrCodeRow(input)
+		if err != nil {
+			return nil, err
+		}
+		rows = append(rows, r)
+		items = append(items, q)
+	}
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var links []qrCodeTagRow
+		for i, input := range inputs {
+			tagIDs, err := checkTags(tx, input.OwnerID, input.TagIDs)
+			if err != nil {
+				return err
+			}
+			links = append(links, linkTags(rows[i].ID, tagIDs)...)
+		}
+		if err := tx.CreateInBatches(&rows, 500).Error; err != nil {
+			return err
+		}
+		if len(links) == 0 {
+			return nil
+		}
+		return tx.CreateInBatches(&links, 500).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+func newQrCodeRow(input CreateInput) (qrCodeRow, model.QrCode, error) {
 	id := uuid.New()
 	active := true
 	if input.Active != nil {
@@ -138,31 +514,54 @@ func (s *PostgresStore) Create(input CreateInput) (model.QrCode, error) {
 	}
 
 	q := model.QrCode{
-		ID:        id.String(),
-		Label:     input.Label,
-		URL:       input.URL,
-		Active:    active,
-		CreatedAt: time.Now().UTC(),
+		ID:      id.String(),
+		OwnerID: input.OwnerID,
+		Label:   input.Label,
+		URL:     input.URL,
+		Active:  active,
+		Style:   model.DefaultQrStyle(),
+		// Postgres keeps microseconds; truncating here keeps list cursors exact.
+		CreatedAt: time.Now().UTC().Truncate(time.Microsecond),
 	}
 	if q.Label == "" {
 		q.Label = "Untitled"
 	}
-
-	r := qrCodeRow{ID: id, Label: q.Label, URL: q.URL, Active: q.Active, CreatedAt: q.CreatedAt}
-	if err := s.db.Create(&r).Error; err != nil {
-		return model.QrCode{}, err
+	if input.Style != nil {
+		q.Style = *input.Style
 	}
-	return q, nil
+	if len(input.TagIDs) > 0 {
+		q.TagIDs = normalizeTagIDs(input.TagIDs)
+	}
+	q.ActiveFrom, q.ActiveUntil = input.Window.From, input.Window.Until
+	q.MaxScans, q.ExhaustedURL = input.ScanLimit.MaxScans, input.ScanLimit.ExhaustedURL
+	q.Rules = input.Rules
+	q.Variants, q.StickyVariants = input.Variants, input.StickyVariants
+	q.TimeZone = input.TimeZone
+	style, err := encodeStyle(q.Style)
+	if err != nil {
+		return qrCodeRow{}, model.QrCode{}, err
+	}
+	rules, err := encodeList(q.Rules)
+	if err != nil {
+		return qrCodeRow{}, model.QrCode{}, err
+	}
+	variants, err := encodeList(q.Variants)
+	if err != nil {
+		return qrCodeRow{}, model.QrCode{}, err
+	}
+
+	r := qrCodeRow{ID: id, OwnerID: q.OwnerID, Label: q.Label, URL: q.URL, Active: q.Active, Style: style, Rules: rules, Variants: variants, StickyVariants: q.StickyVariants, ActiveFrom: q.ActiveFrom, ActiveUntil: q.ActiveUntil, MaxScans: q.MaxScans, ExhaustedURL: q.ExhaustedURL, CreatedAt: q.CreatedAt, TimeZone: q.TimeZone}
+	return r, q, nil
 }
 
-func (s *PostgresStore) Update(id string, input UpdateInput) (model.QrCode, error) {
+func (s *PostgresStore) Update(ownerID, id string, input UpdateInput) (model.QrCode, error) {
 	uid, err := uuid.Parse(id)
 	if err != nil {
 		return model.QrCode{}, ErrNotFound
 	}
 
 	// Load first so we can map not-found cleanly.
-	current, err := s.Get(id)
+	current, err := s.Get(ownerID, id)
 	if err != nil {
 		return model.QrCode{}, err
 	}
@@ -181,53 +580,506 @@ func (s *PostgresStore) Update(id string, input UpdateInput) (model.QrCode, erro
 	}
 
 	updates := map[string]any{"label": current.Label, "url": current.URL, "active": current.Active}
-	if err := s.db.Model(&qrCodeRow{}).Where("id = ?", uid).Updates(updates).Error; err != nil {
+	if input.Window != nil {
+		current.ActiveFrom, current.ActiveUntil = input.Window.From, input.Window.Until
+		updates["active_from"] = current.ActiveFrom
+		updates["active_until"] = current.ActiveUntil
+	}
+	if input.ScanLimit != nil {
+		current.MaxScans, current.ExhaustedURL = input.ScanLimit.MaxScans, input.ScanLimit.ExhaustedURL
+		updates["max_scans"] = current.MaxScans
+		updates["exhausted_url"] = current.ExhaustedURL
+	}
+	if input.Style != nil {
+		current.Style = *input.Style
+		style, err := encodeStyle(current.Style)
+		if err != nil {
+			return model.QrCode{}, err
+		}
+		updates["style"] = style
+	}
+	if input.Rules != nil {
+		current.Rules = *input.Rules
+		rules, err := encodeList(current.Rules)
+		if err != nil {
+			return model.QrCode{}, err
+		}
+		updates["rules"] = rules
+	}
+	if input.Variants != nil {
+		current.Variants = *input.Variants
+		variants, err := encodeList(current.Variants)
+		if err != nil {
+			return model.QrCode{}, err
+		}
+		updates["variants"] = variants
+	}
+	if input.StickyVariants != nil {
+		current.StickyVariants = *input.StickyVariants
+		updates["sticky_variants"] = current.StickyVariants
+	}
+	if input.TimeZone != nil {
+		current.TimeZone = *input.TimeZone
+		updates["time_zone"] = current.TimeZone
+	}
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if input.TagIDs != nil {
+			tagIDs, err := checkTags(tx, ownerID, *input.TagIDs)
+			if err != nil {
+				return err
+			}
+			if err := tx.Where("qr_code_id = ?", uid).Delete(&qrCodeTagRow{}).Error; err != nil {
+				return err
+			}
+			if len(tagIDs) > 0 {
+				if err := tx.Create(linkTags(uid, tagIDs)).Error; err != nil {
+					return err
+				}
+			}
+			current.TagIDs = normalizeTagIDs(*input.TagIDs)
+		}
+		return tx.Model(&qrCodeRow{}).Where("id = ? AND owner_id = ?", uid, ownerID).Updates(updates).Error
+	})
+	if err != nil {
 		return model.QrCode{}, err
 	}
 	return current, nil
 }
 
-func (s *PostgresStore) Delete(id string) error {
+func (s *PostgresStore) Delete(ownerID, id string) error {
 	uid, err := uuid.Parse(id)
 	if err != nil {
 		return ErrNotFound
 	}
 
-	res := s.db.Delete(&qrCodeRow{}, "id = ?", uid)
-	if res.Error != nil {
-		return res.Error
-	}
-	if res.RowsAffected == 0 {
-		return ErrNotFound
-	}
-	return nil
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		res := tx.Delete(&qrCodeRow{}, "id = ? AND owner_id = ?", uid, ownerID)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return ErrNotFound
+		}
+		return tx.Where("qr_code_id = ?", uid).Delete(&qrCodeTagRow{}).Error
+	})
 }
 
-func (s *PostgresStore) CountTotal() (int, error) {
+func (s *PostgresStore) CountTotal(ownerID string) (int, error) {
 	var n int64
-	if err := s.db.Model(&qrCodeRow{}).Count(&n).Error; err != nil {
+	if err := s.db.Model(&qrCodeRow{}).Where("owner_id = ?", ownerID).Count(&n).Error; err != nil {
 		return 0, err
 	}
 	return int(n), nil
 }
 
-func (s *PostgresStore) CountActive() (int, error) {
+func (s *PostgresStore) CountActive(ownerID string) (int, error) {
 	var n int64
-	if err := s.db.Model(&qrCodeRow{}).Where("active = ?", true).Count(&n).Error; err != nil {
+	if err := s.db.Model(&qrCodeRow{}).Where("owner_id = ? AND active = ? AND (active_until IS NULL OR active_until > ?)", ownerID, true, time.Now().UTC()).Count(&n).Error; err != nil {
 		return 0, err
 	}
 	return int(n), nil
 }
 
-func (s *PostgresStore) GetSettings() (model.UserSettings, error) {
+func (s *PostgresStore) GetSettings(ownerID string) (model.UserSettings, error) {
 	var row settingsRow
-	err := s.db.FirstOrCreate(&row, settingsRow{ID: 1}).Error
+	err := s.db.Where("owner_id = ?", ownerID).Limit(1).Find(&row).Error
 	if err != nil {
 		return model.UserSettings{}, err
 	}
-	return model.UserSettings{DefaultRedirectURL: row.DefaultRedirectURL}, nil
+	return row.toModel(), nil
+}
+
+func (r settingsRow) toModel() model.UserSettings {
+	return model.UserSettings{DefaultRedirectURL: r.DefaultRedirectURL, TimeZone: r.TimeZone, ReportEmail: r.ReportEmail}
+}
+
+func (s *PostgresStore) UpdateSettings(ownerID string, settings model.UserSettings) error {
+	row := settingsRow{OwnerID: ownerID, DefaultRedirectURL: settings.DefaultRedirectURL, TimeZone: settings.TimeZone, ReportEmail: settings.ReportEmail}
+	return s.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "owner_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"default_redirect_url", "time_zone", "report_email"}),
+	}).Create(&row).Error
+}
+
+func (s *PostgresStore) ReportSubscribers() (map[string]model.UserSettings, error) {
+	var rows []settingsRow
+	if err := s.db.Where("report_email <> ''").Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	out := make(map[string]model.UserSettings, len(rows))
+	for _, row := range rows {
+		out[row.OwnerID] = row.toModel()
+	}
+	return out, nil
+}
+
+// tagWithCount is a tags row plus the number of codes carrying it.
+type tagWithCount struct {
+	ID          uuid.UUID
+	OwnerID     string
+	Name        string
+	CreatedAt   time.Time
+	QrCodeCount int
+}
+
+func (r tagWithCount) toModel() model.Tag {
+	return model.Tag{ID: r.ID.String(), OwnerID: r.OwnerID, Name: r.Name, QrCodeCount: r.QrCodeCount, CreatedAt: r.CreatedAt}
+}
+
+func (s *PostgresStore) tagsWithCounts() *gorm.DB {
+	return s.db.Table("tags").Select("tags.*, (SELECT COUNT(*) FROM qr_code_tags WHERE qr_code_tags.tag_id = tags.id) AS qr_code_count")
+}
+
+func (s *PostgresStore) ListTags(ownerID string) ([]model.Tag, error) {
+	var rows []tagWithCount
+	if err := s.tagsWithCounts().Where("owner_id = ?", ownerID).Order("lower(name)").Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	items := make([]model.Tag, 0, len(rows))
+	for _, r := range rows {
+		items = append(items, r.toModel())
+	}
+	return items, nil
+}
+
+func (s *PostgresStore) GetTag(ownerID, id string) (model.Tag, error) {
+	uid, err := uuid.Parse(id)
+	if err != nil {
+		return model.Tag{}, ErrNotFound
+	}
+	var rows []tagWithCount
+	if err := s.tagsWithCounts().Where("id = ? AND owner_id = ?", uid, ownerID).Limit(1).Scan(&rows).Error; err != nil {
+		return model.Tag{}, err
+	}
+	if len(rows) == 0 {
+		return model.Tag{}, ErrNotFound
+	}
+	return rows[0].toModel(), nil
+}
+
+func (s *PostgresStore) CreateTag(ownerID, name string) (model.Tag, error) {
+	r := tagRow{ID: uuid.New(), OwnerID: ownerID, Name: name, CreatedAt: time.Now().UTC()}
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := checkTagNameFree(tx, ownerID, uuid.Nil, name); err != nil {
+			return err
+		}
+		return tx.Create(&r).Error
+	})
+	if err != nil {
+		return model.Tag{}, err
+	}
+	return model.Tag{ID: r.ID.String(), OwnerID: r.OwnerID, Name: r.Name, CreatedAt: r.CreatedAt}, nil
+}
+
+func (s *PostgresStore) RenameTag(ownerID, id, name string) (model.Tag, error) {
+	uid, err := uuid.Parse(id)
+	if err != nil {
+		return model.Tag{}, ErrNotFound
+	}
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := checkTagNameFree(tx, ownerID, uid, name); err != nil {
+			return err
+		}
+		res := tx.Model(&tagRow{}).Where("id = ? AND owner_id = ?", uid, ownerID).Update("name", name)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return ErrNotFound
+		}
+		return nil
+	})
+	if err != nil {
+		return model.Tag{}, err
+	}
+	return s.GetTag(ownerID, id)
+}
+
+// checkTagNameFree backs up tags_owner_name_idx with a clean ErrTagExists.
+func checkTagNameFree(tx *gorm.DB, ownerID string, exceptID uuid.UUID, name string) error {
+	var n int64
+	err := tx.Model(&tagRow{}).Where("owner_id = ? AND lower(name) = lower(?) AND id <> ?", ownerID, name, exceptID).Count(&n).Error
+	if err != nil {
+		return err
+	}
+	if n > 0 {
+		return ErrTagExists
+	}
+	return nil
+}
+
+func (s *PostgresStore) DeleteTag(ownerID, id string) error {
+	uid, err := uuid.Parse(id)
+	if err != nil {
+		return ErrNotFound
+	}
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		res := tx.Delete(&tagRow{}, "id = ? AND owner_id = ?", uid, ownerID)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return ErrNotFound
+		}
+		return tx.Where("tag_id = ?", uid).Delete(&qrCodeTagRow{}).Error
+	})
+}
+
+func (s *PostgresStore) TagQrCodeIDs(tagID string) ([]string, error) {
+	uid, err := uuid.Parse(tagID)
+	if err != nil {
+		return nil, ErrNotFound
+	}
+	var n int64
+	if err := s.db.Model(&tagRow{}).Where("id = ?", uid).Count(&n).Error; err != nil {
+		return nil, err
+	}
+	if n == 0 {
+		return nil, ErrNotFound
+	}
+	var ids []uuid.UUID
+	if err := s.db.Model(&qrCodeTagRow{}).Where("tag_id = ?", uid).Order("qr_code_id").Pluck("qr_code_id", &ids).Error; err != nil {
+		return nil, err
+	}
+	out := make([]string, 0, len(ids))
+	for _, id := range ids {
+		out = append(out, id.String())
+	}
+	return out, nil
+}
+
+func (s *PostgresStore) ListWebhooks(ownerID string) ([]model.Webhook, error) {
+	var rows []webhookRow
+	if err := s.db.Where("owner_id = ?", ownerID).Order("created_at, id").Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	items := make([]model.Webhook, 0, len(rows))
+	for _, r := range rows {
+		items = append(items, r.toModel())
+	}
+	return items, nil
+}
+
+func (s *PostgresStore) GetWebhook(ownerID, id string) (model.Webhook, error) {
+	uid, err := uuid.Parse(id)
+	if err != nil {
+		return model.Webhook{}, ErrNotFound
+	}
+	var rows []webhookRow
+	if err := s.db.Where("id = ? AND owner_id = ?", uid, ownerID).Limit(1).Find(&rows).Error; err != nil {
+		return model.Webhook{}, err
+	}
+	if len(rows) == 0 {
+		return model.Webhook{}, ErrNotFound
+	}
+	return rows[0].toModel(), nil
+}
+
+func (s *PostgresStore) CreateWebhook(input WebhookInput) (model.Webhook, error) {
+	events, err := encodeList(input.Events)
+	if err != nil {
+		return model.Webhook{}, err
+	}
+	r := webhookRow{ID: uuid.New(), OwnerID: input.OwnerID, URL: input.URL, Events: events, Active: input.Active, Secret: input.Secret, CreatedAt: time.Now().UTC()}
+	if err := s.db.Create(&r).Error; err != nil {
+		return model.Webhook{}, err
+	}
+	return r.toModel(), nil
 }
 
-func (s *PostgresStore) UpdateSettings(settings model.UserSettings) error {
-	return s.db.Model(&settingsRow{}).Where("id = ?", 1).Update("default_redirect_url", settings.DefaultRedirectURL).Error
+func (s *PostgresStore) UpdateWebhook(ownerID, id string, input WebhookUpdate) (model.Webhook, error) {
+	uid, err := uuid.Parse(id)
+	if err != nil {
+		return model.Webhook{}, ErrNotFound
+	}
+	updates := map[string]any{}
+	if input.URL != nil {
+		updates["url"] = *input.URL
+	}
+	if input.Events != nil {
+		events, err := encodeList(*input.Events)
+		if err != nil {
+			return model.Webhook{}, err
+		}
+		updates["events"] = events
+	}
+	if input.Active != nil {
+		updates["active"] = *input.Active
+	}
+	if len(updates) > 0 {
+		res := s.db.Model(&webhookRow{}).Where("id = ? AND owner_id = ?", uid, ownerID).Updates(updates)
+		if res.Error != nil {
+			return model.Webhook{}, res.Error
+		}
+		if res.RowsAffected == 0 {
+			return model.Webhook{}, ErrNotFound
+		}
+	}
+	return s.GetWebhook(ownerID, id)
+}
+
+func (s *PostgresStore) DeleteWebhook(ownerID, id string) error {
+	uid, err := uuid.Parse(id)
+	if err != nil {
+		return ErrNotFound
+	}
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		res := tx.Delete(&webhookRow{}, "id = ? AND owner_id = ?", uid, ownerID)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return ErrNotFound
+		}
+		return tx.Where("webhook_id = ?", uid).Delete(&webhookDeliveryRow{}).Error
+	})
+}
+
+// subscribedWebhooks selects an owner's active webhooks that take event.
+func subscribedWebhooks(db *gorm.DB, ownerID, event string) *gorm.DB {
+	return db.Model(&webhookRow{}).Where("owner_id = ? AND active AND events @> jsonb_build_array(?::text)", ownerID, event)
+}
+
+func (s *PostgresStore) HasWebhook(ownerID, event string) (bool, error) {
+	var n int64
+	if err := subscribedWebhooks(s.db, ownerID, event).Count(&n).Error; err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+func (s *PostgresStore) EnqueueWebhookEvents(evs ...WebhookEvent) (int, error) {
+	var rows []webhookDeliveryRow
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		// A batch of scans is mostly the same few owners.
+		subscribed := make(map[[2]string][]uuid.UUID)
+		for _, ev := range evs {
+			key := [2]string{ev.OwnerID, ev.Event}
+			ids, ok := subscribed[key]
+			if !ok {
+				if err := subscribedWebhooks(tx, ev.OwnerID, ev.Event).Pluck("id", &ids).Error; err != nil {
+					return err
+				}
+				subscribed[key] = ids
+			}
+			at := ev.At.UTC()
+			for _, id := range ids {
+				rows = append(rows, webhookDeliveryRow{ID: uuid.New(), WebhookID: id, OwnerID: ev.OwnerID, EventID: ev.ID, Event: ev.Event, Payload: string(ev.Payload), Status: model.DeliveryPending, NextAttemptAt: at, CreatedAt: at})
+			}
+		}
+		if len(rows) == 0 {
+			return nil
+		}
+		return tx.CreateInBatches(&rows, 500).Error
+	})
+	if err != nil {
+		return 0, err
+	}
+	return len(rows), nil
+}
+
+func (s *PostgresStore) ListWebhookDeliveries(ownerID, webhookID string, limit int) ([]model.WebhookDelivery, error) {
+	if _, err := s.GetWebhook(ownerID, webhookID); err != nil {
+		return nil, err
+	}
+	q := s.db.Where("webhook_id = ?", uuid.MustParse(webhookID)).Order("created_at DESC, id DESC")
+	if limit > 0 {
+		q = q.Limit(limit)
+	}
+	var rows []webhookDeliveryRow
+	if err := q.Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	items := make([]model.WebhookDelivery, 0, len(rows))
+	for _, r := range rows {
+		items = append(items, r.toModel())
+	}
+	return items, nil
+}
+
+func (s *PostgresStore) RedeliverWebhook(ownerID, webhookID, deliveryID string) (model.WebhookDelivery, error) {
+	if _, err := s.GetWebhook(ownerID, webhookID); err != nil {
+		return model.WebhookDelivery{}, err
+	}
+	did, err := uuid.Parse(deliveryID)
+	if err != nil {
+		return model.WebhookDelivery{}, ErrNotFound
+	}
+	var orig []webhookDeliveryRow
+	if err := s.db.Where("id = ? AND webhook_id = ?", did, uuid.MustParse(webhookID)).Limit(1).Find(&orig).Error; err != nil {
+		return model.WebhookDelivery{}, err
+	}
+	if len(orig) == 0 {
+		return model.WebhookDelivery{}, ErrNotFound
+	}
+	now := time.Now().UTC()
+	r := webhookDeliveryRow{ID: uuid.New(), WebhookID: orig[0].WebhookID, OwnerID: ownerID, EventID: orig[0].EventID, Event: orig[0].Event, Payload: orig[0].Payload, Status: model.DeliveryPending, NextAttemptAt: now, CreatedAt: now}
+	if err := s.db.Create(&r).Error; err != nil {
+		return model.WebhookDelivery{}, err
+	}
+	return r.toModel(), nil
+}
+
+// ClaimWebhookDeliveries leases due rows with SKIP LOCKED, so dispatchers on
+// several instances split the outbox between them instead of waiting.
+func (s *PostgresStore) ClaimWebhookDeliveries(now time.Time, lease time.Duration, limit int) ([]PendingDelivery, error) {
+	var rows []webhookDeliveryRow
+	err := s.db.Raw(`
+		UPDATE webhook_deliveries SET next_attempt_at = ?
+		WHERE id IN (
+			SELECT id FROM webhook_deliveries
+			WHERE status = ? AND next_attempt_at <= ?
+			ORDER BY next_attempt_at
+			LIMIT ?
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING *`, now.Add(lease).UTC(), model.DeliveryPending, now.UTC(), limit).Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, nil
+	}
+
+	hookIDs := make([]uuid.UUID, 0, len(rows))
+	for _, r := range rows {
+		hookIDs = append(hookIDs, r.WebhookID)
+	}
+	var hooks []webhookRow
+	if err := s.db.Where("id IN ?", hookIDs).Find(&hooks).Error; err != nil {
+		return nil, err
+	}
+	byID := make(map[uuid.UUID]webhookRow, len(hooks))
+	for _, h := range hooks {
+		byID[h.ID] = h
+	}
+
+	out := make([]PendingDelivery, 0, len(rows))
+	for _, r := range rows {
+		h := byID[r.WebhookID]
+		out = append(out, PendingDelivery{WebhookDelivery: r.toModel(), URL: h.URL, Secret: h.Secret, Active: h.Active})
+	}
+	return out, nil
+}
+
+func (s *PostgresStore) RecordWebhookAttempt(deliveryID string, attempt WebhookAttempt) error {
+	uid, err := uuid.Parse(deliveryID)
+	if err != nil {
+		return ErrNotFound
+	}
+	res := s.db.Model(&webhookDeliveryRow{}).Where("id = ?", uid).Updates(map[string]any{
+		"attempts":        gorm.Expr("attempts + 1"),
+		"status":          attempt.Status,
+		"response_status": attempt.ResponseStatus,
+		"last_error":      attempt.Error,
+		"last_attempt_at": attempt.At.UTC(),
+		"next_attempt_at": attempt.NextAttemptAt.UTC(),
+	})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
 }
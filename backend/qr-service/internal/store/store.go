@@ -2,35 +2,177 @@ package store
 
 import (
 	"errors"
+	"time"
 
 	"qr-service/internal/model"
 )
 
-var ErrNotFound = errors.New("not found")
+var (
+	ErrNotFound      = errors.New("not found")
+	ErrInvalidCursor = errors.New("invalid cursor")
+	// ErrTagExists means the owner already has a tag with that name.
+	ErrTagExists = errors.New("tag exists")
+	// ErrUnknownTag means a code was given a tag ID the owner does not have.
+	ErrUnknownTag = errors.New("unknown tag")
+)
 
+// Store methods are scoped to an owner: a code that belongs to someone else
+// behaves exactly like one that does not exist.
 type Store interface {
-	List() []model.QrCode
-	Get(id string) (model.QrCode, error)
+	List(ownerID string, query ListQuery) (ListPage, error)
+	Get(ownerID, id string) (model.QrCode, error)
 	Create(input CreateInput) (model.QrCode, error)
-	Update(id string, input UpdateInput) (model.QrCode, error)
-	Delete(id string) error
+	// CreateMany inserts all inputs or none of them.
+	CreateMany(inputs []CreateInput) ([]model.QrCode, error)
+	Update(ownerID, id string, input UpdateInput) (model.QrCode, error)
+	Delete(ownerID, id string) error
+
+	// Resolve looks a code up by ID regardless of owner. It only backs the
+	// public redirect lookup used by click-service.
+	Resolve(id string) (model.QrCode, error)
+	// IncrementClicks adds n to a code's click counter regardless of owner. It
+	// is fed by click-service and only backs sorting by clicks.
+	IncrementClicks(id string, n int64) error
+
+	CountTotal(ownerID string) (int, error)
+	// CountActive counts codes holding an active slot (see model.QrCode.HoldsActiveSlot).
+	CountActive(ownerID string) (int, error)
 
-	CountTotal() (int, error)
-	CountActive() (int, error)
+	// Tags
+	ListTags(ownerID string) ([]model.Tag, error)
+	GetTag(ownerID, id string) (model.Tag, error)
+	CreateTag(ownerID, name string) (model.Tag, error)
+	RenameTag(ownerID, id, name string) (model.Tag, error)
+	// DeleteTag also removes the tag from every code carrying it.
+	DeleteTag(ownerID, id string) error
+	// TagQrCodeIDs lists the codes carrying a tag regardless of owner. It
+	// only backs click-service's per-tag stats.
+	TagQrCodeIDs(tagID string) ([]string, error)
 
 	// Settings
-	GetSettings() (model.UserSettings, error)
-	UpdateSettings(settings model.UserSettings) error
+	GetSettings(ownerID string) (model.UserSettings, error)
+	UpdateSettings(ownerID string, settings model.UserSettings) error
+	// ReportSubscribers returns the settings of every owner with a report
+	// email, keyed by owner. It only backs click-service's weekly reports.
+	ReportSubscribers() (map[string]model.UserSettings, error)
+
+	// Webhooks
+	ListWebhooks(ownerID string) ([]model.Webhook, error)
+	GetWebhook(ownerID, id string) (model.Webhook, error)
+	CreateWebhook(input WebhookInput) (model.Webhook, error)
+	UpdateWebhook(ownerID, id string, input WebhookUpdate) (model.Webhook, error)
+	// DeleteWebhook also drops the webhook's deliveries.
+	DeleteWebhook(ownerID, id string) error
+	// HasWebhook reports whether any of the owner's active webhooks takes event.
+	HasWebhook(ownerID, event string) (bool, error)
+	// EnqueueWebhookEvents queues a pending delivery of each event for each
+	// of its owner's active webhooks subscribed to it, and returns how many.
+	// Either every delivery is queued or none is.
+	EnqueueWebhookEvents(evs ...WebhookEvent) (int, error)
+	// ListWebhookDeliveries returns up to limit of a webhook's deliveries,
+	// newest first.
+	ListWebhookDeliveries(ownerID, webhookID string, limit int) ([]model.WebhookDelivery, error)
+	// RedeliverWebhook queues a new pending copy of one of a webhook's
+	// deliveries, leaving the original's log as it was.
+	RedeliverWebhook(ownerID, webhookID, deliveryID string) (model.WebhookDelivery, error)
+	// ClaimWebhookDeliveries returns up to limit pending deliveries due at now
+	// and pushes their next attempt to now+lease, so no other dispatcher
+	// sends them meanwhile.
+	ClaimWebhookDeliveries(now time.Time, lease time.Duration, limit int) ([]PendingDelivery, error)
+	// RecordWebhookAttempt stores the outcome of one attempt at a delivery.
+	RecordWebhookAttempt(deliveryID string, attempt WebhookAttempt) error
 }
 
 type CreateInput struct {
-	Label  string
-	URL    string
-	Active *bool
+	OwnerID string
+	Label   string
+	URL     string
+	Active  *bool
+	// Style must already be normalized; nil means the default style.
+	Style *model.QrStyle
+	// TagIDs must all be the owner's tags, or the create fails with ErrUnknownTag.
+	TagIDs    []string
+	Window    ActiveWindow
+	ScanLimit ScanLimit
+	// Rules must already be validated.
+	Rules []model.RedirectRule
+	// Variants must already be validated; empty means no split.
+	Variants       []model.Variant
+	StickyVariants bool
+	// TimeZone must already be validated; empty inherits the owner's.
+	TimeZone string
 }
 
 type UpdateInput struct {
 	Label  *string
 	URL    *string
 	Active *bool
+	// Style replaces the whole stored style when set.
+	Style *model.QrStyle
+	// TagIDs replaces the code's whole tag set when set.
+	TagIDs *[]string
+	// Window replaces both schedule bounds when set.
+	Window *ActiveWindow
+	// ScanLimit replaces the whole scan limit when set.
+	ScanLimit *ScanLimit
+	// Rules replaces the whole rule list when set; an empty list clears it.
+	Rules *[]model.RedirectRule
+	// Variants replaces the whole split when set; an empty list turns it off.
+	Variants       *[]model.Variant
+	StickyVariants *bool
+	TimeZone       *string
+}
+
+// ScanLimit caps a code's redirects; a nil MaxScans means unlimited.
+type ScanLimit struct {
+	MaxScans     *int64
+	ExhaustedURL string
+}
+
+// ActiveWindow is a code's optional schedule; nil bounds are open.
+type ActiveWindow struct {
+	From  *time.Time
+	Until *time.Time
+}
+
+type ListSort string
+
+const (
+	SortCreated ListSort = "created"
+	SortLabel   ListSort = "label"
+	SortClicks  ListSort = "clicks"
+)
+
+const (
+	DefaultListLimit = 50
+	MaxListLimit     = 200
+)
+
+// ListQuery selects one page of an owner's codes. Zero values mean "no
+// filter"; the default order is newest first.
+type ListQuery struct {
+	// Limit is the page size, clamped to [1, MaxListLimit]; 0 means DefaultListLimit.
+	Limit int
+	// Cursor is the NextCursor of the previous page. It must be used with the
+	// same sort and filters that produced it.
+	Cursor string
+
+	Active *bool
+	// Search matches label or URL, case-insensitively.
+	Search string
+	// CreatedFrom is inclusive and CreatedTo exclusive.
+	CreatedFrom time.Time
+	CreatedTo   time.Time
+	// TagIDs keeps only codes carrying every one of these tags.
+	TagIDs []string
+
+	Sort ListSort
+	// Asc reverses the default descending order.
+	Asc bool
+}
+
+type ListPage struct {
+	Items []model.QrCode
+	// NextCursor is empty on the last page.
+	NextCursor string
 }
@@ -0,0 +1,19 @@
+package store
+
+import (
+	"slices"
+	"strings"
+)
+
+// normalizeTagIDs trims, de-duplicates and sorts tag IDs so a code's tag set
+// compares and serializes the same however it was written.
+func normalizeTagIDs(ids []string) []string {
+	out := make([]string, 0, len(ids))
+	for _, id := range ids {
+		if id = strings.TrimSpace(id); id != "" {
+			out = append(out, id)
+		}
+	}
+	slices.Sort(out)
+	return slices.Compact(out)
+}
@@ -0,0 +1,53 @@
+package store
+
+import (
+	"time"
+
+	"qr-service/internal/model"
+)
+
+type WebhookInput struct {
+	OwnerID string
+	// URL and Events must already be validated.
+	URL    string
+	Events []string
+	Active bool
+	Secret string
+}
+
+type WebhookUpdate struct {
+	URL *string
+	// Events replaces the whole subscription when set.
+	Events *[]string
+	Active *bool
+}
+
+// WebhookEvent is one event to fan out to an owner's webhooks. Payload is the
+// signed body every delivery sends.
+type WebhookEvent struct {
+	OwnerID string
+	ID      string
+	Event   string
+	Payload []byte
+	At      time.Time
+}
+
+// PendingDelivery is a claimed delivery with what is needed to send it.
+// Active is the webhook's current switch, which may have been turned off
+// since the event was queued.
+type PendingDelivery struct {
+	model.WebhookDelivery
+	URL    string
+	Secret string
+	Active bool
+}
+
+// WebhookAttempt is the outcome of sending a delivery once. Status is the
+// delivery's status afterwards; NextAttemptAt only matters while pending.
+type WebhookAttempt struct {
+	At             time.Time
+	Status         string
+	ResponseStatus int
+	Error          string
+	NextAttemptAt  time.Time
+}
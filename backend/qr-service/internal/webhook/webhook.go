@@ -0,0 +1,190 @@
+// Package webhook sends queued webhook deliveries to owners' endpoints. The
+// outbox lives in the store, so deliveries survive restarts and any number of
+// instances can run a dispatcher.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"qr-service/internal/model"
+	"qr-service/internal/store"
+)
+
+// Headers sent with every delivery. The signature is
+// "t=<unix seconds>,v1=<hex HMAC-SHA256 of "<unix seconds>.<body>">" keyed
+// with the webhook's secret; receivers should also reject old timestamps.
+const (
+	HeaderEvent     = "X-Webhook-Event"
+	HeaderDelivery  = "X-Webhook-Delivery"
+	HeaderSignature = "X-Webhook-Signature"
+)
+
+// maxErrorLength bounds the error kept in the delivery log.
+const maxErrorLength = 300
+
+// Sign returns the signature header value for body sent at t.
+func Sign(secret string, t time.Time, body []byte) string {
+	ts := strconv.FormatInt(t.Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(ts))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "t=" + ts + ",v1=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Dispatcher sends due deliveries from the store. A delivery that fails is
+// retried after Backoff, doubling per attempt up to MaxBackoff, until
+// MaxAttempts have been made.
+type Dispatcher struct {
+	Store  store.Store
+	Client *http.Client
+	// BatchSize bounds how many deliveries one run claims and sends at once.
+	BatchSize   int
+	MaxAttempts int
+	Backoff     time.Duration
+	MaxBackoff  time.Duration
+	// Lease is how long a claimed delivery is hidden from other dispatchers;
+	// it must outlast Client's timeout.
+	Lease time.Duration
+	Now   func() time.Time
+}
+
+func New(st store.Store) *Dispatcher {
+	return &Dispatcher{
+		Store: st,
+		Client: &http.Client{
+			Timeout: 10 * time.Second,
+			// Owners choose the URL; it must not be a way into our network.
+			Transport: publicTransport(),
+			// A redirect is a failed delivery; following it would send the
+			// signed body somewhere the owner never registered.
+			CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+		},
+		BatchSize:   50,
+		MaxAttempts: 8,
+		Backoff:     30 * time.Second,
+		MaxBackoff:  6 * time.Hour,
+		Lease:       2 * time.Minute,
+		Now:         time.Now,
+	}
+}
+
+// Run sends due deliveries every interval until ctx is done. A full batch is
+// followed straight away by the next one.
+func (d *Dispatcher) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		n, err := d.RunOnce(ctx)
+		if err != nil && ctx.Err() == nil {
+			log.Printf("webhook: run failed: %v", err)
+		}
+		if err == nil && n == d.BatchSize {
+			if ctx.Err() != nil {
+				return
+			}
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce claims one batch of due deliveries, sends them concurrently and
+// records each outcome. It returns how many it claimed.
+func (d *Dispatcher) RunOnce(ctx context.Context) (int, error) {
+	now := d.Now()
+	due, err := d.Store.ClaimWebhookDeliveries(now, d.Lease, d.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+	var wg sync.WaitGroup
+	for _, p := range due {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			attempt := d.attempt(ctx, p)
+			if err := d.Store.RecordWebhookAttempt(p.ID, attempt); err != nil {
+				log.Printf("webhook: record failed delivery=%s err=%v", p.ID, err)
+			}
+		}()
+	}
+	wg.Wait()
+	return len(due), nil
+}
+
+// attempt sends p once and decides what happens to it next.
+func (d *Dispatcher) attempt(ctx context.Context, p store.PendingDelivery) store.WebhookAttempt {
+	if !p.Active {
+		return store.WebhookAttempt{At: d.Now(), Status: model.DeliveryFailed, Error: "webhook is inactive"}
+	}
+	status, err := d.send(ctx, p)
+	at := d.Now()
+	if err == nil {
+		return store.WebhookAttempt{At: at, Status: model.DeliverySucceeded, ResponseStatus: status}
+	}
+	out := store.WebhookAttempt{At: at, Status: model.DeliveryPending, ResponseStatus: status, Error: truncate(err.Error(), maxErrorLength)}
+	attempts := p.Attempts + 1
+	if attempts >= d.MaxAttempts {
+		out.Status = model.DeliveryFailed
+		return out
+	}
+	out.NextAttemptAt = at.Add(d.backoff(attempts))
+	return out
+}
+
+// backoff is the wait after the given number of failed attempts.
+func (d *Dispatcher) backoff(attempts int) time.Duration {
+	wait := d.Backoff
+	for i := 1; i < attempts && wait < d.MaxBackoff; i++ {
+		wait *= 2
+	}
+	return min(wait, d.MaxBackoff)
+}
+
+// send posts the delivery and returns the response status. Anything but a
+// 2xx is an error.
+func (d *Dispatcher) send(ctx context.Context, p store.PendingDelivery) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.URL, bytes.NewReader(p.Payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "qr-service-webhooks/1")
+	req.Header.Set(HeaderEvent, p.Event)
+	req.Header.Set(HeaderDelivery, p.ID)
+	req.Header.Set(HeaderSignature, Sign(p.Secret, d.Now(), p.Payload))
+
+	resp, err := d.Client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	// Drain a little so the connection can be reused; the body is not kept.
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n]
+}
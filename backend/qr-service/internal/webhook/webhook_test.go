@@ -0,0 +1,147 @@
+package webhook
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"qr-service/internal/model"
+	"qr-service/internal/store"
+)
+
+func TestDispatcher_SignsAndRetriesWithBackoff(t *testing.T) {
+	var fail atomic.Bool
+	fail.Store(true)
+	var last atomic.Value
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		last.Store([]string{r.Header.Get(HeaderEvent), r.Header.Get(HeaderSignature), string(body)})
+		if fail.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer receiver.Close()
+
+	st := store.NewMemoryStore()
+	hook, _ := st.CreateWebhook(store.WebhookInput{OwnerID: "alice", URL: receiver.URL, Events: []string{model.EventQrCreated}, Active: true, Secret: "whsec_test"})
+	now := time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)
+	payload := []byte(`{"type":"qr.created"}`)
+	if n, _ := st.EnqueueWebhookEvents(store.WebhookEvent{OwnerID: "alice", ID: "ev1", Event: model.EventQrCreated, Payload: payload, At: now}); n != 1 {
+		t.Fatalf("expected one delivery, got %d", n)
+	}
+
+	d := New(st)
+	d.Client = receiver.Client()
+	d.MaxAttempts = 3
+	d.Now = func() time.Time { return now }
+	ctx := context.Background()
+
+	delivery := func() model.WebhookDelivery {
+		items, _ := st.ListWebhookDeliveries("alice", hook.ID, 1)
+		return items[0]
+	}
+
+	// First failure waits Backoff, the second twice that.
+	for i, wait := range []time.Duration{d.Backoff, 2 * d.Backoff} {
+		if n, err := d.RunOnce(ctx); err != nil || n != 1 {
+			t.Fatalf("attempt %d: expected one send, got %d %v", i+1, n, err)
+		}
+		got := delivery()
+		if got.Status != model.DeliveryPending || got.Attempts != i+1 || got.ResponseStatus != http.StatusServiceUnavailable || !got.NextAttemptAt.Equal(now.Add(wait)) {
+			t.Fatalf("attempt %d: unexpected delivery %+v", i+1, got)
+		}
+		if n, _ := d.RunOnce(ctx); n != 0 {
+			t.Fatalf("attempt %d: expected nothing due before the backoff", i+1)
+		}
+		now = now.Add(wait)
+	}
+
+	fail.Store(false)
+	if _, err := d.RunOnce(ctx); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if got := delivery(); got.Status != model.DeliverySucceeded || got.Attempts != 3 {
+		t.Fatalf("expected success on the last attempt, got %+v", got)
+	}
+	sent := last.Load().([]string)
+	if sent[0] != model.EventQrCreated || sent[1] != Sign("whsec_test", now, payload) || sent[2] != string(payload) {
+		t.Fatalf("unexpected request %q", sent)
+	}
+
+	// A delivery that keeps failing gives up after MaxAttempts. Redeliveries
+	// are due from the wall clock.
+	fail.Store(true)
+	again, _ := st.RedeliverWebhook("alice", hook.ID, delivery().ID)
+	now = time.Now()
+	for i := 0; i < d.MaxAttempts; i++ {
+		now = now.Add(d.MaxBackoff)
+		_, _ = d.RunOnce(ctx)
+	}
+	items, _ := st.ListWebhookDeliveries("alice", hook.ID, 0)
+	for _, item := range items {
+		if item.ID != again.ID {
+			continue
+		}
+		if item.Status != model.DeliveryFailed || item.Attempts != d.MaxAttempts {
+			t.Fatalf("expected the redelivery to fail for good, got %+v", item)
+		}
+		return
+	}
+	t.Fatalf("redelivery %s missing from the log", again.ID)
+}
+
+func TestSign_MatchesReceiverCheck(t *testing.T) {
+	at := time.Unix(1760605200, 0)
+	body := []byte(`{"type":"qr.deleted"}`)
+	mac := hmac.New(sha256.New, []byte("whsec_test"))
+	mac.Write([]byte("1760605200." + string(body)))
+	if got, want := Sign("whsec_test", at, body), "t=1760605200,v1="+hex.EncodeToString(mac.Sum(nil)); got != want {
+		t.Fatalf("expected %q, got %q", want, got)
+	}
+}
+
+func TestDispatcher_RefusesNonPublicAddresses(t *testing.T) {
+	var hits atomic.Int64
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer receiver.Close()
+
+	st := store.NewMemoryStore()
+	hook, _ := st.CreateWebhook(store.WebhookInput{OwnerID: "alice", URL: receiver.URL, Events: []string{model.EventQrCreated}, Active: true, Secret: "whsec_test"})
+	now := time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)
+	_, _ = st.EnqueueWebhookEvents(store.WebhookEvent{OwnerID: "alice", ID: "ev1", Event: model.EventQrCreated, Payload: []byte(`{}`), At: now})
+
+	d := New(st)
+	d.Now = func() time.Time { return now }
+	if _, err := d.RunOnce(context.Background()); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	items, _ := st.ListWebhookDeliveries("alice", hook.ID, 1)
+	if hits.Load() != 0 || items[0].Status != model.DeliveryPending || !strings.Contains(items[0].LastError, "non-public address") {
+		t.Fatalf("expected the loopback receiver to be refused, got %d hits and %+v", hits.Load(), items[0])
+	}
+
+	for addr, want := range map[string]bool{
+		"127.0.0.1": false, "10.1.2.3": false, "172.16.0.1": false, "192.168.1.1": false,
+		"169.254.169.254": false, "100.64.0.1": false, "0.0.0.0": false, "::1": false,
+		"fd00::1": false, "fe80::1": false, "::ffff:127.0.0.1": false,
+		"93.184.216.34": true, "2606:4700::1111": true,
+	} {
+		if got := isPublic(netip.MustParseAddr(addr)); got != want {
+			t.Fatalf("isPublic(%s) = %v, want %v", addr, got, want)
+		}
+	}
+}
@@ -13,7 +13,8 @@ import (
 
 	"user-service/internal/cognito"
 	"user-service/internal/httpapi"
-	"user-service/internal/middleware"
+	"user-service/internal/ratelimit"
+	"user-service/internal/store"
 	"user-service/internal/stripe"
 )
 
@@ -26,6 +27,8 @@ func main() {
 	clientID := envOr("COGNITO_CLIENT_ID", "")
 	clientSecret := envOr("COGNITO_CLIENT_SECRET", "")
 	adminKey := envOr("ADMIN_API_KEY", "")
+	internalKey := envOr("INTERNAL_API_KEY", "")
+	databaseURL := envOr("DATABASE_URL", "")
 
 	cookieSecure := envBool("COOKIE_SECURE", false)
 	sameSite := parseSameSite(envOr("COOKIE_SAMESITE", "Lax"))
@@ -49,6 +52,23 @@ func main() {
 		log.Fatalf("aws config error: %v", err)
 	}
 
+	var st store.Store
+	if databaseURL != "" {
+		pg, err := store.NewPostgresStore(ctx, databaseURL)
+		if err != nil {
+			log.Fatalf("postgres init failed: %v", err)
+		}
+		defer func() { _ = pg.Close() }()
+		st = pg
+		log.Printf("user-service using postgres storage")
+	} else {
+		st = store.NewMemoryStore()
+		log.Printf("user-service using in-memory storage for API keys (set DATABASE_URL to persist)")
+	}
+	if internalKey == "" {
+		log.Printf("INTERNAL_API_KEY not set; API keys cannot be used against qr-service or click-service")
+	}
+
 	var stripeClient *stripe.Client
 	if stripeSecretKey != "" && stripeWebhookSecret != "" {
 		stripeClient = stripe.NewClient(stripe.Config{
@@ -65,15 +85,30 @@ func main() {
 		log.Printf("stripe not configured (missing STRIPE_SECRET_KEY or STRIPE_WEBHOOK_SECRET)")
 	}
 
+	limits, err := ratelimit.ParseLimits(envOr("RATE_LIMITS", ""), map[string]ratelimit.Limit{
+		ratelimit.AnonymousPlan: ratelimit.PerMinute(100),
+	})
+	if err != nil {
+		log.Fatalf("RATE_LIMITS: %v", err)
+	}
+	limitStore, err := ratelimit.Open(ctx, envOr("RATE_LIMIT_BACKEND", ""), envOr("REDIS_URL", ""), databaseURL)
+	if err != nil {
+		log.Fatalf("rate limit store init failed: %v", err)
+	}
+	defer func() { _ = limitStore.Close() }()
+
 	router := httpapi.NewRouter(httpapi.Server{
 		Cognito:        awsClient,
 		UserPoolID:     userPoolID,
 		ClientID:       clientID,
 		ClientSecret:   clientSecret,
 		AdminAPIKey:    adminKey,
+		Store:          st,
+		InternalAPIKey: internalKey,
 		CookieSecure:   cookieSecure,
 		CookieSameSite: sameSite,
 		StripeClient:   stripeClient,
+		RateLimit:      &ratelimit.Limiter{Store: limitStore, Name: "user", Limits: limits},
 	})
 
 	// Apply middleware layers (order matters!)
@@ -85,10 +120,6 @@ func main() {
 		AllowCredentials: true,
 	})(handler)
 
-	// 2. Rate limiting (100 requests per minute per IP)
-	rateLimiter := middleware.NewRateLimiter(100, time.Minute)
-	handler = rateLimiter.Middleware(handler)
-
 	srv := &http.Server{
 		Addr:              ":" + port,
 		Handler:           handler,
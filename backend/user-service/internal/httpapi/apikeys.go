@@ -0,0 +1,283 @@
+package httpapi
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cognitoidentityprovider"
+
+	"user-service/internal/model"
+	"user-service/internal/store"
+)
+
+// apiKeyPrefix marks personal API keys so the other services can tell them
+// from Cognito tokens without asking.
+const apiKeyPrefix = "qrk_"
+
+const (
+	maxAPIKeysPerUser = 25
+	maxAPIKeyName     = 100
+)
+
+type createAPIKeyInput struct {
+	Name   string   `json:"name"`
+	Scopes []string `json:"scopes"`
+}
+
+type verifyAPIKeyInput struct {
+	Key string `json:"key"`
+}
+
+// verifiedAPIKey is what qr-service and click-service learn about a valid key.
+type verifiedAPIKey struct {
+	KeyID        string   `json:"keyId"`
+	UserID       string   `json:"userId"`
+	Email        string   `json:"email,omitempty"`
+	Entitlements string   `json:"entitlements"`
+	Scopes       []string `json:"scopes"`
+}
+
+// newAPIKey returns a fresh key and the hash it is stored under.
+func newAPIKey() (key, hash string, err error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	key = apiKeyPrefix + base64.RawURLEncoding.EncodeToString(b)
+	return key, hashAPIKey(key), nil
+}
+
+func hashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// normalizeScopes validates scopes and returns them deduplicated, in the
+// order of model.APIKeyScopes.
+func normalizeScopes(scopes []string) ([]string, string) {
+	if len(scopes) == 0 {
+		return nil, "scopes_required"
+	}
+	want := make(map[string]bool, len(scopes))
+	for _, s := range scopes {
+		s = strings.TrimSpace(strings.ToLower(s))
+		if !slices.Contains(model.APIKeyScopes, s) {
+			return nil, "scope_invalid"
+		}
+		want[s] = true
+	}
+	out := make([]string, 0, len(want))
+	for _, s := range model.APIKeyScopes {
+		if want[s] {
+			out = append(out, s)
+		}
+	}
+	return out, ""
+}
+
+// keyOwner identifies the signed-in caller from their access token cookie.
+// Keys belong to the Cognito sub, the user ID the other services read from
+// tokens; the username is kept to look up entitlements later. API keys
+// cannot manage API keys.
+func (srv Server) keyOwner(ctx context.Context, r *http.Request) (sub, username string, ok bool) {
+	access, _ := readCookie(r, "access_token")
+	if access == "" {
+		return "", "", false
+	}
+	out, err := srv.Cognito.GetUser(ctx, &cognitoidentityprovider.GetUserInput{AccessToken: aws.String(access)})
+	if err != nil {
+		return "", "", false
+	}
+	for _, a := range out.UserAttributes {
+		if aws.ToString(a.Name) == "sub" {
+			sub = aws.ToString(a.Value)
+		}
+	}
+	username = aws.ToString(out.Username)
+	return sub, username, sub != "" && username != ""
+}
+
+func (srv Server) handleAPIKeys(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	ownerID, username, ok := srv.keyOwner(ctx, r)
+	if !ok {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "not_authenticated"})
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		keys, err := srv.Store.ListAPIKeys(ownerID)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "list_failed"})
+			return
+		}
+		for i := range keys {
+			keys[i] = keys[i].NormalizeForResponse()
+		}
+		writeJSON(w, http.StatusOK, keys)
+	case http.MethodPost:
+		var req createAPIKeyInput
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_json"})
+			return
+		}
+		req.Name = strings.TrimSpace(req.Name)
+		if req.Name == "" {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "name_required"})
+			return
+		}
+		if len(req.Name) > maxAPIKeyName {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "name_too_long"})
+			return
+		}
+		scopes, code := normalizeScopes(req.Scopes)
+		if code != "" {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": code})
+			return
+		}
+
+		existing, err := srv.Store.ListAPIKeys(ownerID)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "create_failed"})
+			return
+		}
+		if len(existing) >= maxAPIKeysPerUser {
+			writeJSON(w, http.StatusConflict, map[string]string{"error": "api_key_limit_reached"})
+			return
+		}
+
+		key, hash, err := newAPIKey()
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "create_failed"})
+			return
+		}
+		created, err := srv.Store.CreateAPIKey(model.APIKey{OwnerID: ownerID, Username: username, Name: req.Name, Prefix: key[:len(apiKeyPrefix)+8], Scopes: scopes, Hash: hash})
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "create_failed"})
+			return
+		}
+		created.Key = key
+		writeJSON(w, http.StatusCreated, created.NormalizeForResponse())
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (srv Server) handleAPIKey(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	id := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/api-keys/"), "/")
+	if id == "" {
+		srv.handleAPIKeys(w, r)
+		return
+	}
+	if r.Method != http.MethodDelete {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	ownerID, _, ok := srv.keyOwner(ctx, r)
+	if !ok {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "not_authenticated"})
+		return
+	}
+	if err := srv.Store.DeleteAPIKey(ownerID, id); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": "not_found"})
+			return
+		}
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "delete_failed"})
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// internalCall reports whether r carries the shared internal key. The
+// comparison takes constant time so the key cannot be guessed byte by byte.
+func (srv Server) internalCall(r *http.Request) bool {
+	return srv.InternalAPIKey != "" && subtle.ConstantTimeCompare([]byte(r.Header.Get("X-Internal-Key")), []byte(srv.InternalAPIKey)) == 1
+}
+
+// handleInternalVerifyAPIKey lets qr-service and click-service turn an API
+// key into the owner's identity and the key's scopes. Entitlements are read
+// from Cognito on every call so plan changes apply to existing keys, and a
+// disabled or deleted user's keys stop working.
+func (srv Server) handleInternalVerifyAPIKey(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if !srv.internalCall(r) {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "unauthorized"})
+		return
+	}
+	var req verifyAPIKeyInput
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_json"})
+		return
+	}
+	if !strings.HasPrefix(req.Key, apiKeyPrefix) {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_api_key"})
+		return
+	}
+
+	key, err := srv.Store.GetAPIKeyByHash(hashAPIKey(req.Key))
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_api_key"})
+			return
+		}
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "verify_failed"})
+		return
+	}
+
+	out, err := srv.Cognito.AdminGetUser(ctx, &cognitoidentityprovider.AdminGetUserInput{UserPoolId: aws.String(srv.UserPoolID), Username: aws.String(key.Username)})
+	if err != nil {
+		if smithyErrorCode(err) == "UserNotFoundException" {
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_api_key"})
+			return
+		}
+		writeJSON(w, http.StatusBadGateway, map[string]string{"error": "user_lookup_failed"})
+		return
+	}
+	if !out.Enabled {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_api_key"})
+		return
+	}
+
+	verified := verifiedAPIKey{KeyID: key.ID, UserID: key.OwnerID, Scopes: key.Scopes}
+	var userType string
+	for _, a := range out.UserAttributes {
+		switch aws.ToString(a.Name) {
+		case "email":
+			verified.Email = aws.ToString(a.Value)
+		case cognitoUserTypeAttr:
+			userType = aws.ToString(a.Value)
+		case cognitoEntitlementsAttr:
+			verified.Entitlements = aws.ToString(a.Value)
+		}
+	}
+	if verified.Entitlements == "" {
+		verified.Entitlements = mapUserTypeToEntitlement(userType)
+	}
+	if verified.Scopes == nil {
+		verified.Scopes = []string{}
+	}
+
+	if err := srv.Store.TouchAPIKey(key.ID, time.Now()); err != nil {
+		log.Printf("api key %s: recording last use failed: %v", key.ID, err)
+	}
+	writeJSON(w, http.StatusOK, verified)
+}
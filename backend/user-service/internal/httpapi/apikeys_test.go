@@ -0,0 +1,146 @@
+package httpapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cognitoidentityprovider"
+	"github.com/aws/aws-sdk-go-v2/service/cognitoidentityprovider/types"
+	"github.com/aws/smithy-go"
+
+	"user-service/internal/cognito"
+	"user-service/internal/model"
+	"user-service/internal/store"
+)
+
+// cognitoFake answers GetUser for access tokens named after a username and
+// AdminGetUser for the users it knows.
+type cognitoFake struct {
+	cognito.API
+	users map[string]cognitoUser
+}
+
+type cognitoUser struct {
+	sub, email, entitlements string
+	disabled                 bool
+}
+
+func (c cognitoFake) GetUser(_ context.Context, in *cognitoidentityprovider.GetUserInput, _ ...func(*cognitoidentityprovider.Options)) (*cognitoidentityprovider.GetUserOutput, error) {
+	name := aws.ToString(in.AccessToken)
+	u, ok := c.users[name]
+	if !ok {
+		return nil, &smithy.GenericAPIError{Code: "NotAuthorizedException"}
+	}
+	return &cognitoidentityprovider.GetUserOutput{Username: aws.String(name), UserAttributes: []types.AttributeType{{Name: aws.String("sub"), Value: aws.String(u.sub)}}}, nil
+}
+
+func (c cognitoFake) AdminGetUser(_ context.Context, in *cognitoidentityprovider.AdminGetUserInput, _ ...func(*cognitoidentityprovider.Options)) (*cognitoidentityprovider.AdminGetUserOutput, error) {
+	u, ok := c.users[aws.ToString(in.Username)]
+	if !ok {
+		return nil, &smithy.GenericAPIError{Code: "UserNotFoundException"}
+	}
+	return &cognitoidentityprovider.AdminGetUserOutput{Username: in.Username, Enabled: !u.disabled, UserAttributes: []types.AttributeType{
+		{Name: aws.String("email"), Value: aws.String(u.email)},
+		{Name: aws.String(cognitoEntitlementsAttr), Value: aws.String(u.entitlements)},
+	}}, nil
+}
+
+func doAs(t *testing.T, h http.Handler, method, path, accessToken string, body any) *httptest.ResponseRecorder {
+	t.Helper()
+	var buf bytes.Buffer
+	if body != nil {
+		_ = json.NewEncoder(&buf).Encode(body)
+	}
+	req := httptest.NewRequest(method, path, &buf)
+	req.Header.Set("Content-Type", "application/json")
+	if accessToken != "" {
+		req.AddCookie(&http.Cookie{Name: "access_token", Value: accessToken})
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	return w
+}
+
+func verifyKey(h http.Handler, key string) *httptest.ResponseRecorder {
+	body, _ := json.Marshal(verifyAPIKeyInput{Key: key})
+	req := httptest.NewRequest(http.MethodPost, "/api/internal/api-keys/verify", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Internal-Key", "secret")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	return w
+}
+
+func TestAPIKeys_CreateVerifyRevoke(t *testing.T) {
+	users := map[string]cognitoUser{
+		"alice": {sub: "sub-alice", email: "alice@example.com", entitlements: "basic"},
+		"bob":   {sub: "sub-bob", email: "bob@example.com"},
+	}
+	st := store.NewMemoryStore()
+	r := NewRouter(Server{Cognito: cognitoFake{users: users}, Store: st, InternalAPIKey: "secret"})
+
+	if w := doAs(t, r, http.MethodGet, "/api/api-keys", "", nil); w.Code != http.StatusUnauthorized {
+		t.Fatalf("expected anonymous callers to be refused, got %d", w.Code)
+	}
+	for code, body := range map[string]createAPIKeyInput{
+		"name_required":   {Scopes: []string{"qr:read"}},
+		"scopes_required": {Name: "ci"},
+		"scope_invalid":   {Name: "ci", Scopes: []string{"qr:read", "admin"}},
+	} {
+		if w := doAs(t, r, http.MethodPost, "/api/api-keys", "alice", body); w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), code) {
+			t.Fatalf("expected %s, got %d %s", code, w.Code, w.Body.String())
+		}
+	}
+
+	w := doAs(t, r, http.MethodPost, "/api/api-keys", "alice", createAPIKeyInput{Name: "ci", Scopes: []string{"clicks:read", "QR:READ", "qr:read"}})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected %d, got %d %s", http.StatusCreated, w.Code, w.Body.String())
+	}
+	var created model.APIKey
+	_ = json.NewDecoder(w.Body).Decode(&created)
+	if !strings.HasPrefix(created.Key, apiKeyPrefix) || !strings.HasPrefix(created.Key, created.Prefix) || strings.Join(created.Scopes, ",") != "qr:read,clicks:read" {
+		t.Fatalf("unexpected key %+v", created)
+	}
+	if stored, _ := st.GetAPIKeyByHash(hashAPIKey(created.Key)); stored.Hash == created.Key || stored.OwnerID != "sub-alice" {
+		t.Fatalf("expected the key to be stored hashed for alice's sub, got %+v", stored)
+	}
+
+	w = doAs(t, r, http.MethodGet, "/api/api-keys", "alice", nil)
+	if strings.Contains(w.Body.String(), created.Key) || strings.Contains(w.Body.String(), "lastUsedAtIso") {
+		t.Fatalf("expected an unused key listed without its secret, got %s", w.Body.String())
+	}
+
+	w = verifyKey(r, created.Key)
+	var verified verifiedAPIKey
+	_ = json.NewDecoder(w.Body).Decode(&verified)
+	if w.Code != http.StatusOK || verified.UserID != "sub-alice" || verified.Entitlements != "basic" || verified.KeyID != created.ID || len(verified.Scopes) != 2 {
+		t.Fatalf("unexpected verification %d %+v", w.Code, verified)
+	}
+	if w := doAs(t, r, http.MethodGet, "/api/api-keys", "alice", nil); !strings.Contains(w.Body.String(), "lastUsedAtIso") {
+		t.Fatalf("expected last use to be recorded, got %s", w.Body.String())
+	}
+	if w := verifyKey(r, created.Key+"x"); w.Code != http.StatusUnauthorized {
+		t.Fatalf("expected an unknown key to be refused, got %d", w.Code)
+	}
+
+	if w := doAs(t, r, http.MethodDelete, "/api/api-keys/"+created.ID, "bob", nil); w.Code != http.StatusNotFound {
+		t.Fatalf("expected bob to be unable to revoke alice's key, got %d", w.Code)
+	}
+	users["alice"] = cognitoUser{sub: "sub-alice", disabled: true}
+	if w := verifyKey(r, created.Key); w.Code != http.StatusUnauthorized {
+		t.Fatalf("expected a disabled user's key to be refused, got %d", w.Code)
+	}
+	if w := doAs(t, r, http.MethodDelete, "/api/api-keys/"+created.ID, "alice", nil); w.Code != http.StatusNoContent {
+		t.Fatalf("expected revoke, got %d", w.Code)
+	}
+	users["alice"] = cognitoUser{sub: "sub-alice"}
+	if w := verifyKey(r, created.Key); w.Code != http.StatusUnauthorized {
+		t.Fatalf("expected a revoked key to be refused, got %d", w.Code)
+	}
+}
@@ -20,6 +20,8 @@ import (
 	"user-service/internal/cognito"
 	"user-service/internal/middleware"
 	"user-service/internal/model"
+	"user-service/internal/ratelimit"
+	"user-service/internal/store"
 )
 
 func smithyErrorCode(err error) string {
@@ -76,6 +78,18 @@ type Server struct {
 
 	AdminAPIKey string
 
+	// Store keeps personal API keys. When nil, /api/api-keys is not served.
+	Store store.Store
+	// InternalAPIKey authenticates qr-service and click-service (X-Internal-Key)
+	// when they verify API keys. Empty disables verification.
+	InternalAPIKey string
+
+	// RateLimit meters requests per client IP; sessions are only known after
+	// a Cognito call, too late to be worth metering by user. NewRouter
+	// exempts internal calls when Exempt is unset. When nil nothing is
+	// metered.
+	RateLimit *ratelimit.Limiter
+
 	CookieSecure   bool
 	CookieSameSite http.SameSite
 
@@ -178,8 +192,16 @@ func isAllowedUserType(value string, allowAdmin bool) bool {
 func NewRouter(srv Server) http.Handler {
 	mux := http.NewServeMux()
 
+	if srv.RateLimit != nil && srv.RateLimit.Exempt == nil {
+		limiter := *srv.RateLimit
+		limiter.Exempt = func(r *http.Request) bool {
+			return srv.internalCall(r)
+		}
+		srv.RateLimit = &limiter
+	}
+	exposed := append([]string{"X-Request-Id"}, ratelimit.Headers...)
 	wrap := func(h http.Handler) http.Handler {
-		return middleware.Recoverer(middleware.RequestID(middleware.ExposeResponseHeaders(middleware.EnforceJSONHandler(h))))
+		return middleware.Recoverer(middleware.RequestID(middleware.ExposeResponseHeaders(srv.RateLimit.Middleware(middleware.EnforceJSONHandler(h)), exposed...)))
 	}
 
 	healthHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -252,6 +274,13 @@ func NewRouter(srv Server) http.Handler {
 	mux.Handle("/api/users", wrap(http.HandlerFunc(requireAdmin(srv.AdminAPIKey, adminCollectionHandler))))
 	mux.Handle("/api/users/", wrap(http.HandlerFunc(requireAdmin(srv.AdminAPIKey, adminItemHandler))))
 
+	// Personal API keys
+	if srv.Store != nil {
+		mux.Handle("/api/api-keys", wrap(http.HandlerFunc(srv.handleAPIKeys)))
+		mux.Handle("/api/api-keys/", wrap(http.HandlerFunc(srv.handleAPIKey)))
+		mux.Handle("/api/internal/api-keys/verify", wrap(http.HandlerFunc(srv.handleInternalVerifyAPIKey)))
+	}
+
 	// Stripe routes (if Stripe is configured)
 	if srv.StripeClient != nil {
 		checkoutHandler := http.HandlerFunc(srv.handleCreateCheckoutSession)
@@ -0,0 +1,49 @@
+package model
+
+import "time"
+
+// Scopes an API key can be issued with. Keys only ever narrow what their
+// owner may do; plan limits still come from the owner's entitlements.
+const (
+	ScopeQrRead     = "qr:read"
+	ScopeQrWrite    = "qr:write"
+	ScopeClicksRead = "clicks:read"
+)
+
+var APIKeyScopes = []string{ScopeQrRead, ScopeQrWrite, ScopeClicksRead}
+
+// APIKey is a personal key for scripts and integrations. Only a SHA-256 hash
+// of the key is stored; the key itself is shown once, when it is created.
+type APIKey struct {
+	ID string `json:"id"`
+	// OwnerID is the owner's Cognito sub, the user ID the other services see.
+	OwnerID string `json:"-"`
+	// Username is the owner's Cognito username, used to look up their
+	// current entitlements when the key is used.
+	Username string   `json:"-"`
+	Name     string   `json:"name"`
+	Prefix   string   `json:"prefix"`
+	Scopes   []string `json:"scopes"`
+	Hash     string   `json:"-"`
+
+	CreatedAt     time.Time  `json:"-"`
+	LastUsedAt    *time.Time `json:"-"`
+	CreatedAtIso  string     `json:"createdAtIso,omitempty"`
+	LastUsedAtIso string     `json:"lastUsedAtIso,omitempty"`
+
+	// Key is only set in the response to the create request.
+	Key string `json:"key,omitempty"`
+}
+
+func (k APIKey) NormalizeForResponse() APIKey {
+	if !k.CreatedAt.IsZero() {
+		k.CreatedAtIso = k.CreatedAt.UTC().Format(time.RFC3339)
+	}
+	if k.LastUsedAt != nil {
+		k.LastUsedAtIso = k.LastUsedAt.UTC().Format(time.RFC3339)
+	}
+	if k.Scopes == nil {
+		k.Scopes = []string{}
+	}
+	return k
+}
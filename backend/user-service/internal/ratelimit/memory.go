@@ -0,0 +1,63 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// MemoryStore keeps buckets in process: limits are per instance and reset on
+// restart. It suits local development and single-instance deployments.
+type MemoryStore struct {
+	mu        sync.Mutex
+	buckets   map[string]memoryBucket
+	lastSweep time.Time
+}
+
+type memoryBucket struct {
+	tokens  float64
+	last    time.Time
+	expires time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{buckets: make(map[string]memoryBucket)}
+}
+
+func (s *MemoryStore) Take(_ context.Context, key string, limit Limit, now time.Time) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sweep(now)
+
+	b, ok := s.buckets[key]
+	if !ok {
+		b = memoryBucket{tokens: float64(limit.Burst), last: now}
+	}
+	tokens := refill(b.tokens, b.last, now, limit)
+	allowed := tokens >= 1
+	if allowed {
+		tokens--
+	}
+	if now.After(b.last) {
+		b.last = now
+	}
+	b.tokens = tokens
+	b.expires = now.Add(limit.ttl())
+	s.buckets[key] = b
+	return result(limit, tokens, allowed), nil
+}
+
+// sweep drops buckets that have refilled completely, at most once a minute.
+func (s *MemoryStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < time.Minute {
+		return
+	}
+	s.lastSweep = now
+	for key, b := range s.buckets {
+		if now.After(b.expires) {
+			delete(s.buckets, key)
+		}
+	}
+}
+
+func (s *MemoryStore) Close() error { return nil }
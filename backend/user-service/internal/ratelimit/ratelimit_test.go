@@ -0,0 +1,130 @@
+package ratelimit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestLimiter_PerPlanBucketsAndHeaders(t *testing.T) {
+	now := time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)
+	l := &Limiter{
+		Store:  NewMemoryStore(),
+		Name:   "qr",
+		Limits: map[string]Limit{AnonymousPlan: PerMinute(1), DefaultPlan: PerMinute(2), "basic": PerMinute(3), "admin": {}},
+		Identify: func(r *http.Request) (string, string, bool) {
+			user := r.Header.Get("X-Test-User")
+			return "user:" + user, r.Header.Get("X-Test-Plan"), user != ""
+		},
+		Exempt: func(r *http.Request) bool { return r.Header.Get("X-Internal-Key") == "secret" },
+		now:    func() time.Time { return now },
+	}
+	h := l.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusNoContent) }))
+
+	call := func(user, plan, ip string, header ...string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/qr-codes", nil)
+		req.RemoteAddr = ip + ":4711"
+		req.Header.Set("X-Test-User", user)
+		req.Header.Set("X-Test-Plan", plan)
+		for i := 0; i+1 < len(header); i += 2 {
+			req.Header.Set(header[i], header[i+1])
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		return w
+	}
+	allowed := func(user, plan string, n int) int {
+		got := 0
+		for i := 0; i < n; i++ {
+			if call(user, plan, "198.51.100.1").Code == http.StatusNoContent {
+				got++
+			}
+		}
+		return got
+	}
+
+	if got := allowed("alice", "basic", 5); got != 3 {
+		t.Fatalf("expected the basic plan to allow 3, got %d", got)
+	}
+	if got := allowed("bob", "platinum", 5); got != 2 {
+		t.Fatalf("expected unknown plans to get the default, got %d", got)
+	}
+	if got := allowed("root", "admin", 50); got != 50 {
+		t.Fatalf("expected a zero limit to mean unlimited, got %d", got)
+	}
+
+	if w := call("", "", "203.0.113.9"); w.Code != http.StatusNoContent || w.Header().Get("X-RateLimit-Limit") != "1" || w.Header().Get("X-RateLimit-Remaining") != "0" || w.Header().Get("X-RateLimit-Reset") != "60" {
+		t.Fatalf("unexpected anonymous response %d %v", w.Code, w.Header())
+	}
+	w := call("", "", "203.0.113.9")
+	if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") != "60" || w.Body.String() != "{\"error\":\"rate_limited\"}\n" {
+		t.Fatalf("expected a refused anonymous caller, got %d %v %s", w.Code, w.Header(), w.Body.String())
+	}
+	// Forged X-Forwarded-For entries before the router's do not buy a new bucket.
+	if w := call("", "", "10.0.0.1", "X-Forwarded-For", "192.0.2.1, 203.0.113.9"); w.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected the router-added address to be used, got %d", w.Code)
+	}
+	if w := call("", "", "203.0.113.9", "X-Internal-Key", "secret"); w.Code != http.StatusNoContent || w.Header().Get("X-RateLimit-Limit") != "" {
+		t.Fatalf("expected exempt requests to pass unmetered, got %d %v", w.Code, w.Header())
+	}
+
+	now = now.Add(30 * time.Second)
+	if got := allowed("bob", "free", 2); got != 1 {
+		t.Fatalf("expected half a minute to refill one of two, got %d", got)
+	}
+}
+
+func TestLimiter_BeforeMetersCostlyRequestsPerIP(t *testing.T) {
+	l := &Limiter{
+		Store:  NewMemoryStore(),
+		Name:   "qr",
+		Limits: map[string]Limit{AnonymousPlan: PerMinute(1), DefaultPlan: PerMinute(100)},
+		Exempt: func(r *http.Request) bool { return r.Header.Get("X-Internal-Key") == "secret" },
+	}
+	h := l.Before(func(r *http.Request) bool { return r.Header.Get("X-API-Key") != "" })(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	call := func(ip string, header ...string) int {
+		req := httptest.NewRequest(http.MethodGet, "/api/qr-codes", nil)
+		req.RemoteAddr = ip + ":4711"
+		for i := 0; i+1 < len(header); i += 2 {
+			req.Header.Set(header[i], header[i+1])
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	if got := call("203.0.113.9", "X-API-Key", "qrk_made_up_1"); got != http.StatusNoContent {
+		t.Fatalf("expected the first lookup through, got %d", got)
+	}
+	if got := call("203.0.113.9", "X-API-Key", "qrk_made_up_2"); got != http.StatusTooManyRequests {
+		t.Fatalf("expected a second lookup from the same address to be refused, got %d", got)
+	}
+	if got := call("203.0.113.9"); got != http.StatusNoContent {
+		t.Fatalf("expected cheap requests to pass, got %d", got)
+	}
+	if got := call("198.51.100.1", "X-API-Key", "qrk_made_up_3"); got != http.StatusNoContent {
+		t.Fatalf("expected another address to have its own bucket, got %d", got)
+	}
+	if got := call("203.0.113.9", "X-API-Key", "qrk_made_up_4", "X-Internal-Key", "secret"); got != http.StatusNoContent {
+		t.Fatalf("expected exempt requests to pass, got %d", got)
+	}
+}
+
+func TestParseLimits(t *testing.T) {
+	got, err := ParseLimits(" free=120 , Enterprise=6000", map[string]Limit{AnonymousPlan: PerMinute(60), DefaultPlan: PerMinute(100)})
+	if err != nil || got[AnonymousPlan] != PerMinute(60) || got[DefaultPlan] != PerMinute(120) || got["enterprise"] != PerMinute(6000) {
+		t.Fatalf("unexpected limits %+v %v", got, err)
+	}
+	for _, raw := range []string{"free", "free=0", "free=-5", "=100", "free=unlimted"} {
+		if _, err := ParseLimits(raw, nil); err == nil {
+			t.Fatalf("%q: expected a malformed entry to be rejected", raw)
+		}
+	}
+	got, err = ParseLimits("admin=Unlimited", map[string]Limit{"admin": PerMinute(10)})
+	if err != nil || !got["admin"].unlimited() {
+		t.Fatalf("expected unlimited to lift the limit, got %+v %v", got, err)
+	}
+}
@@ -0,0 +1,79 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisStore keeps buckets in Redis, or anything speaking its protocol, as
+// hashes that expire once full. Each Take is one atomic script call.
+type RedisStore struct {
+	client *redis.Client
+}
+
+// takeScript refills and takes from the bucket in KEYS[1]. ARGV holds the
+// rate per second, the burst, the caller's clock in milliseconds and the
+// bucket's TTL in milliseconds. The clock is the caller's so every store
+// meters the same way; it never moves a bucket's clock backwards.
+var takeScript = redis.NewScript(`
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+local state = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens = tonumber(state[1])
+local ts = tonumber(state[2])
+if tokens == nil or ts == nil then
+	tokens = burst
+	ts = now
+end
+if now > ts then
+	tokens = math.min(burst, tokens + (now - ts) / 1000 * rate)
+	ts = now
+end
+local allowed = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+end
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'ts', tostring(ts))
+redis.call('PEXPIRE', KEYS[1], ARGV[4])
+return {allowed, tostring(tokens)}
+`)
+
+// NewRedisStore connects to a redis:// or rediss:// URL.
+func NewRedisStore(ctx context.Context, redisURL string) (*RedisStore, error) {
+	opts, err := redis.ParseURL(redisURL)
+	if err != nil {
+		return nil, err
+	}
+	client := redis.NewClient(opts)
+	if err := client.Ping(ctx).Err(); err != nil {
+		_ = client.Close()
+		return nil, err
+	}
+	return &RedisStore{client: client}, nil
+}
+
+func (s *RedisStore) Take(ctx context.Context, key string, limit Limit, now time.Time) (Result, error) {
+	args := []any{strconv.FormatFloat(limit.Rate, 'f', -1, 64), limit.Burst, now.UnixMilli(), limit.ttl().Milliseconds()}
+	out, err := takeScript.Run(ctx, s.client, []string{"ratelimit:" + key}, args...).Slice()
+	if err != nil {
+		return Result{}, err
+	}
+	if len(out) != 2 {
+		return Result{}, fmt.Errorf("ratelimit: unexpected script reply %v", out)
+	}
+	allowed, _ := out[0].(int64)
+	raw, _ := out[1].(string)
+	tokens, err := strconv.ParseFloat(raw, 64)
+	if err != nil {
+		return Result{}, fmt.Errorf("ratelimit: unexpected token count %q", raw)
+	}
+	return result(limit, tokens, allowed == 1), nil
+}
+
+func (s *RedisStore) Close() error { return s.client.Close() }
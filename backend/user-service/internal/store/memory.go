@@ -0,0 +1,96 @@
+package store
+
+import (
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"user-service/internal/model"
+)
+
+type MemoryStore struct {
+	mu      sync.RWMutex
+	apiKeys map[string]model.APIKey
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{apiKeys: make(map[string]model.APIKey)}
+}
+
+func (s *MemoryStore) ListAPIKeys(ownerID string) ([]model.APIKey, error) {
+	s.mu.RLock()
+	items := make([]model.APIKey, 0)
+	for _, k := range s.apiKeys {
+		if k.OwnerID == ownerID {
+			items = append(items, copyAPIKey(k))
+		}
+	}
+	s.mu.RUnlock()
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].CreatedAt.Equal(items[j].CreatedAt) {
+			return items[i].ID > items[j].ID
+		}
+		return items[i].CreatedAt.After(items[j].CreatedAt)
+	})
+	return items, nil
+}
+
+func (s *MemoryStore) CreateAPIKey(key model.APIKey) (model.APIKey, error) {
+	key.ID = uuid.NewString()
+	key.CreatedAt = time.Now().UTC()
+	key.LastUsedAt = nil
+	key.Key = ""
+	key.Scopes = slices.Clone(key.Scopes)
+
+	s.mu.Lock()
+	s.apiKeys[key.ID] = key
+	s.mu.Unlock()
+	return copyAPIKey(key), nil
+}
+
+func (s *MemoryStore) DeleteAPIKey(ownerID, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	k, ok := s.apiKeys[id]
+	if !ok || k.OwnerID != ownerID {
+		return ErrNotFound
+	}
+	delete(s.apiKeys, id)
+	return nil
+}
+
+func (s *MemoryStore) GetAPIKeyByHash(hash string) (model.APIKey, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, k := range s.apiKeys {
+		if k.Hash == hash {
+			return copyAPIKey(k), nil
+		}
+	}
+	return model.APIKey{}, ErrNotFound
+}
+
+func (s *MemoryStore) TouchAPIKey(id string, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	k, ok := s.apiKeys[id]
+	if !ok {
+		return ErrNotFound
+	}
+	at = at.UTC()
+	k.LastUsedAt = &at
+	s.apiKeys[id] = k
+	return nil
+}
+
+func copyAPIKey(k model.APIKey) model.APIKey {
+	k.Scopes = slices.Clone(k.Scopes)
+	if k.LastUsedAt != nil {
+		at := *k.LastUsedAt
+		k.LastUsedAt = &at
+	}
+	return k
+}
@@ -0,0 +1,159 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+
+	"user-service/internal/model"
+)
+
+type PostgresStore struct {
+	db *gorm.DB
+}
+
+type apiKeyRow struct {
+	ID         uuid.UUID `gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
+	OwnerID    string    `gorm:"not null;index:api_keys_owner_id_idx"`
+	Username   string    `gorm:"not null"`
+	Name       string    `gorm:"not null;default:''"`
+	Prefix     string    `gorm:"not null"`
+	Scopes     string    `gorm:"type:jsonb;not null;default:'[]'"`
+	Hash       string    `gorm:"not null;uniqueIndex:api_keys_hash_idx"`
+	CreatedAt  time.Time `gorm:"not null"`
+	LastUsedAt *time.Time
+}
+
+func (apiKeyRow) TableName() string { return "api_keys" }
+
+func (r apiKeyRow) toModel() model.APIKey {
+	var scopes []string
+	_ = json.Unmarshal([]byte(r.Scopes), &scopes)
+	k := model.APIKey{ID: r.ID.String(), OwnerID: r.OwnerID, Username: r.Username, Name: r.Name, Prefix: r.Prefix, Scopes: scopes, Hash: r.Hash, CreatedAt: r.CreatedAt.UTC()}
+	if r.LastUsedAt != nil {
+		at := r.LastUsedAt.UTC()
+		k.LastUsedAt = &at
+	}
+	return k
+}
+
+func NewPostgresStore(ctx context.Context, databaseURL string) (*PostgresStore, error) {
+	gdb, err := gorm.Open(postgres.Open(databaseURL), &gorm.Config{})
+	if err != nil {
+		return nil, err
+	}
+
+	sqlDB, err := gdb.DB()
+	if err != nil {
+		return nil, err
+	}
+	sqlDB.SetMaxOpenConns(5)
+	sqlDB.SetMaxIdleConns(2)
+	sqlDB.SetConnMaxLifetime(30 * time.Minute)
+	if err := sqlDB.PingContext(ctx); err != nil {
+		_ = sqlDB.Close()
+		return nil, err
+	}
+
+	s := &PostgresStore{db: gdb}
+	if err := s.ensureSchema(ctx); err != nil {
+		_ = sqlDB.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *PostgresStore) Close() error {
+	if s == nil || s.db == nil {
+		return nil
+	}
+	sqlDB, err := s.db.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.Close()
+}
+
+func (s *PostgresStore) ensureSchema(ctx context.Context) error {
+	db := s.db.WithContext(ctx)
+
+	// Needed for gen_random_uuid().
+	if err := db.Exec(`CREATE EXTENSION IF NOT EXISTS pgcrypto;`).Error; err != nil {
+		return err
+	}
+	return db.AutoMigrate(&apiKeyRow{})
+}
+
+func (s *PostgresStore) ListAPIKeys(ownerID string) ([]model.APIKey, error) {
+	var rows []apiKeyRow
+	if err := s.db.Where("owner_id = ?", ownerID).Order("created_at DESC, id DESC").Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	items := make([]model.APIKey, 0, len(rows))
+	for _, r := range rows {
+		items = append(items, r.toModel())
+	}
+	return items, nil
+}
+
+func (s *PostgresStore) CreateAPIKey(key model.APIKey) (model.APIKey, error) {
+	scopes, err := json.Marshal(key.Scopes)
+	if err != nil {
+		return model.APIKey{}, err
+	}
+	if key.Scopes == nil {
+		scopes = []byte("[]")
+	}
+	r := apiKeyRow{ID: uuid.New(), OwnerID: key.OwnerID, Username: key.Username, Name: key.Name, Prefix: key.Prefix, Scopes: string(scopes), Hash: key.Hash, CreatedAt: time.Now().UTC()}
+	if err := s.db.Create(&r).Error; err != nil {
+		return model.APIKey{}, err
+	}
+	return r.toModel(), nil
+}
+
+func (s *PostgresStore) DeleteAPIKey(ownerID, id string) error {
+	uid, err := uuid.Parse(id)
+	if err != nil {
+		return ErrNotFound
+	}
+	res := s.db.Delete(&apiKeyRow{}, "id = ? AND owner_id = ?", uid, ownerID)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s *PostgresStore) GetAPIKeyByHash(hash string) (model.APIKey, error) {
+	var rows []apiKeyRow
+	if err := s.db.Where("hash = ?", hash).Limit(1).Find(&rows).Error; err != nil {
+		return model.APIKey{}, err
+	}
+	if len(rows) == 0 {
+		return model.APIKey{}, ErrNotFound
+	}
+	return rows[0].toModel(), nil
+}
+
+func (s *PostgresStore) TouchAPIKey(id string, at time.Time) error {
+	uid, err := uuid.Parse(id)
+	if err != nil {
+		return ErrNotFound
+	}
+	// Callers cache verified keys, so this runs at most once per key and
+	// cache period rather than on every request.
+	res := s.db.Model(&apiKeyRow{}).Where("id = ?", uid).Update("last_used_at", at.UTC())
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
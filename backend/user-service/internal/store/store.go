@@ -0,0 +1,22 @@
+package store
+
+import (
+	"errors"
+	"time"
+
+	"user-service/internal/model"
+)
+
+var ErrNotFound = errors.New("not found")
+
+// Store keeps the data user-service owns itself; users live in Cognito.
+type Store interface {
+	// ListAPIKeys returns the owner's keys, newest first.
+	ListAPIKeys(ownerID string) ([]model.APIKey, error)
+	// CreateAPIKey saves key, assigning its ID and creation time.
+	CreateAPIKey(key model.APIKey) (model.APIKey, error)
+	DeleteAPIKey(ownerID, id string) error
+	GetAPIKeyByHash(hash string) (model.APIKey, error)
+	// TouchAPIKey records that the key was used at at.
+	TouchAPIKey(id string, at time.Time) error
+}
@@ -0,0 +1,26 @@
+import { requestJson } from '../http'
+import type { ApiKey, CreateApiKeyInput } from './apiKeys.types'
+
+export const apiKeysApi = {
+  list(): Promise<ApiKey[]> {
+    return requestJson<ApiKey[]>({
+      method: 'GET',
+      path: '/api/api-keys',
+    })
+  },
+
+  create(input: CreateApiKeyInput): Promise<ApiKey> {
+    return requestJson<ApiKey>({
+      method: 'POST',
+      path: '/api/api-keys',
+      body: input,
+    })
+  },
+
+  revoke(id: string): Promise<void> {
+    return requestJson<void>({
+      method: 'DELETE',
+      path: `/api/api-keys/${encodeURIComponent(id)}`,
+    })
+  },
+}
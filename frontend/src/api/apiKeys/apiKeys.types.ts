@@ -0,0 +1,18 @@
+export type ApiKeyScope = 'qr:read' | 'qr:write' | 'clicks:read'
+
+export type ApiKey = {
+  id: string
+  name: string
+  // First characters of the key, to tell keys apart.
+  prefix: string
+  scopes: ApiKeyScope[]
+  createdAtIso: string
+  lastUsedAtIso?: string
+  // Only returned when the key is created.
+  key?: string
+}
+
+export type CreateApiKeyInput = {
+  name: string
+  scopes: ApiKeyScope[]
+}
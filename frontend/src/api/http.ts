@@ -40,6 +40,14 @@ function buildUrl(baseUrl: string, path: string, query?: RequestJsonOptions['que
 }
 
 export async function requestJson<T>(options: RequestJsonOptions): Promise<T> {
+  const { payload } = await requestJsonWithHeaders<T>(options)
+  return payload
+}
+
+// Like requestJson, but also returns the response headers (e.g. pagination cursors).
+export async function requestJsonWithHeaders<T>(
+  options: RequestJsonOptions,
+): Promise<{ payload: T; headers: Headers }> {
   const baseUrl = options.baseUrl ?? API_BASE_URL
   const url = buildUrl(baseUrl, options.path, options.query)
 
@@ -72,5 +80,5 @@ export async function requestJson<T>(options: RequestJsonOptions): Promise<T> {
     throw new ApiError(message, response.status, payload)
   }
 
-  return payload as T
+  return { payload: payload as T, headers: response.headers }
 }
@@ -5,7 +5,13 @@ export type { AdminUser, UpdateUserRequest } from './admin/admin.api'
 export { qrCodesApi } from './qrCodes/qrCodes.api'
 export type { QrCode, CreateQrCodeInput, UpdateQrCodeInput } from './qrCodes/qrCodes.types'
 export { settingsApi } from './settings/settings.api'
+export { tagsApi } from './tags/tags.api'
+export type { Tag } from './tags/tags.types'
 export type { UserSettings } from './settings/settings.types'
+export { webhooksApi } from './webhooks/webhooks.api'
+export type { Webhook, WebhookEvent, WebhookDelivery, CreateWebhookInput, UpdateWebhookInput } from './webhooks/webhooks.types'
+export { apiKeysApi } from './apiKeys/apiKeys.api'
+export type { ApiKey, ApiKeyScope, CreateApiKeyInput } from './apiKeys/apiKeys.types'
 export { usersApi } from './users/users.api'
 export type {
 	User,
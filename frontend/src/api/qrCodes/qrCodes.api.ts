@@ -1,58 +1,79 @@
-import { requestJson } from '../http'
+import { requestJson, requestJsonWithHeaders } from '../http'
 import { QR_API_BASE_URL } from '../config'
 import type { CreateQrCodeInput, QrCode, UpdateQrCodeInput } from './qrCodes.types'
 
 export type ListQrCodesParams = {
   limit?: number
   cursor?: string
+  active?: boolean
+  q?: string
+  createdFrom?: string
+  createdTo?: string
+  // Comma-separated tag IDs; codes must carry all of them.
+  tag?: string
+  sort?: 'created' | 'label' | 'clicks'
+  order?: 'asc' | 'desc'
+}
+
+export type QrCodePage = {
+  items: QrCode[]
+  nextCursor: string | null
 }
 
 export const qrCodesApi = {
-  list(params?: ListQrCodesParams, userType?: string): Promise<QrCode[]> {
-    return requestJson<QrCode[]>({
+  async listPage(params?: ListQrCodesParams): Promise<QrCodePage> {
+    const { payload, headers } = await requestJsonWithHeaders<QrCode[]>({
       baseUrl: QR_API_BASE_URL,
       method: 'GET',
       path: '/api/qr-codes',
-      query: params ? { limit: params.limit, cursor: params.cursor } : undefined,
-      headers: userType ? { 'X-User-Type': userType } : undefined,
+      query: params ? { ...params } : undefined,
     })
+    return { items: payload ?? [], nextCursor: headers.get('X-Next-Cursor') || null }
+  },
+
+  // list follows the pagination cursor until every matching code is loaded.
+  async list(params?: Omit<ListQrCodesParams, 'cursor'>): Promise<QrCode[]> {
+    const items: QrCode[] = []
+    let cursor: string | undefined
+    do {
+      const page = await qrCodesApi.listPage({ limit: 200, ...params, cursor })
+      items.push(...page.items)
+      cursor = page.nextCursor ?? undefined
+    } while (cursor)
+    return items
   },
 
-  getById(id: string, userType?: string): Promise<QrCode> {
+  getById(id: string): Promise<QrCode> {
     return requestJson<QrCode>({
       baseUrl: QR_API_BASE_URL,
       method: 'GET',
       path: `/api/qr-codes/${encodeURIComponent(id)}`,
-      headers: userType ? { 'X-User-Type': userType } : undefined,
     })
   },
 
-  create(input: CreateQrCodeInput, userType?: string): Promise<QrCode> {
+  create(input: CreateQrCodeInput): Promise<QrCode> {
     return requestJson<QrCode>({
       baseUrl: QR_API_BASE_URL,
       method: 'POST',
       path: '/api/qr-codes',
       body: input,
-      headers: userType ? { 'X-User-Type': userType } : undefined,
     })
   },
 
-  update(id: string, patch: UpdateQrCodeInput, userType?: string): Promise<QrCode> {
+  update(id: string, patch: UpdateQrCodeInput): Promise<QrCode> {
     return requestJson<QrCode>({
       baseUrl: QR_API_BASE_URL,
       method: 'PATCH',
       path: `/api/qr-codes/${encodeURIComponent(id)}`,
       body: patch,
-      headers: userType ? { 'X-User-Type': userType } : undefined,
     })
   },
 
-  delete(id: string, userType?: string): Promise<void> {
+  delete(id: string): Promise<void> {
     return requestJson<void>({
       baseUrl: QR_API_BASE_URL,
       method: 'DELETE',
       path: `/api/qr-codes/${encodeURIComponent(id)}`,
-      headers: userType ? { 'X-User-Type': userType } : undefined,
     })
   },
 }
@@ -1,8 +1,40 @@
+export type RedirectPlatform = 'ios' | 'android' | 'desktop'
+
+// Rules are tried in order; the first whose set conditions all match wins.
+export type RedirectRule = {
+  platforms?: RedirectPlatform[]
+  languages?: string[]
+  countries?: string[]
+  weekdays?: ('sun' | 'mon' | 'tue' | 'wed' | 'thu' | 'fri' | 'sat')[]
+  timeFrom?: string
+  timeUntil?: string
+  timeZone?: string
+  url: string
+}
+
+// One weighted target of an A/B split; id keys the per-variant analytics.
+export type QrVariant = {
+  id: string
+  url: string
+  weight: number
+}
+
 export type QrCode = {
   id: string
   label: string
   url: string
   active: boolean
+  tagIds?: string[]
+  activeFrom?: string
+  activeUntil?: string
+  activeNow?: boolean
+  maxScans?: number
+  exhaustedUrl?: string
+  rules?: RedirectRule[]
+  variants?: QrVariant[]
+  stickyVariants?: boolean
+  // IANA zone click analytics are reported in; empty inherits the user's.
+  timeZone?: string
   createdAtIso: string
   qrDataUrl?: string
 }
@@ -11,10 +43,33 @@ export type CreateQrCodeInput = {
   label: string
   url: string
   active?: boolean
+  tagIds?: string[]
+  activeFrom?: string
+  activeUntil?: string
+  maxScans?: number
+  exhaustedUrl?: string
+  rules?: RedirectRule[]
+  variants?: QrVariant[]
+  stickyVariants?: boolean
+  timeZone?: string
 }
 
 export type UpdateQrCodeInput = {
   label?: string
   url?: string
   active?: boolean
+  tagIds?: string[]
+  // null clears a schedule bound.
+  activeFrom?: string | null
+  activeUntil?: string | null
+  // null removes the scan limit.
+  maxScans?: number | null
+  exhaustedUrl?: string
+  // Replaces the whole list; [] removes all rules.
+  rules?: RedirectRule[]
+  // Replaces the whole split; [] turns it off.
+  variants?: QrVariant[]
+  stickyVariants?: boolean
+  // '' makes the code inherit the user's zone again.
+  timeZone?: string
 }
@@ -1,3 +1,7 @@
 export type UserSettings = {
   defaultRedirectUrl: string
+  // IANA zone for analytics of codes without their own.
+  timeZone?: string
+  // Receives a weekly click summary; empty turns it off.
+  reportEmail?: string
 }
@@ -0,0 +1,39 @@
+import { requestJson } from '../http'
+import { QR_API_BASE_URL } from '../config'
+import type { Tag } from './tags.types'
+
+export const tagsApi = {
+  list(): Promise<Tag[]> {
+    return requestJson<Tag[]>({
+      baseUrl: QR_API_BASE_URL,
+      method: 'GET',
+      path: '/api/tags',
+    })
+  },
+
+  create(name: string): Promise<Tag> {
+    return requestJson<Tag>({
+      baseUrl: QR_API_BASE_URL,
+      method: 'POST',
+      path: '/api/tags',
+      body: { name },
+    })
+  },
+
+  rename(id: string, name: string): Promise<Tag> {
+    return requestJson<Tag>({
+      baseUrl: QR_API_BASE_URL,
+      method: 'PATCH',
+      path: `/api/tags/${encodeURIComponent(id)}`,
+      body: { name },
+    })
+  },
+
+  delete(id: string): Promise<void> {
+    return requestJson<void>({
+      baseUrl: QR_API_BASE_URL,
+      method: 'DELETE',
+      path: `/api/tags/${encodeURIComponent(id)}`,
+    })
+  },
+}
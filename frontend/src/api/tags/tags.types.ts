@@ -0,0 +1,6 @@
+export type Tag = {
+  id: string
+  name: string
+  qrCodeCount: number
+  createdAtIso: string
+}
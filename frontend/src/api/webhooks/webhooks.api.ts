@@ -0,0 +1,55 @@
+import { requestJson } from '../http'
+import { QR_API_BASE_URL } from '../config'
+import type { CreateWebhookInput, UpdateWebhookInput, Webhook, WebhookDelivery } from './webhooks.types'
+
+export const webhooksApi = {
+  list(): Promise<Webhook[]> {
+    return requestJson<Webhook[]>({
+      baseUrl: QR_API_BASE_URL,
+      method: 'GET',
+      path: '/api/webhooks',
+    })
+  },
+
+  create(input: CreateWebhookInput): Promise<Webhook> {
+    return requestJson<Webhook>({
+      baseUrl: QR_API_BASE_URL,
+      method: 'POST',
+      path: '/api/webhooks',
+      body: input,
+    })
+  },
+
+  update(id: string, input: UpdateWebhookInput): Promise<Webhook> {
+    return requestJson<Webhook>({
+      baseUrl: QR_API_BASE_URL,
+      method: 'PATCH',
+      path: `/api/webhooks/${encodeURIComponent(id)}`,
+      body: input,
+    })
+  },
+
+  delete(id: string): Promise<void> {
+    return requestJson<void>({
+      baseUrl: QR_API_BASE_URL,
+      method: 'DELETE',
+      path: `/api/webhooks/${encodeURIComponent(id)}`,
+    })
+  },
+
+  deliveries(id: string): Promise<WebhookDelivery[]> {
+    return requestJson<WebhookDelivery[]>({
+      baseUrl: QR_API_BASE_URL,
+      method: 'GET',
+      path: `/api/webhooks/${encodeURIComponent(id)}/deliveries`,
+    })
+  },
+
+  redeliver(id: string, deliveryId: string): Promise<WebhookDelivery> {
+    return requestJson<WebhookDelivery>({
+      baseUrl: QR_API_BASE_URL,
+      method: 'POST',
+      path: `/api/webhooks/${encodeURIComponent(id)}/deliveries/${encodeURIComponent(deliveryId)}/redeliver`,
+    })
+  },
+}
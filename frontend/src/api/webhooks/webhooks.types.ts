@@ -0,0 +1,34 @@
+export type WebhookEvent = 'qr.created' | 'qr.updated' | 'qr.deleted' | 'qr.scanned'
+
+export type Webhook = {
+  id: string
+  url: string
+  events: WebhookEvent[]
+  active: boolean
+  // Only returned when the webhook is created.
+  secret?: string
+  createdAtIso: string
+}
+
+export type CreateWebhookInput = {
+  url: string
+  events: WebhookEvent[]
+  active?: boolean
+}
+
+export type UpdateWebhookInput = Partial<CreateWebhookInput>
+
+export type WebhookDelivery = {
+  id: string
+  webhookId: string
+  eventId: string
+  event: WebhookEvent
+  payload: unknown
+  status: 'pending' | 'succeeded' | 'failed'
+  attempts: number
+  responseStatus?: number
+  lastError?: string
+  createdAtIso: string
+  lastAttemptAtIso?: string
+  nextAttemptAtIso?: string
+}
@@ -70,7 +70,7 @@ export function useQrCodes() {
 
   const hasQrCodes = computed(() => qrCodes.value.length > 0)
 
-  const { userType, isAuthed } = useUser()
+  const { isAuthed } = useUser()
 
   async function hydrateQrDataUrls(items: { id: string; url: string }[]): Promise<Record<string, string>> {
     const out: Record<string, string> = {}
@@ -140,7 +140,7 @@ export function useQrCodes() {
 
     isCreating.value = true
     try {
-      const created = await qrCodesApi.create({ label, url, active: true }, userType.value)
+      const created = await qrCodesApi.create({ label, url, active: true })
       const qrDataUrl = await generateQrDataUrl(trackingUrlForQrId(created.id))
       const item: QrCodeItem = {
         id: created.id,
@@ -165,7 +165,7 @@ export function useQrCodes() {
     if (!isAuthed.value) return
     errorMessage.value = null
     try {
-      await qrCodesApi.delete(id, userType.value)
+      await qrCodesApi.delete(id)
       qrCodes.value = qrCodes.value.filter((q) => q.id !== id)
     } catch (err) {
       errorMessage.value = qrCodesErrorMessage(err)
@@ -188,7 +188,7 @@ export function useQrCodes() {
 
     updatingId.value = id
     try {
-      const updated = await qrCodesApi.update(id, patch, userType.value)
+      const updated = await qrCodesApi.update(id, patch)
       const nextQrDataUrl = current.qrDataUrl
 
       qrCodes.value = qrCodes.value.map((q) =>
@@ -220,7 +220,7 @@ export function useQrCodes() {
 
     updatingId.value = id
     try {
-      const updated = await qrCodesApi.update(id, { active }, userType.value)
+      const updated = await qrCodesApi.update(id, { active })
       qrCodes.value = qrCodes.value.map((q) => (q.id === id ? { ...q, active: updated.active } : q))
     } catch (err) {
       errorMessage.value = qrCodesErrorMessage(err)